@@ -15,6 +15,10 @@ vi.mock('./pages/ActivatePage', () => ({
   default: () => <h1>Activate Page</h1>,
 }))
 
+vi.mock('./pages/UnsubscribePage', () => ({
+  default: () => <h1>Unsubscribe Page</h1>,
+}))
+
 vi.mock('./pages/LandingPage', () => ({
   default: () => <h1>Landing Page</h1>,
 }))
@@ -44,11 +48,12 @@ describe('AppRouter route access', () => {
     vi.unstubAllGlobals()
   })
 
-  it('allows anonymous users to access landing, auth, and activate routes', async () => {
+  it('allows anonymous users to access landing, auth, activate, and unsubscribe routes', async () => {
     const cases = [
       { path: '/', expected: 'Landing Page' },
       { path: '/auth', expected: 'Auth Page' },
       { path: '/activate', expected: 'Activate Page' },
+      { path: '/unsubscribe?token=t', expected: 'Unsubscribe Page' },
     ]
 
     for (const testCase of cases) {
@@ -94,6 +99,7 @@ describe('AppRouter route access', () => {
       { path: '/timespending', expected: 'Timespending Page' },
       { path: '/energy/levels', expected: 'Energy Levels Chart Page' },
       { path: '/energy/levels/edit', expected: 'Energy Levels Page' },
+      { path: '/unsubscribe?token=t', expected: 'Unsubscribe Page' },
     ]
 
     for (const testCase of protectedCases) {
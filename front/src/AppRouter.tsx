@@ -8,6 +8,7 @@ import AuthPage from './pages/AuthPage'
 import EnergyLevelsEditPage from './pages/EnergyLevelsEditPage'
 import EnergyLevelsPage from './pages/EnergyLevelsPage'
 import LandingPage from './pages/LandingPage'
+import UnsubscribePage from './pages/UnsubscribePage'
 
 export default function AppRouter() {
   return (
@@ -54,6 +55,8 @@ export default function AppRouter() {
             </AnonymousOnlyRoute>
           }
         />
+        {/* Reached from email links, signed in or not */}
+        <Route path="/unsubscribe" element={<UnsubscribePage />} />
         <Route path="*" element={<Navigate to="/" replace />} />
       </Route>
     </Routes>
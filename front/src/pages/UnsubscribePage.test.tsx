@@ -0,0 +1,44 @@
+import { fireEvent, render, screen } from '@testing-library/react'
+import { MemoryRouter } from 'react-router-dom'
+import UnsubscribePage from './UnsubscribePage'
+
+function renderAt(path: string) {
+  render(
+    <MemoryRouter initialEntries={[path]}>
+      <UnsubscribePage />
+    </MemoryRouter>,
+  )
+}
+
+describe('UnsubscribePage', () => {
+  afterEach(() => {
+    vi.unstubAllGlobals()
+  })
+
+  it('unsubscribes only once the recipient confirms', async () => {
+    const fetchMock = vi.fn().mockResolvedValue({
+      ok: true,
+      status: 200,
+      json: async () => ({ message: 'You have been unsubscribed.' }),
+    })
+    vi.stubGlobal('fetch', fetchMock)
+
+    renderAt('/unsubscribe?token=signed%2Btoken')
+    expect(fetchMock).not.toHaveBeenCalled()
+
+    fireEvent.click(screen.getByRole('button', { name: 'Unsubscribe' }))
+
+    expect(await screen.findByText('You have been unsubscribed.')).toBeInTheDocument()
+    expect(fetchMock).toHaveBeenCalledWith(
+      expect.stringContaining('/users/unsubscribe?token=signed%2Btoken'),
+      expect.objectContaining({ method: 'POST' }),
+    )
+  })
+
+  it('reports links without a token as invalid', () => {
+    renderAt('/unsubscribe')
+
+    expect(screen.getByText('This unsubscribe link is invalid.')).toBeInTheDocument()
+    expect(screen.queryByRole('button')).not.toBeInTheDocument()
+  })
+})
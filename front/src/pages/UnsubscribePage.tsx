@@ -0,0 +1,68 @@
+import { useState } from 'react'
+import { useSearchParams } from 'react-router-dom'
+import { unsubscribe } from '@/services/auth'
+import '../styles/auth.css'
+
+type Status = 'idle' | 'loading' | 'success' | 'error'
+
+// Unsubscribing waits for a click: mail scanners open the links of an email,
+// and must not unsubscribe its recipient by doing so.
+export default function UnsubscribePage() {
+  const [searchParams] = useSearchParams()
+  const token = searchParams.get('token')
+
+  const [status, setStatus] = useState<Status>(token ? 'idle' : 'error')
+
+  const confirm = async () => {
+    if (!token) return
+    setStatus('loading')
+    const result = await unsubscribe(token)
+    setStatus(result.ok ? 'success' : 'error')
+  }
+
+  return (
+    <div className="app">
+      <div className="ambient-glow ambient-glow-1" />
+      <div className="ambient-glow ambient-glow-2" />
+      <div className="grain-overlay" />
+
+      <main className="activate-content">
+        <div className="activate-card">
+          <h1 className="auth-headline">Email Preferences</h1>
+
+          <div className="activate-status" role="status" aria-live="polite">
+            {(status === 'idle' || status === 'loading') && (
+              <>
+                <p className="auth-card-description">
+                  Stop receiving these emails from Energy Journal?
+                </p>
+                <button
+                  type="button"
+                  className="auth-btn auth-btn-login"
+                  onClick={confirm}
+                  disabled={status === 'loading'}
+                >
+                  {status === 'loading' ? 'Unsubscribing…' : 'Unsubscribe'}
+                </button>
+              </>
+            )}
+
+            {status === 'success' && (
+              <div className="auth-feedback auth-feedback-success" style={{ display: 'inline-block' }}>
+                You have been unsubscribed.
+              </div>
+            )}
+
+            {status === 'error' && (
+              <div className="auth-feedback auth-feedback-error" style={{ display: 'inline-block' }}>
+                {!token
+                  ? 'This unsubscribe link is invalid.'
+                  : 'Unable to unsubscribe. The link may be invalid.'}
+              </div>
+            )}
+          </div>
+        </div>
+      </main>
+    </div>
+  )
+}
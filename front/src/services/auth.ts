@@ -39,6 +39,10 @@ export interface ActivationResponse {
   message: string
 }
 
+export interface UnsubscribeResponse {
+  message: string
+}
+
 // ── Normalized result ──────────────────────────────────────────────────────
 
 export type ApiResult<T> =
@@ -96,6 +100,15 @@ export function activateUser(
   )
 }
 
+export function unsubscribe(
+  token: string,
+): Promise<ApiResult<UnsubscribeResponse>> {
+  return request<UnsubscribeResponse>(
+    `/users/unsubscribe?token=${encodeURIComponent(token)}`,
+    { method: 'POST' },
+  )
+}
+
 export function refreshTokens(
   body: RefreshRequest,
 ): Promise<ApiResult<AuthTokensResponse>> {
@@ -1,13 +1,14 @@
 package main
 
 import (
-	"errors"
-	"log"
-	"net/http"
+	"context"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
-	"energyjournal/internal/server"
+	"energyjournal/internal/bootstrap"
 )
 
 // @title Energy Journal API
@@ -18,12 +19,26 @@ import (
 // @in header
 // @name Authorization
 func main() {
-	logrus.SetLevel(logrus.DebugLevel)
-	// Wire real services into the server here once implementations exist.
-	srv := server.New(":8888")
-	log.Printf("HTTP server listening on %s", srv.Addr)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
 
-	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-		log.Fatalf("server failed: %v", err)
+	cfg, shutdownTelemetry, err := bootstrap.Init(ctx, bootstrap.Container)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to initialize")
 	}
+	app, err := bootstrap.New(ctx, cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to start")
+	}
+	runErr := app.Run(ctx)
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTelemetry(flushCtx); err != nil {
+		logrus.WithError(err).Error("Failed to flush telemetry")
+	}
+	if runErr != nil {
+		logrus.WithError(runErr).Fatal("Server failed")
+	}
+	logrus.Info("Server stopped")
 }
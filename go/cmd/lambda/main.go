@@ -2,13 +2,14 @@ package main
 
 import (
 	"context"
-	"log"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/awslabs/aws-lambda-go-api-proxy/httpadapter"
+	"github.com/sirupsen/logrus"
 
-	"energyjournal/internal/server"
+	"energyjournal/internal/bootstrap"
+	"energyjournal/internal/pkg/telemetry"
 )
 
 // lambdaHandler adapts the existing HTTP server to the AWS Lambda invocation model.
@@ -16,20 +17,37 @@ type lambdaHandler struct {
 	adapter *httpadapter.HandlerAdapter
 }
 
-func newLambdaHandler() *lambdaHandler {
-	srv := server.New(":0")
+func newLambdaHandler(ctx context.Context) (*lambdaHandler, error) {
+	// Telemetry is flushed after each invocation rather than on shutdown.
+	cfg, _, err := bootstrap.Init(ctx, bootstrap.Lambda)
+	if err != nil {
+		return nil, err
+	}
+	// Jobs are not started: without a long-running process they are triggered
+	// over HTTP by an external scheduler.
+	app, err := bootstrap.New(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
 
 	return &lambdaHandler{
-		adapter: httpadapter.New(srv.Handler),
-	}
+		adapter: httpadapter.New(app.Server.Handler),
+	}, nil
 }
 
 func (h *lambdaHandler) Handle(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	return h.adapter.ProxyWithContext(ctx, req)
+	resp, err := h.adapter.ProxyWithContext(ctx, req)
+	if flushErr := telemetry.Flush(ctx); flushErr != nil {
+		logrus.WithError(flushErr).Error("Failed to flush telemetry")
+	}
+	return resp, err
 }
 
 func main() {
-	handler := newLambdaHandler()
-	log.Printf("Lambda HTTP adapter initialized")
+	handler, err := newLambdaHandler(context.Background())
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to initialize")
+	}
+	logrus.Info("Lambda HTTP adapter initialized")
 	lambda.Start(handler.Handle)
 }
@@ -25,7 +25,15 @@ const docTemplate = `{
                 "tags": [
                     "calendar"
                 ],
-                "summary": "Build Google OAuth authorization URL",
+                "summary": "Build calendar OAuth authorization URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Calendar provider: google (default) or microsoft",
+                        "name": "provider",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -33,6 +41,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/calendar.AuthURLResponse"
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.ErrorResponse"
+                        }
+                    },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
@@ -50,11 +64,11 @@ const docTemplate = `{
         },
         "/calendar/auth/callback": {
             "get": {
-                "description": "Receives the authorization code from Google, validates state and stores tokens.",
+                "description": "Receives the authorization code from the calendar provider, validates state and stores tokens.",
                 "tags": [
                     "calendar"
                 ],
-                "summary": "Google OAuth callback",
+                "summary": "Calendar OAuth callback",
                 "parameters": [
                     {
                         "type": "string",
@@ -103,16 +117,384 @@ const docTemplate = `{
                 "tags": [
                     "calendar"
                 ],
-                "summary": "List user's Google Calendars",
+                "summary": "List user's Google Calendars",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/calendar.CalendarItem"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.ErrorResponse"
+                        }
+                    },
+                    "424": {
+                        "description": "Failed Dependency",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/calendar/categories": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the user's category rules in evaluation order (ascending priority).",
+                "tags": [
+                    "calendar"
+                ],
+                "summary": "List spending category rules",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/calendar.CategoryRuleResponse"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Events matching the rule (by color ID, calendar ID, title keyword or title regex) are counted under its category.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "calendar"
+                ],
+                "summary": "Create a spending category rule",
+                "parameters": [
+                    {
+                        "description": "Category rule",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/calendar.CategoryRuleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.CategoryRuleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/calendar/categories/preview": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Categorizes every event of the week starting at week_start in the user's timezone (default: this week's Monday). Draft rules, when given, replace the stored rules for the preview only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "calendar"
+                ],
+                "summary": "Preview how a week would be categorized",
+                "parameters": [
+                    {
+                        "description": "Week and optional draft rules",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/calendar.PreviewCategoriesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.CategoryPreviewResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.ErrorResponse"
+                        }
+                    },
+                    "424": {
+                        "description": "Failed Dependency",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/calendar/categories/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "calendar"
+                ],
+                "summary": "Update a spending category rule",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Rule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Category rule",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/calendar.CategoryRuleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.CategoryRuleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "tags": [
+                    "calendar"
+                ],
+                "summary": "Delete a spending category rule",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Rule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/calendar/connection": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Persists the user's chosen Google Calendar IDs. The legacy calendar_id field selects a single calendar.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "calendar"
+                ],
+                "summary": "Save selected calendars",
+                "parameters": [
+                    {
+                        "description": "Selected calendar IDs",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/calendar.SetConnectionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.ErrorResponse"
+                        }
+                    },
+                    "424": {
+                        "description": "Failed Dependency",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Revokes the Google grant and deletes the stored connection and cached events. Succeeds when already disconnected.",
+                "tags": [
+                    "calendar"
+                ],
+                "summary": "Disconnect Google Calendar",
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/calendar.CalendarItem"
-                            }
-                        }
+                    "204": {
+                        "description": "No Content"
                     },
                     "401": {
                         "description": "Unauthorized",
@@ -120,12 +502,6 @@ const docTemplate = `{
                             "$ref": "#/definitions/calendar.ErrorResponse"
                         }
                     },
-                    "424": {
-                        "description": "Failed Dependency",
-                        "schema": {
-                            "$ref": "#/definitions/calendar.ErrorResponse"
-                        }
-                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -135,14 +511,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/calendar/connection": {
-            "put": {
+        "/calendar/feed": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Persists the user's chosen Google Calendar ID.",
+                "description": "Replaces the calendar connection with an ICS feed URL (webcal:// links are accepted) or a CalDAV server, after checking it can be read. A feed is its only calendar and is selected right away; CalDAV calendars are then chosen like Google ones.",
                 "consumes": [
                     "application/json"
                 ],
@@ -152,15 +528,15 @@ const docTemplate = `{
                 "tags": [
                     "calendar"
                 ],
-                "summary": "Save selected calendar",
+                "summary": "Connect an ICS feed or CalDAV server",
                 "parameters": [
                     {
-                        "description": "Selected calendar ID",
+                        "description": "Calendar source",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/calendar.SetConnectionRequest"
+                            "$ref": "#/definitions/calendar.ConnectFeedRequest"
                         }
                     }
                 ],
@@ -186,12 +562,6 @@ const docTemplate = `{
                             "$ref": "#/definitions/calendar.ErrorResponse"
                         }
                     },
-                    "424": {
-                        "description": "Failed Dependency",
-                        "schema": {
-                            "$ref": "#/definitions/calendar.ErrorResponse"
-                        }
-                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -208,11 +578,11 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Aggregates event durations from the user's selected Google Calendar grouped by event color label.",
+                "description": "Aggregates event durations from the user's selected Google Calendars grouped by event color label.\nBy default the result is a calendar.Spendings, hours keyed by category: {\"Meetings\": 12.5, \"Sport\": 3}.\nWith breakdown=calendar the result is a calendar.CalendarSpendings instead, keyed by calendar ID, each holding its own category breakdown: {\"primary\": {\"Meetings\": 12.5}, \"family\": {\"Sport\": 3}}.\nWith granularity=day or week the result is a calendar.SpendingSeriesResponse of ordered buckets split at midnight in the user's timezone (weeks start on Monday); events crossing a boundary are prorated.\nWith overlaps=flatten the result is a calendar.TimeUsageResponse: overlapping events count once, for the highest-priority category, and booked time is compared with free time within working hours.",
                 "tags": [
                     "calendar"
                 ],
-                "summary": "Get time spendings from the selected Google Calendar",
+                "summary": "Get time spendings from the selected Google Calendars",
                 "parameters": [
                     {
                         "type": "string",
@@ -227,11 +597,41 @@ const docTemplate = `{
                         "name": "end",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "enum": [
+                            "color",
+                            "calendar"
+                        ],
+                        "type": "string",
+                        "description": "Grouping (color or calendar, default color)",
+                        "name": "breakdown",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "day",
+                            "week"
+                        ],
+                        "type": "string",
+                        "description": "Split into buckets (day or week); not combinable with breakdown=calendar",
+                        "name": "granularity",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "sum",
+                            "flatten"
+                        ],
+                        "type": "string",
+                        "description": "How overlapping events are counted (sum or flatten, default sum); flatten is not combinable with breakdown or granularity",
+                        "name": "overlaps",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Hours per category: {\"\u003ccategory\u003e\": hours}. With breakdown=calendar, a calendar.CalendarSpendings instead: {\"\u003ccalendar ID\u003e\": {\"\u003ccategory\u003e\": hours}}",
                         "schema": {
                             "$ref": "#/definitions/calendar.Spendings"
                         }
@@ -270,7 +670,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Returns a tri-state status: disconnected (no OAuth), pending_selection (OAuth done, no calendar chosen), connected (ready).",
+                "description": "Returns the connection status: disconnected (no OAuth), pending_selection (OAuth done, no calendar chosen), needs_reauth (Google grant revoked, reconnect through OAuth), connected (ready).",
                 "tags": [
                     "calendar"
                 ],
@@ -297,6 +697,68 @@ const docTemplate = `{
                 }
             }
         },
+        "/calendar/webhook": {
+            "post": {
+                "description": "Called by Google for every change on a watched calendar. The channel token and resource ID must match the registered channel; the calendar is then synced incrementally.",
+                "tags": [
+                    "calendar"
+                ],
+                "summary": "Receive Google Calendar push notifications",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Channel ID",
+                        "name": "X-Goog-Channel-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Channel token",
+                        "name": "X-Goog-Channel-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Watched resource ID",
+                        "name": "X-Goog-Resource-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "sync, exists or not_exists",
+                        "name": "X-Goog-Resource-State",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/calendar.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/energy/levels": {
             "get": {
                 "security": [
@@ -477,6 +939,68 @@ const docTemplate = `{
                     }
                 }
             }
+        },
+        "/insights/time-energy": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Joins daily hours per calendar category with the energy score (mean of physical, mental and emotional) of the same day and of the next day.\nEach category reports the days with and without time in it, the mean scores of both groups, Cohen's d and the Pearson correlation between hours and score.\nfrom and to are given together; without them the last 90 days are used. The range may not exceed 365 days.",
+                "tags": [
+                    "insights"
+                ],
+                "summary": "Correlate calendar time with energy scores",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "2026-01-01",
+                        "description": "Start date (YYYY-MM-DD, inclusive)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2026-03-31",
+                        "description": "End date (YYYY-MM-DD, inclusive)",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/insights.TimeEnergyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/insights.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/insights.ErrorResponse"
+                        }
+                    },
+                    "424": {
+                        "description": "Failed Dependency",
+                        "schema": {
+                            "$ref": "#/definitions/insights.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/insights.ErrorResponse"
+                        }
+                    }
+                }
+            }
         }
     },
     "definitions": {
@@ -502,32 +1026,174 @@ const docTemplate = `{
                 }
             }
         },
+        "calendar.CategorizedEventResponse": {
+            "type": "object",
+            "properties": {
+                "all_day": {
+                    "type": "boolean"
+                },
+                "calendar_id": {
+                    "type": "string"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "color_id": {
+                    "type": "string"
+                },
+                "end": {
+                    "type": "string"
+                },
+                "hours": {
+                    "type": "number"
+                },
+                "rule_id": {
+                    "type": "string"
+                },
+                "start": {
+                    "type": "string"
+                },
+                "summary": {
+                    "type": "string"
+                }
+            }
+        },
+        "calendar.CategoryPreviewResponse": {
+            "type": "object",
+            "properties": {
+                "end": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/calendar.CategorizedEventResponse"
+                    }
+                },
+                "spendings": {
+                    "$ref": "#/definitions/calendar.Spendings"
+                },
+                "start": {
+                    "type": "string"
+                }
+            }
+        },
+        "calendar.CategoryRuleRequest": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "match_type": {
+                    "type": "string",
+                    "enum": [
+                        "color",
+                        "calendar",
+                        "keyword",
+                        "regex"
+                    ]
+                },
+                "priority": {
+                    "type": "integer"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "calendar.CategoryRuleResponse": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "match_type": {
+                    "type": "string"
+                },
+                "priority": {
+                    "type": "integer"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "calendar.ConnectFeedRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string",
+                    "enum": [
+                        "ics",
+                        "caldav"
+                    ]
+                },
+                "url": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
         "calendar.ConnectionStatus": {
             "type": "string",
             "enum": [
                 "disconnected",
                 "pending_selection",
-                "connected"
+                "connected",
+                "needs_reauth"
             ],
             "x-enum-varnames": [
                 "StatusDisconnected",
                 "StatusPendingSelection",
-                "StatusConnected"
+                "StatusConnected",
+                "StatusNeedsReauth"
             ]
         },
         "calendar.ErrorResponse": {
             "type": "object",
             "properties": {
+                "code": {
+                    "description": "Code is set to reauth_required when the user must reconnect Google Calendar.",
+                    "type": "string"
+                },
                 "error": {
                     "type": "string"
                 }
             }
         },
+        "calendar.PreviewCategoriesRequest": {
+            "type": "object",
+            "properties": {
+                "rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/calendar.CategoryRuleRequest"
+                    }
+                },
+                "week_start": {
+                    "type": "string"
+                }
+            }
+        },
         "calendar.SetConnectionRequest": {
             "type": "object",
             "properties": {
                 "calendar_id": {
                     "type": "string"
+                },
+                "calendar_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
@@ -675,6 +1341,75 @@ const docTemplate = `{
                     ]
                 }
             }
+        },
+        "insights.CategoryEffectResponse": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "correlation": {
+                    "type": "number"
+                },
+                "daysWith": {
+                    "type": "integer"
+                },
+                "daysWithout": {
+                    "type": "integer"
+                },
+                "effectSize": {
+                    "type": "number"
+                },
+                "meanScoreWith": {
+                    "type": "number"
+                },
+                "meanScoreWithout": {
+                    "type": "number"
+                },
+                "signal": {
+                    "type": "string",
+                    "enum": [
+                        "high_energy",
+                        "low_energy",
+                        "inconclusive"
+                    ]
+                }
+            }
+        },
+        "insights.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Code is set to reauth_required when the user must reconnect Google Calendar.",
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "insights.TimeEnergyResponse": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "nextDay": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/insights.CategoryEffectResponse"
+                    }
+                },
+                "sameDay": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/insights.CategoryEffectResponse"
+                    }
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
         }
     },
     "securityDefinitions": {
@@ -0,0 +1,162 @@
+// Package bootstrap builds the application from its configuration: it creates
+// the external clients and repositories, wires the services and hands them to
+// the HTTP server. cmd/container and cmd/lambda both start from here.
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+	oauth2google "golang.org/x/oauth2/google"
+
+	"energyjournal/internal/config"
+	integgoogle "energyjournal/internal/integration/google"
+	"energyjournal/internal/integration/ical"
+	integmicrosoft "energyjournal/internal/integration/microsoft"
+	"energyjournal/internal/pkg/encryption"
+	"energyjournal/internal/pkg/firebase"
+	"energyjournal/internal/pkg/health"
+	"energyjournal/internal/pkg/ratelimit"
+	"energyjournal/internal/pkg/scheduler"
+	"energyjournal/internal/pkg/telemetry"
+	"energyjournal/internal/server"
+	"energyjournal/internal/server/middleware"
+	calendarservice "energyjournal/internal/service/calendar"
+	energyservice "energyjournal/internal/service/energy"
+	insightsservice "energyjournal/internal/service/insights"
+	userservice "energyjournal/internal/service/user"
+)
+
+// App is the configured HTTP server and its background jobs. Run serves both
+// until shutdown; on Lambda only the server's handler is used and jobs are
+// triggered over HTTP when a trigger token is configured.
+type App struct {
+	Server    *http.Server
+	Jobs      *scheduler.Scheduler
+	Readiness *server.Readiness
+
+	shutdownDelay   time.Duration
+	shutdownTimeout time.Duration
+	closers         []io.Closer
+}
+
+// New connects to the services cfg names and builds the application.
+func New(ctx context.Context, cfg config.Config) (*App, error) {
+	firebaseClient, err := firebase.NewClient(ctx, cfg.Firebase.CredentialsJSON)
+	if err != nil {
+		return nil, fmt.Errorf("initialize Firebase client: %w", err)
+	}
+	tokenKeys, err := tokenKeyProvider(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("initialize token encryption: %w", err)
+	}
+	repos, err := openRepositories(ctx, cfg, encryption.NewEnvelope(tokenKeys))
+	if err != nil {
+		return nil, fmt.Errorf("initialize storage: %w", err)
+	}
+
+	authProvider := firebase.NewAuthProvider(firebaseClient, cfg.Firebase.APIKey)
+	emailSender := &noopEmailSender{} // TODO: implement real email sender
+
+	userService := userservice.NewUserService(repos.users, repos.tokens, authProvider, emailSender, cfg.Frontend.ActivationBaseURL)
+	preferencesService := userservice.NewPreferencesService(repos.preferences, repos.users, cfg.UnsubscribeSecret, cfg.Frontend.ActivationBaseURL)
+	energyLevelsService := energyservice.NewEnergyService(repos.energy)
+	authMiddleware := middleware.NewAuthMiddleware(firebaseClient, repos.users)
+	googleClient := integgoogle.NewGoogleCalendarClient()
+	calendarOAuthConfig := &oauth2.Config{
+		ClientID:     cfg.Google.ClientID,
+		ClientSecret: cfg.Google.ClientSecret,
+		Endpoint:     oauth2google.Endpoint,
+		RedirectURL:  cfg.Google.RedirectURI,
+		Scopes:       []string{"https://www.googleapis.com/auth/calendar.readonly"},
+	}
+	calendarService := calendarservice.NewCalendarService(repos.connections, integgoogle.NewProvider(googleClient, calendarOAuthConfig), cfg.Google.StateSecret, repos.categories).
+		WithAllDayHours(cfg.Calendar.AllDayEventHours).
+		WithWorkingHours(cfg.Calendar.WorkingHours).
+		WithEventCache(repos.eventCache).
+		WithUsers(repos.users)
+	calendarService.
+		WithProvider(ical.NewFeedProvider(cfg.Calendar.AllowPrivateFeeds)).
+		WithProvider(ical.NewCalDAVProvider(cfg.Calendar.AllowPrivateFeeds))
+	if cfg.Microsoft.Enabled() {
+		microsoftOAuthConfig := integmicrosoft.NewOAuthConfig(cfg.Microsoft.ClientID, cfg.Microsoft.ClientSecret, cfg.Microsoft.RedirectURI, cfg.Microsoft.Tenant)
+		calendarService.WithProvider(integmicrosoft.NewProvider(integmicrosoft.NewGraphCalendarClient(), microsoftOAuthConfig))
+	}
+	if cfg.Calendar.WebhookURL != "" {
+		calendarService.WithWatchChannels(repos.watchChannels, cfg.Calendar.WebhookURL)
+	}
+
+	jobs := scheduler.New(
+		scheduler.Job{Name: "calendar-sync", Interval: cfg.Jobs.CalendarSyncInterval, Run: calendarService.SyncAll},
+		scheduler.Job{Name: "calendar-watch-renewal", Interval: cfg.Jobs.WatchRenewInterval, Run: calendarService.RenewChannels},
+		scheduler.Job{Name: "token-encryption-migration", Interval: cfg.Jobs.TokenMigrationInterval, Run: func(ctx context.Context) error {
+			migrated, err := repos.connections.MigrateTokens(ctx)
+			if migrated > 0 {
+				logrus.WithField("connections", migrated).Info("Re-encrypted OAuth tokens")
+			}
+			return err
+		}},
+	)
+
+	// Google is only needed to connect calendars and refresh their tokens, so
+	// its outages are reported without making instances unready.
+	googleOAuth := health.Reachable("google_oauth", oauth2google.Endpoint.TokenURL)
+	googleOAuth.Optional = true
+	checkers := []health.Checker{
+		repos.check,
+		health.HTTPOK("firebase", firebase.PublicKeysURL),
+		googleOAuth,
+	}
+	if cfg.SMTPAddr != "" {
+		checkers = append(checkers, health.SMTP("smtp", cfg.SMTPAddr))
+	}
+	readiness := server.NewReadiness(cfg.HTTP.ReadinessTimeout, checkers...)
+	var metrics http.Handler
+	if cfg.Telemetry.Prometheus {
+		metrics = telemetry.MetricsHandler()
+	}
+	var rateLimits ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateLimit.Store == config.RateLimitDatabase {
+		rateLimits = repos.rateLimits
+	}
+	srv, err := server.NewWithDependencies(cfg, server.Dependencies{
+		CalendarService:    calendarService,
+		CategoryService:    calendarService,
+		WatchService:       calendarService,
+		UserService:        userService,
+		PreferencesService: preferencesService,
+		EnergyService:      energyLevelsService,
+		InsightsService:    insightsservice.NewInsightsService(calendarService, repos.energy),
+		AuthMiddleware:     authMiddleware,
+		Jobs:               jobs,
+		Readiness:          readiness,
+		Metrics:            metrics,
+		RateLimiter:        middleware.NewRateLimiter(rateLimits, cfg.RateLimit.Routes, cfg.RateLimit.TrustForwardedFor),
+	})
+	if err != nil {
+		_ = repos.closer.Close()
+		return nil, err
+	}
+	return &App{
+		Server:          srv,
+		Jobs:            jobs,
+		Readiness:       readiness,
+		shutdownDelay:   cfg.HTTP.ShutdownDelay,
+		shutdownTimeout: cfg.HTTP.ShutdownTimeout,
+		closers:         []io.Closer{repos.closer},
+	}, nil
+}
+
+// noopEmailSender is a placeholder email sender that does nothing.
+// TODO: Replace with real implementation (e.g., Brevo, SendGrid).
+type noopEmailSender struct{}
+
+func (s *noopEmailSender) SendActivationEmail(ctx context.Context, email, activationLink string) error {
+	middleware.Logger(ctx).WithFields(logrus.Fields{"email": email, "link": activationLink}).Info("Would send activation email")
+	return nil
+}
@@ -0,0 +1,92 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Run listens on the server's address and serves until ctx is cancelled,
+// typically by SIGTERM or SIGINT, then shuts down gracefully.
+func (a *App) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", a.Server.Addr)
+	if err != nil {
+		return err
+	}
+	return a.Serve(ctx, listener)
+}
+
+// Serve starts the jobs and serves requests on listener until ctx is
+// cancelled, then calls Shutdown.
+func (a *App) Serve(ctx context.Context, listener net.Listener) error {
+	// Job runs must not be cut short by the signal itself: Shutdown drains them.
+	a.Jobs.Start(context.WithoutCancel(ctx))
+	logrus.WithField("addr", listener.Addr().String()).Info("HTTP server listening")
+
+	served := make(chan error, 1)
+	go func() { served <- a.Server.Serve(listener) }()
+
+	select {
+	case err := <-served:
+		a.Readiness.ShuttingDown()
+		stopCtx, cancel := context.WithTimeout(context.Background(), a.shutdownTimeout)
+		defer cancel()
+		return errors.Join(err, a.Jobs.Stop(stopCtx), a.Close())
+	case <-ctx.Done():
+	}
+
+	shutdownErr := a.Shutdown(context.Background())
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		return errors.Join(err, shutdownErr)
+	}
+	return shutdownErr
+}
+
+// Shutdown turns /readyz unready and, after the shutdown delay, stops
+// accepting connections. Requests and job runs in flight then get until the
+// shutdown timeout to finish before the database clients are closed.
+func (a *App) Shutdown(ctx context.Context) error {
+	a.Readiness.ShuttingDown()
+	logrus.Info("Shutting down")
+
+	if a.shutdownDelay > 0 {
+		select {
+		case <-time.After(a.shutdownDelay):
+		case <-ctx.Done():
+		}
+	}
+
+	if a.shutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.shutdownTimeout)
+		defer cancel()
+	}
+	var errs []error
+	if err := a.Server.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("shut down HTTP server: %w", err))
+	}
+	if err := a.Jobs.Stop(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("stop jobs: %w", err))
+	}
+	if err := a.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Close releases the clients the App was built with.
+func (a *App) Close() error {
+	var errs []error
+	for _, closer := range a.closers {
+		if err := closer.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close %T: %w", closer, err))
+		}
+	}
+	a.closers = nil
+	return errors.Join(errs...)
+}
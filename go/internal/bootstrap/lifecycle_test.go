@@ -0,0 +1,105 @@
+package bootstrap
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"energyjournal/internal/pkg/scheduler"
+	"energyjournal/internal/server"
+)
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+func TestServeDrainsRequestsAndJobsOnShutdown(t *testing.T) {
+	t.Parallel()
+
+	requestStarted, releaseRequest := make(chan struct{}), make(chan struct{})
+	jobStarted, releaseJob := make(chan struct{}), make(chan struct{})
+	jobFinished := false
+	closed := false
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(requestStarted)
+		<-releaseRequest
+		_, _ = w.Write([]byte("done"))
+	})
+	app := &App{
+		Server: &http.Server{Handler: mux},
+		Jobs: scheduler.New(scheduler.Job{Name: "sync", Interval: time.Millisecond, Run: func(ctx context.Context) error {
+			select {
+			case jobStarted <- struct{}{}:
+			default:
+				return nil
+			}
+			<-releaseJob
+			jobFinished = ctx.Err() == nil
+			return nil
+		}}),
+		Readiness:       server.NewReadiness(time.Second),
+		shutdownTimeout: 5 * time.Second,
+		closers: []io.Closer{closerFunc(func() error {
+			closed = true
+			return nil
+		})},
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- app.Serve(ctx, listener) }()
+
+	response := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String() + "/slow")
+		if err != nil {
+			t.Errorf("request failed: %v", err)
+			close(response)
+			return
+		}
+		response <- resp
+	}()
+	<-requestStarted
+	<-jobStarted
+
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for {
+		rr := httptest.NewRecorder()
+		app.Readiness.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		if rr.Code == http.StatusServiceUnavailable {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected /readyz to fail during shutdown, got %d", rr.Code)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case err := <-served:
+		t.Fatalf("Serve returned before the request finished: %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(releaseRequest)
+	if resp := <-response; resp == nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the request in flight to complete, got %+v", resp)
+	}
+	close(releaseJob)
+	if err := <-served; err != nil {
+		t.Fatalf("Serve returned error: %v", err)
+	}
+	if !jobFinished || !closed {
+		t.Fatalf("expected the job drained (%v) and the clients closed (%v)", jobFinished, closed)
+	}
+}
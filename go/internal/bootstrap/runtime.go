@@ -0,0 +1,52 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+
+	"energyjournal/internal/config"
+	"energyjournal/internal/pkg/logging"
+	"energyjournal/internal/pkg/telemetry"
+)
+
+// Runtime is the environment the process runs in.
+type Runtime int
+
+const (
+	// Container is a long-running process serving requests and running jobs.
+	Container Runtime = iota
+	// Lambda is a function invoked once per request.
+	Lambda
+)
+
+// Init prepares the process for runtime: it sets up logging, loads the
+// configuration from the environment, adjusts it to what runtime supports and
+// starts telemetry. The returned function flushes and stops telemetry.
+func Init(ctx context.Context, runtime Runtime) (config.Config, func(context.Context) error, error) {
+	logging.Setup(logrus.StandardLogger())
+	if runtime == Container {
+		logrus.SetLevel(logrus.DebugLevel)
+	}
+
+	cfg, err := config.Load(os.Getenv)
+	if err != nil {
+		return config.Config{}, nil, err
+	}
+	if runtime == Lambda {
+		// A function cannot be scraped: metrics are only pushed over OTLP.
+		cfg.Telemetry.Prometheus = false
+	}
+
+	shutdownTelemetry, err := telemetry.Setup(ctx, telemetry.Options{
+		ServiceName:  cfg.Telemetry.ServiceName,
+		OTLPEndpoint: cfg.Telemetry.OTLPEndpoint,
+		Prometheus:   cfg.Telemetry.Prometheus,
+	})
+	if err != nil {
+		return config.Config{}, nil, fmt.Errorf("set up telemetry: %w", err)
+	}
+	return cfg, shutdownTelemetry, nil
+}
@@ -0,0 +1,126 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/api/option"
+
+	"energyjournal/internal/config"
+	"energyjournal/internal/domain/calendar"
+	"energyjournal/internal/domain/energy"
+	"energyjournal/internal/domain/user"
+	"energyjournal/internal/pkg/encryption"
+	"energyjournal/internal/pkg/firestore"
+	"energyjournal/internal/pkg/health"
+	"energyjournal/internal/pkg/ratelimit"
+	"energyjournal/internal/pkg/sqldb"
+	calendarstorage "energyjournal/internal/service/calendar/storage"
+	energystorage "energyjournal/internal/service/energy/storage"
+	userstorage "energyjournal/internal/service/user/storage"
+)
+
+// connectionStore is a connection repository whose tokens can be re-encrypted
+// under the primary key.
+type connectionStore interface {
+	calendar.CalendarConnectionRepository
+	MigrateTokens(ctx context.Context) (int, error)
+}
+
+// repositories are the domain repositories of one storage backend.
+type repositories struct {
+	users         user.UserRepository
+	tokens        user.ActivationTokenRepository
+	preferences   user.PreferencesRepository
+	energy        energy.EnergyRepository
+	connections   connectionStore
+	categories    calendar.CategoryRuleRepository
+	eventCache    calendar.EventCacheRepository
+	watchChannels calendar.WatchChannelRepository
+	// rateLimits shares rate limit buckets between instances.
+	rateLimits ratelimit.Store
+	// closer releases the database client once the repositories are unused.
+	closer io.Closer
+	// check pings the database for GET /readyz.
+	check health.Checker
+}
+
+// tokenKeyProvider returns the keys wrapping stored OAuth tokens, or nil when
+// none is configured, which config only allows on local backends or with
+// TOKEN_ENCRYPTION_DISABLED; tokens are then stored in plaintext.
+func tokenKeyProvider(ctx context.Context, cfg config.Config) (encryption.KeyProvider, error) {
+	switch {
+	case cfg.TokenEncryptionKMSKey != "":
+		client, err := encryption.NewCloudKMSClient(ctx, option.WithAuthCredentialsJSON(option.ServiceAccount, cfg.Firebase.CredentialsJSON))
+		if err != nil {
+			return nil, err
+		}
+		return encryption.NewKMSKeyProvider(client, cfg.TokenEncryptionKMSKey), nil
+	case cfg.TokenEncryptionKeys != nil:
+		return cfg.TokenEncryptionKeys, nil
+	}
+	logrus.Warn("Token encryption is not configured: OAuth tokens are stored in plaintext")
+	return nil, nil
+}
+
+// openRepositories connects to the configured backend: firestore, postgres,
+// sqlite (a database file) or memory (an SQLite database lost on exit, for
+// local runs and demos).
+func openRepositories(ctx context.Context, cfg config.Config, tokens *encryption.Envelope) (repositories, error) {
+	switch cfg.Storage.Backend {
+	case config.StorageFirestore:
+		client, err := firestore.NewClient(ctx, cfg.Storage.GCPProjectID, cfg.Firebase.CredentialsJSON)
+		if err != nil {
+			return repositories{}, fmt.Errorf("initialize Firestore client: %w", err)
+		}
+		return repositories{
+			users:         userstorage.NewUserRepository(client.Client),
+			tokens:        userstorage.NewActivationTokenRepository(client.Client),
+			preferences:   userstorage.NewPreferencesRepository(client.Client),
+			energy:        energystorage.NewEnergyRepository(client.Client),
+			connections:   calendarstorage.NewConnectionRepository(client.Client, tokens),
+			categories:    calendarstorage.NewCategoryRuleRepository(client.Client),
+			eventCache:    calendarstorage.NewEventCacheRepository(client.Client),
+			watchChannels: calendarstorage.NewWatchChannelRepository(client.Client),
+			rateLimits:    ratelimit.NewFirestoreStore(client.Client),
+			closer:        client.Client,
+			check:         health.Checker{Name: "firestore", Check: client.Ping},
+		}, nil
+	case config.StoragePostgres:
+		db, err := sqldb.Open(ctx, sqldb.Postgres, cfg.Storage.DatabaseURL)
+		if err != nil {
+			return repositories{}, fmt.Errorf("open Postgres database: %w", err)
+		}
+		return sqlRepositories(db, cfg.Storage.Backend, tokens), nil
+	case config.StorageSQLite, config.StorageMemory:
+		dsn := sqldb.MemoryDSN
+		if cfg.Storage.Backend == config.StorageSQLite {
+			dsn = cfg.Storage.SQLitePath
+		}
+		db, err := sqldb.Open(ctx, sqldb.SQLite, dsn)
+		if err != nil {
+			return repositories{}, fmt.Errorf("open SQLite database: %w", err)
+		}
+		return sqlRepositories(db, cfg.Storage.Backend, tokens), nil
+	default:
+		return repositories{}, fmt.Errorf("unknown storage backend %q", cfg.Storage.Backend)
+	}
+}
+
+func sqlRepositories(db *sqldb.DB, backend string, tokens *encryption.Envelope) repositories {
+	return repositories{
+		users:         userstorage.NewSQLUserRepository(db),
+		tokens:        userstorage.NewSQLActivationTokenRepository(db),
+		preferences:   userstorage.NewSQLPreferencesRepository(db),
+		energy:        energystorage.NewSQLEnergyRepository(db),
+		connections:   calendarstorage.NewSQLConnectionRepository(db, tokens),
+		categories:    calendarstorage.NewSQLCategoryRuleRepository(db),
+		eventCache:    calendarstorage.NewSQLEventCacheRepository(db),
+		watchChannels: calendarstorage.NewSQLWatchChannelRepository(db),
+		rateLimits:    ratelimit.NewSQLStore(db),
+		closer:        db,
+		check:         health.Checker{Name: backend, Check: db.Ping},
+	}
+}
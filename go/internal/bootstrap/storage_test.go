@@ -0,0 +1,47 @@
+package bootstrap
+
+import (
+	"context"
+	"encoding/base64"
+	"path/filepath"
+	"testing"
+
+	"energyjournal/internal/config"
+	"energyjournal/internal/domain/energy"
+	"energyjournal/internal/pkg/encryption"
+)
+
+func testEnvelope(t *testing.T) *encryption.Envelope {
+	t.Helper()
+
+	keys, err := encryption.NewLocalKeyProvider("k1:" + base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encryption.NewEnvelope(keys)
+}
+
+func TestOpenRepositoriesUsesSQLiteFile(t *testing.T) {
+	cfg := config.Config{Storage: config.Storage{Backend: config.StorageSQLite, SQLitePath: filepath.Join(t.TempDir(), "journal.db")}}
+
+	ctx := context.Background()
+	repos, err := openRepositories(ctx, cfg, testEnvelope(t))
+	if err != nil {
+		t.Fatalf("openRepositories returned error: %v", err)
+	}
+	if repos.check.Name != "sqlite" || repos.check.Check(ctx) != nil {
+		t.Fatalf("expected a passing sqlite readiness check, got %q", repos.check.Name)
+	}
+	if err := repos.energy.Upsert(ctx, energy.EnergyLevels{UID: "uid-1", Date: "2026-03-02", Physical: 2}); err != nil {
+		t.Fatalf("Upsert returned error: %v", err)
+	}
+
+	reopened, err := openRepositories(ctx, cfg, testEnvelope(t))
+	if err != nil {
+		t.Fatalf("reopen returned error: %v", err)
+	}
+	levels, err := reopened.energy.GetByDate(ctx, "uid-1", "2026-03-02")
+	if err != nil || levels.Physical != 2 {
+		t.Fatalf("expected the entry persisted, got %+v (%v)", levels, err)
+	}
+}
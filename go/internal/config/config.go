@@ -0,0 +1,517 @@
+// Package config loads the server configuration from environment variables.
+// Every variable is read and validated here, so that a misconfigured
+// deployment reports all of its problems at once instead of one per restart.
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"energyjournal/internal/domain/calendar"
+	"energyjournal/internal/pkg/encryption"
+	"energyjournal/internal/pkg/ratelimit"
+)
+
+// Storage backends selectable with STORAGE_BACKEND.
+const (
+	StorageFirestore = "firestore"
+	StoragePostgres  = "postgres"
+	StorageSQLite    = "sqlite"
+	StorageMemory    = "memory"
+)
+
+// Rate limit stores selectable with RATE_LIMIT_STORE.
+const (
+	// RateLimitMemory counts in each instance's memory.
+	RateLimitMemory = "memory"
+	// RateLimitDatabase counts in the storage backend, shared by all instances.
+	RateLimitDatabase = "database"
+)
+
+// DefaultRateLimits are the limits of the rate-limited routes, keyed by mux
+// pattern. RATE_LIMITS overrides them one route at a time.
+var DefaultRateLimits = map[string]ratelimit.Limit{
+	"POST /users":                       {Burst: 5, Every: 12 * time.Minute},
+	"POST /users/login":                 {Burst: 10, Every: 90 * time.Second},
+	"POST /users/activate":              {Burst: 10, Every: 6 * time.Minute},
+	"POST /users/refresh":               {Burst: 60, Every: time.Minute},
+	"POST /users/unsubscribe":           {Burst: 20, Every: 3 * time.Minute},
+	"PUT /users/me":                     {Burst: 30, Every: 2 * time.Minute},
+	"PUT /energy/levels":                {Burst: 120, Every: 30 * time.Second},
+	"POST /calendar/feed":               {Burst: 20, Every: 3 * time.Minute},
+	"POST /calendar/categories":         {Burst: 60, Every: time.Minute},
+	"POST /calendar/categories/preview": {Burst: 120, Every: 30 * time.Second},
+}
+
+// Config is the whole server configuration.
+type Config struct {
+	HTTP      HTTP
+	Storage   Storage
+	Firebase  Firebase
+	Frontend  Frontend
+	Google    GoogleOAuth
+	Microsoft MicrosoftOAuth
+	Calendar  Calendar
+	Jobs      Jobs
+	Telemetry Telemetry
+	RateLimit RateLimit
+	// TokenEncryptionKeys encrypt stored OAuth tokens; the first key encrypts
+	// new ones. Nil when unset, or when TokenEncryptionKMSKey is used instead.
+	TokenEncryptionKeys *encryption.LocalKeyProvider
+	// TokenEncryptionKMSKey is the Cloud KMS key encrypting stored OAuth
+	// tokens, if any. Without either, tokens are stored in plaintext, which
+	// only local backends or TOKEN_ENCRYPTION_DISABLED allow.
+	TokenEncryptionKMSKey string
+	// UnsubscribeSecret signs unsubscribe links in notification emails.
+	UnsubscribeSecret string
+	// SMTPAddr is the mail server (host:port) checked by GET /readyz, if any.
+	SMTPAddr string
+}
+
+// HTTP configures the listener.
+type HTTP struct {
+	Addr           string
+	AllowedOrigins []string
+	ReadTimeout    time.Duration
+	WriteTimeout   time.Duration
+	IdleTimeout    time.Duration
+	// ShutdownDelay is how long /readyz reports failure before the listener
+	// closes, giving load balancers time to stop routing to the instance.
+	ShutdownDelay time.Duration
+	// ShutdownTimeout bounds the wait for requests and jobs in flight.
+	ShutdownTimeout time.Duration
+	// ReadinessTimeout bounds each dependency check of GET /readyz.
+	ReadinessTimeout time.Duration
+}
+
+// Storage selects where data is stored. Only the fields of the selected
+// backend are set.
+type Storage struct {
+	Backend      string
+	GCPProjectID string
+	SQLitePath   string
+	DatabaseURL  string
+}
+
+// Firebase configures Firebase Authentication, used with every storage backend.
+type Firebase struct {
+	APIKey string
+	// CredentialsJSON is the service-account key, also used for Firestore.
+	CredentialsJSON []byte
+}
+
+// Frontend locates the web app users are sent back to.
+type Frontend struct {
+	BaseURL           string
+	ActivationBaseURL string
+}
+
+// GoogleOAuth configures the Google Calendar consent flow.
+type GoogleOAuth struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURI  string
+	StateSecret  string
+}
+
+// MicrosoftOAuth configures Microsoft 365 calendars. It is disabled when
+// ClientID is empty.
+type MicrosoftOAuth struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURI  string
+	Tenant       string
+}
+
+// Enabled reports whether Microsoft calendars are offered.
+func (m MicrosoftOAuth) Enabled() bool {
+	return m.ClientID != ""
+}
+
+// Calendar configures how calendar time is measured and fetched.
+type Calendar struct {
+	// AllDayEventHours is what each day of an all-day event counts for; 0
+	// ignores all-day events.
+	AllDayEventHours float64
+	WorkingHours     calendar.WorkingHours
+	// WebhookURL enables Google push notifications when set.
+	WebhookURL        string
+	AllowPrivateFeeds bool
+}
+
+// Jobs configures the background jobs.
+type Jobs struct {
+	CalendarSyncInterval   time.Duration
+	WatchRenewInterval     time.Duration
+	TokenMigrationInterval time.Duration
+	// TriggerToken enables POST /internal/jobs/{name} when set.
+	TriggerToken string
+}
+
+// Telemetry configures the export of traces and metrics. Nothing is exported
+// when OTLPEndpoint is empty and Prometheus is false.
+type Telemetry struct {
+	// OTLPEndpoint is the base URL of an OTLP/HTTP collector.
+	OTLPEndpoint string
+	ServiceName  string
+	// Prometheus serves the metrics at GET /metrics.
+	Prometheus bool
+}
+
+// RateLimit configures the limits of the public user routes and of writes.
+type RateLimit struct {
+	// Routes maps the limited routes to their limit; routes not listed are
+	// not limited.
+	Routes map[string]ratelimit.Limit
+	// Store is RateLimitMemory or RateLimitDatabase.
+	Store string
+	// TrustForwardedFor takes the client IP from the last X-Forwarded-For
+	// entry, for servers behind a load balancer.
+	TrustForwardedFor bool
+}
+
+// Load reads the configuration through getenv, usually os.Getenv. The error
+// lists every missing or invalid variable.
+func Load(getenv func(string) string) (Config, error) {
+	l := &loader{getenv: getenv}
+
+	cfg := Config{
+		HTTP: HTTP{
+			Addr:             l.string("HTTP_ADDR", ":8888"),
+			AllowedOrigins:   l.list("ALLOWED_ORIGINS", "http://localhost:8080"),
+			ReadTimeout:      l.duration("HTTP_READ_TIMEOUT", 15*time.Second),
+			WriteTimeout:     l.duration("HTTP_WRITE_TIMEOUT", 60*time.Second),
+			IdleTimeout:      l.duration("HTTP_IDLE_TIMEOUT", 2*time.Minute),
+			ShutdownDelay:    l.delay("HTTP_SHUTDOWN_DELAY"),
+			ShutdownTimeout:  l.duration("HTTP_SHUTDOWN_TIMEOUT", 25*time.Second),
+			ReadinessTimeout: l.duration("READINESS_CHECK_TIMEOUT", 2*time.Second),
+		},
+		Storage: l.storage(),
+		Firebase: Firebase{
+			APIKey:          l.string("FIREBASE_API_KEY", ""),
+			CredentialsJSON: l.firebaseCredentials(),
+		},
+		Frontend: Frontend{
+			BaseURL:           l.required("FRONTEND_BASE_URL"),
+			ActivationBaseURL: l.string("FRONTEND_ACTIVATION_BASE_URL", "http://localhost:8080"),
+		},
+		Google: GoogleOAuth{
+			ClientID:     l.required("GOOGLE_CLIENT_ID"),
+			ClientSecret: l.required("GOOGLE_CLIENT_SECRET"),
+			RedirectURI:  l.required("GOOGLE_OAUTH_REDIRECT_URI"),
+			StateSecret:  l.required("GOOGLE_OAUTH_STATE_SECRET"),
+		},
+		Calendar: Calendar{
+			AllDayEventHours:  l.allDayEventHours(),
+			WorkingHours:      l.workingHours(),
+			WebhookURL:        l.string("CALENDAR_WEBHOOK_URL", ""),
+			AllowPrivateFeeds: l.bool("CALENDAR_FEEDS_ALLOW_PRIVATE_NETWORKS"),
+		},
+		Jobs: Jobs{
+			CalendarSyncInterval:   l.duration("CALENDAR_SYNC_INTERVAL", 15*time.Minute),
+			WatchRenewInterval:     l.duration("CALENDAR_WATCH_RENEW_INTERVAL", 6*time.Hour),
+			TokenMigrationInterval: l.duration("TOKEN_MIGRATION_INTERVAL", 24*time.Hour),
+			TriggerToken:           l.string("JOBS_TRIGGER_TOKEN", ""),
+		},
+		Telemetry: Telemetry{
+			OTLPEndpoint: l.url("OTEL_EXPORTER_OTLP_ENDPOINT"),
+			ServiceName:  l.string("OTEL_SERVICE_NAME", "energyjournal"),
+			Prometheus:   l.bool("METRICS_ENABLED"),
+		},
+		RateLimit: RateLimit{
+			Routes:            l.rateLimits(),
+			Store:             l.rateLimitStore(),
+			TrustForwardedFor: l.bool("RATE_LIMIT_TRUST_FORWARDED_FOR"),
+		},
+		UnsubscribeSecret: l.required("UNSUBSCRIBE_TOKEN_SECRET"),
+		SMTPAddr:          l.string("SMTP_ADDR", ""),
+	}
+	if clientID := l.string("MICROSOFT_CLIENT_ID", ""); clientID != "" {
+		cfg.Microsoft = MicrosoftOAuth{
+			ClientID:     clientID,
+			ClientSecret: l.required("MICROSOFT_CLIENT_SECRET"),
+			RedirectURI:  l.required("MICROSOFT_OAUTH_REDIRECT_URI"),
+			Tenant:       l.string("MICROSOFT_TENANT", "common"),
+		}
+	}
+	cfg.TokenEncryptionKeys, cfg.TokenEncryptionKMSKey = l.tokenEncryption(cfg.Storage.Backend)
+
+	if len(l.errs) > 0 {
+		return Config{}, fmt.Errorf("invalid configuration:\n%w", errors.Join(l.errs...))
+	}
+	return cfg, nil
+}
+
+// loader reads variables, recording every problem instead of stopping at the first.
+type loader struct {
+	getenv func(string) string
+	errs   []error
+}
+
+func (l *loader) failf(format string, args ...any) {
+	l.errs = append(l.errs, fmt.Errorf(format, args...))
+}
+
+func (l *loader) string(key, def string) string {
+	if value := strings.TrimSpace(l.getenv(key)); value != "" {
+		return value
+	}
+	return def
+}
+
+func (l *loader) required(key string) string {
+	value := strings.TrimSpace(l.getenv(key))
+	if value == "" {
+		l.failf("%s environment variable is required", key)
+	}
+	return value
+}
+
+// list splits a comma-separated value, dropping empty items.
+func (l *loader) list(key, def string) []string {
+	var items []string
+	for _, item := range strings.Split(l.string(key, def), ",") {
+		if trimmed := strings.TrimSpace(item); trimmed != "" {
+			items = append(items, trimmed)
+		}
+	}
+	return items
+}
+
+// duration parses a Go duration (e.g. "15m"), returning def when unset.
+func (l *loader) duration(key string, def time.Duration) time.Duration {
+	value := l.string(key, "")
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		l.failf("%s must be a positive duration such as 15m", key)
+		return def
+	}
+	return d
+}
+
+// delay parses a duration that may be zero, its default.
+func (l *loader) delay(key string) time.Duration {
+	value := l.string(key, "")
+	if value == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		l.failf("%s must be a duration such as 5s", key)
+		return 0
+	}
+	return d
+}
+
+// bool parses a boolean (e.g. "true"), returning false when unset.
+func (l *loader) bool(key string) bool {
+	value := l.string(key, "")
+	if value == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		l.failf("%s must be true or false", key)
+	}
+	return b
+}
+
+// url reads an absolute http(s) URL, returning "" when unset.
+func (l *loader) url(key string) string {
+	value := l.string(key, "")
+	if value == "" {
+		return ""
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		l.failf("%s must be an http or https URL such as http://localhost:4318", key)
+		return ""
+	}
+	return value
+}
+
+func (l *loader) storage() Storage {
+	storage := Storage{Backend: strings.ToLower(l.string("STORAGE_BACKEND", StorageFirestore))}
+	switch storage.Backend {
+	case StorageFirestore:
+		storage.GCPProjectID = l.required("GCP_PROJECT_ID")
+	case StoragePostgres:
+		storage.DatabaseURL = l.required("DATABASE_URL")
+	case StorageSQLite:
+		storage.SQLitePath = l.string("SQLITE_PATH", "energyjournal.db")
+	case StorageMemory:
+	default:
+		l.failf("STORAGE_BACKEND must be firestore, postgres, sqlite or memory, got %q", storage.Backend)
+	}
+	return storage
+}
+
+// rateLimits reads RATE_LIMITS, comma-separated route=limit overrides of
+// DefaultRateLimits such as "POST /users/login=5/1m". A limit of "off" lifts
+// the route's limit.
+func (l *loader) rateLimits() map[string]ratelimit.Limit {
+	routes := maps.Clone(DefaultRateLimits)
+	for _, item := range l.list("RATE_LIMITS", "") {
+		route, spec, ok := strings.Cut(item, "=")
+		route = strings.Join(strings.Fields(route), " ")
+		if _, known := DefaultRateLimits[route]; !ok || !known {
+			l.failf("RATE_LIMITS must list route=limit pairs such as POST /users/login=5/1m, for routes among %s", strings.Join(slices.Sorted(maps.Keys(DefaultRateLimits)), ", "))
+			continue
+		}
+		if strings.EqualFold(strings.TrimSpace(spec), "off") {
+			delete(routes, route)
+			continue
+		}
+		limit, err := ratelimit.ParseLimit(spec)
+		if err != nil {
+			l.failf("RATE_LIMITS: %v", err)
+			continue
+		}
+		routes[route] = limit
+	}
+	return routes
+}
+
+// rateLimitStore defaults to the database on Lambda, where concurrent
+// invocations run in separate instances and memory would only see a share of
+// a client's requests.
+func (l *loader) rateLimitStore() string {
+	onLambda := l.string("AWS_LAMBDA_FUNCTION_NAME", "") != ""
+	def := RateLimitMemory
+	if onLambda {
+		def = RateLimitDatabase
+	}
+
+	store := strings.ToLower(l.string("RATE_LIMIT_STORE", def))
+	switch {
+	case store != RateLimitMemory && store != RateLimitDatabase:
+		l.failf("RATE_LIMIT_STORE must be memory or database, got %q", store)
+	case store == RateLimitMemory && onLambda:
+		l.failf("RATE_LIMIT_STORE cannot be memory on Lambda, where each instance would count apart; use database")
+	}
+	return store
+}
+
+// tokenEncryption reads the keys of stored OAuth tokens: local keys from
+// TOKEN_ENCRYPTION_KEYS or a Cloud KMS key from TOKEN_ENCRYPTION_KMS_KEY. One
+// of them is required with the firestore and postgres backends, unless
+// TOKEN_ENCRYPTION_DISABLED=true explicitly keeps tokens in plaintext; the
+// local sqlite and memory backends may go without.
+func (l *loader) tokenEncryption(backend string) (*encryption.LocalKeyProvider, string) {
+	spec := l.string("TOKEN_ENCRYPTION_KEYS", "")
+	kmsKey := l.string("TOKEN_ENCRYPTION_KMS_KEY", "")
+	disabled := l.bool("TOKEN_ENCRYPTION_DISABLED")
+	switch {
+	case disabled && (spec != "" || kmsKey != ""):
+		l.failf("TOKEN_ENCRYPTION_DISABLED cannot be set together with TOKEN_ENCRYPTION_KEYS or TOKEN_ENCRYPTION_KMS_KEY")
+	case spec == "" && kmsKey == "" && !disabled && (backend == StorageFirestore || backend == StoragePostgres):
+		l.failf("TOKEN_ENCRYPTION_KEYS or TOKEN_ENCRYPTION_KMS_KEY is required with STORAGE_BACKEND=%s; set TOKEN_ENCRYPTION_DISABLED=true to store OAuth tokens in plaintext", backend)
+	case spec != "" && kmsKey != "":
+		l.failf("TOKEN_ENCRYPTION_KEYS and TOKEN_ENCRYPTION_KMS_KEY cannot both be set")
+	case kmsKey != "":
+		if !strings.HasPrefix(kmsKey, "projects/") || !strings.Contains(kmsKey, "/cryptoKeys/") {
+			l.failf("TOKEN_ENCRYPTION_KMS_KEY must be a key name such as projects/p/locations/l/keyRings/r/cryptoKeys/k")
+		}
+	case spec != "":
+		keys, err := encryption.NewLocalKeyProvider(spec)
+		if err != nil {
+			l.failf("TOKEN_ENCRYPTION_KEYS is invalid: %v", err)
+		}
+		return keys, ""
+	}
+	return nil, kmsKey
+}
+
+// firebaseCredentials reads the service-account key from FIREBASE_CREDENTIALS
+// (base64) or else from FIREBASE_CREDENTIALS_FILE or firebase-credentials.json.
+func (l *loader) firebaseCredentials() []byte {
+	if encoded := l.string("FIREBASE_CREDENTIALS", ""); encoded != "" {
+		credentialsJSON, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			l.failf("FIREBASE_CREDENTIALS must be base64 encoded")
+		}
+		return credentialsJSON
+	}
+
+	candidates := []string{}
+	if path := l.string("FIREBASE_CREDENTIALS_FILE", ""); path != "" {
+		candidates = append(candidates, path)
+	}
+	candidates = append(candidates, "firebase-credentials.json", "../../firebase-credentials.json")
+	for _, path := range candidates {
+		if credentialsJSON, err := os.ReadFile(path); err == nil {
+			return credentialsJSON
+		}
+	}
+	l.failf("firebase credentials not found: set FIREBASE_CREDENTIALS or provide firebase-credentials.json (or FIREBASE_CREDENTIALS_FILE)")
+	return nil
+}
+
+// allDayEventHours reads CALENDAR_ALL_DAY_EVENT_HOURS, the number of hours each
+// day of an all-day event counts for. Unset means all-day events are ignored.
+func (l *loader) allDayEventHours() float64 {
+	value := l.string("CALENDAR_ALL_DAY_EVENT_HOURS", "")
+	if value == "" {
+		return 0
+	}
+	hours, err := strconv.ParseFloat(value, 64)
+	if err != nil || hours < 0 || hours > 24 {
+		l.failf("CALENDAR_ALL_DAY_EVENT_HOURS must be a number of hours between 0 and 24")
+		return 0
+	}
+	return hours
+}
+
+var weekdaysByName = map[string]time.Weekday{
+	"mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday, "thu": time.Thursday,
+	"fri": time.Friday, "sat": time.Saturday, "sun": time.Sunday,
+}
+
+// workingHours reads CALENDAR_WORKING_HOURS ("09:00-18:00") and
+// CALENDAR_WORKING_DAYS ("mon,tue,wed,thu,fri"), the local window free time is
+// measured against. Both default to the values shown.
+func (l *loader) workingHours() calendar.WorkingHours {
+	var hours calendar.WorkingHours
+	from, to, ok := strings.Cut(l.string("CALENDAR_WORKING_HOURS", "09:00-18:00"), "-")
+	startMinute, startErr := minuteOfDay(from)
+	endMinute, endErr := minuteOfDay(to)
+	if !ok || startErr != nil || endErr != nil || endMinute <= startMinute {
+		l.failf("CALENDAR_WORKING_HOURS must be a window such as 09:00-18:00")
+	} else {
+		hours.StartMinute, hours.EndMinute = startMinute, endMinute
+	}
+
+	for _, name := range l.list("CALENDAR_WORKING_DAYS", "mon,tue,wed,thu,fri") {
+		day, ok := weekdaysByName[strings.ToLower(name)]
+		if !ok {
+			l.failf("CALENDAR_WORKING_DAYS must list days such as mon,tue,wed,thu,fri")
+			break
+		}
+		hours.Days = append(hours.Days, day)
+	}
+	return hours
+}
+
+// minuteOfDay parses "HH:MM" (24:00 allowed) into minutes after midnight.
+func minuteOfDay(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
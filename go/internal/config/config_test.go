@@ -0,0 +1,198 @@
+package config
+
+import (
+	"encoding/base64"
+	"maps"
+	"strings"
+	"testing"
+	"time"
+)
+
+func env(values map[string]string) func(string) string {
+	return func(key string) string { return values[key] }
+}
+
+func validEnv() map[string]string {
+	return map[string]string{
+		"STORAGE_BACKEND":           "sqlite",
+		"FIREBASE_CREDENTIALS":      base64.StdEncoding.EncodeToString([]byte(`{"type":"service_account"}`)),
+		"FRONTEND_BASE_URL":         "https://app.example",
+		"GOOGLE_CLIENT_ID":          "client",
+		"GOOGLE_CLIENT_SECRET":      "secret",
+		"GOOGLE_OAUTH_REDIRECT_URI": "https://api.example/calendar/auth/callback",
+		"GOOGLE_OAUTH_STATE_SECRET": "state",
+		"UNSUBSCRIBE_TOKEN_SECRET":  "unsubscribe",
+		"TOKEN_ENCRYPTION_KEYS":     "k1:" + base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")),
+	}
+}
+
+func TestLoadAppliesDefaults(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := Load(env(validEnv()))
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.HTTP.Addr != ":8888" || len(cfg.HTTP.AllowedOrigins) != 1 || cfg.HTTP.AllowedOrigins[0] != "http://localhost:8080" {
+		t.Fatalf("unexpected HTTP config: %+v", cfg.HTTP)
+	}
+	if cfg.HTTP.ReadTimeout != 15*time.Second || cfg.HTTP.WriteTimeout != time.Minute || cfg.HTTP.ShutdownDelay != 0 || cfg.HTTP.ShutdownTimeout != 25*time.Second {
+		t.Fatalf("unexpected HTTP timeouts: %+v", cfg.HTTP)
+	}
+	if cfg.Storage.SQLitePath != "energyjournal.db" || string(cfg.Firebase.CredentialsJSON) != `{"type":"service_account"}` {
+		t.Fatalf("unexpected storage or Firebase config: %+v %+v", cfg.Storage, cfg.Firebase)
+	}
+	if cfg.Jobs.CalendarSyncInterval != 15*time.Minute || cfg.Jobs.TokenMigrationInterval != 24*time.Hour {
+		t.Fatalf("unexpected jobs config: %+v", cfg.Jobs)
+	}
+	working := cfg.Calendar.WorkingHours
+	if working.StartMinute != 9*60 || working.EndMinute != 18*60 || len(working.Days) != 5 {
+		t.Fatalf("unexpected working hours: %+v", working)
+	}
+	if cfg.Telemetry.OTLPEndpoint != "" || cfg.Telemetry.ServiceName != "energyjournal" || cfg.Telemetry.Prometheus {
+		t.Fatalf("unexpected telemetry config: %+v", cfg.Telemetry)
+	}
+	if cfg.RateLimit.Store != RateLimitMemory || cfg.RateLimit.TrustForwardedFor || len(cfg.RateLimit.Routes) != len(DefaultRateLimits) {
+		t.Fatalf("unexpected rate limit config: %+v", cfg.RateLimit)
+	}
+	if cfg.Microsoft.Enabled() || cfg.TokenEncryptionKeys == nil || cfg.TokenEncryptionKeys.PrimaryKeyID() != "k1" {
+		t.Fatalf("unexpected optional config: %+v", cfg)
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	t.Parallel()
+
+	values := validEnv()
+	delete(values, "GOOGLE_CLIENT_ID")
+	delete(values, "FRONTEND_BASE_URL")
+	values["STORAGE_BACKEND"] = "postgres"
+	values["CALENDAR_SYNC_INTERVAL"] = "often"
+	values["HTTP_SHUTDOWN_DELAY"] = "-1s"
+	values["CALENDAR_WORKING_HOURS"] = "18:00-09:00"
+	values["MICROSOFT_CLIENT_ID"] = "ms-client"
+	values["TOKEN_ENCRYPTION_KEYS"] = "k1:short"
+	values["OTEL_EXPORTER_OTLP_ENDPOINT"] = "localhost:4318"
+	values["RATE_LIMIT_STORE"] = "redis"
+
+	_, err := Load(env(values))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		"GOOGLE_CLIENT_ID", "FRONTEND_BASE_URL", "DATABASE_URL", "CALENDAR_SYNC_INTERVAL", "HTTP_SHUTDOWN_DELAY",
+		"CALENDAR_WORKING_HOURS", "MICROSOFT_CLIENT_SECRET", "TOKEN_ENCRYPTION_KEYS", "OTEL_EXPORTER_OTLP_ENDPOINT",
+		"RATE_LIMIT_STORE",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected the error to mention %s, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoadRejectsUnknownStorageBackend(t *testing.T) {
+	t.Parallel()
+
+	values := validEnv()
+	values["STORAGE_BACKEND"] = "mongodb"
+	if _, err := Load(env(values)); err == nil || !strings.Contains(err.Error(), "STORAGE_BACKEND") {
+		t.Fatalf("expected a STORAGE_BACKEND error, got %v", err)
+	}
+}
+
+func TestLoadOverridesRateLimitsPerRoute(t *testing.T) {
+	t.Parallel()
+
+	values := validEnv()
+	values["RATE_LIMITS"] = "POST /users/login=5/1m, PUT  /energy/levels=off"
+	cfg, err := Load(env(values))
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if login := cfg.RateLimit.Routes["POST /users/login"]; login.Burst != 5 || login.Every != 12*time.Second {
+		t.Fatalf("expected the login limit overridden, got %v", login)
+	}
+	if _, ok := cfg.RateLimit.Routes["PUT /energy/levels"]; ok {
+		t.Fatal("expected the energy limit lifted")
+	}
+	if cfg.RateLimit.Routes["POST /users"] != DefaultRateLimits["POST /users"] {
+		t.Fatal("expected the other routes to keep their default")
+	}
+
+	for _, spec := range []string{"GET /healthz=5/1m", "POST /users/login", "POST /users/login=lots"} {
+		values["RATE_LIMITS"] = spec
+		if _, err := Load(env(values)); err == nil || !strings.Contains(err.Error(), "RATE_LIMITS") {
+			t.Errorf("expected a RATE_LIMITS error for %q, got %v", spec, err)
+		}
+	}
+}
+
+func TestLoadKeepsRateLimitsInTheDatabaseOnLambda(t *testing.T) {
+	t.Parallel()
+
+	values := validEnv()
+	values["AWS_LAMBDA_FUNCTION_NAME"] = "energyjournal-api"
+	cfg, err := Load(env(values))
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.RateLimit.Store != RateLimitDatabase {
+		t.Fatalf("expected the database store on Lambda, got %q", cfg.RateLimit.Store)
+	}
+
+	values["RATE_LIMIT_STORE"] = "memory"
+	if _, err := Load(env(values)); err == nil || !strings.Contains(err.Error(), "RATE_LIMIT_STORE") {
+		t.Fatalf("expected a RATE_LIMIT_STORE error, got %v", err)
+	}
+}
+
+func TestLoadReadsTokenEncryptionKeysOrKMSKey(t *testing.T) {
+	t.Parallel()
+
+	values := validEnv()
+	delete(values, "TOKEN_ENCRYPTION_KEYS")
+	cfg, err := Load(env(values))
+	if err != nil || cfg.TokenEncryptionKeys != nil || cfg.TokenEncryptionKMSKey != "" {
+		t.Fatalf("expected tokens left unencrypted without keys on sqlite, got %+v, %v", cfg.TokenEncryptionKeys, err)
+	}
+
+	kmsKey := "projects/p/locations/europe-west1/keyRings/r/cryptoKeys/tokens"
+	values["TOKEN_ENCRYPTION_KMS_KEY"] = kmsKey
+	if cfg, err := Load(env(values)); err != nil || cfg.TokenEncryptionKMSKey != kmsKey {
+		t.Fatalf("expected the KMS key read, got %q, %v", cfg.TokenEncryptionKMSKey, err)
+	}
+
+	for _, extra := range []map[string]string{
+		{"TOKEN_ENCRYPTION_KMS_KEY": "tokens"},
+		{"TOKEN_ENCRYPTION_KMS_KEY": kmsKey, "TOKEN_ENCRYPTION_KEYS": validEnv()["TOKEN_ENCRYPTION_KEYS"]},
+	} {
+		values := validEnv()
+		delete(values, "TOKEN_ENCRYPTION_KEYS")
+		maps.Copy(values, extra)
+		if _, err := Load(env(values)); err == nil || !strings.Contains(err.Error(), "TOKEN_ENCRYPTION_KMS_KEY") {
+			t.Errorf("expected a TOKEN_ENCRYPTION_KMS_KEY error for %v, got %v", extra, err)
+		}
+	}
+}
+
+func TestLoadRequiresTokenEncryptionOnSharedBackends(t *testing.T) {
+	t.Parallel()
+
+	values := validEnv()
+	delete(values, "TOKEN_ENCRYPTION_KEYS")
+	values["STORAGE_BACKEND"] = "postgres"
+	values["DATABASE_URL"] = "postgres://localhost/energyjournal"
+	if _, err := Load(env(values)); err == nil || !strings.Contains(err.Error(), "TOKEN_ENCRYPTION_DISABLED=true") {
+		t.Fatalf("expected a missing key error, got %v", err)
+	}
+
+	values["TOKEN_ENCRYPTION_DISABLED"] = "true"
+	if cfg, err := Load(env(values)); err != nil || cfg.TokenEncryptionKeys != nil || cfg.TokenEncryptionKMSKey != "" {
+		t.Fatalf("expected the explicit opt-out to keep tokens in plaintext, got %+v, %v", cfg.TokenEncryptionKeys, err)
+	}
+
+	values["TOKEN_ENCRYPTION_KEYS"] = validEnv()["TOKEN_ENCRYPTION_KEYS"]
+	if _, err := Load(env(values)); err == nil || !strings.Contains(err.Error(), "TOKEN_ENCRYPTION_DISABLED") {
+		t.Fatalf("expected the opt-out to conflict with a key, got %v", err)
+	}
+}
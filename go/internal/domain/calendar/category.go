@@ -0,0 +1,68 @@
+package calendar
+
+import (
+	"context"
+	"time"
+)
+
+// MatchType selects which event attribute a CategoryRule matches on.
+type MatchType string
+
+const (
+	MatchColor    MatchType = "color"
+	MatchCalendar MatchType = "calendar"
+	MatchKeyword  MatchType = "keyword"
+	MatchRegex    MatchType = "regex"
+)
+
+// CategoryRule maps events to a user-defined spending category.
+// Rules are evaluated by ascending Priority; the first match wins.
+type CategoryRule struct {
+	ID        string
+	UID       string
+	Category  string
+	MatchType MatchType
+	Value     string
+	Priority  int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// CategorizedEvent is an event annotated with the category it was assigned.
+// RuleID is empty when no rule matched and the color name was used. Hours is
+// the time the event counts for, which differs from its span for all-day events.
+type CategorizedEvent struct {
+	Event
+	Category string
+	RuleID   string
+	Hours    float64
+}
+
+// CategoryPreview shows how a range of events would be categorized.
+type CategoryPreview struct {
+	Start     time.Time
+	End       time.Time
+	Events    []CategorizedEvent
+	Spendings Spendings
+}
+
+type CategoryRuleRepository interface {
+	List(ctx context.Context, uid string) ([]CategoryRule, error)
+	Get(ctx context.Context, uid, id string) (*CategoryRule, error)
+	Create(ctx context.Context, rule *CategoryRule) error
+	Update(ctx context.Context, rule CategoryRule) error
+	Delete(ctx context.Context, uid, id string) error
+}
+
+// CategoryService manages per-user category rules.
+type CategoryService interface {
+	ListCategoryRules(ctx context.Context, uid string) ([]CategoryRule, error)
+	CreateCategoryRule(ctx context.Context, rule CategoryRule) (*CategoryRule, error)
+	UpdateCategoryRule(ctx context.Context, rule CategoryRule) (*CategoryRule, error)
+	DeleteCategoryRule(ctx context.Context, uid, id string) error
+	// PreviewCategories categorizes the week starting at weekStart, or the
+	// current week when it is zero, in the user's timezone. When rules is nil
+	// the stored rules are used, otherwise the given draft rules are applied in
+	// order of priority, then of appearance.
+	PreviewCategories(ctx context.Context, uid string, weekStart time.Time, rules []CategoryRule) (*CategoryPreview, error)
+}
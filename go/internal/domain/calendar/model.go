@@ -2,26 +2,106 @@ package calendar
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
 // Spendings represents time spent per event type (in hours).
 type Spendings map[string]float64
 
+// CalendarSpendings breaks spendings down per selected calendar ID.
+type CalendarSpendings map[string]Spendings
+
+// Granularity selects the bucket size of a spending time series.
+type Granularity string
+
+const (
+	GranularityDay  Granularity = "day"
+	GranularityWeek Granularity = "week"
+)
+
+// SpendingBucket holds the spendings of the half-open interval [Start, End).
+type SpendingBucket struct {
+	Start     time.Time
+	End       time.Time
+	Spendings Spendings
+}
+
+// SpendingSeries is a spending breakdown split into ordered buckets at
+// midnight in Location, the user's timezone. Weeks start on Monday.
+type SpendingSeries struct {
+	Granularity Granularity
+	Location    *time.Location
+	Buckets     []SpendingBucket
+}
+
+// WorkingHours is the daily local time window, in minutes after midnight,
+// counted as available time on the given weekdays.
+type WorkingHours struct {
+	StartMinute int
+	EndMinute   int
+	Days        []time.Weekday
+}
+
+// TimeUsage is a spending breakdown where overlapping events are flattened:
+// each instant is counted once, for the highest-priority category booked then.
+// All-day events are not part of the timeline; they only add to Spendings.
+type TimeUsage struct {
+	Location           *time.Location
+	Spendings          Spendings
+	BookedHours        float64
+	WorkingHours       float64
+	BookedWorkingHours float64
+	FreeWorkingHours   float64
+}
+
 type ConnectionStatus string
 
 const (
 	StatusDisconnected     ConnectionStatus = "disconnected"
 	StatusPendingSelection ConnectionStatus = "pending_selection"
 	StatusConnected        ConnectionStatus = "connected"
+	// StatusNeedsReauth means Google rejected the stored grant (revoked or
+	// expired refresh token); the user must go through OAuth again.
+	StatusNeedsReauth ConnectionStatus = "needs_reauth"
+)
+
+// ErrAuthorizationRevoked is matched (via errors.Is) by provider errors
+// meaning the access token was rejected, such as Google API 401 responses.
+var ErrAuthorizationRevoked = errors.New("calendar authorization rejected")
+
+// Provider identifies the calendar backend a connection belongs to.
+type Provider string
+
+const (
+	ProviderGoogle    Provider = "google"
+	ProviderMicrosoft Provider = "microsoft"
+	// ProviderICS is an iCalendar feed such as a subscription URL.
+	ProviderICS Provider = "ics"
+	// ProviderCalDAV is a CalDAV server reached with a username and password.
+	ProviderCalDAV Provider = "caldav"
 )
 
+// FeedSource locates a calendar connected without OAuth. Username and
+// Password are optional for ICS feeds.
+type FeedSource struct {
+	URL      string
+	Username string
+	Password string
+}
+
 type CalendarConnection struct {
-	UID          string
-	CalendarID   string
+	UID string
+	// Provider is empty for connections stored before Microsoft support,
+	// which are Google connections.
+	Provider    Provider
+	CalendarIDs []string
+	// AccessToken is the credential passed to the provider: an OAuth access
+	// token, or the encoded feed source of ICS and CalDAV connections.
 	AccessToken  string
 	RefreshToken string
 	Expiry       time.Time
+	NeedsReauth  bool
 }
 
 type CalendarItem struct {
@@ -30,15 +110,70 @@ type CalendarItem struct {
 	Color string `json:"color"`
 }
 
+type EventStatus string
+
+const (
+	EventConfirmed EventStatus = "confirmed"
+	EventTentative EventStatus = "tentative"
+	EventCancelled EventStatus = "cancelled"
+)
+
+type ResponseStatus string
+
+const (
+	ResponseNeedsAction ResponseStatus = "needsAction"
+	ResponseDeclined    ResponseStatus = "declined"
+	ResponseTentative   ResponseStatus = "tentative"
+	ResponseAccepted    ResponseStatus = "accepted"
+)
+
+type Attendee struct {
+	Email          string
+	Self           bool
+	Organizer      bool
+	ResponseStatus ResponseStatus
+}
+
+// Event is a calendar event. For all-day events Start and End are midnights
+// in the calendar's timezone and End is exclusive, as returned by Google.
 type Event struct {
-	ColorID string
-	Start   time.Time
-	End     time.Time
+	ID          string
+	CalendarID  string
+	Summary     string
+	ColorID     string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+	Status      EventStatus
+	Transparent bool
+	Attendees   []Attendee
+	// Categories are the provider's labels for the event, such as Outlook categories.
+	Categories []string
+}
+
+// DeclinedBySelf reports whether the calendar owner declined the invitation.
+func (e Event) DeclinedBySelf() bool {
+	for _, attendee := range e.Attendees {
+		if attendee.Self {
+			return attendee.ResponseStatus == ResponseDeclined
+		}
+	}
+	return false
+}
+
+// CountsAsSpending reports whether the event represents time actually spent.
+// Transparent events, shown as free, such as birthdays or reminders, do not.
+func (e Event) CountsAsSpending() bool {
+	return e.Status != EventCancelled && !e.DeclinedBySelf() && !e.Transparent
 }
 
 type CalendarConnectionRepository interface {
 	Get(ctx context.Context, uid string) (*CalendarConnection, error)
 	Upsert(ctx context.Context, conn CalendarConnection) error
+	// Delete removes the connection; deleting a missing connection is not an error.
+	Delete(ctx context.Context, uid string) error
+	// List returns every stored connection, for background jobs.
+	List(ctx context.Context) ([]CalendarConnection, error)
 }
 
 // SpendingService defines the contract for spending retrieval.
@@ -47,12 +182,27 @@ type SpendingService interface {
 	GetSpending(start, end time.Time) (Spendings, error)
 }
 
-// CalendarService defines the full Google Calendar feature contract.
+// CalendarService defines the full calendar feature contract.
 type CalendarService interface {
 	GetStatus(ctx context.Context, uid string) (ConnectionStatus, error)
-	BuildAuthURL(uid string) string
+	// BuildAuthURL returns the consent page of the given provider (the default
+	// one when empty).
+	BuildAuthURL(uid string, provider Provider) (string, error)
 	HandleCallback(ctx context.Context, code, state string) error
+	// ConnectFeed connects an ICS feed or CalDAV server after checking that it
+	// can be read. A source with a single calendar has it selected.
+	ConnectFeed(ctx context.Context, uid string, provider Provider, source FeedSource) error
 	GetCalendars(ctx context.Context, uid string) ([]CalendarItem, error)
-	SetCalendar(ctx context.Context, uid, calendarID string) error
+	SetCalendars(ctx context.Context, uid string, calendarIDs []string) error
+	// Disconnect revokes the OAuth grant, if any, and forgets the connection and its
+	// cached events. Disconnecting twice is not an error.
+	Disconnect(ctx context.Context, uid string) error
 	GetSpending(ctx context.Context, uid string, start, end time.Time) (Spendings, error)
+	GetSpendingByCalendar(ctx context.Context, uid string, start, end time.Time) (CalendarSpendings, error)
+	// GetSpendingSeries splits spendings between the start and end dates into
+	// day or week buckets; only the calendar date of start and end is used.
+	GetSpendingSeries(ctx context.Context, uid string, start, end time.Time, granularity Granularity) (*SpendingSeries, error)
+	// GetTimeUsage flattens overlapping events between the start and end dates
+	// and compares booked time with the configured working hours.
+	GetTimeUsage(ctx context.Context, uid string, start, end time.Time) (*TimeUsage, error)
 }
@@ -0,0 +1,52 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrSyncTokenExpired is returned by an incremental sync when the provider no
+// longer accepts the sync token (Google answers 410 Gone). A full resync is required.
+var ErrSyncTokenExpired = errors.New("calendar sync token expired")
+
+// SyncState tracks the cached copy of one calendar.
+type SyncState struct {
+	UID        string
+	CalendarID string
+	SyncToken  string
+	// WindowStart and WindowEnd bound the times covered by the cache, set on
+	// full sync. Ranges reaching outside them are read live.
+	WindowStart  time.Time
+	WindowEnd    time.Time
+	LastFullSync time.Time
+	LastSyncedAt time.Time
+}
+
+// EventChanges is one sync batch returned by the provider. On a full sync
+// Events holds every event; on an incremental sync it only holds changes,
+// with cancelled events marking deletions.
+type EventChanges struct {
+	Events        []Event
+	NextSyncToken string
+}
+
+// EventCacheRepository stores a per-user copy of calendar events.
+type EventCacheRepository interface {
+	// ReplaceCalendar drops every cached event of the calendar and stores events.
+	ReplaceCalendar(ctx context.Context, uid, calendarID string, events []Event) error
+	// ApplyChanges upserts changed events and removes cancelled ones.
+	ApplyChanges(ctx context.Context, uid, calendarID string, events []Event) error
+	// ListEvents returns cached events of the given calendars overlapping [start, end).
+	ListEvents(ctx context.Context, uid string, calendarIDs []string, start, end time.Time) ([]Event, error)
+	GetSyncState(ctx context.Context, uid, calendarID string) (*SyncState, error)
+	SaveSyncState(ctx context.Context, state SyncState) error
+	// DeleteUser removes every cached event and sync state of the user.
+	DeleteUser(ctx context.Context, uid string) error
+}
+
+// SyncService keeps the event cache current.
+type SyncService interface {
+	SyncUser(ctx context.Context, uid string) error
+	SyncAll(ctx context.Context) error
+}
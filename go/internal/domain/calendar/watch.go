@@ -0,0 +1,51 @@
+package calendar
+
+import (
+	"context"
+	"time"
+)
+
+// WatchChannel is a Google push notification channel for one calendar.
+// Token is the per-channel secret Google echoes back on every notification.
+type WatchChannel struct {
+	ID         string
+	UID        string
+	CalendarID string
+	ResourceID string
+	Token      string
+	Expiration time.Time
+	CreatedAt  time.Time
+}
+
+// WatchRegistration is what the provider returns when a channel is opened.
+type WatchRegistration struct {
+	ResourceID string
+	Expiration time.Time
+}
+
+// Notification is a push notification received on the webhook.
+type Notification struct {
+	ChannelID     string
+	ChannelToken  string
+	ResourceID    string
+	ResourceState string
+}
+
+type WatchChannelRepository interface {
+	// Get returns nil when the channel does not exist.
+	Get(ctx context.Context, id string) (*WatchChannel, error)
+	ListByUID(ctx context.Context, uid string) ([]WatchChannel, error)
+	Save(ctx context.Context, channel WatchChannel) error
+	Delete(ctx context.Context, id string) error
+}
+
+// WatchService registers push channels and reacts to their notifications.
+type WatchService interface {
+	// HandleNotification validates a notification and syncs the calendar it targets.
+	HandleNotification(ctx context.Context, notification Notification) error
+	// WatchUser opens channels for newly selected calendars, renews channels
+	// close to expiry and stops channels of deselected calendars.
+	WatchUser(ctx context.Context, uid string) error
+	// RenewChannels runs WatchUser for every connected user.
+	RenewChannels(ctx context.Context) error
+}
@@ -0,0 +1,50 @@
+package insights
+
+import "context"
+
+// Horizon is the delay between a day's calendar and the energy score it is compared with.
+type Horizon string
+
+const (
+	HorizonSameDay Horizon = "same_day"
+	HorizonNextDay Horizon = "next_day"
+)
+
+// Signal summarizes what a category's effect suggests.
+type Signal string
+
+const (
+	SignalHighEnergy   Signal = "high_energy"
+	SignalLowEnergy    Signal = "low_energy"
+	SignalInconclusive Signal = "inconclusive"
+)
+
+// CategoryEffect compares energy scores after days with and without time in a
+// category. Scores are the mean of the physical, mental and emotional levels.
+type CategoryEffect struct {
+	Category         string
+	Horizon          Horizon
+	DaysWith         int
+	DaysWithout      int
+	MeanScoreWith    float64
+	MeanScoreWithout float64
+	// EffectSize is Cohen's d of the scores with versus without the category;
+	// positive means higher energy on days with it.
+	EffectSize float64
+	// Correlation is the Pearson coefficient between hours spent and the score.
+	Correlation float64
+	Signal      Signal
+}
+
+// TimeEnergyInsights holds the effects of every category seen in the range,
+// strongest first. From and To are inclusive YYYY-MM-DD dates.
+type TimeEnergyInsights struct {
+	From    string
+	To      string
+	SameDay []CategoryEffect
+	NextDay []CategoryEffect
+}
+
+type InsightsService interface {
+	TimeEnergy(ctx context.Context, uid, from, to string) (*TimeEnergyInsights, error)
+}
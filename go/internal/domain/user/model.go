@@ -36,3 +36,31 @@ type AuthTokens struct {
 	RefreshToken string
 	ExpiresIn    string
 }
+
+type NotificationChannel string
+
+const (
+	ChannelEmail NotificationChannel = "email"
+	ChannelPush  NotificationChannel = "push"
+)
+
+// UnsubscribeTopic identifies what an unsubscribe link turns off.
+type UnsubscribeTopic string
+
+const (
+	TopicReminders UnsubscribeTopic = "reminders"
+	TopicDigest    UnsubscribeTopic = "digest"
+	TopicAll       UnsubscribeTopic = "all"
+)
+
+// Preferences holds a user's notification settings.
+// ReminderTime is a local "HH:MM" time interpreted in the user's timezone.
+type Preferences struct {
+	UID          string
+	ReminderTime string
+	ReminderDays []string
+	DigestOptIn  bool
+	Channels     []NotificationChannel
+	Locale       string
+	UpdatedAt    time.Time
+}
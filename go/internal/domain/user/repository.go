@@ -14,3 +14,8 @@ type ActivationTokenRepository interface {
 	Delete(ctx context.Context, token string) error
 	FindExpired(ctx context.Context) ([]*ActivationToken, error)
 }
+
+type PreferencesRepository interface {
+	Get(ctx context.Context, uid string) (*Preferences, error)
+	Upsert(ctx context.Context, prefs Preferences) error
+}
@@ -22,3 +22,10 @@ type AuthProvider interface {
 type EmailSender interface {
 	SendActivationEmail(ctx context.Context, email, activationLink string) error
 }
+
+type PreferencesService interface {
+	Get(ctx context.Context, uid string) (*Preferences, error)
+	Update(ctx context.Context, prefs Preferences) (*Preferences, error)
+	UnsubscribeLink(uid string, topic UnsubscribeTopic) string
+	Unsubscribe(ctx context.Context, token string) error
+}
@@ -0,0 +1,232 @@
+package calendar
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"energyjournal/internal/domain/calendar"
+	errpkg "energyjournal/internal/pkg/error"
+	"energyjournal/internal/server/middleware"
+)
+
+// CategoryHandler handles HTTP requests for user-defined spending categories.
+type CategoryHandler struct {
+	service calendar.CategoryService
+}
+
+// NewCategoryHandler creates a new CategoryHandler.
+func NewCategoryHandler(service calendar.CategoryService) *CategoryHandler {
+	return &CategoryHandler{service: service}
+}
+
+// ListRules godoc
+// @Summary List spending category rules
+// @Description Returns the user's category rules in evaluation order (ascending priority).
+// @Tags calendar
+// @Security BearerAuth
+// @Success 200 {array} calendar.CategoryRuleResponse
+// @Failure 401 {object} calendar.ErrorResponse
+// @Failure 500 {object} calendar.ErrorResponse
+// @Router /calendar/categories [get]
+func (h *CategoryHandler) ListRules(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UIDFromContext(r.Context())
+	if !ok || uid == "" {
+		writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	rules, err := h.service.ListCategoryRules(r.Context(), uid)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response := make([]CategoryRuleResponse, 0, len(rules))
+	for _, rule := range rules {
+		response = append(response, newCategoryRuleResponse(rule))
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// CreateRule godoc
+// @Summary Create a spending category rule
+// @Description Events matching the rule (by color ID, calendar ID, title keyword or title regex) are counted under its category.
+// @Tags calendar
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param body body calendar.CategoryRuleRequest true "Category rule"
+// @Success 201 {object} calendar.CategoryRuleResponse
+// @Failure 400 {object} calendar.ErrorResponse
+// @Failure 401 {object} calendar.ErrorResponse
+// @Failure 500 {object} calendar.ErrorResponse
+// @Router /calendar/categories [post]
+func (h *CategoryHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UIDFromContext(r.Context())
+	if !ok || uid == "" {
+		writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	var req CategoryRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	created, err := h.service.CreateCategoryRule(r.Context(), toCategoryRule(uid, "", req))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, newCategoryRuleResponse(*created))
+}
+
+// UpdateRule godoc
+// @Summary Update a spending category rule
+// @Tags calendar
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Rule ID"
+// @Param body body calendar.CategoryRuleRequest true "Category rule"
+// @Success 200 {object} calendar.CategoryRuleResponse
+// @Failure 400 {object} calendar.ErrorResponse
+// @Failure 401 {object} calendar.ErrorResponse
+// @Failure 404 {object} calendar.ErrorResponse
+// @Failure 500 {object} calendar.ErrorResponse
+// @Router /calendar/categories/{id} [put]
+func (h *CategoryHandler) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UIDFromContext(r.Context())
+	if !ok || uid == "" {
+		writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	var req CategoryRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	updated, err := h.service.UpdateCategoryRule(r.Context(), toCategoryRule(uid, r.PathValue("id"), req))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newCategoryRuleResponse(*updated))
+}
+
+// DeleteRule godoc
+// @Summary Delete a spending category rule
+// @Tags calendar
+// @Security BearerAuth
+// @Param id path string true "Rule ID"
+// @Success 204
+// @Failure 401 {object} calendar.ErrorResponse
+// @Failure 404 {object} calendar.ErrorResponse
+// @Failure 500 {object} calendar.ErrorResponse
+// @Router /calendar/categories/{id} [delete]
+func (h *CategoryHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UIDFromContext(r.Context())
+	if !ok || uid == "" {
+		writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	if err := h.service.DeleteCategoryRule(r.Context(), uid, r.PathValue("id")); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Preview godoc
+// @Summary Preview how a week would be categorized
+// @Description Categorizes every event of the week starting at week_start in the user's timezone (default: this week's Monday). Draft rules, when given, replace the stored rules for the preview only.
+// @Tags calendar
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param body body calendar.PreviewCategoriesRequest true "Week and optional draft rules"
+// @Success 200 {object} calendar.CategoryPreviewResponse
+// @Failure 400 {object} calendar.ErrorResponse
+// @Failure 401 {object} calendar.ErrorResponse
+// @Failure 424 {object} calendar.ErrorResponse
+// @Failure 500 {object} calendar.ErrorResponse
+// @Router /calendar/categories/preview [post]
+func (h *CategoryHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UIDFromContext(r.Context())
+	if !ok || uid == "" {
+		writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	var req PreviewCategoriesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	// A zero week lets the service pick the current week in the user's timezone.
+	var weekStart time.Time
+	if req.WeekStart != "" {
+		parsed, err := time.Parse(dateFormat, req.WeekStart)
+		if err != nil {
+			writeError(w, errpkg.NewInputValidationError("week_start", "invalid date format, expected YYYY-MM-DD"))
+			return
+		}
+		weekStart = parsed
+	}
+
+	var rules []calendar.CategoryRule
+	if req.Rules != nil {
+		rules = make([]calendar.CategoryRule, 0, len(req.Rules))
+		for _, rule := range req.Rules {
+			rules = append(rules, toCategoryRule(uid, "", rule))
+		}
+	}
+
+	preview, err := h.service.PreviewCategories(r.Context(), uid, weekStart, rules)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	events := make([]CategorizedEventResponse, 0, len(preview.Events))
+	for _, event := range preview.Events {
+		events = append(events, CategorizedEventResponse{
+			CalendarID: event.CalendarID,
+			Summary:    event.Summary,
+			ColorID:    event.ColorID,
+			Start:      event.Start,
+			End:        event.End,
+			AllDay:     event.AllDay,
+			Hours:      event.Hours,
+			Category:   event.Category,
+			RuleID:     event.RuleID,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, CategoryPreviewResponse{
+		Start:     preview.Start.Format(dateFormat),
+		End:       preview.End.Format(dateFormat),
+		Events:    events,
+		Spendings: preview.Spendings,
+	})
+}
+
+func toCategoryRule(uid, id string, req CategoryRuleRequest) calendar.CategoryRule {
+	return calendar.CategoryRule{
+		ID:        id,
+		UID:       uid,
+		Category:  req.Category,
+		MatchType: calendar.MatchType(req.MatchType),
+		Value:     req.Value,
+		Priority:  req.Priority,
+	}
+}
@@ -0,0 +1,156 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"energyjournal/internal/domain/calendar"
+	errpkg "energyjournal/internal/pkg/error"
+	"energyjournal/internal/server/middleware"
+)
+
+type stubCategoryService struct {
+	list    func(ctx context.Context, uid string) ([]calendar.CategoryRule, error)
+	create  func(ctx context.Context, rule calendar.CategoryRule) (*calendar.CategoryRule, error)
+	update  func(ctx context.Context, rule calendar.CategoryRule) (*calendar.CategoryRule, error)
+	remove  func(ctx context.Context, uid, id string) error
+	preview func(ctx context.Context, uid string, weekStart time.Time, rules []calendar.CategoryRule) (*calendar.CategoryPreview, error)
+}
+
+func (s *stubCategoryService) ListCategoryRules(ctx context.Context, uid string) ([]calendar.CategoryRule, error) {
+	return s.list(ctx, uid)
+}
+func (s *stubCategoryService) CreateCategoryRule(ctx context.Context, rule calendar.CategoryRule) (*calendar.CategoryRule, error) {
+	return s.create(ctx, rule)
+}
+func (s *stubCategoryService) UpdateCategoryRule(ctx context.Context, rule calendar.CategoryRule) (*calendar.CategoryRule, error) {
+	return s.update(ctx, rule)
+}
+func (s *stubCategoryService) DeleteCategoryRule(ctx context.Context, uid, id string) error {
+	return s.remove(ctx, uid, id)
+}
+func (s *stubCategoryService) PreviewCategories(ctx context.Context, uid string, weekStart time.Time, rules []calendar.CategoryRule) (*calendar.CategoryPreview, error) {
+	return s.preview(ctx, uid, weekStart, rules)
+}
+
+func withUID(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUID, "uid-1"))
+}
+
+func TestCategoryCreateRuleParsesRequest(t *testing.T) {
+	t.Parallel()
+
+	handler := NewCategoryHandler(&stubCategoryService{
+		create: func(ctx context.Context, rule calendar.CategoryRule) (*calendar.CategoryRule, error) {
+			if rule.UID != "uid-1" || rule.Category != "Repas" || rule.MatchType != calendar.MatchKeyword || rule.Value != "lunch" || rule.Priority != 2 {
+				t.Fatalf("unexpected rule: %+v", rule)
+			}
+			rule.ID = "rule-1"
+			return &rule, nil
+		},
+	})
+
+	body := `{"category":"Repas","match_type":"keyword","value":"lunch","priority":2}`
+	req := withUID(httptest.NewRequest(http.MethodPost, "/calendar/categories", strings.NewReader(body)))
+	rr := httptest.NewRecorder()
+	handler.CreateRule(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", rr.Code)
+	}
+	var payload CategoryRuleResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if payload.ID != "rule-1" || payload.MatchType != "keyword" {
+		t.Fatalf("unexpected payload: %+v", payload)
+	}
+}
+
+func TestCategoryUpdateRuleUsesPathID(t *testing.T) {
+	t.Parallel()
+
+	handler := NewCategoryHandler(&stubCategoryService{
+		update: func(ctx context.Context, rule calendar.CategoryRule) (*calendar.CategoryRule, error) {
+			return nil, errpkg.NewNotFoundError("category_rule", rule.ID)
+		},
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /calendar/categories/{id}", handler.UpdateRule)
+	req := withUID(httptest.NewRequest(http.MethodPut, "/calendar/categories/missing", strings.NewReader(`{"category":"A","match_type":"keyword","value":"x"}`)))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), "missing") {
+		t.Fatalf("expected rule id in error, got %s", rr.Body.String())
+	}
+}
+
+func TestCategoryPreviewLeavesDefaultWeekToService(t *testing.T) {
+	t.Parallel()
+
+	var gotWeek time.Time
+	var gotRules []calendar.CategoryRule
+	handler := NewCategoryHandler(&stubCategoryService{
+		preview: func(ctx context.Context, uid string, weekStart time.Time, rules []calendar.CategoryRule) (*calendar.CategoryPreview, error) {
+			gotWeek = weekStart
+			gotRules = rules
+			monday := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+			start := monday.Add(9 * time.Hour)
+			return &calendar.CategoryPreview{
+				Start: monday,
+				End:   monday.AddDate(0, 0, 7),
+				Events: []calendar.CategorizedEvent{{
+					Event:    calendar.Event{CalendarID: "primary", Summary: "Lunch", Start: start, End: start.Add(90 * time.Minute)},
+					Category: "Repas",
+					Hours:    1.5,
+				}},
+				Spendings: calendar.Spendings{"Repas": 1.5},
+			}, nil
+		},
+	})
+
+	req := withUID(httptest.NewRequest(http.MethodPost, "/calendar/categories/preview", strings.NewReader(`{}`)))
+	rr := httptest.NewRecorder()
+	handler.Preview(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if !gotWeek.IsZero() {
+		t.Fatalf("expected the service to pick the week, got %s", gotWeek)
+	}
+	if gotRules != nil {
+		t.Fatalf("expected stored rules (nil), got %v", gotRules)
+	}
+
+	var payload CategoryPreviewResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if payload.Start != "2026-03-02" || payload.End != "2026-03-09" || len(payload.Events) != 1 || payload.Events[0].Hours != 1.5 {
+		t.Fatalf("unexpected payload: %+v", payload)
+	}
+}
+
+func TestCategoryPreviewRejectsInvalidWeekStart(t *testing.T) {
+	t.Parallel()
+
+	handler := NewCategoryHandler(&stubCategoryService{})
+	req := withUID(httptest.NewRequest(http.MethodPost, "/calendar/categories/preview", strings.NewReader(`{"week_start":"03/02/2026"}`)))
+	rr := httptest.NewRecorder()
+	handler.Preview(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
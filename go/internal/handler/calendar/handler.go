@@ -19,7 +19,7 @@ func NewCalendarHandler(service calendar.CalendarService) *CalendarHandler {
 
 // GetStatus godoc
 // @Summary Get Google Calendar connection status
-// @Description Returns a tri-state status: disconnected (no OAuth), pending_selection (OAuth done, no calendar chosen), connected (ready).
+// @Description Returns the connection status: disconnected (no OAuth), pending_selection (OAuth done, no calendar chosen), needs_reauth (Google grant revoked, reconnect through OAuth), connected (ready).
 // @Tags calendar
 // @Security BearerAuth
 // @Success 200 {object} calendar.StatusResponse
@@ -68,13 +68,13 @@ func (h *CalendarHandler) GetCalendars(w http.ResponseWriter, r *http.Request) {
 }
 
 // SetConnection godoc
-// @Summary Save selected calendar
-// @Description Persists the user's chosen Google Calendar ID.
+// @Summary Save selected calendars
+// @Description Persists the user's chosen Google Calendar IDs. The legacy calendar_id field selects a single calendar.
 // @Tags calendar
 // @Security BearerAuth
 // @Accept json
 // @Produce json
-// @Param body body calendar.SetConnectionRequest true "Selected calendar ID"
+// @Param body body calendar.SetConnectionRequest true "Selected calendar IDs"
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} calendar.ErrorResponse
 // @Failure 401 {object} calendar.ErrorResponse
@@ -93,15 +93,78 @@ func (h *CalendarHandler) SetConnection(w http.ResponseWriter, r *http.Request)
 		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
 		return
 	}
-	if req.CalendarID == "" {
-		writeError(w, errpkg.NewInputValidationError("calendar_id", "required"))
+	calendarIDs := req.CalendarIDs
+	if len(calendarIDs) == 0 && req.CalendarID != "" {
+		calendarIDs = []string{req.CalendarID}
+	}
+	if len(calendarIDs) == 0 {
+		writeError(w, errpkg.NewInputValidationError("calendar_ids", "required"))
 		return
 	}
 
-	if err := h.service.SetCalendar(r.Context(), uid, req.CalendarID); err != nil {
+	if err := h.service.SetCalendars(r.Context(), uid, calendarIDs); err != nil {
 		writeError(w, err)
 		return
 	}
 
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
+
+// ConnectFeed godoc
+// @Summary Connect an ICS feed or CalDAV server
+// @Description Replaces the calendar connection with an ICS feed URL (webcal:// links are accepted) or a CalDAV server, after checking it can be read. A feed is its only calendar and is selected right away; CalDAV calendars are then chosen like Google ones.
+// @Tags calendar
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param body body calendar.ConnectFeedRequest true "Calendar source"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} calendar.ErrorResponse
+// @Failure 401 {object} calendar.ErrorResponse
+// @Failure 500 {object} calendar.ErrorResponse
+// @Router /calendar/feed [post]
+func (h *CalendarHandler) ConnectFeed(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UIDFromContext(r.Context())
+	if !ok || uid == "" {
+		writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	var req ConnectFeedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	source := calendar.FeedSource{URL: req.URL, Username: req.Username, Password: req.Password}
+	if err := h.service.ConnectFeed(r.Context(), uid, calendar.Provider(req.Provider), source); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Disconnect godoc
+// @Summary Disconnect Google Calendar
+// @Description Revokes the Google grant and deletes the stored connection and cached events. Succeeds when already disconnected.
+// @Tags calendar
+// @Security BearerAuth
+// @Success 204
+// @Failure 401 {object} calendar.ErrorResponse
+// @Failure 500 {object} calendar.ErrorResponse
+// @Router /calendar/connection [delete]
+func (h *CalendarHandler) Disconnect(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UIDFromContext(r.Context())
+	if !ok || uid == "" {
+		writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	if err := h.service.Disconnect(r.Context(), uid); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
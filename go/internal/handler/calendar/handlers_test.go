@@ -10,36 +10,57 @@ import (
 	"time"
 
 	"energyjournal/internal/domain/calendar"
+	errpkg "energyjournal/internal/pkg/error"
 	"energyjournal/internal/server/middleware"
 )
 
 type stubCalendarService struct {
 	getStatus    func(ctx context.Context, uid string) (calendar.ConnectionStatus, error)
-	buildAuthURL func(uid string) string
+	buildAuthURL func(uid string, provider calendar.Provider) (string, error)
 	callback     func(ctx context.Context, code, state string) error
+	connectFeed  func(ctx context.Context, uid string, provider calendar.Provider, source calendar.FeedSource) error
 	getCalendars func(ctx context.Context, uid string) ([]calendar.CalendarItem, error)
-	setCalendar  func(ctx context.Context, uid, calendarID string) error
+	setCalendars func(ctx context.Context, uid string, calendarIDs []string) error
+	disconnect   func(ctx context.Context, uid string) error
 	getSpending  func(ctx context.Context, uid string, start, end time.Time) (calendar.Spendings, error)
+	getByCal     func(ctx context.Context, uid string, start, end time.Time) (calendar.CalendarSpendings, error)
+	getSeries    func(ctx context.Context, uid string, start, end time.Time, granularity calendar.Granularity) (*calendar.SpendingSeries, error)
+	getUsage     func(ctx context.Context, uid string, start, end time.Time) (*calendar.TimeUsage, error)
 }
 
 func (s *stubCalendarService) GetStatus(ctx context.Context, uid string) (calendar.ConnectionStatus, error) {
 	return s.getStatus(ctx, uid)
 }
-func (s *stubCalendarService) BuildAuthURL(uid string) string {
-	return s.buildAuthURL(uid)
+func (s *stubCalendarService) BuildAuthURL(uid string, provider calendar.Provider) (string, error) {
+	return s.buildAuthURL(uid, provider)
 }
 func (s *stubCalendarService) HandleCallback(ctx context.Context, code, state string) error {
 	return s.callback(ctx, code, state)
 }
+func (s *stubCalendarService) ConnectFeed(ctx context.Context, uid string, provider calendar.Provider, source calendar.FeedSource) error {
+	return s.connectFeed(ctx, uid, provider, source)
+}
 func (s *stubCalendarService) GetCalendars(ctx context.Context, uid string) ([]calendar.CalendarItem, error) {
 	return s.getCalendars(ctx, uid)
 }
-func (s *stubCalendarService) SetCalendar(ctx context.Context, uid, calendarID string) error {
-	return s.setCalendar(ctx, uid, calendarID)
+func (s *stubCalendarService) SetCalendars(ctx context.Context, uid string, calendarIDs []string) error {
+	return s.setCalendars(ctx, uid, calendarIDs)
+}
+func (s *stubCalendarService) Disconnect(ctx context.Context, uid string) error {
+	return s.disconnect(ctx, uid)
 }
 func (s *stubCalendarService) GetSpending(ctx context.Context, uid string, start, end time.Time) (calendar.Spendings, error) {
 	return s.getSpending(ctx, uid, start, end)
 }
+func (s *stubCalendarService) GetSpendingByCalendar(ctx context.Context, uid string, start, end time.Time) (calendar.CalendarSpendings, error) {
+	return s.getByCal(ctx, uid, start, end)
+}
+func (s *stubCalendarService) GetTimeUsage(ctx context.Context, uid string, start, end time.Time) (*calendar.TimeUsage, error) {
+	return s.getUsage(ctx, uid, start, end)
+}
+func (s *stubCalendarService) GetSpendingSeries(ctx context.Context, uid string, start, end time.Time, granularity calendar.Granularity) (*calendar.SpendingSeries, error) {
+	return s.getSeries(ctx, uid, start, end, granularity)
+}
 
 func TestStatusHandlerResponseShape(t *testing.T) {
 	t.Parallel()
@@ -71,8 +92,8 @@ func TestOAuthGetAuthURLResponseShape(t *testing.T) {
 	t.Parallel()
 
 	handler := NewOAuthHandler(&stubCalendarService{
-		buildAuthURL: func(uid string) string {
-			return "https://accounts.google.com/o/oauth2/auth?state=abc"
+		buildAuthURL: func(uid string, provider calendar.Provider) (string, error) {
+			return "https://accounts.google.com/o/oauth2/auth?state=abc", nil
 		},
 	}, "http://localhost:8080")
 
@@ -93,13 +114,68 @@ func TestOAuthGetAuthURLResponseShape(t *testing.T) {
 	}
 }
 
+func TestOAuthGetAuthURLPassesProvider(t *testing.T) {
+	t.Parallel()
+
+	var requested calendar.Provider
+	handler := NewOAuthHandler(&stubCalendarService{
+		buildAuthURL: func(uid string, provider calendar.Provider) (string, error) {
+			requested = provider
+			if provider != calendar.ProviderMicrosoft {
+				return "", errpkg.NewInputValidationError("provider", "unknown calendar provider")
+			}
+			return "https://login.microsoftonline.com/common/oauth2/v2.0/authorize?state=abc", nil
+		},
+	}, "http://localhost:8080")
+
+	req := httptest.NewRequest(http.MethodGet, "/calendar/auth?provider=microsoft", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUID, "uid-1"))
+	rr := httptest.NewRecorder()
+	handler.GetAuthURL(rr, req)
+	if rr.Code != http.StatusOK || requested != calendar.ProviderMicrosoft {
+		t.Fatalf("expected 200 for microsoft, got %d (provider %q)", rr.Code, requested)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/calendar/auth?provider=yahoo", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUID, "uid-1"))
+	rr = httptest.NewRecorder()
+	handler.GetAuthURL(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown provider, got %d", rr.Code)
+	}
+}
+
 func TestCalendarsSetConnectionParsesRequest(t *testing.T) {
 	t.Parallel()
 
-	captured := ""
+	var captured []string
 	handler := NewCalendarHandler(&stubCalendarService{
-		setCalendar: func(ctx context.Context, uid, calendarID string) error {
-			captured = calendarID
+		setCalendars: func(ctx context.Context, uid string, calendarIDs []string) error {
+			captured = calendarIDs
+			return nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPut, "/calendar/connection", strings.NewReader(`{"calendar_ids":["primary","work@example.com"]}`))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUID, "uid-1"))
+	rr := httptest.NewRecorder()
+	handler.SetConnection(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if len(captured) != 2 || captured[0] != "primary" || captured[1] != "work@example.com" {
+		t.Fatalf("unexpected captured calendar ids %v", captured)
+	}
+}
+
+func TestCalendarsSetConnectionAcceptsLegacyCalendarID(t *testing.T) {
+	t.Parallel()
+
+	var captured []string
+	handler := NewCalendarHandler(&stubCalendarService{
+		setCalendars: func(ctx context.Context, uid string, calendarIDs []string) error {
+			captured = calendarIDs
 			return nil
 		},
 	})
@@ -112,8 +188,48 @@ func TestCalendarsSetConnectionParsesRequest(t *testing.T) {
 	if rr.Code != http.StatusOK {
 		t.Fatalf("expected 200, got %d", rr.Code)
 	}
-	if captured != "primary" {
-		t.Fatalf("expected captured calendar id primary, got %q", captured)
+	if len(captured) != 1 || captured[0] != "primary" {
+		t.Fatalf("expected captured calendar ids [primary], got %v", captured)
+	}
+}
+
+func TestConnectFeedHandlerPassesSource(t *testing.T) {
+	t.Parallel()
+
+	var captured calendar.FeedSource
+	handler := NewCalendarHandler(&stubCalendarService{
+		connectFeed: func(ctx context.Context, uid string, provider calendar.Provider, source calendar.FeedSource) error {
+			if uid != "uid-1" || provider != calendar.ProviderCalDAV {
+				t.Fatalf("unexpected uid %q or provider %q", uid, provider)
+			}
+			captured = source
+			if source.Password != "secret" {
+				return errpkg.NewInputValidationError("password", "credentials rejected")
+			}
+			return nil
+		},
+	})
+
+	body := `{"provider":"caldav","url":"https://dav.example.com/calendars/ana/","username":"ana","password":"secret"}`
+	req := httptest.NewRequest(http.MethodPost, "/calendar/feed", strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUID, "uid-1"))
+	rr := httptest.NewRecorder()
+	handler.ConnectFeed(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if captured.URL != "https://dav.example.com/calendars/ana/" || captured.Username != "ana" {
+		t.Fatalf("unexpected source %+v", captured)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/calendar/feed", strings.NewReader(strings.Replace(body, "secret", "wrong", 1)))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUID, "uid-1"))
+	rr = httptest.NewRecorder()
+	handler.ConnectFeed(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for rejected credentials, got %d", rr.Code)
 	}
 }
 
@@ -146,3 +262,193 @@ func TestSpendingHandlerParsesDatesAndReturnsMap(t *testing.T) {
 		t.Fatalf("expected Sage=3.5, got %v", payload["Sage"])
 	}
 }
+
+func TestSpendingHandlerReportsReauthRequired(t *testing.T) {
+	t.Parallel()
+
+	handler := NewSpendingHandler(&stubCalendarService{
+		getSpending: func(context.Context, string, time.Time, time.Time) (calendar.Spendings, error) {
+			return nil, errpkg.NewCalendarReauthRequiredError("")
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/calendar/spending?start=2026-03-01&end=2026-03-03", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUID, "uid-1"))
+	rr := httptest.NewRecorder()
+	handler.GetSpending(rr, req)
+
+	if rr.Code != http.StatusFailedDependency {
+		t.Fatalf("expected 424, got %d", rr.Code)
+	}
+	var payload ErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if payload.Code != "reauth_required" || payload.Error == "" {
+		t.Fatalf("unexpected payload: %+v", payload)
+	}
+}
+
+func TestSpendingHandlerCalendarBreakdown(t *testing.T) {
+	t.Parallel()
+
+	handler := NewSpendingHandler(&stubCalendarService{
+		getByCal: func(ctx context.Context, uid string, start, end time.Time) (calendar.CalendarSpendings, error) {
+			return calendar.CalendarSpendings{
+				"primary": {"Sage": 2},
+				"work":    {"Tomato": 1.5},
+			}, nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/calendar/spending?start=2026-03-01&end=2026-03-03&breakdown=calendar", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUID, "uid-1"))
+	rr := httptest.NewRecorder()
+	handler.GetSpending(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+
+	var payload map[string]map[string]float64
+	if err := json.Unmarshal(rr.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if payload["primary"]["Sage"] != 2 || payload["work"]["Tomato"] != 1.5 {
+		t.Fatalf("unexpected payload: %v", payload)
+	}
+}
+
+func TestSpendingHandlerReturnsSeries(t *testing.T) {
+	t.Parallel()
+
+	paris, _ := time.LoadLocation("Europe/Paris")
+	handler := NewSpendingHandler(&stubCalendarService{
+		getSeries: func(_ context.Context, _ string, start, end time.Time, granularity calendar.Granularity) (*calendar.SpendingSeries, error) {
+			if granularity != calendar.GranularityWeek || start.Format("2006-01-02") != "2026-03-04" {
+				t.Fatalf("unexpected call: %s %s", granularity, start)
+			}
+			monday := time.Date(2026, 3, 9, 0, 0, 0, 0, paris)
+			return &calendar.SpendingSeries{
+				Granularity: granularity,
+				Location:    paris,
+				Buckets: []calendar.SpendingBucket{
+					{Start: time.Date(2026, 3, 4, 0, 0, 0, 0, paris), End: monday, Spendings: calendar.Spendings{"Sage": 2}},
+					{Start: monday, End: time.Date(2026, 3, 11, 0, 0, 0, 0, paris), Spendings: calendar.Spendings{}},
+				},
+			}, nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/calendar/spending?start=2026-03-04&end=2026-03-11&granularity=week", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUID, "uid-1"))
+	rr := httptest.NewRecorder()
+	handler.GetSpending(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var payload SpendingSeriesResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if payload.Timezone != "Europe/Paris" || len(payload.Buckets) != 2 {
+		t.Fatalf("unexpected payload: %+v", payload)
+	}
+	if payload.Buckets[0].Start != "2026-03-04" || payload.Buckets[0].End != "2026-03-09" || payload.Buckets[0].Spendings["Sage"] != 2 {
+		t.Fatalf("unexpected first bucket: %+v", payload.Buckets[0])
+	}
+}
+
+func TestSpendingHandlerRejectsGranularityWithCalendarBreakdown(t *testing.T) {
+	t.Parallel()
+
+	handler := NewSpendingHandler(&stubCalendarService{})
+	req := httptest.NewRequest(http.MethodGet, "/calendar/spending?start=2026-03-01&end=2026-03-03&granularity=day&breakdown=calendar", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUID, "uid-1"))
+	rr := httptest.NewRecorder()
+	handler.GetSpending(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestSpendingHandlerFlattensOverlaps(t *testing.T) {
+	t.Parallel()
+
+	handler := NewSpendingHandler(&stubCalendarService{
+		getUsage: func(context.Context, string, time.Time, time.Time) (*calendar.TimeUsage, error) {
+			return &calendar.TimeUsage{
+				Location:           time.UTC,
+				Spendings:          calendar.Spendings{"Sage": 6},
+				BookedHours:        6,
+				WorkingHours:       9,
+				BookedWorkingHours: 5,
+				FreeWorkingHours:   4,
+			}, nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/calendar/spending?start=2026-03-02&end=2026-03-03&overlaps=flatten", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUID, "uid-1"))
+	rr := httptest.NewRecorder()
+	handler.GetSpending(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var payload TimeUsageResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if payload.Spendings["Sage"] != 6 || payload.FreeWorkingHours != 4 || payload.Timezone != "UTC" {
+		t.Fatalf("unexpected payload: %+v", payload)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/calendar/spending?start=2026-03-02&end=2026-03-03&overlaps=flatten&granularity=day", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUID, "uid-1"))
+	rr = httptest.NewRecorder()
+	handler.GetSpending(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 when combined with granularity, got %d", rr.Code)
+	}
+}
+
+func TestSpendingHandlerRejectsUnknownBreakdown(t *testing.T) {
+	t.Parallel()
+
+	handler := NewSpendingHandler(&stubCalendarService{})
+
+	req := httptest.NewRequest(http.MethodGet, "/calendar/spending?start=2026-03-01&end=2026-03-03&breakdown=week", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUID, "uid-1"))
+	rr := httptest.NewRecorder()
+	handler.GetSpending(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestDisconnectHandlerReturnsNoContent(t *testing.T) {
+	t.Parallel()
+
+	var gotUID string
+	handler := NewCalendarHandler(&stubCalendarService{
+		disconnect: func(ctx context.Context, uid string) error {
+			gotUID = uid
+			return nil
+		},
+	})
+
+	req := withUID(httptest.NewRequest(http.MethodDelete, "/calendar/connection", nil))
+	rr := httptest.NewRecorder()
+	handler.Disconnect(rr, req)
+
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rr.Code)
+	}
+	if gotUID != "uid-1" {
+		t.Fatalf("expected uid-1, got %q", gotUID)
+	}
+}
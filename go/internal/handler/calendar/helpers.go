@@ -2,10 +2,8 @@ package calendar
 
 import (
 	"encoding/json"
-	"errors"
 	"net/http"
 
-	errpkg "energyjournal/internal/pkg/error"
 	"energyjournal/internal/pkg/httputil"
 )
 
@@ -16,12 +14,6 @@ func writeJSON(w http.ResponseWriter, statusCode int, payload any) {
 }
 
 func writeError(w http.ResponseWriter, err error) {
-	var notConnected *errpkg.CalendarNotConnectedError
-	if errors.As(err, &notConnected) {
-		writeJSON(w, http.StatusFailedDependency, ErrorResponse{Error: notConnected.Error()})
-		return
-	}
-
 	statusCode, message := httputil.MapErrors(err)
-	writeJSON(w, statusCode, ErrorResponse{Error: message})
+	writeJSON(w, statusCode, ErrorResponse{Error: message, Code: httputil.ErrorCode(err)})
 }
@@ -21,10 +21,12 @@ func NewOAuthHandler(service calendar.CalendarService, frontendBaseURL string) *
 }
 
 // GetAuthURL godoc
-// @Summary Build Google OAuth authorization URL
+// @Summary Build calendar OAuth authorization URL
 // @Tags calendar
 // @Security BearerAuth
+// @Param provider query string false "Calendar provider: google (default) or microsoft"
 // @Success 200 {object} calendar.AuthURLResponse
+// @Failure 400 {object} calendar.ErrorResponse
 // @Failure 401 {object} calendar.ErrorResponse
 // @Failure 500 {object} calendar.ErrorResponse
 // @Router /calendar/auth [get]
@@ -35,12 +37,17 @@ func (h *OAuthHandler) GetAuthURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	writeJSON(w, http.StatusOK, AuthURLResponse{AuthURL: h.service.BuildAuthURL(uid)})
+	authURL, err := h.service.BuildAuthURL(uid, calendar.Provider(r.URL.Query().Get("provider")))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, AuthURLResponse{AuthURL: authURL})
 }
 
 // Callback godoc
-// @Summary Google OAuth callback
-// @Description Receives the authorization code from Google, validates state and stores tokens.
+// @Summary Calendar OAuth callback
+// @Description Receives the authorization code from the calendar provider, validates state and stores tokens.
 // @Tags calendar
 // @Param code query string true "Authorization code"
 // @Param state query string true "Signed state"
@@ -1,5 +1,30 @@
 package calendar
 
+// SetConnectionRequest selects the calendars used for spending.
+// CalendarID is the legacy single-calendar field and is still accepted.
 type SetConnectionRequest struct {
-	CalendarID string `json:"calendar_id"`
+	CalendarIDs []string `json:"calendar_ids"`
+	CalendarID  string   `json:"calendar_id,omitempty"`
+}
+
+// ConnectFeedRequest connects an ICS feed or a CalDAV server. Username and
+// Password are sent with HTTP basic authentication when set.
+type ConnectFeedRequest struct {
+	Provider string `json:"provider" enums:"ics,caldav"`
+	URL      string `json:"url"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+type CategoryRuleRequest struct {
+	Category  string `json:"category"`
+	MatchType string `json:"match_type" enums:"color,calendar,keyword,regex"`
+	Value     string `json:"value"`
+	Priority  int    `json:"priority"`
+}
+
+// PreviewCategoriesRequest previews a week. When Rules is omitted the stored rules are used.
+type PreviewCategoriesRequest struct {
+	WeekStart string                `json:"week_start"`
+	Rules     []CategoryRuleRequest `json:"rules,omitempty"`
 }
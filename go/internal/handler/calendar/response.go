@@ -1,6 +1,10 @@
 package calendar
 
-import "energyjournal/internal/domain/calendar"
+import (
+	"time"
+
+	"energyjournal/internal/domain/calendar"
+)
 
 type StatusResponse struct {
 	Status calendar.ConnectionStatus `json:"status"`
@@ -12,4 +16,67 @@ type AuthURLResponse struct {
 
 type ErrorResponse struct {
 	Error string `json:"error"`
+	// Code is set to reauth_required when the user must reconnect Google Calendar.
+	Code string `json:"code,omitempty"`
+}
+
+type CategoryRuleResponse struct {
+	ID        string `json:"id"`
+	Category  string `json:"category"`
+	MatchType string `json:"match_type"`
+	Value     string `json:"value"`
+	Priority  int    `json:"priority"`
+}
+
+type CategorizedEventResponse struct {
+	CalendarID string    `json:"calendar_id"`
+	Summary    string    `json:"summary"`
+	ColorID    string    `json:"color_id"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	AllDay     bool      `json:"all_day"`
+	Hours      float64   `json:"hours"`
+	Category   string    `json:"category"`
+	RuleID     string    `json:"rule_id,omitempty"`
+}
+
+type CategoryPreviewResponse struct {
+	Start     string                     `json:"start"`
+	End       string                     `json:"end"`
+	Events    []CategorizedEventResponse `json:"events"`
+	Spendings calendar.Spendings         `json:"spendings"`
+}
+
+// SpendingBucketResponse covers the dates from Start (inclusive) to End (exclusive).
+type SpendingBucketResponse struct {
+	Start     string             `json:"start"`
+	End       string             `json:"end"`
+	Spendings calendar.Spendings `json:"spendings"`
+}
+
+type SpendingSeriesResponse struct {
+	Granularity string                   `json:"granularity"`
+	Timezone    string                   `json:"timezone"`
+	Buckets     []SpendingBucketResponse `json:"buckets"`
+}
+
+// TimeUsageResponse reports flattened spendings and booked versus free time
+// within working hours, all in hours.
+type TimeUsageResponse struct {
+	Timezone           string             `json:"timezone"`
+	Spendings          calendar.Spendings `json:"spendings"`
+	BookedHours        float64            `json:"booked_hours"`
+	WorkingHours       float64            `json:"working_hours"`
+	BookedWorkingHours float64            `json:"booked_working_hours"`
+	FreeWorkingHours   float64            `json:"free_working_hours"`
+}
+
+func newCategoryRuleResponse(rule calendar.CategoryRule) CategoryRuleResponse {
+	return CategoryRuleResponse{
+		ID:        rule.ID,
+		Category:  rule.Category,
+		MatchType: string(rule.MatchType),
+		Value:     rule.Value,
+		Priority:  rule.Priority,
+	}
 }
@@ -12,6 +12,16 @@ import (
 
 const dateFormat = "2006-01-02"
 
+const (
+	breakdownColor    = "color"
+	breakdownCalendar = "calendar"
+)
+
+const (
+	overlapsSum     = "sum"
+	overlapsFlatten = "flatten"
+)
+
 // SpendingHandler handles HTTP requests for calendar spending.
 type SpendingHandler struct {
 	service calendar.CalendarService
@@ -23,13 +33,20 @@ func NewSpendingHandler(service calendar.CalendarService) *SpendingHandler {
 }
 
 // GetSpending handles GET /calendar/spending requests.
-// @Summary Get time spendings from the selected Google Calendar
-// @Description Aggregates event durations from the user's selected Google Calendar grouped by event color label.
+// @Summary Get time spendings from the selected Google Calendars
+// @Description Aggregates event durations from the user's selected Google Calendars grouped by event color label.
+// @Description By default the result is a calendar.Spendings, hours keyed by category: {"Meetings": 12.5, "Sport": 3}.
+// @Description With breakdown=calendar the result is a calendar.CalendarSpendings instead, keyed by calendar ID, each holding its own category breakdown: {"primary": {"Meetings": 12.5}, "family": {"Sport": 3}}.
+// @Description With granularity=day or week the result is a calendar.SpendingSeriesResponse of ordered buckets split at midnight in the user's timezone (weeks start on Monday); events crossing a boundary are prorated.
+// @Description With overlaps=flatten the result is a calendar.TimeUsageResponse: overlapping events count once, for the highest-priority category, and booked time is compared with free time within working hours.
 // @Tags calendar
 // @Security BearerAuth
 // @Param start query string true "Start date (YYYY-MM-DD)"
 // @Param end query string true "End date (YYYY-MM-DD)"
-// @Success 200 {object} calendar.Spendings
+// @Param breakdown query string false "Grouping (color or calendar, default color)" Enums(color, calendar)
+// @Param granularity query string false "Split into buckets (day or week); not combinable with breakdown=calendar" Enums(day, week)
+// @Param overlaps query string false "How overlapping events are counted (sum or flatten, default sum); flatten is not combinable with breakdown or granularity" Enums(sum, flatten)
+// @Success 200 {object} calendar.Spendings "Hours per category: {"<category>": hours}. With breakdown=calendar, a calendar.CalendarSpendings instead: {"<calendar ID>": {"<category>": hours}}"
 // @Failure 400 {object} calendar.ErrorResponse
 // @Failure 401 {object} calendar.ErrorResponse
 // @Failure 424 {object} calendar.ErrorResponse
@@ -60,13 +77,79 @@ func (h *SpendingHandler) GetSpending(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	breakdown := r.URL.Query().Get("breakdown")
+	if breakdown == "" {
+		breakdown = breakdownColor
+	}
+	if breakdown != breakdownColor && breakdown != breakdownCalendar {
+		writeError(w, errpkg.NewInputValidationError("breakdown", "must be color or calendar"))
+		return
+	}
+
+	granularity := calendar.Granularity(r.URL.Query().Get("granularity"))
+	if granularity != "" {
+		if granularity != calendar.GranularityDay && granularity != calendar.GranularityWeek {
+			writeError(w, errpkg.NewInputValidationError("granularity", "must be day or week"))
+			return
+		}
+		if breakdown != breakdownColor {
+			writeError(w, errpkg.NewInputValidationError("granularity", "not supported with breakdown=calendar"))
+			return
+		}
+	}
+
+	overlaps := r.URL.Query().Get("overlaps")
+	if overlaps == "" {
+		overlaps = overlapsSum
+	}
+	if overlaps != overlapsSum && overlaps != overlapsFlatten {
+		writeError(w, errpkg.NewInputValidationError("overlaps", "must be sum or flatten"))
+		return
+	}
+	if overlaps == overlapsFlatten && (breakdown != breakdownColor || granularity != "") {
+		writeError(w, errpkg.NewInputValidationError("overlaps", "flatten is not supported with breakdown or granularity"))
+		return
+	}
+
 	uid, ok := middleware.UIDFromContext(r.Context())
 	if !ok || uid == "" {
 		writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
 		return
 	}
 
-	spendings, err := h.service.GetSpending(r.Context(), uid, start, end)
+	if overlaps == overlapsFlatten {
+		usage, err := h.service.GetTimeUsage(r.Context(), uid, start, end)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, TimeUsageResponse{
+			Timezone:           usage.Location.String(),
+			Spendings:          usage.Spendings,
+			BookedHours:        usage.BookedHours,
+			WorkingHours:       usage.WorkingHours,
+			BookedWorkingHours: usage.BookedWorkingHours,
+			FreeWorkingHours:   usage.FreeWorkingHours,
+		})
+		return
+	}
+
+	if granularity != "" {
+		series, err := h.service.GetSpendingSeries(r.Context(), uid, start, end, granularity)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, newSpendingSeriesResponse(series))
+		return
+	}
+
+	var spendings any
+	if breakdown == breakdownCalendar {
+		spendings, err = h.service.GetSpendingByCalendar(r.Context(), uid, start, end)
+	} else {
+		spendings, err = h.service.GetSpending(r.Context(), uid, start, end)
+	}
 	if err != nil {
 		writeError(w, err)
 		return
@@ -75,3 +158,19 @@ func (h *SpendingHandler) GetSpending(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(spendings)
 }
+
+func newSpendingSeriesResponse(series *calendar.SpendingSeries) SpendingSeriesResponse {
+	buckets := make([]SpendingBucketResponse, 0, len(series.Buckets))
+	for _, bucket := range series.Buckets {
+		buckets = append(buckets, SpendingBucketResponse{
+			Start:     bucket.Start.Format(dateFormat),
+			End:       bucket.End.Format(dateFormat),
+			Spendings: bucket.Spendings,
+		})
+	}
+	return SpendingSeriesResponse{
+		Granularity: string(series.Granularity),
+		Timezone:    series.Location.String(),
+		Buckets:     buckets,
+	}
+}
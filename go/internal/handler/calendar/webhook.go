@@ -0,0 +1,50 @@
+package calendar
+
+import (
+	"net/http"
+
+	"energyjournal/internal/domain/calendar"
+)
+
+// WebhookHandler receives Google Calendar push notifications.
+type WebhookHandler struct {
+	service calendar.WatchService
+}
+
+// NewWebhookHandler creates a new WebhookHandler.
+func NewWebhookHandler(service calendar.WatchService) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+// Notify godoc
+// @Summary Receive Google Calendar push notifications
+// @Description Called by Google for every change on a watched calendar. The channel token and resource ID must match the registered channel; the calendar is then synced incrementally.
+// @Tags calendar
+// @Param X-Goog-Channel-ID header string true "Channel ID"
+// @Param X-Goog-Channel-Token header string true "Channel token"
+// @Param X-Goog-Resource-ID header string true "Watched resource ID"
+// @Param X-Goog-Resource-State header string true "sync, exists or not_exists"
+// @Success 204
+// @Failure 401 {object} calendar.ErrorResponse
+// @Failure 404 {object} calendar.ErrorResponse
+// @Failure 500 {object} calendar.ErrorResponse
+// @Router /calendar/webhook [post]
+func (h *WebhookHandler) Notify(w http.ResponseWriter, r *http.Request) {
+	notification := calendar.Notification{
+		ChannelID:     r.Header.Get("X-Goog-Channel-ID"),
+		ChannelToken:  r.Header.Get("X-Goog-Channel-Token"),
+		ResourceID:    r.Header.Get("X-Goog-Resource-ID"),
+		ResourceState: r.Header.Get("X-Goog-Resource-State"),
+	}
+	if notification.ChannelID == "" || notification.ChannelToken == "" {
+		writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	if err := h.service.HandleNotification(r.Context(), notification); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,68 @@
+package calendar
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"energyjournal/internal/domain/calendar"
+	errpkg "energyjournal/internal/pkg/error"
+)
+
+type stubWatchService struct {
+	handle func(ctx context.Context, notification calendar.Notification) error
+}
+
+func (s *stubWatchService) HandleNotification(ctx context.Context, notification calendar.Notification) error {
+	return s.handle(ctx, notification)
+}
+func (s *stubWatchService) WatchUser(context.Context, string) error { return nil }
+func (s *stubWatchService) RenewChannels(context.Context) error     { return nil }
+
+func TestWebhookNotifyPassesGoogleHeaders(t *testing.T) {
+	t.Parallel()
+
+	var got calendar.Notification
+	handler := NewWebhookHandler(&stubWatchService{
+		handle: func(_ context.Context, notification calendar.Notification) error {
+			got = notification
+			return nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/calendar/webhook", nil)
+	req.Header.Set("X-Goog-Channel-ID", "ch-1")
+	req.Header.Set("X-Goog-Channel-Token", "secret")
+	req.Header.Set("X-Goog-Resource-ID", "res-1")
+	req.Header.Set("X-Goog-Resource-State", "exists")
+	rr := httptest.NewRecorder()
+	handler.Notify(rr, req)
+
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rr.Code)
+	}
+	if got != (calendar.Notification{ChannelID: "ch-1", ChannelToken: "secret", ResourceID: "res-1", ResourceState: "exists"}) {
+		t.Fatalf("unexpected notification: %+v", got)
+	}
+}
+
+func TestWebhookNotifyRejectsInvalidToken(t *testing.T) {
+	t.Parallel()
+
+	handler := NewWebhookHandler(&stubWatchService{
+		handle: func(context.Context, calendar.Notification) error {
+			return errpkg.NewAuthenticationError("invalid channel token")
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/calendar/webhook", nil)
+	req.Header.Set("X-Goog-Channel-ID", "ch-1")
+	req.Header.Set("X-Goog-Channel-Token", "guess")
+	rr := httptest.NewRecorder()
+	handler.Notify(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rr.Code)
+	}
+}
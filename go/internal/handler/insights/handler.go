@@ -0,0 +1,80 @@
+package insights
+
+import (
+	"net/http"
+
+	"energyjournal/internal/domain/insights"
+	"energyjournal/internal/pkg/httputil"
+	"energyjournal/internal/server/middleware"
+)
+
+type InsightsHandler struct {
+	service insights.InsightsService
+}
+
+func New(service insights.InsightsService) *InsightsHandler {
+	return &InsightsHandler{service: service}
+}
+
+// GetTimeEnergy godoc
+// @Summary Correlate calendar time with energy scores
+// @Description Joins daily hours per calendar category with the energy score (mean of physical, mental and emotional) of the same day and of the next day.
+// @Description Each category reports the days with and without time in it, the mean scores of both groups, Cohen's d and the Pearson correlation between hours and score.
+// @Description from and to are given together; without them the last 90 days are used. The range may not exceed 365 days.
+// @Tags insights
+// @Security BearerAuth
+// @Param from query string false "Start date (YYYY-MM-DD, inclusive)" example(2026-01-01)
+// @Param to query string false "End date (YYYY-MM-DD, inclusive)" example(2026-03-31)
+// @Success 200 {object} insights.TimeEnergyResponse
+// @Failure 400 {object} insights.ErrorResponse
+// @Failure 401 {object} insights.ErrorResponse
+// @Failure 424 {object} insights.ErrorResponse
+// @Failure 500 {object} insights.ErrorResponse
+// @Router /insights/time-energy [get]
+func (h *InsightsHandler) GetTimeEnergy(w http.ResponseWriter, r *http.Request) {
+	u, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	result, err := h.service.TimeEnergy(r.Context(), u.UID, r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, TimeEnergyResponse{
+		From:    result.From,
+		To:      result.To,
+		SameDay: newCategoryEffectResponses(result.SameDay),
+		NextDay: newCategoryEffectResponses(result.NextDay),
+	})
+}
+
+func newCategoryEffectResponses(effects []insights.CategoryEffect) []CategoryEffectResponse {
+	out := make([]CategoryEffectResponse, 0, len(effects))
+	for _, effect := range effects {
+		out = append(out, CategoryEffectResponse{
+			Category:         effect.Category,
+			DaysWith:         effect.DaysWith,
+			DaysWithout:      effect.DaysWithout,
+			MeanScoreWith:    effect.MeanScoreWith,
+			MeanScoreWithout: effect.MeanScoreWithout,
+			EffectSize:       effect.EffectSize,
+			Correlation:      effect.Correlation,
+			Signal:           string(effect.Signal),
+		})
+	}
+	return out
+}
+
+// writeDomainError hides the message of unexpected errors, which may carry
+// provider or storage details.
+func writeDomainError(w http.ResponseWriter, err error) {
+	statusCode, message := httputil.MapErrors(err)
+	if statusCode == http.StatusInternalServerError {
+		message = "internal server error"
+	}
+	writeJSON(w, statusCode, ErrorResponse{Error: message, Code: httputil.ErrorCode(err)})
+}
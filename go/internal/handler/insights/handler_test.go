@@ -0,0 +1,130 @@
+package insights
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"energyjournal/internal/domain/insights"
+	"energyjournal/internal/domain/user"
+	pkgerror "energyjournal/internal/pkg/error"
+	"energyjournal/internal/server/middleware"
+)
+
+type stubInsightsService struct {
+	timeEnergy func(ctx context.Context, uid, from, to string) (*insights.TimeEnergyInsights, error)
+}
+
+func (s *stubInsightsService) TimeEnergy(ctx context.Context, uid, from, to string) (*insights.TimeEnergyInsights, error) {
+	return s.timeEnergy(ctx, uid, from, to)
+}
+
+func withUserContext(req *http.Request, uid string) *http.Request {
+	ctx := context.WithValue(req.Context(), middleware.ContextKeyUser, &user.User{UID: uid, Status: user.StatusActive})
+	return req.WithContext(ctx)
+}
+
+func TestInsightsHandler_GetTimeEnergy_Success(t *testing.T) {
+	t.Parallel()
+
+	handler := New(&stubInsightsService{
+		timeEnergy: func(_ context.Context, uid, from, to string) (*insights.TimeEnergyInsights, error) {
+			if uid != "uid-1" || from != "2026-03-01" || to != "2026-03-20" {
+				t.Fatalf("unexpected call %s %s %s", uid, from, to)
+			}
+			return &insights.TimeEnergyInsights{
+				From: from,
+				To:   to,
+				NextDay: []insights.CategoryEffect{{
+					Category: "Sport", Horizon: insights.HorizonNextDay, DaysWith: 10, DaysWithout: 9,
+					EffectSize: 3.2, Signal: insights.SignalHighEnergy,
+				}},
+			}, nil
+		},
+	})
+	req := withUserContext(httptest.NewRequest(http.MethodGet, "/insights/time-energy?from=2026-03-01&to=2026-03-20", nil), "uid-1")
+	rr := httptest.NewRecorder()
+
+	handler.GetTimeEnergy(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	var payload TimeEnergyResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(payload.SameDay) != 0 || payload.SameDay == nil {
+		t.Fatalf("expected an empty sameDay array, got %+v", payload.SameDay)
+	}
+	if len(payload.NextDay) != 1 || payload.NextDay[0].Signal != "high_energy" || payload.NextDay[0].DaysWithout != 9 {
+		t.Fatalf("unexpected payload: %+v", payload)
+	}
+}
+
+func TestInsightsHandler_GetTimeEnergy_CalendarNotConnected(t *testing.T) {
+	t.Parallel()
+
+	handler := New(&stubInsightsService{
+		timeEnergy: func(context.Context, string, string, string) (*insights.TimeEnergyInsights, error) {
+			return nil, pkgerror.NewCalendarNotConnectedError("calendar not connected")
+		},
+	})
+	req := withUserContext(httptest.NewRequest(http.MethodGet, "/insights/time-energy", nil), "uid-1")
+	rr := httptest.NewRecorder()
+
+	handler.GetTimeEnergy(rr, req)
+
+	if rr.Code != http.StatusFailedDependency {
+		t.Fatalf("expected status %d, got %d", http.StatusFailedDependency, rr.Code)
+	}
+}
+
+func TestInsightsHandler_GetTimeEnergy_MapsDomainErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		err        error
+		statusCode int
+		payload    ErrorResponse
+	}{
+		{pkgerror.NewNotFoundError("user", "uid-1"), http.StatusNotFound, ErrorResponse{Error: pkgerror.NewNotFoundError("user", "uid-1").Error()}},
+		{pkgerror.NewRateLimitError("slow down"), http.StatusTooManyRequests, ErrorResponse{Error: "slow down"}},
+		{pkgerror.NewCalendarReauthRequiredError("reconnect"), http.StatusFailedDependency, ErrorResponse{Error: "reconnect", Code: "reauth_required"}},
+		{errors.New("firestore: deadline exceeded"), http.StatusInternalServerError, ErrorResponse{Error: "internal server error"}},
+	} {
+		handler := New(&stubInsightsService{
+			timeEnergy: func(context.Context, string, string, string) (*insights.TimeEnergyInsights, error) {
+				return nil, tc.err
+			},
+		})
+		req := withUserContext(httptest.NewRequest(http.MethodGet, "/insights/time-energy", nil), "uid-1")
+		rr := httptest.NewRecorder()
+
+		handler.GetTimeEnergy(rr, req)
+
+		var payload ErrorResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &payload); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if rr.Code != tc.statusCode || payload != tc.payload {
+			t.Fatalf("%v: expected %d %+v, got %d %+v", tc.err, tc.statusCode, tc.payload, rr.Code, payload)
+		}
+	}
+}
+
+func TestInsightsHandler_GetTimeEnergy_Unauthorized(t *testing.T) {
+	t.Parallel()
+
+	handler := New(&stubInsightsService{})
+	rr := httptest.NewRecorder()
+
+	handler.GetTimeEnergy(rr, httptest.NewRequest(http.MethodGet, "/insights/time-energy", nil))
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, rr.Code)
+	}
+}
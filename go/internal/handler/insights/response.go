@@ -0,0 +1,36 @@
+package insights
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type CategoryEffectResponse struct {
+	Category         string  `json:"category"`
+	DaysWith         int     `json:"daysWith"`
+	DaysWithout      int     `json:"daysWithout"`
+	MeanScoreWith    float64 `json:"meanScoreWith"`
+	MeanScoreWithout float64 `json:"meanScoreWithout"`
+	EffectSize       float64 `json:"effectSize"`
+	Correlation      float64 `json:"correlation"`
+	Signal           string  `json:"signal" enums:"high_energy,low_energy,inconclusive"`
+}
+
+type TimeEnergyResponse struct {
+	From    string                   `json:"from"`
+	To      string                   `json:"to"`
+	SameDay []CategoryEffectResponse `json:"sameDay"`
+	NextDay []CategoryEffectResponse `json:"nextDay"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+	// Code is set to reauth_required when the user must reconnect Google Calendar.
+	Code string `json:"code,omitempty"`
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(payload)
+}
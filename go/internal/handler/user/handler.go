@@ -2,7 +2,6 @@ package user
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 
 	"energyjournal/internal/domain/user"
@@ -41,8 +40,8 @@ func (h *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
 	_, err := h.userService.Create(r.Context(), req.Email, req.Password, req.FirstName, req.LastName, req.Timezone)
 	if err != nil {
 		// Anti-enumeration: return the same success response regardless of error cause.
-		// Log the actual error for operational visibility.
-		log.Printf("user create error (suppressed for anti-enumeration): %v", err)
+		// Log the actual error for operational visibility; emails in it are redacted.
+		middleware.Logger(r.Context()).WithError(err).Warn("user create failed (suppressed for anti-enumeration)")
 	}
 
 	writeJSON(w, http.StatusCreated, CreateUserAcceptedResponse{
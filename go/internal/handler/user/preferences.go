@@ -0,0 +1,89 @@
+package user
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"energyjournal/internal/domain/user"
+	"energyjournal/internal/pkg/httputil"
+	"energyjournal/internal/server/middleware"
+)
+
+type PreferencesHandler struct {
+	preferencesService user.PreferencesService
+}
+
+func NewPreferencesHandler(preferencesService user.PreferencesService) *PreferencesHandler {
+	return &PreferencesHandler{preferencesService: preferencesService}
+}
+
+// GetPreferences handles GET /users/me/preferences.
+func (h *PreferencesHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	u, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	prefs, err := h.preferencesService.Get(r.Context(), u.UID)
+	if err != nil {
+		httputil.WriteError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewPreferencesResponse(prefs))
+}
+
+// UpdatePreferences handles PUT /users/me/preferences.
+// The request replaces the whole preferences document.
+func (h *PreferencesHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	u, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req UpdatePreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	channels := make([]user.NotificationChannel, 0, len(req.Channels))
+	for _, channel := range req.Channels {
+		channels = append(channels, user.NotificationChannel(channel))
+	}
+
+	updated, err := h.preferencesService.Update(r.Context(), user.Preferences{
+		UID:          u.UID,
+		ReminderTime: req.ReminderTime,
+		ReminderDays: req.ReminderDays,
+		DigestOptIn:  req.DigestOptIn,
+		Channels:     channels,
+		Locale:       req.Locale,
+	})
+	if err != nil {
+		httputil.WriteError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewPreferencesResponse(updated))
+}
+
+// Unsubscribe handles POST /users/unsubscribe?token=...
+// The signed token identifies the user, so no login is required.
+func (h *PreferencesHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		writeJSON(w, http.StatusBadRequest, GenericErrorResponse{Message: "Missing unsubscribe token."})
+		return
+	}
+
+	if err := h.preferencesService.Unsubscribe(r.Context(), token); err != nil {
+		statusCode, _ := httputil.MapErrors(err)
+		writeJSON(w, statusCode, GenericErrorResponse{Message: "Unsubscribe failed."})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, UnsubscribeResponse{Message: "You have been unsubscribed."})
+}
@@ -0,0 +1,187 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"energyjournal/internal/domain/user"
+	pkgerror "energyjournal/internal/pkg/error"
+	"energyjournal/internal/server/middleware"
+)
+
+// --- Mock service ---
+
+type mockPreferencesService struct {
+	getFn         func(ctx context.Context, uid string) (*user.Preferences, error)
+	updateFn      func(ctx context.Context, prefs user.Preferences) (*user.Preferences, error)
+	unsubscribeFn func(ctx context.Context, token string) error
+}
+
+func (m *mockPreferencesService) Get(ctx context.Context, uid string) (*user.Preferences, error) {
+	return m.getFn(ctx, uid)
+}
+
+func (m *mockPreferencesService) Update(ctx context.Context, prefs user.Preferences) (*user.Preferences, error) {
+	return m.updateFn(ctx, prefs)
+}
+
+func (m *mockPreferencesService) UnsubscribeLink(uid string, topic user.UnsubscribeTopic) string {
+	return ""
+}
+
+func (m *mockPreferencesService) Unsubscribe(ctx context.Context, token string) error {
+	return m.unsubscribeFn(ctx, token)
+}
+
+func withActiveUser(req *http.Request, uid string) *http.Request {
+	u := &user.User{UID: uid, Status: user.StatusActive}
+	return req.WithContext(context.WithValue(req.Context(), middleware.ContextKeyUser, u))
+}
+
+// --- Tests ---
+
+// GetPreferences: returns the service result for the authenticated user.
+func TestGetPreferences_ReturnsPreferences(t *testing.T) {
+	svc := &mockPreferencesService{
+		getFn: func(ctx context.Context, uid string) (*user.Preferences, error) {
+			if uid != "uid-1" {
+				t.Fatalf("unexpected uid %s", uid)
+			}
+			return &user.Preferences{
+				UID:          uid,
+				ReminderTime: "20:00",
+				Channels:     []user.NotificationChannel{user.ChannelEmail},
+				Locale:       "en",
+			}, nil
+		},
+	}
+	h := NewPreferencesHandler(svc)
+
+	req := withActiveUser(httptest.NewRequest(http.MethodGet, "/users/me/preferences", nil), "uid-1")
+	rr := httptest.NewRecorder()
+	h.GetPreferences(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+
+	resp := decodeJSON[PreferencesResponse](t, rr)
+	if resp.ReminderTime != "20:00" || resp.Locale != "en" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if resp.ReminderDays == nil || len(resp.ReminderDays) != 0 {
+		t.Errorf("expected empty reminder days array, got %v", resp.ReminderDays)
+	}
+	if len(resp.Channels) != 1 || resp.Channels[0] != "email" {
+		t.Errorf("unexpected channels: %v", resp.Channels)
+	}
+}
+
+// UpdatePreferences: request body is mapped onto the domain model.
+func TestUpdatePreferences_ParsesRequest(t *testing.T) {
+	var captured user.Preferences
+	svc := &mockPreferencesService{
+		updateFn: func(ctx context.Context, prefs user.Preferences) (*user.Preferences, error) {
+			captured = prefs
+			return &prefs, nil
+		},
+	}
+	h := NewPreferencesHandler(svc)
+
+	body, _ := json.Marshal(map[string]any{
+		"reminderTime": "07:15",
+		"reminderDays": []string{"mon", "fri"},
+		"digestOptIn":  true,
+		"channels":     []string{"push"},
+		"locale":       "fr",
+	})
+	req := withActiveUser(httptest.NewRequest(http.MethodPut, "/users/me/preferences", bytes.NewReader(body)), "uid-1")
+	rr := httptest.NewRecorder()
+	h.UpdatePreferences(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if captured.UID != "uid-1" || captured.ReminderTime != "07:15" || !captured.DigestOptIn || captured.Locale != "fr" {
+		t.Errorf("unexpected captured preferences: %+v", captured)
+	}
+	if len(captured.Channels) != 1 || captured.Channels[0] != user.ChannelPush {
+		t.Errorf("unexpected channels: %v", captured.Channels)
+	}
+}
+
+// UpdatePreferences: validation errors map to 400.
+func TestUpdatePreferences_ValidationError_Returns400(t *testing.T) {
+	svc := &mockPreferencesService{
+		updateFn: func(ctx context.Context, prefs user.Preferences) (*user.Preferences, error) {
+			return nil, pkgerror.NewInputValidationError("reminderTime", "invalid time format, expected HH:MM")
+		},
+	}
+	h := NewPreferencesHandler(svc)
+
+	req := withActiveUser(httptest.NewRequest(http.MethodPut, "/users/me/preferences", bytes.NewReader([]byte(`{"reminderTime":"9pm"}`))), "uid-1")
+	rr := httptest.NewRecorder()
+	h.UpdatePreferences(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
+
+// Unsubscribe: missing token returns 400.
+func TestUnsubscribe_MissingToken_Returns400(t *testing.T) {
+	h := NewPreferencesHandler(&mockPreferencesService{})
+
+	req := httptest.NewRequest(http.MethodPost, "/users/unsubscribe", nil)
+	rr := httptest.NewRecorder()
+	h.Unsubscribe(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
+
+// Unsubscribe: invalid token returns a generic error.
+func TestUnsubscribe_InvalidToken_ReturnsGenericError(t *testing.T) {
+	svc := &mockPreferencesService{
+		unsubscribeFn: func(ctx context.Context, token string) error {
+			return pkgerror.NewInputValidationError("token", "invalid unsubscribe token")
+		},
+	}
+	h := NewPreferencesHandler(svc)
+
+	req := httptest.NewRequest(http.MethodPost, "/users/unsubscribe?token=forged", nil)
+	rr := httptest.NewRecorder()
+	h.Unsubscribe(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+
+	resp := decodeJSON[GenericErrorResponse](t, rr)
+	if resp.Message != "Unsubscribe failed." {
+		t.Errorf("unexpected message: %s", resp.Message)
+	}
+}
+
+// Unsubscribe: valid token succeeds without authentication.
+func TestUnsubscribe_ValidToken_Returns200(t *testing.T) {
+	svc := &mockPreferencesService{
+		unsubscribeFn: func(ctx context.Context, token string) error {
+			return nil
+		},
+	}
+	h := NewPreferencesHandler(svc)
+
+	req := httptest.NewRequest(http.MethodPost, "/users/unsubscribe?token=signed", nil)
+	rr := httptest.NewRecorder()
+	h.Unsubscribe(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+}
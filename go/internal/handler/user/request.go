@@ -23,3 +23,11 @@ type LoginRequest struct {
 type RefreshRequest struct {
 	RefreshToken string `json:"refreshToken"`
 }
+
+type UpdatePreferencesRequest struct {
+	ReminderTime string   `json:"reminderTime"`
+	ReminderDays []string `json:"reminderDays"`
+	DigestOptIn  bool     `json:"digestOptIn"`
+	Channels     []string `json:"channels"`
+	Locale       string   `json:"locale"`
+}
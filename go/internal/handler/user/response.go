@@ -16,6 +16,15 @@ type UserResponse struct {
 	CreatedAt time.Time `json:"createdAt"`
 }
 
+type PreferencesResponse struct {
+	ReminderTime string    `json:"reminderTime"`
+	ReminderDays []string  `json:"reminderDays"`
+	DigestOptIn  bool      `json:"digestOptIn"`
+	Channels     []string  `json:"channels"`
+	Locale       string    `json:"locale"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
 type AuthTokensResponse struct {
 	IDToken      string `json:"idToken"`
 	RefreshToken string `json:"refreshToken"`
@@ -35,6 +44,10 @@ type ActivationResponse struct {
 	Message string `json:"message"`
 }
 
+type UnsubscribeResponse struct {
+	Message string `json:"message"`
+}
+
 func NewAuthTokensResponse(t *user.AuthTokens) *AuthTokensResponse {
 	return &AuthTokensResponse{
 		IDToken:      t.IDToken,
@@ -54,3 +67,24 @@ func NewUserResponse(u *user.User) *UserResponse {
 		CreatedAt: u.CreatedAt,
 	}
 }
+
+func NewPreferencesResponse(p *user.Preferences) *PreferencesResponse {
+	days := p.ReminderDays
+	if days == nil {
+		days = []string{}
+	}
+
+	channels := make([]string, 0, len(p.Channels))
+	for _, channel := range p.Channels {
+		channels = append(channels, string(channel))
+	}
+
+	return &PreferencesResponse{
+		ReminderTime: p.ReminderTime,
+		ReminderDays: days,
+		DigestOptIn:  p.DigestOptIn,
+		Channels:     channels,
+		Locale:       p.Locale,
+		UpdatedAt:    p.UpdatedAt,
+	}
+}
@@ -1,19 +1,50 @@
 package google
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	"golang.org/x/oauth2"
 
 	"energyjournal/internal/domain/calendar"
+	"energyjournal/internal/pkg/telemetry"
 )
 
-const googleCalendarAPIBaseURL = "https://www.googleapis.com/calendar/v3"
+const (
+	googleCalendarAPIBaseURL = "https://www.googleapis.com/calendar/v3"
+	googleRevokeURL          = "https://oauth2.googleapis.com/revoke"
+)
+
+var apiCalls = telemetry.Counter("energyjournal/internal/integration/google", "energyjournal.google.api.calls",
+	"Requests to Google APIs, by host and response status (error when none came back).")
+
+// apiClient sends requests to Google, traced and counted.
+var apiClient = &http.Client{Transport: countingTransport{base: telemetry.Transport(http.DefaultTransport)}}
+
+// countingTransport counts the requests it sends in apiCalls.
+type countingTransport struct {
+	base http.RoundTripper
+}
+
+func (t countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	apiCalls.Add(req.Context(), 1, metric.WithAttributes(attribute.String("host", req.URL.Host), attribute.String("status", status)))
+	return resp, err
+}
 
 type GoogleAPIError struct {
 	StatusCode int
@@ -24,113 +55,417 @@ func (e *GoogleAPIError) Error() string {
 	return fmt.Sprintf("google calendar api returned status %d", e.StatusCode)
 }
 
+// Is makes 401 responses match calendar.ErrAuthorizationRevoked.
+func (e *GoogleAPIError) Is(target error) bool {
+	return target == calendar.ErrAuthorizationRevoked && e.StatusCode == http.StatusUnauthorized
+}
+
+const (
+	// Page sizes sent as maxResults; Google caps events at 2500 and calendar list at 250.
+	defaultEventsPageSize    = 250
+	defaultCalendarsPageSize = 250
+	// maxPages stops runaway pagination if the API keeps returning page tokens.
+	maxPages = 100
+)
+
 type GoogleCalendarClient struct {
-	baseURL    string
-	transport  http.RoundTripper
-	httpClient *http.Client
+	baseURL           string
+	revokeURL         string
+	transport         http.RoundTripper
+	httpClient        *http.Client
+	eventsPageSize    int
+	calendarsPageSize int
 }
 
 func NewGoogleCalendarClient() *GoogleCalendarClient {
 	return &GoogleCalendarClient{
-		baseURL: googleCalendarAPIBaseURL,
+		baseURL:           googleCalendarAPIBaseURL,
+		revokeURL:         googleRevokeURL,
+		eventsPageSize:    defaultEventsPageSize,
+		calendarsPageSize: defaultCalendarsPageSize,
 	}
 }
 
+// WithBaseURL points the client at another API root, such as a local fake server.
+func (c *GoogleCalendarClient) WithBaseURL(baseURL string) *GoogleCalendarClient {
+	c.baseURL = strings.TrimSuffix(baseURL, "/")
+	return c
+}
+
+// WithPageSizes overrides the maxResults sent for event and calendar list
+// pages. Non-positive values leave the current setting unchanged.
+func (c *GoogleCalendarClient) WithPageSizes(events, calendars int) *GoogleCalendarClient {
+	if events > 0 {
+		c.eventsPageSize = events
+	}
+	if calendars > 0 {
+		c.calendarsPageSize = calendars
+	}
+	return c
+}
+
 func (c *GoogleCalendarClient) ListCalendars(ctx context.Context, token string) ([]calendar.CalendarItem, error) {
 	endpoint := fmt.Sprintf("%s/users/me/calendarList", c.baseURL)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	query := url.Values{}
+	if c.calendarsPageSize > 0 {
+		query.Set("maxResults", strconv.Itoa(c.calendarsPageSize))
+	}
+
+	items := []calendar.CalendarItem{}
+	err := c.paginate(ctx, token, endpoint, query, func(decoder *json.Decoder) (string, error) {
+		var payload struct {
+			NextPageToken string `json:"nextPageToken"`
+			Items         []struct {
+				ID              string `json:"id"`
+				Summary         string `json:"summary"`
+				BackgroundColor string `json:"backgroundColor"`
+			} `json:"items"`
+		}
+		if err := decoder.Decode(&payload); err != nil {
+			return "", err
+		}
+		for _, item := range payload.Items {
+			items = append(items, calendar.CalendarItem{
+				ID:    item.ID,
+				Name:  item.Summary,
+				Color: item.BackgroundColor,
+			})
+		}
+		return payload.NextPageToken, nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := c.oauthClient(ctx, token).Do(req)
+	return items, nil
+}
+
+// ListEvents returns every event between start and end, following pagination.
+// Recurring events are expanded into single instances (singleEvents=true).
+func (c *GoogleCalendarClient) ListEvents(ctx context.Context, token, calendarID string, start, end time.Time) ([]calendar.Event, error) {
+	endpoint := fmt.Sprintf("%s/calendars/%s/events", c.baseURL, url.PathEscape(calendarID))
+	query := url.Values{}
+	query.Set("timeMin", start.Format(time.RFC3339))
+	query.Set("timeMax", end.Format(time.RFC3339))
+	query.Set("singleEvents", "true")
+	if c.eventsPageSize > 0 {
+		query.Set("maxResults", strconv.Itoa(c.eventsPageSize))
+	}
+
+	events := []calendar.Event{}
+	err := c.paginate(ctx, token, endpoint, query, func(decoder *json.Decoder) (string, error) {
+		var payload struct {
+			NextPageToken string      `json:"nextPageToken"`
+			TimeZone      string      `json:"timeZone"`
+			Items         []eventItem `json:"items"`
+		}
+		if err := decoder.Decode(&payload); err != nil {
+			return "", err
+		}
+
+		loc := calendarLocation(payload.TimeZone)
+		for _, item := range payload.Items {
+			event, ok := item.toEvent(calendarID, loc)
+			if !ok {
+				continue
+			}
+			events = append(events, event)
+		}
+		return payload.NextPageToken, nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
-		return nil, decodeGoogleAPIError(resp)
+	return events, nil
+}
+
+// SyncEvents lists events for the cache. With an empty syncToken it performs a
+// full sync of events overlapping [windowStart, windowEnd), so that recurring
+// events expand to a bounded number of instances; otherwise it returns only
+// the changes since syncToken. The returned NextSyncToken feeds the next call.
+// A 410 Gone response is reported as calendar.ErrSyncTokenExpired.
+func (c *GoogleCalendarClient) SyncEvents(ctx context.Context, token, calendarID, syncToken string, windowStart, windowEnd time.Time) (*calendar.EventChanges, error) {
+	endpoint := fmt.Sprintf("%s/calendars/%s/events", c.baseURL, url.PathEscape(calendarID))
+	query := url.Values{}
+	query.Set("singleEvents", "true")
+	if c.eventsPageSize > 0 {
+		query.Set("maxResults", strconv.Itoa(c.eventsPageSize))
+	}
+	if syncToken != "" {
+		// timeMin and timeMax cannot be combined with syncToken; the token
+		// remembers the window.
+		query.Set("syncToken", syncToken)
+	} else {
+		query.Set("timeMin", windowStart.Format(time.RFC3339))
+		query.Set("timeMax", windowEnd.Format(time.RFC3339))
+	}
+
+	changes := &calendar.EventChanges{Events: []calendar.Event{}}
+	err := c.paginate(ctx, token, endpoint, query, func(decoder *json.Decoder) (string, error) {
+		var payload struct {
+			NextPageToken string      `json:"nextPageToken"`
+			NextSyncToken string      `json:"nextSyncToken"`
+			TimeZone      string      `json:"timeZone"`
+			Items         []eventItem `json:"items"`
+		}
+		if err := decoder.Decode(&payload); err != nil {
+			return "", err
+		}
+
+		loc := calendarLocation(payload.TimeZone)
+		for _, item := range payload.Items {
+			event, ok := item.toEvent(calendarID, loc)
+			if !ok {
+				continue
+			}
+			changes.Events = append(changes.Events, event)
+		}
+		changes.NextSyncToken = payload.NextSyncToken
+		return payload.NextPageToken, nil
+	})
+	if err != nil {
+		var apiErr *GoogleAPIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusGone {
+			return nil, fmt.Errorf("%w: %v", calendar.ErrSyncTokenExpired, err)
+		}
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// WatchEvents opens a push notification channel on the calendar's events.
+// Google will POST to address with the channel ID, token and resource ID headers.
+func (c *GoogleCalendarClient) WatchEvents(ctx context.Context, token, calendarID, channelID, channelToken, address string, ttl time.Duration) (*calendar.WatchRegistration, error) {
+	endpoint := fmt.Sprintf("%s/calendars/%s/events/watch", c.baseURL, url.PathEscape(calendarID))
+	body := map[string]any{
+		"id":      channelID,
+		"type":    "web_hook",
+		"address": address,
+		"token":   channelToken,
+	}
+	if ttl > 0 {
+		body["params"] = map[string]string{"ttl": strconv.Itoa(int(ttl.Seconds()))}
 	}
 
 	var payload struct {
-		Items []struct {
-			ID             string `json:"id"`
-			Summary        string `json:"summary"`
-			BackgroundColor string `json:"backgroundColor"`
-		} `json:"items"`
+		ResourceID string `json:"resourceId"`
+		Expiration string `json:"expiration"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+	if err := c.postJSON(ctx, token, endpoint, body, &payload); err != nil {
 		return nil, err
 	}
 
-	items := make([]calendar.CalendarItem, 0, len(payload.Items))
-	for _, item := range payload.Items {
-		items = append(items, calendar.CalendarItem{
-			ID:    item.ID,
-			Name:  item.Summary,
-			Color: item.BackgroundColor,
-		})
+	registration := &calendar.WatchRegistration{ResourceID: payload.ResourceID}
+	// expiration is a Unix timestamp in milliseconds, encoded as a string.
+	if millis, err := strconv.ParseInt(payload.Expiration, 10, 64); err == nil {
+		registration.Expiration = time.UnixMilli(millis)
 	}
+	return registration, nil
+}
 
-	return items, nil
+// StopChannel stops a push notification channel. Google answers 404 for
+// channels that already expired, which is not treated as an error.
+func (c *GoogleCalendarClient) StopChannel(ctx context.Context, token, channelID, resourceID string) error {
+	endpoint := fmt.Sprintf("%s/channels/stop", c.baseURL)
+	err := c.postJSON(ctx, token, endpoint, map[string]string{"id": channelID, "resourceId": resourceID}, nil)
+	var apiErr *GoogleAPIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
 }
 
-func (c *GoogleCalendarClient) ListEvents(ctx context.Context, token, calendarID string, start, end time.Time) ([]calendar.Event, error) {
-	path := fmt.Sprintf("%s/calendars/%s/events", c.baseURL, url.PathEscape(calendarID))
-	query := url.Values{}
-	query.Set("timeMin", start.Format(time.RFC3339))
-	query.Set("timeMax", end.Format(time.RFC3339))
-	query.Set("singleEvents", "true")
+// RevokeToken revokes an OAuth token; revoking a refresh token also revokes
+// the access tokens issued from it. Tokens Google no longer knows (already
+// revoked or expired) are reported as success so revocation is idempotent.
+func (c *GoogleCalendarClient) RevokeToken(ctx context.Context, token string) error {
+	form := url.Values{"token": {token}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.revokeURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, path+"?"+query.Encode(), nil)
+	client := c.httpClient
+	if client == nil {
+		client = apiClient
+		if c.transport != nil {
+			client = &http.Client{Transport: c.transport}
+		}
+	}
+	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		return nil
+	}
+
+	var payload struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
 	}
+	_ = json.NewDecoder(resp.Body).Decode(&payload)
+	if resp.StatusCode == http.StatusBadRequest && payload.Error == "invalid_token" {
+		return nil
+	}
+	return &GoogleAPIError{StatusCode: resp.StatusCode, Body: payload.ErrorDescription}
+}
+
+// postJSON sends body as JSON and decodes the response into out when non-nil.
+func (c *GoogleCalendarClient) postJSON(ctx context.Context, token, endpoint string, body, out any) error {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.oauthClient(ctx, token).Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
-		return nil, decodeGoogleAPIError(resp)
+		return decodeGoogleAPIError(resp)
+	}
+	if out == nil {
+		return nil
 	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
 
-	var payload struct {
-		Items []struct {
-			ColorID string `json:"colorId"`
-			Start   struct {
-				DateTime string `json:"dateTime"`
-			} `json:"start"`
-			End struct {
-				DateTime string `json:"dateTime"`
-			} `json:"end"`
-		} `json:"items"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
-		return nil, err
+// paginate issues GET requests to endpoint until decodePage returns an empty
+// next page token. The token of each page is sent as pageToken on the next request.
+func (c *GoogleCalendarClient) paginate(ctx context.Context, token, endpoint string, query url.Values, decodePage func(*json.Decoder) (string, error)) error {
+	client := c.oauthClient(ctx, token)
+	for page := 0; page < maxPages; page++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
+		if err != nil {
+			return err
+		}
+
+		next, err := doPage(client, req, decodePage)
+		if err != nil {
+			return err
+		}
+		if next == "" {
+			return nil
+		}
+		query.Set("pageToken", next)
+	}
+	return fmt.Errorf("google calendar api returned more than %d pages", maxPages)
+}
+
+func doPage(client *http.Client, req *http.Request, decodePage func(*json.Decoder) (string, error)) (string, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return "", decodeGoogleAPIError(resp)
+	}
+	return decodePage(json.NewDecoder(resp.Body))
+}
+
+// calendarLocation resolves the calendar's IANA timezone, defaulting to UTC.
+func calendarLocation(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+type eventTime struct {
+	Date     string `json:"date"`
+	DateTime string `json:"dateTime"`
+}
+
+type eventItem struct {
+	ID           string    `json:"id"`
+	Status       string    `json:"status"`
+	Summary      string    `json:"summary"`
+	ColorID      string    `json:"colorId"`
+	Transparency string    `json:"transparency"`
+	Start        eventTime `json:"start"`
+	End          eventTime `json:"end"`
+	Attendees    []struct {
+		Email          string `json:"email"`
+		Self           bool   `json:"self"`
+		Organizer      bool   `json:"organizer"`
+		ResponseStatus string `json:"responseStatus"`
+	} `json:"attendees"`
+}
+
+// toEvent converts an API item into a calendar.Event. All-day events only carry
+// a date, which is interpreted as midnight in loc (the calendar's timezone).
+// Items with unparseable times are reported as not ok, except cancelled items
+// from an incremental sync, which carry only an ID and mark a deletion.
+func (item eventItem) toEvent(calendarID string, loc *time.Location) (calendar.Event, bool) {
+	event := calendar.Event{
+		ID:          item.ID,
+		CalendarID:  calendarID,
+		Summary:     item.Summary,
+		ColorID:     item.ColorID,
+		Status:      calendar.EventStatus(item.Status),
+		Transparent: item.Transparency == "transparent",
+	}
+	if event.Status == "" {
+		event.Status = calendar.EventConfirmed
+	}
+	if event.Status == calendar.EventCancelled && item.Start.Date == "" && item.Start.DateTime == "" {
+		return event, item.ID != ""
 	}
 
-	events := make([]calendar.Event, 0, len(payload.Items))
-	for _, item := range payload.Items {
+	if item.Start.DateTime == "" && item.Start.Date != "" {
+		startAt, err := time.ParseInLocation("2006-01-02", item.Start.Date, loc)
+		if err != nil {
+			return calendar.Event{}, false
+		}
+		endAt, err := time.ParseInLocation("2006-01-02", item.End.Date, loc)
+		if err != nil {
+			return calendar.Event{}, false
+		}
+		event.AllDay = true
+		event.Start = startAt
+		event.End = endAt
+	} else {
 		startAt, err := time.Parse(time.RFC3339, item.Start.DateTime)
 		if err != nil {
-			continue
+			return calendar.Event{}, false
 		}
 		endAt, err := time.Parse(time.RFC3339, item.End.DateTime)
 		if err != nil {
-			continue
+			return calendar.Event{}, false
 		}
-		events = append(events, calendar.Event{
-			ColorID: item.ColorID,
-			Start:   startAt,
-			End:     endAt,
+		event.Start = startAt
+		event.End = endAt
+	}
+
+	for _, attendee := range item.Attendees {
+		event.Attendees = append(event.Attendees, calendar.Attendee{
+			Email:          attendee.Email,
+			Self:           attendee.Self,
+			Organizer:      attendee.Organizer,
+			ResponseStatus: calendar.ResponseStatus(attendee.ResponseStatus),
 		})
 	}
 
-	return events, nil
+	return event, true
 }
 
 func (c *GoogleCalendarClient) oauthClient(ctx context.Context, token string) *http.Client {
@@ -139,7 +474,7 @@ func (c *GoogleCalendarClient) oauthClient(ctx context.Context, token string) *h
 	}
 
 	source := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-	client := oauth2.NewClient(ctx, source)
+	client := oauth2.NewClient(context.WithValue(ctx, oauth2.HTTPClient, apiClient), source)
 	if c.transport != nil {
 		client.Transport = c.transport
 	}
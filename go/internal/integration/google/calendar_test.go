@@ -2,12 +2,22 @@ package google
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
+
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"energyjournal/internal/domain/calendar"
 )
 
 type roundTripFunc func(*http.Request) (*http.Response, error)
@@ -72,6 +82,7 @@ func TestListEvents(t *testing.T) {
 					Body: io.NopCloser(strings.NewReader(`{
 						"items": [
 							{
+								"summary":"Sprint planning",
 								"colorId":"5",
 								"start":{"dateTime":"2026-03-03T09:00:00Z"},
 								"end":{"dateTime":"2026-03-03T11:30:00Z"}
@@ -94,11 +105,254 @@ func TestListEvents(t *testing.T) {
 	if events[0].ColorID != "5" {
 		t.Fatalf("expected colorId 5, got %s", events[0].ColorID)
 	}
+	if events[0].Summary != "Sprint planning" || events[0].CalendarID != "primary" {
+		t.Fatalf("unexpected summary/calendar: %+v", events[0])
+	}
 	if got := events[0].End.Sub(events[0].Start); got != 150*time.Minute {
 		t.Fatalf("expected 150m duration, got %s", got)
 	}
 }
 
+func TestListEventsFollowsPagination(t *testing.T) {
+	t.Parallel()
+
+	pages := map[string]string{
+		"": `{
+			"nextPageToken":"page-2",
+			"items":[{"summary":"Standup","start":{"dateTime":"2026-03-02T09:00:00Z"},"end":{"dateTime":"2026-03-02T09:15:00Z"}}]
+		}`,
+		"page-2": `{
+			"nextPageToken":"page-3",
+			"items":[{"summary":"Standup","start":{"dateTime":"2026-03-03T09:00:00Z"},"end":{"dateTime":"2026-03-03T09:15:00Z"}}]
+		}`,
+		"page-3": `{
+			"items":[{"summary":"Review","start":{"dateTime":"2026-03-04T14:00:00Z"},"end":{"dateTime":"2026-03-04T15:00:00Z"}}]
+		}`,
+	}
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("singleEvents") != "true" || query.Get("maxResults") != "2" || query.Get("timeMin") == "" {
+			t.Errorf("unexpected query: %s", r.URL.RawQuery)
+		}
+		pageToken := query.Get("pageToken")
+		requests = append(requests, pageToken)
+		body, ok := pages[pageToken]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+
+	client := (&GoogleCalendarClient{baseURL: server.URL, httpClient: server.Client()}).WithPageSizes(2, 0)
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	events, err := client.ListEvents(context.Background(), "token", "primary", start, start.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatalf("ListEvents returned error: %v", err)
+	}
+	if len(events) != 3 || events[2].Summary != "Review" {
+		t.Fatalf("expected events from all 3 pages, got %+v", events)
+	}
+	if strings.Join(requests, ",") != ",page-2,page-3" {
+		t.Fatalf("unexpected page token sequence: %q", requests)
+	}
+}
+
+func TestListCalendarsFollowsPagination(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("maxResults") != "1" {
+			t.Errorf("expected maxResults=1, got %s", r.URL.RawQuery)
+		}
+		switch r.URL.Query().Get("pageToken") {
+		case "":
+			_, _ = io.WriteString(w, `{"nextPageToken":"next","items":[{"id":"primary","summary":"Main"}]}`)
+		case "next":
+			_, _ = io.WriteString(w, `{"items":[{"id":"work","summary":"Work"}]}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := (&GoogleCalendarClient{baseURL: server.URL, httpClient: server.Client()}).WithPageSizes(0, 1)
+	calendars, err := client.ListCalendars(context.Background(), "token")
+	if err != nil {
+		t.Fatalf("ListCalendars returned error: %v", err)
+	}
+	if len(calendars) != 2 || calendars[0].ID != "primary" || calendars[1].ID != "work" {
+		t.Fatalf("unexpected calendars: %+v", calendars)
+	}
+}
+
+func TestListEventsReturnsErrorFromLaterPage(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("pageToken") == "" {
+			_, _ = io.WriteString(w, `{"nextPageToken":"next","items":[]}`)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = io.WriteString(w, `{"error":{"message":"backend error"}}`)
+	}))
+	t.Cleanup(server.Close)
+
+	client := &GoogleCalendarClient{baseURL: server.URL, httpClient: server.Client()}
+	_, err := client.ListEvents(context.Background(), "token", "primary", time.Now(), time.Now().Add(time.Hour))
+
+	var apiErr *GoogleAPIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 GoogleAPIError, got %v", err)
+	}
+}
+
+func TestSyncEventsReturnsNextSyncTokenAndDeletions(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("syncToken") != "old" || query.Get("timeMin") != "" {
+			t.Errorf("unexpected query: %s", r.URL.RawQuery)
+		}
+		if query.Get("pageToken") == "" {
+			_, _ = io.WriteString(w, `{"nextPageToken":"p2","items":[
+				{"id":"changed","summary":"Moved","start":{"dateTime":"2026-03-03T09:00:00Z"},"end":{"dateTime":"2026-03-03T10:00:00Z"}}
+			]}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"nextSyncToken":"new","items":[{"id":"removed","status":"cancelled"}]}`)
+	}))
+	t.Cleanup(server.Close)
+
+	client := &GoogleCalendarClient{baseURL: server.URL, httpClient: server.Client()}
+	changes, err := client.SyncEvents(context.Background(), "token", "primary", "old", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("SyncEvents returned error: %v", err)
+	}
+	if changes.NextSyncToken != "new" || len(changes.Events) != 2 {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+	if changes.Events[1].ID != "removed" || changes.Events[1].Status != calendar.EventCancelled {
+		t.Fatalf("expected cancelled deletion marker, got %+v", changes.Events[1])
+	}
+}
+
+func TestSyncEventsBoundsFullSyncToWindow(t *testing.T) {
+	t.Parallel()
+
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		_, _ = io.WriteString(w, `{"nextSyncToken":"first","items":[]}`)
+	}))
+	t.Cleanup(server.Close)
+
+	client := &GoogleCalendarClient{baseURL: server.URL, httpClient: server.Client()}
+	windowStart := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	if _, err := client.SyncEvents(context.Background(), "token", "primary", "", windowStart, windowStart.AddDate(2, 0, 0)); err != nil {
+		t.Fatalf("SyncEvents returned error: %v", err)
+	}
+	if query.Get("timeMin") != "2025-03-03T00:00:00Z" || query.Get("timeMax") != "2027-03-03T00:00:00Z" || query.Get("syncToken") != "" {
+		t.Fatalf("unexpected full sync query: %v", query)
+	}
+}
+
+func TestSyncEventsMapsGoneToExpiredToken(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+		_, _ = io.WriteString(w, `{"error":{"message":"Sync token is no longer valid"}}`)
+	}))
+	t.Cleanup(server.Close)
+
+	client := &GoogleCalendarClient{baseURL: server.URL, httpClient: server.Client()}
+	_, err := client.SyncEvents(context.Background(), "token", "primary", "stale", time.Time{}, time.Time{})
+	if !errors.Is(err, calendar.ErrSyncTokenExpired) {
+		t.Fatalf("expected ErrSyncTokenExpired, got %v", err)
+	}
+}
+
+func TestWatchEventsAndStopChannel(t *testing.T) {
+	t.Parallel()
+
+	var watchBody, stopBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/calendars/work@example.com/events/watch":
+			_ = json.NewDecoder(r.Body).Decode(&watchBody)
+			_, _ = io.WriteString(w, `{"id":"ch-1","resourceId":"res-1","expiration":"1773100800000"}`)
+		case "/channels/stop":
+			_ = json.NewDecoder(r.Body).Decode(&stopBody)
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewGoogleCalendarClient().WithBaseURL(server.URL)
+	registration, err := client.WatchEvents(context.Background(), "token", "work@example.com", "ch-1", "secret", "https://api.test/hook", time.Hour)
+	if err != nil {
+		t.Fatalf("WatchEvents returned error: %v", err)
+	}
+	if registration.ResourceID != "res-1" || !registration.Expiration.Equal(time.UnixMilli(1773100800000)) {
+		t.Fatalf("unexpected registration: %+v", registration)
+	}
+	if watchBody["type"] != "web_hook" || watchBody["token"] != "secret" || watchBody["address"] != "https://api.test/hook" {
+		t.Fatalf("unexpected watch body: %v", watchBody)
+	}
+	if params, _ := watchBody["params"].(map[string]any); params["ttl"] != "3600" {
+		t.Fatalf("expected ttl param, got %v", watchBody["params"])
+	}
+
+	if err := client.StopChannel(context.Background(), "token", "ch-1", "res-1"); err != nil {
+		t.Fatalf("stopping an expired channel should succeed, got %v", err)
+	}
+	if stopBody["id"] != "ch-1" || stopBody["resourceId"] != "res-1" {
+		t.Fatalf("unexpected stop body: %v", stopBody)
+	}
+}
+
+func TestRevokeToken(t *testing.T) {
+	t.Parallel()
+
+	revoked := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		token := r.PostForm.Get("token")
+		switch {
+		case token == "boom":
+			w.WriteHeader(http.StatusInternalServerError)
+		case revoked[token]:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"error":"invalid_token","error_description":"Token expired or revoked"}`)
+		default:
+			revoked[token] = true
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := &GoogleCalendarClient{revokeURL: server.URL, httpClient: server.Client()}
+	for range 2 {
+		if err := client.RevokeToken(context.Background(), "refresh"); err != nil {
+			t.Fatalf("RevokeToken should be idempotent, got %v", err)
+		}
+	}
+	if !revoked["refresh"] {
+		t.Fatal("expected the token to be sent to the revoke endpoint")
+	}
+
+	var apiErr *GoogleAPIError
+	if err := client.RevokeToken(context.Background(), "boom"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected 500 GoogleAPIError, got %v", err)
+	}
+}
+
 func TestListCalendarsNon2xxReturnsTypedError(t *testing.T) {
 	t.Parallel()
 
@@ -165,6 +419,68 @@ func TestListEventsSkipsInvalidDateTime(t *testing.T) {
 	}
 }
 
+func TestListEventsParsesAllDayAttendeesAndStatus(t *testing.T) {
+	t.Parallel()
+
+	client := &GoogleCalendarClient{
+		baseURL: "https://example.test/calendar/v3",
+		httpClient: &http.Client{
+			Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body: io.NopCloser(strings.NewReader(`{
+						"timeZone": "Europe/Paris",
+						"items": [
+							{
+								"summary":"Offsite",
+								"transparency":"transparent",
+								"start":{"date":"2026-03-03"},
+								"end":{"date":"2026-03-05"}
+							},
+							{
+								"status":"cancelled",
+								"summary":"Retro",
+								"start":{"dateTime":"2026-03-03T09:00:00Z"},
+								"end":{"dateTime":"2026-03-03T10:00:00Z"},
+								"attendees":[
+									{"email":"boss@example.com","organizer":true,"responseStatus":"accepted"},
+									{"email":"me@example.com","self":true,"responseStatus":"declined"}
+								]
+							}
+						]
+					}`)),
+					Header: make(http.Header),
+				}, nil
+			}),
+		},
+	}
+
+	events, err := client.ListEvents(context.Background(), "token", "primary", time.Now(), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("ListEvents returned error: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+
+	paris, _ := time.LoadLocation("Europe/Paris")
+	allDay := events[0]
+	if !allDay.AllDay || !allDay.Transparent || allDay.Status != calendar.EventConfirmed {
+		t.Fatalf("unexpected all-day event: %+v", allDay)
+	}
+	if !allDay.Start.Equal(time.Date(2026, 3, 3, 0, 0, 0, 0, paris)) || !allDay.End.Equal(time.Date(2026, 3, 5, 0, 0, 0, 0, paris)) {
+		t.Fatalf("expected Paris midnights, got %s - %s", allDay.Start, allDay.End)
+	}
+
+	retro := events[1]
+	if retro.AllDay || retro.Status != calendar.EventCancelled || len(retro.Attendees) != 2 {
+		t.Fatalf("unexpected timed event: %+v", retro)
+	}
+	if !retro.Attendees[0].Organizer || !retro.DeclinedBySelf() {
+		t.Fatalf("unexpected attendees: %+v", retro.Attendees)
+	}
+}
+
 func TestGoogleAPIErrorError(t *testing.T) {
 	t.Parallel()
 	err := (&GoogleAPIError{StatusCode: 418}).Error()
@@ -174,7 +490,57 @@ func TestGoogleAPIErrorError(t *testing.T) {
 	}
 }
 
+func TestGoogleAPIErrorMatchesRevokedAuthorization(t *testing.T) {
+	t.Parallel()
+	if !errors.Is(fmt.Errorf("list: %w", &GoogleAPIError{StatusCode: http.StatusUnauthorized}), calendar.ErrAuthorizationRevoked) {
+		t.Fatal("expected 401 to match ErrAuthorizationRevoked")
+	}
+	if errors.Is(&GoogleAPIError{StatusCode: http.StatusForbidden}, calendar.ErrAuthorizationRevoked) {
+		t.Fatal("403 must not match ErrAuthorizationRevoked")
+	}
+}
+
 func ExampleGoogleAPIError_Error() {
 	fmt.Println((&GoogleAPIError{StatusCode: 500}).Error())
 	// Output: google calendar api returned status 500
 }
+
+func TestCountingTransportCountsCallsByStatus(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	previous := otel.GetMeterProvider()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	t.Cleanup(func() { otel.SetMeterProvider(previous) })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+	client := &http.Client{Transport: countingTransport{base: http.DefaultTransport}}
+	for _, url := range []string{server.URL, "http://127.0.0.1:0"} {
+		if resp, err := client.Get(url); err == nil {
+			resp.Body.Close()
+		}
+	}
+
+	var metrics metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &metrics); err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]int64{}
+	for _, scope := range metrics.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if !ok || m.Name != "energyjournal.google.api.calls" {
+				continue
+			}
+			for _, point := range sum.DataPoints {
+				host, _ := point.Attributes.Value("host")
+				status, _ := point.Attributes.Value("status")
+				counts[host.AsString()+" "+status.AsString()] += point.Value
+			}
+		}
+	}
+	if counts[strings.TrimPrefix(server.URL, "http://")+" 401"] != 1 || counts["127.0.0.1:0 error"] != 1 {
+		t.Fatalf("unexpected call counts: %v", counts)
+	}
+}
@@ -0,0 +1,75 @@
+package google
+
+import (
+	"context"
+
+	"golang.org/x/oauth2"
+
+	"energyjournal/internal/domain/calendar"
+)
+
+// ColorNames maps Google Calendar event color IDs to their labels; the empty
+// ID is an event using its calendar's color.
+var ColorNames = map[string]string{
+	"":   "Default",
+	"1":  "Tomato",
+	"2":  "Flamingo",
+	"3":  "Tangerine",
+	"4":  "Banana",
+	"5":  "Sage",
+	"6":  "Basil",
+	"7":  "Peacock",
+	"8":  "Blueberry",
+	"9":  "Lavender",
+	"10": "Grape",
+	"11": "Graphite",
+}
+
+// Provider is Google Calendar as a calendar provider: the API client plus the
+// OAuth configuration of the app. It supports incremental sync, push
+// notifications and token revocation through the embedded client.
+type Provider struct {
+	*GoogleCalendarClient
+	config *oauth2.Config
+}
+
+func NewProvider(client *GoogleCalendarClient, config *oauth2.Config) *Provider {
+	return &Provider{GoogleCalendarClient: client, config: config}
+}
+
+func (p *Provider) Name() calendar.Provider {
+	return calendar.ProviderGoogle
+}
+
+// AuthCodeURL forces the consent screen so that Google issues a refresh token
+// on every connection, not only the first.
+func (p *Provider) AuthCodeURL(state string) string {
+	return p.config.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.SetAuthURLParam("prompt", "consent"))
+}
+
+func (p *Provider) Exchange(ctx context.Context, code string) (*oauth2.Token, error) {
+	return p.config.Exchange(oauthContext(ctx), code)
+}
+
+func (p *Provider) TokenSource(ctx context.Context, t *oauth2.Token) oauth2.TokenSource {
+	return p.config.TokenSource(oauthContext(ctx), t)
+}
+
+// oauthContext sends the token requests made with ctx through apiClient.
+func oauthContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, apiClient)
+}
+
+// Category names events by their color label.
+func (p *Provider) Category(event calendar.Event) string {
+	name, ok := ColorNames[event.ColorID]
+	if !ok {
+		return "Default"
+	}
+	return name
+}
+
+func (p *Provider) ColorName(colorID string) (string, bool) {
+	name, ok := ColorNames[colorID]
+	return name, ok
+}
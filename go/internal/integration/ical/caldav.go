@@ -0,0 +1,212 @@
+package ical
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"energyjournal/internal/domain/calendar"
+)
+
+const (
+	caldavNS = "urn:ietf:params:xml:ns:caldav"
+	// caldavTimeLayout is the UTC format of time-range filters.
+	caldavTimeLayout = "20060102T150405Z"
+
+	propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:" xmlns:a="http://apple.com/ns/ical/">
+  <d:prop><d:resourcetype/><d:displayname/><a:calendar-color/></d:prop>
+</d:propfind>`
+
+	calendarQueryBody = `<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><c:calendar-data/></d:prop>
+  <c:filter>
+    <c:comp-filter name="VCALENDAR">
+      <c:comp-filter name="VEVENT"><c:time-range start="%s" end="%s"/></c:comp-filter>
+    </c:comp-filter>
+  </c:filter>
+</c:calendar-query>`
+)
+
+// multistatus is a WebDAV 207 response (RFC 4918 section 14.16).
+type multistatus struct {
+	XMLName   xml.Name `xml:"DAV: multistatus"`
+	Responses []struct {
+		Href     string `xml:"DAV: href"`
+		Propstat []struct {
+			Status string `xml:"DAV: status"`
+			Prop   struct {
+				ResourceType struct {
+					Calendar *struct{} `xml:"urn:ietf:params:xml:ns:caldav calendar"`
+				} `xml:"DAV: resourcetype"`
+				DisplayName  string `xml:"DAV: displayname"`
+				Color        string `xml:"http://apple.com/ns/ical/ calendar-color"`
+				CalendarData string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+// CalDAVProvider reads a CalDAV server with basic authentication. The source
+// URL is a calendar home, whose calendars are listed, or a single calendar.
+// Calendar IDs are the paths of the calendar collections on that server.
+type CalDAVProvider struct {
+	source
+}
+
+// NewCalDAVProvider returns the CalDAV provider. allowPrivateNetworks permits
+// servers on loopback or private addresses.
+func NewCalDAVProvider(allowPrivateNetworks bool) *CalDAVProvider {
+	return &CalDAVProvider{source: newSource(allowPrivateNetworks)}
+}
+
+func (p *CalDAVProvider) Name() calendar.Provider {
+	return calendar.ProviderCalDAV
+}
+
+func (p *CalDAVProvider) Connect(ctx context.Context, src calendar.FeedSource) (string, error) {
+	src, err := normalizeSource(src)
+	if err != nil {
+		return "", err
+	}
+	if _, err := p.propfind(ctx, src, src.URL, "0"); err != nil {
+		return "", err
+	}
+	return encodeCredential(src)
+}
+
+func (p *CalDAVProvider) ListCalendars(ctx context.Context, token string) ([]calendar.CalendarItem, error) {
+	src, err := decodeCredential(token)
+	if err != nil {
+		return nil, err
+	}
+	status, err := p.propfind(ctx, src, src.URL, "1")
+	if err != nil {
+		return nil, err
+	}
+
+	items := []calendar.CalendarItem{}
+	for _, response := range status.Responses {
+		for _, propstat := range response.Propstat {
+			prop := propstat.Prop
+			if !statusOK(propstat.Status) || prop.ResourceType.Calendar == nil {
+				continue
+			}
+			name := prop.DisplayName
+			if name == "" {
+				name = response.Href
+			}
+			items = append(items, calendar.CalendarItem{
+				ID:    response.Href,
+				Name:  name,
+				Color: trimAlpha(prop.Color),
+			})
+		}
+	}
+	return items, nil
+}
+
+func (p *CalDAVProvider) ListEvents(ctx context.Context, token, calendarID string, start, end time.Time) ([]calendar.Event, error) {
+	src, err := decodeCredential(token)
+	if err != nil {
+		return nil, err
+	}
+	endpoint, err := resolveOnServer(src.URL, calendarID)
+	if err != nil {
+		return nil, err
+	}
+
+	body := fmt.Sprintf(calendarQueryBody, start.UTC().Format(caldavTimeLayout), end.UTC().Format(caldavTimeLayout))
+	status, err := p.request(ctx, src, "REPORT", endpoint, "1", body)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []calendar.Event{}
+	budget := newExpansionBudget()
+	for _, response := range status.Responses {
+		for _, propstat := range response.Propstat {
+			data := propstat.Prop.CalendarData
+			if !statusOK(propstat.Status) || data == "" {
+				continue
+			}
+			cal, err := parseCalendar(ctx, strings.NewReader(data), time.UTC)
+			if err != nil {
+				return nil, fmt.Errorf("parse %s: %w", response.Href, err)
+			}
+			events = append(events, cal.eventsBetween(calendarID, start, end, budget)...)
+		}
+	}
+	return events, nil
+}
+
+// Category names events by their first CATEGORIES value.
+func (p *CalDAVProvider) Category(event calendar.Event) string {
+	return firstCategory(event)
+}
+
+func (p *CalDAVProvider) ColorName(string) (string, bool) {
+	return "", false
+}
+
+func (p *CalDAVProvider) propfind(ctx context.Context, src calendar.FeedSource, endpoint, depth string) (*multistatus, error) {
+	return p.request(ctx, src, "PROPFIND", endpoint, depth, propfindBody)
+}
+
+func (p *CalDAVProvider) request(ctx context.Context, src calendar.FeedSource, method, endpoint, depth, body string) (*multistatus, error) {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewBufferString(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	req.Header.Set("Depth", depth)
+
+	payload, err := p.do(req, src)
+	if err != nil {
+		return nil, err
+	}
+	var status multistatus
+	if err := xml.Unmarshal(payload, &status); err != nil {
+		return nil, fmt.Errorf("decode %s response: %w", method, err)
+	}
+	return &status, nil
+}
+
+// resolveOnServer resolves a calendar path against the source URL. Calendars
+// on other hosts are refused so the credentials are only sent to the server
+// the user connected.
+func resolveOnServer(base, calendarID string) (string, error) {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(calendarID)
+	if err != nil {
+		return "", err
+	}
+	resolved := baseURL.ResolveReference(ref)
+	if resolved.Scheme != baseURL.Scheme || resolved.Host != baseURL.Host {
+		return "", fmt.Errorf("calendar %q is not on the connected server", calendarID)
+	}
+	return resolved.String(), nil
+}
+
+// statusOK reports a propstat status line such as "HTTP/1.1 200 OK". A
+// missing status is accepted.
+func statusOK(status string) bool {
+	return status == "" || strings.Contains(status, " 200")
+}
+
+// trimAlpha drops the alpha channel of #RRGGBBAA colors.
+func trimAlpha(color string) string {
+	if len(color) == len("#RRGGBBAA") && strings.HasPrefix(color, "#") {
+		return color[:7]
+	}
+	return color
+}
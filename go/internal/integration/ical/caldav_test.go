@@ -0,0 +1,113 @@
+package ical
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"energyjournal/internal/domain/calendar"
+)
+
+const caldavHome = `<?xml version="1.0" encoding="utf-8"?>
+<d:multistatus xmlns:d="DAV:" xmlns:cal="urn:ietf:params:xml:ns:caldav" xmlns:a="http://apple.com/ns/ical/">
+  <d:response>
+    <d:href>/dav/calendars/ana/</d:href>
+    <d:propstat><d:prop><d:resourcetype><d:collection/></d:resourcetype></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat>
+  </d:response>
+  <d:response>
+    <d:href>/dav/calendars/ana/work/</d:href>
+    <d:propstat>
+      <d:prop>
+        <d:resourcetype><d:collection/><cal:calendar/></d:resourcetype>
+        <d:displayname>Work</d:displayname>
+        <a:calendar-color>#3A87ADFF</a:calendar-color>
+      </d:prop>
+      <d:status>HTTP/1.1 200 OK</d:status>
+    </d:propstat>
+  </d:response>
+</d:multistatus>`
+
+const caldavReport = `<?xml version="1.0" encoding="utf-8"?>
+<d:multistatus xmlns:d="DAV:" xmlns:cal="urn:ietf:params:xml:ns:caldav">
+  <d:response>
+    <d:href>/dav/calendars/ana/work/planning.ics</d:href>
+    <d:propstat>
+      <d:prop><cal:calendar-data>BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+UID:planning
+SUMMARY:Planning
+CATEGORIES:Meetings
+DTSTART:20260302T090000Z
+DTEND:20260302T100000Z
+RRULE:FREQ=DAILY;COUNT=10
+END:VEVENT
+END:VCALENDAR
+</cal:calendar-data></d:prop>
+      <d:status>HTTP/1.1 200 OK</d:status>
+    </d:propstat>
+  </d:response>
+</d:multistatus>`
+
+func TestCalDAVProviderListsCalendarsAndEvents(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "ana" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.Method == "PROPFIND" && r.URL.Path == "/dav/calendars/ana/":
+			w.WriteHeader(http.StatusMultiStatus)
+			_, _ = w.Write([]byte(caldavHome))
+		case r.Method == "REPORT" && r.URL.Path == "/dav/calendars/ana/work/":
+			if r.Header.Get("Depth") != "1" || !strings.Contains(string(body), `start="20260302T000000Z" end="20260305T000000Z"`) {
+				t.Errorf("unexpected calendar query: depth %q, body %s", r.Header.Get("Depth"), body)
+			}
+			w.WriteHeader(http.StatusMultiStatus)
+			_, _ = w.Write([]byte(caldavReport))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	provider := NewCalDAVProvider(true)
+
+	_, err := provider.Connect(context.Background(), calendar.FeedSource{URL: server.URL + "/dav/calendars/ana/", Username: "ana", Password: "wrong"})
+	if !errors.Is(err, calendar.ErrAuthorizationRevoked) {
+		t.Fatalf("expected rejected credentials, got %v", err)
+	}
+	token, err := provider.Connect(context.Background(), calendar.FeedSource{URL: server.URL + "/dav/calendars/ana/", Username: "ana", Password: "secret"})
+	if err != nil {
+		t.Fatalf("Connect returned error: %v", err)
+	}
+
+	calendars, err := provider.ListCalendars(context.Background(), token)
+	if err != nil {
+		t.Fatalf("ListCalendars returned error: %v", err)
+	}
+	if len(calendars) != 1 || calendars[0].ID != "/dav/calendars/ana/work/" || calendars[0].Name != "Work" || calendars[0].Color != "#3A87AD" {
+		t.Fatalf("unexpected calendars: %+v", calendars)
+	}
+
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	events, err := provider.ListEvents(context.Background(), token, calendars[0].ID, start, start.AddDate(0, 0, 3))
+	if err != nil {
+		t.Fatalf("ListEvents returned error: %v", err)
+	}
+	if len(events) != 3 || events[2].ID != "planning_20260304T090000Z" || provider.Category(events[0]) != "Meetings" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+
+	if _, err := provider.ListEvents(context.Background(), token, "https://elsewhere.example/cal/", start, start.AddDate(0, 0, 3)); err == nil {
+		t.Fatal("expected calendars on another host to be refused")
+	}
+}
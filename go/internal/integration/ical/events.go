@@ -0,0 +1,268 @@
+package ical
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"energyjournal/internal/domain/calendar"
+	"energyjournal/internal/server/middleware"
+)
+
+// feedCalendar is a parsed VCALENDAR.
+type feedCalendar struct {
+	name     string
+	color    string
+	location *time.Location
+	events   []vevent
+}
+
+// vevent is a VEVENT. An event with a recurrenceID overrides one occurrence
+// of the recurring event sharing its UID.
+type vevent struct {
+	uid          string
+	summary      string
+	status       calendar.EventStatus
+	transparent  bool
+	categories   []string
+	allDay       bool
+	start        time.Time
+	end          time.Time
+	rule         *rrule
+	rdates       []time.Time
+	exdates      []time.Time
+	recurrenceID time.Time
+}
+
+// parseCalendar reads the first VCALENDAR of r. Floating times and dates use
+// the X-WR-TIMEZONE of the calendar, or def. Events that cannot be read are
+// skipped.
+func parseCalendar(ctx context.Context, r io.Reader, def *time.Location) (*feedCalendar, error) {
+	roots, err := parseComponents(r)
+	if err != nil {
+		return nil, err
+	}
+	var root *component
+	for _, comp := range roots {
+		if comp.name == "VCALENDAR" {
+			root = comp
+			break
+		}
+	}
+	if root == nil {
+		return nil, errNotCalendar
+	}
+
+	cal := &feedCalendar{location: def}
+	if prop, ok := root.first("X-WR-TIMEZONE"); ok {
+		cal.location = resolveZone(prop.value, def)
+	}
+	if prop, ok := root.first("X-WR-CALNAME"); ok {
+		cal.name = unescapeText(prop.value)
+	}
+	if prop, ok := root.first("X-APPLE-CALENDAR-COLOR"); ok {
+		cal.color = prop.value
+	}
+	for _, child := range root.children {
+		if child.name != "VEVENT" {
+			continue
+		}
+		event, err := parseEvent(ctx, child, cal.location)
+		if err != nil {
+			middleware.Logger(ctx).WithError(err).Warn("Skipping unreadable calendar event")
+			continue
+		}
+		cal.events = append(cal.events, event)
+	}
+	return cal, nil
+}
+
+func parseEvent(ctx context.Context, comp *component, loc *time.Location) (vevent, error) {
+	event := vevent{status: calendar.EventConfirmed}
+	if prop, ok := comp.first("UID"); ok {
+		event.uid = prop.value
+	}
+	if prop, ok := comp.first("SUMMARY"); ok {
+		event.summary = unescapeText(prop.value)
+	}
+	if prop, ok := comp.first("STATUS"); ok && strings.EqualFold(prop.value, "CANCELLED") {
+		event.status = calendar.EventCancelled
+	}
+	if prop, ok := comp.first("TRANSP"); ok {
+		event.transparent = strings.EqualFold(prop.value, "TRANSPARENT")
+	}
+	for _, prop := range comp.all("CATEGORIES") {
+		for _, category := range splitText(prop.value) {
+			if category = strings.TrimSpace(category); category != "" {
+				event.categories = append(event.categories, category)
+			}
+		}
+	}
+
+	dtstart, ok := comp.first("DTSTART")
+	if !ok {
+		return vevent{}, errMissingStart
+	}
+	var err error
+	event.start, event.allDay, err = parseTime(dtstart.value, dtstart.params, loc)
+	if err != nil {
+		return vevent{}, err
+	}
+
+	switch {
+	case hasProperty(comp, "DTEND"):
+		dtend, _ := comp.first("DTEND")
+		if event.end, _, err = parseTime(dtend.value, dtend.params, loc); err != nil {
+			return vevent{}, err
+		}
+	case hasProperty(comp, "DURATION"):
+		duration, _ := comp.first("DURATION")
+		days, clock, err := parseDuration(duration.value)
+		if err != nil {
+			return vevent{}, err
+		}
+		event.end = event.start.AddDate(0, 0, days).Add(clock)
+	case event.allDay:
+		event.end = event.start.AddDate(0, 0, 1)
+	default:
+		event.end = event.start
+	}
+
+	if prop, ok := comp.first("RRULE"); ok {
+		rule, err := parseRRule(prop.value, event.start.Location())
+		if err != nil {
+			// Unsupported rules keep the first occurrence only.
+			middleware.Logger(ctx).WithError(err).WithField("event_uid", event.uid).Warn("Unsupported recurrence rule, keeping the first occurrence")
+		} else {
+			event.rule = &rule
+		}
+	}
+	for _, prop := range comp.all("RDATE") {
+		if prop.params["VALUE"] == "PERIOD" {
+			continue
+		}
+		times, err := parseTimes(prop, loc)
+		if err != nil {
+			return vevent{}, err
+		}
+		event.rdates = append(event.rdates, times...)
+	}
+	for _, prop := range comp.all("EXDATE") {
+		times, err := parseTimes(prop, loc)
+		if err != nil {
+			return vevent{}, err
+		}
+		event.exdates = append(event.exdates, times...)
+	}
+	if prop, ok := comp.first("RECURRENCE-ID"); ok {
+		if event.recurrenceID, _, err = parseTime(prop.value, prop.params, loc); err != nil {
+			return vevent{}, err
+		}
+	}
+	return event, nil
+}
+
+func hasProperty(comp *component, name string) bool {
+	_, ok := comp.first(name)
+	return ok
+}
+
+// eventsBetween expands recurring events and returns every occurrence
+// overlapping [start, end), with overridden occurrences replaced. Expansion
+// is taken from budget, shared by every calendar of one read.
+func (cal *feedCalendar) eventsBetween(calendarID string, start, end time.Time, budget *expansionBudget) []calendar.Event {
+	overrides := map[string]bool{}
+	for _, event := range cal.events {
+		if !event.recurrenceID.IsZero() {
+			overrides[occurrenceKey(event.uid, event.recurrenceID)] = true
+		}
+	}
+
+	out := []calendar.Event{}
+	for _, event := range cal.events {
+		if !event.recurrenceID.IsZero() {
+			if overlaps(event.start, event.end, start, end) {
+				out = append(out, event.toEvent(calendarID, instanceID(event.uid, event.recurrenceID, event.allDay), event.start, event.end))
+			}
+			continue
+		}
+		if event.rule == nil && len(event.rdates) == 0 {
+			if overlaps(event.start, event.end, start, end) {
+				out = append(out, event.toEvent(calendarID, event.uid, event.start, event.end))
+			}
+			continue
+		}
+
+		excluded := map[string]bool{}
+		for _, exdate := range event.exdates {
+			excluded[occurrenceKey(event.uid, exdate)] = true
+		}
+		starts := []time.Time{event.start}
+		if event.rule != nil {
+			// Occurrences starting one event length before start still overlap it.
+			starts = event.rule.starts(event.start, start.Add(-event.end.Sub(event.start)), end, budget)
+		}
+		starts = append(starts, event.rdates...)
+
+		seen := map[string]bool{}
+		for _, occurrence := range starts {
+			key := occurrenceKey(event.uid, occurrence)
+			if seen[key] || excluded[key] || overrides[key] {
+				continue
+			}
+			seen[key] = true
+			occurrenceEnd := event.endFor(occurrence)
+			if overlaps(occurrence, occurrenceEnd, start, end) {
+				out = append(out, event.toEvent(calendarID, instanceID(event.uid, occurrence, event.allDay), occurrence, occurrenceEnd))
+			}
+		}
+	}
+	return out
+}
+
+// endFor keeps the event's length for an occurrence: whole days for all-day
+// events, and elapsed time otherwise.
+func (e vevent) endFor(occurrence time.Time) time.Time {
+	if e.allDay {
+		days := int(dateOf(e.end).Sub(dateOf(e.start)).Hours() / 24)
+		return occurrence.AddDate(0, 0, days)
+	}
+	return occurrence.Add(e.end.Sub(e.start))
+}
+
+func (e vevent) toEvent(calendarID, id string, start, end time.Time) calendar.Event {
+	return calendar.Event{
+		ID:          id,
+		CalendarID:  calendarID,
+		Summary:     e.summary,
+		Start:       start,
+		End:         end,
+		AllDay:      e.allDay,
+		Status:      e.status,
+		Transparent: e.transparent,
+		Categories:  e.categories,
+	}
+}
+
+// overlaps reports whether [from, to) meets [start, end). Zero-length events
+// count when they start inside the range.
+func overlaps(from, to, start, end time.Time) bool {
+	if !to.After(from) {
+		return !from.Before(start) && from.Before(end)
+	}
+	return from.Before(end) && to.After(start)
+}
+
+func occurrenceKey(uid string, t time.Time) string {
+	return uid + "|" + t.UTC().Format(time.RFC3339)
+}
+
+// instanceID names an occurrence like Google does: the UID and the original
+// start in UTC, or its date for all-day events.
+func instanceID(uid string, t time.Time, allDay bool) string {
+	if allDay {
+		return uid + "_" + t.Format(dateLayout)
+	}
+	return uid + "_" + t.UTC().Format(dateTimeLayout) + "Z"
+}
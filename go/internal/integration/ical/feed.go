@@ -0,0 +1,233 @@
+package ical
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"energyjournal/internal/domain/calendar"
+)
+
+const (
+	// FeedCalendarID is the single calendar of an ICS feed connection.
+	FeedCalendarID = "feed"
+	// maxBodyBytes bounds a downloaded feed or CalDAV response.
+	maxBodyBytes   = 10 << 20
+	requestTimeout = 30 * time.Second
+)
+
+var (
+	errNotCalendar       = errors.New("response is not an iCalendar document")
+	errMissingStart      = errors.New("event has no DTSTART")
+	errPrivateNetwork    = errors.New("calendar sources on private networks are not allowed")
+	errUnsupportedScheme = errors.New("calendar url must use http, https or webcal")
+)
+
+// SourceError is a non-2xx answer of an ICS feed or CalDAV server.
+type SourceError struct {
+	StatusCode int
+}
+
+func (e *SourceError) Error() string {
+	return fmt.Sprintf("calendar source returned status %d", e.StatusCode)
+}
+
+// Is makes 401 responses match calendar.ErrAuthorizationRevoked.
+func (e *SourceError) Is(target error) bool {
+	return target == calendar.ErrAuthorizationRevoked && e.StatusCode == http.StatusUnauthorized
+}
+
+// source fetches user-supplied URLs. Unless private networks are allowed, it
+// refuses to connect to loopback, private and link-local addresses, including
+// after redirects.
+type source struct {
+	httpClient *http.Client
+}
+
+func newSource(allowPrivateNetworks bool) source {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !allowPrivateNetworks {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+				ip.IsLinkLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+				return errPrivateNetwork
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	return source{httpClient: &http.Client{Transport: transport, Timeout: requestTimeout}}
+}
+
+// do sends req with the source's basic credentials and returns the body of a
+// 2xx response, up to maxBodyBytes.
+func (s source) do(req *http.Request, creds calendar.FeedSource) ([]byte, error) {
+	if creds.Username != "" || creds.Password != "" {
+		req.SetBasicAuth(creds.Username, creds.Password)
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, &SourceError{StatusCode: resp.StatusCode}
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxBodyBytes {
+		return nil, fmt.Errorf("calendar source response exceeds %d bytes", maxBodyBytes)
+	}
+	return body, nil
+}
+
+// normalizeSource checks the URL, turning webcal:// subscription links into https.
+func normalizeSource(src calendar.FeedSource) (calendar.FeedSource, error) {
+	u, err := url.Parse(strings.TrimSpace(src.URL))
+	if err != nil {
+		return calendar.FeedSource{}, err
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+	case "webcal", "webcals":
+		u.Scheme = "https"
+	default:
+		return calendar.FeedSource{}, errUnsupportedScheme
+	}
+	if u.Host == "" {
+		return calendar.FeedSource{}, errUnsupportedScheme
+	}
+	src.URL = u.String()
+	return src, nil
+}
+
+// encodeCredential turns the source into the connection credential, which is
+// stored encrypted with the connection like an OAuth token.
+func encodeCredential(src calendar.FeedSource) (string, error) {
+	encoded, err := json.Marshal(struct {
+		URL      string `json:"url"`
+		Username string `json:"username,omitempty"`
+		Password string `json:"password,omitempty"`
+	}{src.URL, src.Username, src.Password})
+	return string(encoded), err
+}
+
+func decodeCredential(token string) (calendar.FeedSource, error) {
+	var payload struct {
+		URL      string `json:"url"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.Unmarshal([]byte(token), &payload); err != nil {
+		return calendar.FeedSource{}, fmt.Errorf("decode calendar source: %w", err)
+	}
+	return calendar.FeedSource{URL: payload.URL, Username: payload.Username, Password: payload.Password}, nil
+}
+
+// FeedProvider reads an iCalendar (ICS) feed, such as the secret iCal address
+// of a calendar. The feed is downloaded on every read, is a single calendar,
+// and its events are named by their first CATEGORIES value.
+type FeedProvider struct {
+	source
+}
+
+// NewFeedProvider returns the ICS feed provider. allowPrivateNetworks permits
+// feeds on loopback or private addresses, e.g. a self-hosted server on the LAN.
+func NewFeedProvider(allowPrivateNetworks bool) *FeedProvider {
+	return &FeedProvider{source: newSource(allowPrivateNetworks)}
+}
+
+func (p *FeedProvider) Name() calendar.Provider {
+	return calendar.ProviderICS
+}
+
+func (p *FeedProvider) Connect(ctx context.Context, src calendar.FeedSource) (string, error) {
+	src, err := normalizeSource(src)
+	if err != nil {
+		return "", err
+	}
+	if _, err := p.fetch(ctx, src); err != nil {
+		return "", err
+	}
+	return encodeCredential(src)
+}
+
+func (p *FeedProvider) ListCalendars(ctx context.Context, token string) ([]calendar.CalendarItem, error) {
+	src, err := decodeCredential(token)
+	if err != nil {
+		return nil, err
+	}
+	cal, err := p.fetch(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	name := cal.name
+	if name == "" {
+		if u, err := url.Parse(src.URL); err == nil {
+			name = u.Host
+		}
+	}
+	return []calendar.CalendarItem{{ID: FeedCalendarID, Name: name, Color: cal.color}}, nil
+}
+
+func (p *FeedProvider) ListEvents(ctx context.Context, token, calendarID string, start, end time.Time) ([]calendar.Event, error) {
+	if calendarID != FeedCalendarID {
+		return nil, fmt.Errorf("unknown feed calendar %q", calendarID)
+	}
+	src, err := decodeCredential(token)
+	if err != nil {
+		return nil, err
+	}
+	cal, err := p.fetch(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	return cal.eventsBetween(calendarID, start, end, newExpansionBudget()), nil
+}
+
+// Category names events by their first CATEGORIES value.
+func (p *FeedProvider) Category(event calendar.Event) string {
+	return firstCategory(event)
+}
+
+func (p *FeedProvider) ColorName(string) (string, bool) {
+	return "", false
+}
+
+func (p *FeedProvider) fetch(ctx context.Context, src calendar.FeedSource) (*feedCalendar, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/calendar")
+	body, err := p.do(req, src)
+	if err != nil {
+		return nil, err
+	}
+	return parseCalendar(ctx, bytes.NewReader(body), time.UTC)
+}
+
+func firstCategory(event calendar.Event) string {
+	if len(event.Categories) == 0 {
+		return "Default"
+	}
+	return event.Categories[0]
+}
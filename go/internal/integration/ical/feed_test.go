@@ -0,0 +1,128 @@
+package ical
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"energyjournal/internal/domain/calendar"
+)
+
+func TestFeedProviderReadsLocalFile(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer server.Close()
+	provider := NewFeedProvider(true)
+
+	token, err := provider.Connect(context.Background(), calendar.FeedSource{URL: server.URL + "/team.ics"})
+	if err != nil {
+		t.Fatalf("Connect returned error: %v", err)
+	}
+	calendars, err := provider.ListCalendars(context.Background(), token)
+	if err != nil {
+		t.Fatalf("ListCalendars returned error: %v", err)
+	}
+	if len(calendars) != 1 || calendars[0].ID != FeedCalendarID || calendars[0].Name != "Team" {
+		t.Fatalf("unexpected calendars: %+v", calendars)
+	}
+
+	start := time.Date(2026, 3, 23, 0, 0, 0, 0, time.UTC)
+	events, err := provider.ListEvents(context.Background(), token, FeedCalendarID, start, start.AddDate(0, 0, 14))
+	if err != nil {
+		t.Fatalf("ListEvents returned error: %v", err)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+
+	paris, _ := time.LoadLocation("Europe/Paris")
+	want := []struct {
+		id      string
+		summary string
+		start   time.Time
+		hours   float64
+	}{
+		// The standup keeps 09:30 in Paris across the switch to summer time.
+		{"standup@example.com_20260323T083000Z", "Standup", time.Date(2026, 3, 23, 8, 30, 0, 0, time.UTC), 0.5},
+		{"review@example.com", "A very long quarterly review title that an exporter folds across two lines", time.Date(2026, 3, 24, 13, 0, 0, 0, time.UTC), 2},
+		{"cancelled@example.com", "Cancelled sync", time.Date(2026, 3, 24, 16, 0, 0, 0, time.UTC), 1},
+		{"offsite@example.com", "Offsite, day one", time.Date(2026, 3, 26, 0, 0, 0, 0, paris), 48},
+		{"standup@example.com_20260330T073000Z", "Standup (moved)", time.Date(2026, 3, 30, 12, 0, 0, 0, time.UTC), 1},
+		{"standup@example.com_20260401T073000Z", "Standup", time.Date(2026, 4, 1, 7, 30, 0, 0, time.UTC), 0.5},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d events, got %+v", len(want), events)
+	}
+	for i, w := range want {
+		got := events[i]
+		if got.ID != w.id || got.Summary != w.summary || !got.Start.Equal(w.start) || got.End.Sub(got.Start).Hours() != w.hours {
+			t.Fatalf("event %d: unexpected %+v", i, got)
+		}
+		if got.CalendarID != FeedCalendarID {
+			t.Fatalf("event %d: unexpected calendar %q", i, got.CalendarID)
+		}
+	}
+
+	if events[2].Status != calendar.EventCancelled || events[2].CountsAsSpending() {
+		t.Fatalf("expected the cancelled event not to count: %+v", events[2])
+	}
+	if offsite := events[3]; !offsite.AllDay || !offsite.Transparent || provider.Category(offsite) != "Travel" {
+		t.Fatalf("unexpected all-day event: %+v", offsite)
+	}
+	if provider.Category(events[0]) != "Meetings" || provider.Category(events[1]) != "Default" {
+		t.Fatalf("unexpected categories: %v %v", events[0].Categories, events[1].Categories)
+	}
+}
+
+func TestFeedProviderSendsCredentialsAndReportsRejection(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "ana" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.ServeFile(w, r, "testdata/team.ics")
+	}))
+	defer server.Close()
+	provider := NewFeedProvider(true)
+
+	if _, err := provider.Connect(context.Background(), calendar.FeedSource{URL: server.URL, Username: "ana", Password: "secret"}); err != nil {
+		t.Fatalf("Connect returned error: %v", err)
+	}
+	_, err := provider.Connect(context.Background(), calendar.FeedSource{URL: server.URL, Username: "ana", Password: "wrong"})
+	if !errors.Is(err, calendar.ErrAuthorizationRevoked) {
+		t.Fatalf("expected rejected credentials, got %v", err)
+	}
+}
+
+func TestFeedProviderRefusesPrivateNetworks(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer server.Close()
+
+	_, err := NewFeedProvider(false).Connect(context.Background(), calendar.FeedSource{URL: server.URL + "/team.ics"})
+	if !errors.Is(err, errPrivateNetwork) {
+		t.Fatalf("expected a private network error, got %v", err)
+	}
+	_, err = NewFeedProvider(false).Connect(context.Background(), calendar.FeedSource{URL: "file:///etc/passwd"})
+	if !errors.Is(err, errUnsupportedScheme) {
+		t.Fatalf("expected a scheme error, got %v", err)
+	}
+}
+
+func TestNormalizeSourceRewritesWebcal(t *testing.T) {
+	t.Parallel()
+
+	src, err := normalizeSource(calendar.FeedSource{URL: " webcal://calendar.example.com/u/abc/basic.ics "})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if src.URL != "https://calendar.example.com/u/abc/basic.ics" {
+		t.Fatalf("unexpected url: %s", src.URL)
+	}
+}
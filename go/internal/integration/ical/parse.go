@@ -0,0 +1,308 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	dateLayout     = "20060102"
+	dateTimeLayout = "20060102T150405"
+	// maxLineBytes bounds a single unfolded content line.
+	maxLineBytes = 1 << 20
+)
+
+// property is a content line: NAME;PARAM=value:VALUE.
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// component is a BEGIN/END block such as VCALENDAR or VEVENT.
+type component struct {
+	name       string
+	properties []property
+	children   []*component
+}
+
+func (c *component) first(name string) (property, bool) {
+	for _, prop := range c.properties {
+		if prop.name == name {
+			return prop, true
+		}
+	}
+	return property{}, false
+}
+
+func (c *component) all(name string) []property {
+	var out []property
+	for _, prop := range c.properties {
+		if prop.name == name {
+			out = append(out, prop)
+		}
+	}
+	return out
+}
+
+// parseComponents reads every top-level component of an iCalendar stream,
+// unfolding continuation lines (RFC 5545 section 3.1).
+func parseComponents(r io.Reader) ([]*component, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineBytes)
+
+	var (
+		roots []*component
+		stack []*component
+		line  strings.Builder
+	)
+	flush := func() error {
+		if line.Len() == 0 {
+			return nil
+		}
+		prop, err := parseLine(line.String())
+		line.Reset()
+		if err != nil {
+			return err
+		}
+		switch prop.name {
+		case "BEGIN":
+			comp := &component{name: strings.ToUpper(prop.value)}
+			if len(stack) == 0 {
+				roots = append(roots, comp)
+			} else {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, comp)
+			}
+			stack = append(stack, comp)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].name != strings.ToUpper(prop.value) {
+				return fmt.Errorf("unexpected END:%s", prop.value)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) > 0 {
+				current := stack[len(stack)-1]
+				current.properties = append(current.properties, prop)
+			}
+		}
+		return nil
+	}
+
+	for scanner.Scan() {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t") {
+			line.WriteString(text[1:])
+			continue
+		}
+		if err := flush(); err != nil {
+			return nil, err
+		}
+		line.WriteString(text)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("unterminated %s", stack[len(stack)-1].name)
+	}
+	return roots, nil
+}
+
+// parseLine splits a content line into name, parameters and value. Parameter
+// values may be quoted to contain ':', ';' or ','.
+func parseLine(line string) (property, error) {
+	prop := property{params: map[string]string{}}
+	i := strings.IndexAny(line, ";:")
+	if i <= 0 {
+		return property{}, fmt.Errorf("malformed content line %q", line)
+	}
+	prop.name = strings.ToUpper(line[:i])
+
+	for line[i] == ';' {
+		rest := line[i+1:]
+		eq := strings.IndexByte(rest, '=')
+		if eq < 0 {
+			return property{}, fmt.Errorf("malformed parameter in %q", line)
+		}
+		key := strings.ToUpper(rest[:eq])
+		j := i + 1 + eq + 1
+
+		var value strings.Builder
+		quoted := false
+		for ; j < len(line); j++ {
+			c := line[j]
+			if c == '"' {
+				quoted = !quoted
+				continue
+			}
+			if !quoted && (c == ';' || c == ':') {
+				break
+			}
+			value.WriteByte(c)
+		}
+		if j >= len(line) {
+			return property{}, fmt.Errorf("missing value in %q", line)
+		}
+		prop.params[key] = value.String()
+		i = j
+	}
+
+	prop.value = line[i+1:]
+	return prop, nil
+}
+
+// unescapeText decodes a TEXT value (RFC 5545 section 3.3.11).
+func unescapeText(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+	var out strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			out.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n', 'N':
+			out.WriteByte('\n')
+		default:
+			out.WriteByte(value[i])
+		}
+	}
+	return out.String()
+}
+
+// splitText splits a multi-valued TEXT property on unescaped commas.
+func splitText(value string) []string {
+	var (
+		out     []string
+		current strings.Builder
+	)
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && i+1 < len(value):
+			current.WriteByte(value[i])
+			current.WriteByte(value[i+1])
+			i++
+		case value[i] == ',':
+			out = append(out, unescapeText(current.String()))
+			current.Reset()
+		default:
+			current.WriteByte(value[i])
+		}
+	}
+	return append(out, unescapeText(current.String()))
+}
+
+// parseTime reads a DATE or DATE-TIME value. UTC times end in Z, TZID names
+// an IANA zone, and floating times and dates use loc. The second result
+// reports a DATE value.
+func parseTime(value string, params map[string]string, loc *time.Location) (time.Time, bool, error) {
+	if params["VALUE"] == "DATE" || len(value) == len(dateLayout) {
+		t, err := time.ParseInLocation(dateLayout, value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.ParseInLocation(dateTimeLayout, strings.TrimSuffix(value, "Z"), time.UTC)
+		return t, false, err
+	}
+	if tzid := params["TZID"]; tzid != "" {
+		loc = resolveZone(tzid, loc)
+	}
+	t, err := time.ParseInLocation(dateTimeLayout, value, loc)
+	return t, false, err
+}
+
+// parseTimes reads a comma-separated EXDATE or RDATE value.
+func parseTimes(prop property, loc *time.Location) ([]time.Time, error) {
+	var out []time.Time
+	for _, value := range strings.Split(prop.value, ",") {
+		if value == "" {
+			continue
+		}
+		t, _, err := parseTime(value, prop.params, loc)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+// resolveZone maps a TZID to a location. Some producers prefix IANA names
+// (e.g. "/mozilla.org/20050126_1/Europe/Paris"); other names, such as Windows
+// zone names, fall back to def since VTIMEZONE definitions are not read.
+func resolveZone(tzid string, def *time.Location) *time.Location {
+	if loc, err := time.LoadLocation(tzid); err == nil {
+		return loc
+	}
+	parts := strings.Split(strings.Trim(tzid, "/"), "/")
+	if len(parts) >= 2 {
+		if loc, err := time.LoadLocation(strings.Join(parts[len(parts)-2:], "/")); err == nil {
+			return loc
+		}
+	}
+	return def
+}
+
+var errInvalidDuration = errors.New("invalid duration")
+
+// parseDuration reads a DURATION value such as PT1H30M or P1D. Weeks and days
+// are returned separately so that all-day events keep whole days.
+func parseDuration(value string) (days int, clock time.Duration, err error) {
+	sign := 1
+	if strings.HasPrefix(value, "-") {
+		sign = -1
+	}
+	value = strings.TrimLeft(value, "+-")
+	if !strings.HasPrefix(value, "P") {
+		return 0, 0, errInvalidDuration
+	}
+	value = value[1:]
+
+	inTime := false
+	number := ""
+	for _, c := range value {
+		switch {
+		case c >= '0' && c <= '9':
+			number += string(c)
+			continue
+		case c == 'T':
+			inTime = true
+			continue
+		}
+		n, convErr := strconv.Atoi(number)
+		if convErr != nil {
+			return 0, 0, errInvalidDuration
+		}
+		number = ""
+		switch {
+		case c == 'W' && !inTime:
+			days += 7 * n
+		case c == 'D' && !inTime:
+			days += n
+		case c == 'H' && inTime:
+			clock += time.Duration(n) * time.Hour
+		case c == 'M' && inTime:
+			clock += time.Duration(n) * time.Minute
+		case c == 'S' && inTime:
+			clock += time.Duration(n) * time.Second
+		default:
+			return 0, 0, errInvalidDuration
+		}
+	}
+	if number != "" {
+		return 0, 0, errInvalidDuration
+	}
+	return sign * days, time.Duration(sign) * clock, nil
+}
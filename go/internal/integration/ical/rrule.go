@@ -0,0 +1,376 @@
+package ical
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxPeriods stops the expansion of rules whose filters never match, such
+	// as the 30th of February.
+	maxPeriods = 100000
+	// maxReadPeriods bounds the periods expanded for one read across every
+	// rule of the calendars read: feeds are user-supplied and expanded again
+	// on every read.
+	maxReadPeriods = 1000000
+)
+
+// expansionBudget counts down the periods a read may still expand. Once it
+// is spent, rules stop expanding and only the occurrences found so far are
+// returned.
+type expansionBudget struct {
+	periods int
+}
+
+func newExpansionBudget() *expansionBudget {
+	return &expansionBudget{periods: maxReadPeriods}
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// weekdayNum is a BYDAY entry: a weekday, with an optional ordinal within
+// the month (2TU is the second Tuesday, -1FR the last Friday).
+type weekdayNum struct {
+	n   int
+	day time.Weekday
+}
+
+// rrule is a recurrence rule (RFC 5545 section 3.3.10). DAILY, WEEKLY,
+// MONTHLY and YEARLY rules are supported with INTERVAL, COUNT, UNTIL, BYDAY,
+// BYMONTHDAY, BYMONTH, BYSETPOS and WKST; BYDAY of YEARLY rules applies
+// within each month.
+type rrule struct {
+	freq       string
+	interval   int
+	count      int
+	until      time.Time
+	byDay      []weekdayNum
+	byMonthDay []int
+	byMonth    []time.Month
+	bySetPos   []int
+	wkst       time.Weekday
+}
+
+// parseRRule reads an RRULE value. A date-only UNTIL includes its whole day
+// in loc.
+func parseRRule(value string, loc *time.Location) (rrule, error) {
+	rule := rrule{interval: 1, wkst: time.Monday}
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.freq = strings.ToUpper(val)
+		case "INTERVAL":
+			rule.interval, err = strconv.Atoi(val)
+		case "COUNT":
+			rule.count, err = strconv.Atoi(val)
+		case "UNTIL":
+			var isDate bool
+			rule.until, isDate, err = parseTime(val, nil, loc)
+			if isDate {
+				rule.until = rule.until.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			}
+		case "BYDAY":
+			rule.byDay, err = parseByDay(val)
+		case "BYMONTHDAY":
+			rule.byMonthDay, err = parseInts(val)
+		case "BYMONTH":
+			var months []int
+			months, err = parseInts(val)
+			for _, m := range months {
+				rule.byMonth = append(rule.byMonth, time.Month(m))
+			}
+		case "BYSETPOS":
+			rule.bySetPos, err = parseInts(val)
+		case "WKST":
+			day, ok := weekdays[strings.ToUpper(val)]
+			if !ok {
+				err = fmt.Errorf("invalid WKST %q", val)
+			}
+			rule.wkst = day
+		}
+		if err != nil {
+			return rrule{}, fmt.Errorf("invalid RRULE %s: %w", key, err)
+		}
+	}
+
+	switch rule.freq {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	default:
+		return rrule{}, fmt.Errorf("unsupported RRULE frequency %q", rule.freq)
+	}
+	if rule.interval < 1 {
+		return rrule{}, fmt.Errorf("invalid RRULE interval %d", rule.interval)
+	}
+	return rule, nil
+}
+
+func parseByDay(value string) ([]weekdayNum, error) {
+	var out []weekdayNum
+	for _, item := range strings.Split(value, ",") {
+		item = strings.ToUpper(strings.TrimSpace(item))
+		if len(item) < 2 {
+			return nil, fmt.Errorf("invalid BYDAY %q", item)
+		}
+		day, ok := weekdays[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid BYDAY %q", item)
+		}
+		entry := weekdayNum{day: day}
+		if prefix := item[:len(item)-2]; prefix != "" {
+			n, err := strconv.Atoi(prefix)
+			if err != nil {
+				return nil, fmt.Errorf("invalid BYDAY %q", item)
+			}
+			entry.n = n
+		}
+		out = append(out, entry)
+	}
+	return out, nil
+}
+
+func parseInts(value string) ([]int, error) {
+	var out []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+// starts returns the occurrence starts of the rule from dtstart until limit
+// (exclusive), leaving out those before from. dtstart always counts as the
+// first occurrence. Occurrences keep dtstart's wall clock time in its
+// location, so they follow daylight saving changes. Each period expanded is
+// taken from budget.
+func (r rrule) starts(dtstart, from, limit time.Time, budget *expansionBudget) []time.Time {
+	if !dtstart.Before(limit) {
+		return nil
+	}
+	out := []time.Time{dtstart}
+	emitted := 1
+	if r.count == 1 {
+		return out
+	}
+	for period := r.firstPeriod(dtstart, from); period < maxPeriods; period++ {
+		if budget.periods <= 0 {
+			return out
+		}
+		budget.periods--
+		for _, candidate := range r.candidates(dtstart, period) {
+			if !candidate.After(dtstart) {
+				continue
+			}
+			if !r.until.IsZero() && candidate.After(r.until) {
+				return out
+			}
+			if !candidate.Before(limit) {
+				return out
+			}
+			emitted++
+			if !candidate.Before(from) {
+				out = append(out, candidate)
+			}
+			if r.count > 0 && emitted >= r.count {
+				return out
+			}
+		}
+	}
+	return out
+}
+
+// firstPeriod returns the period to start expanding from so that no
+// occurrence from from onwards is missed. Rules with COUNT are expanded from
+// dtstart, since every occurrence counts.
+func (r rrule) firstPeriod(dtstart, from time.Time) int {
+	if r.count > 0 || !from.After(dtstart) {
+		return 0
+	}
+	var elapsed int
+	switch r.freq {
+	case "DAILY":
+		elapsed = int(dateOf(from).Sub(dateOf(dtstart)).Hours() / 24)
+	case "WEEKLY":
+		elapsed = int(dateOf(from).Sub(dateOf(dtstart)).Hours()/24) / 7
+	case "MONTHLY":
+		elapsed = (from.Year()-dtstart.Year())*12 + int(from.Month()) - int(dtstart.Month())
+	case "YEARLY":
+		elapsed = from.Year() - dtstart.Year()
+	}
+	// The period before also holds occurrences on or after from when periods
+	// do not align with it.
+	return max(elapsed/r.interval-1, 0)
+}
+
+// candidates returns the sorted occurrences of the period-th period after
+// the one holding dtstart.
+func (r rrule) candidates(dtstart time.Time, period int) []time.Time {
+	step := period * r.interval
+	var dates []time.Time
+	switch r.freq {
+	case "DAILY":
+		day := dateOf(dtstart).AddDate(0, 0, step)
+		if r.matchesMonth(day.Month()) && r.matchesMonthDay(day) && r.matchesWeekday(day.Weekday()) {
+			dates = append(dates, day)
+		}
+	case "WEEKLY":
+		offset := (int(dtstart.Weekday()) - int(r.wkst) + 7) % 7
+		weekStart := dateOf(dtstart).AddDate(0, 0, 7*step-offset)
+		days := r.byDay
+		if len(days) == 0 {
+			days = []weekdayNum{{day: dtstart.Weekday()}}
+		}
+		for _, wd := range days {
+			day := weekStart.AddDate(0, 0, (int(wd.day)-int(r.wkst)+7)%7)
+			if r.matchesMonth(day.Month()) {
+				dates = append(dates, day)
+			}
+		}
+	case "MONTHLY":
+		month := time.Date(dtstart.Year(), dtstart.Month()+time.Month(step), 1, 0, 0, 0, 0, time.UTC)
+		if r.matchesMonth(month.Month()) {
+			dates = r.daysInMonth(month, dtstart)
+		}
+	case "YEARLY":
+		months := r.byMonth
+		if len(months) == 0 {
+			months = []time.Month{dtstart.Month()}
+		}
+		for _, m := range months {
+			dates = append(dates, r.daysInMonth(time.Date(dtstart.Year()+step, m, 1, 0, 0, 0, 0, time.UTC), dtstart)...)
+		}
+	}
+
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	dates = r.applySetPos(dates)
+
+	out := make([]time.Time, 0, len(dates))
+	for _, day := range dates {
+		out = append(out, time.Date(day.Year(), day.Month(), day.Day(), dtstart.Hour(), dtstart.Minute(), dtstart.Second(), 0, dtstart.Location()))
+	}
+	return out
+}
+
+// daysInMonth applies BYMONTHDAY and BYDAY to the month starting at first,
+// defaulting to dtstart's day of the month. Months too short for it are skipped.
+func (r rrule) daysInMonth(first, dtstart time.Time) []time.Time {
+	length := first.AddDate(0, 1, -1).Day()
+	var days []time.Time
+	switch {
+	case len(r.byMonthDay) > 0:
+		for _, n := range r.byMonthDay {
+			if n < 0 {
+				n = length + n + 1
+			}
+			if n < 1 || n > length {
+				continue
+			}
+			day := first.AddDate(0, 0, n-1)
+			if len(r.byDay) == 0 || r.matchesWeekday(day.Weekday()) {
+				days = append(days, day)
+			}
+		}
+	case len(r.byDay) > 0:
+		for _, wd := range r.byDay {
+			var matching []time.Time
+			for d := 0; d < length; d++ {
+				if day := first.AddDate(0, 0, d); day.Weekday() == wd.day {
+					matching = append(matching, day)
+				}
+			}
+			switch {
+			case wd.n == 0:
+				days = append(days, matching...)
+			case wd.n > 0 && wd.n <= len(matching):
+				days = append(days, matching[wd.n-1])
+			case wd.n < 0 && -wd.n <= len(matching):
+				days = append(days, matching[len(matching)+wd.n])
+			}
+		}
+	default:
+		if dtstart.Day() <= length {
+			days = append(days, first.AddDate(0, 0, dtstart.Day()-1))
+		}
+	}
+	return days
+}
+
+// applySetPos keeps the BYSETPOS-th entries of the sorted period set.
+func (r rrule) applySetPos(dates []time.Time) []time.Time {
+	if len(r.bySetPos) == 0 {
+		return dates
+	}
+	var out []time.Time
+	for _, pos := range r.bySetPos {
+		i := pos - 1
+		if pos < 0 {
+			i = len(dates) + pos
+		}
+		if i >= 0 && i < len(dates) {
+			out = append(out, dates[i])
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+	return out
+}
+
+func (r rrule) matchesMonth(month time.Month) bool {
+	if len(r.byMonth) == 0 {
+		return true
+	}
+	for _, m := range r.byMonth {
+		if m == month {
+			return true
+		}
+	}
+	return false
+}
+
+func (r rrule) matchesMonthDay(day time.Time) bool {
+	if len(r.byMonthDay) == 0 {
+		return true
+	}
+	length := day.AddDate(0, 1, -day.Day()).Day()
+	for _, n := range r.byMonthDay {
+		if n == day.Day() || (n < 0 && length+n+1 == day.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r rrule) matchesWeekday(weekday time.Weekday) bool {
+	if len(r.byDay) == 0 {
+		return true
+	}
+	for _, wd := range r.byDay {
+		if wd.day == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// dateOf returns the calendar date of t as midnight UTC, so that day
+// arithmetic is not affected by daylight saving changes.
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
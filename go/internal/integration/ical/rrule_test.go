@@ -0,0 +1,182 @@
+package ical
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRRuleStarts(t *testing.T) {
+	t.Parallel()
+
+	at := func(y int, m time.Month, d, hour int) time.Time {
+		return time.Date(y, m, d, hour, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart time.Time
+		limit   time.Time
+		want    []time.Time
+	}{
+		{
+			name:    "daily interval until",
+			rule:    "FREQ=DAILY;INTERVAL=2;UNTIL=20260307T090000Z",
+			dtstart: at(2026, 3, 1, 9),
+			limit:   at(2026, 12, 1, 0),
+			want:    []time.Time{at(2026, 3, 1, 9), at(2026, 3, 3, 9), at(2026, 3, 5, 9), at(2026, 3, 7, 9)},
+		},
+		{
+			name:    "daily on weekdays only",
+			rule:    "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR;COUNT=4",
+			dtstart: at(2026, 3, 5, 9), // Thursday
+			limit:   at(2026, 12, 1, 0),
+			want:    []time.Time{at(2026, 3, 5, 9), at(2026, 3, 6, 9), at(2026, 3, 9, 9), at(2026, 3, 10, 9)},
+		},
+		{
+			name:    "biweekly on two days",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH",
+			dtstart: at(2026, 3, 3, 9), // Tuesday
+			limit:   at(2026, 3, 20, 0),
+			want:    []time.Time{at(2026, 3, 3, 9), at(2026, 3, 5, 9), at(2026, 3, 17, 9), at(2026, 3, 19, 9)},
+		},
+		{
+			name:    "monthly last day",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=3",
+			dtstart: at(2026, 1, 31, 18),
+			limit:   at(2027, 1, 1, 0),
+			want:    []time.Time{at(2026, 1, 31, 18), at(2026, 2, 28, 18), at(2026, 3, 31, 18)},
+		},
+		{
+			name:    "monthly on the 31st skips short months",
+			rule:    "FREQ=MONTHLY;COUNT=3",
+			dtstart: at(2026, 1, 31, 18),
+			limit:   at(2027, 1, 1, 0),
+			want:    []time.Time{at(2026, 1, 31, 18), at(2026, 3, 31, 18), at(2026, 5, 31, 18)},
+		},
+		{
+			name:    "monthly second tuesday",
+			rule:    "FREQ=MONTHLY;BYDAY=2TU;COUNT=3",
+			dtstart: at(2026, 1, 13, 10),
+			limit:   at(2027, 1, 1, 0),
+			want:    []time.Time{at(2026, 1, 13, 10), at(2026, 2, 10, 10), at(2026, 3, 10, 10)},
+		},
+		{
+			name:    "monthly last workday",
+			rule:    "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1;COUNT=3",
+			dtstart: at(2026, 1, 30, 16),
+			limit:   at(2027, 1, 1, 0),
+			want:    []time.Time{at(2026, 1, 30, 16), at(2026, 2, 27, 16), at(2026, 3, 31, 16)},
+		},
+		{
+			name:    "yearly in two months",
+			rule:    "FREQ=YEARLY;BYMONTH=3,9;BYMONTHDAY=1",
+			dtstart: at(2026, 3, 1, 8),
+			limit:   at(2027, 6, 1, 0),
+			want:    []time.Time{at(2026, 3, 1, 8), at(2026, 9, 1, 8), at(2027, 3, 1, 8)},
+		},
+		{
+			name:    "stops at the limit",
+			rule:    "FREQ=WEEKLY",
+			dtstart: at(2026, 3, 2, 9),
+			limit:   at(2026, 3, 16, 9),
+			want:    []time.Time{at(2026, 3, 2, 9), at(2026, 3, 9, 9)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rule, err := parseRRule(tt.rule, time.UTC)
+			if err != nil {
+				t.Fatalf("parseRRule returned error: %v", err)
+			}
+			got := rule.starts(tt.dtstart, time.Time{}, tt.limit, newExpansionBudget())
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Fatalf("occurrence %d: expected %v, got %v", i, tt.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRRuleKeepsWallClockAcrossDST(t *testing.T) {
+	t.Parallel()
+
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	rule, err := parseRRule("FREQ=DAILY;COUNT=3", paris)
+	if err != nil {
+		t.Fatalf("parseRRule returned error: %v", err)
+	}
+	got := rule.starts(time.Date(2026, 3, 28, 9, 0, 0, 0, paris), time.Time{}, time.Date(2027, 1, 1, 0, 0, 0, 0, paris), newExpansionBudget())
+	if len(got) != 3 || got[2].Hour() != 9 || got[2].Sub(got[1]) != 24*time.Hour || got[1].Sub(got[0]) != 23*time.Hour {
+		t.Fatalf("unexpected occurrences: %v", got)
+	}
+}
+
+func TestRRuleSkipsPeriodsBeforeFrom(t *testing.T) {
+	t.Parallel()
+
+	at := func(y int, m time.Month, d, hour int) time.Time {
+		return time.Date(y, m, d, hour, 0, 0, 0, time.UTC)
+	}
+	for _, value := range []string{"FREQ=DAILY", "FREQ=WEEKLY;INTERVAL=2", "FREQ=MONTHLY;BYMONTHDAY=-1", "FREQ=YEARLY;BYMONTH=3"} {
+		rule, err := parseRRule(value, time.UTC)
+		if err != nil {
+			t.Fatalf("parseRRule(%s) returned error: %v", value, err)
+		}
+		dtstart := at(2000, 3, 31, 9)
+		from, limit := at(2026, 3, 1, 0), at(2026, 4, 1, 0)
+		budget := newExpansionBudget()
+		got := rule.starts(dtstart, from, limit, budget)
+		if spent := maxReadPeriods - budget.periods; spent > 40 {
+			t.Errorf("%s: expected periods before from to be skipped, spent %d", value, spent)
+		}
+		want := []time.Time{dtstart}
+		for _, occurrence := range rule.starts(dtstart, time.Time{}, limit, newExpansionBudget())[1:] {
+			if !occurrence.Before(from) {
+				want = append(want, occurrence)
+			}
+		}
+		if len(want) < 2 || fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s: expected %v, got %v", value, want, got)
+		}
+	}
+}
+
+func TestRRuleStopsWhenTheBudgetIsSpent(t *testing.T) {
+	t.Parallel()
+
+	at := func(y int, m time.Month, d, hour int) time.Time {
+		return time.Date(y, m, d, hour, 0, 0, 0, time.UTC)
+	}
+	// The 30th of February never occurs: every period is expanded in vain.
+	rule, err := parseRRule("FREQ=DAILY;BYMONTH=2;BYMONTHDAY=30;COUNT=5", time.UTC)
+	if err != nil {
+		t.Fatalf("parseRRule returned error: %v", err)
+	}
+	budget := &expansionBudget{periods: 1000}
+	got := rule.starts(at(2000, 1, 1, 9), time.Time{}, at(2100, 1, 1, 0), budget)
+	if len(got) != 1 || budget.periods != 0 {
+		t.Fatalf("expected expansion to stop with the budget, got %v and %d periods left", got, budget.periods)
+	}
+	if got := rule.starts(at(2000, 1, 1, 9), time.Time{}, at(2100, 1, 1, 0), budget); len(got) != 1 {
+		t.Fatalf("expected a spent budget to leave only dtstart, got %v", got)
+	}
+}
+
+func TestParseRRuleRejectsUnsupportedFrequency(t *testing.T) {
+	t.Parallel()
+
+	if _, err := parseRRule("FREQ=HOURLY;COUNT=3", time.UTC); err == nil {
+		t.Fatal("expected an error")
+	}
+}
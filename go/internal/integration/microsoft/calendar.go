@@ -0,0 +1,270 @@
+package microsoft
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"energyjournal/internal/domain/calendar"
+	"energyjournal/internal/pkg/telemetry"
+)
+
+const graphAPIBaseURL = "https://graph.microsoft.com/v1.0"
+
+const (
+	// defaultPageSize is sent as $top; Graph caps calendarView pages at 1000.
+	defaultPageSize = 250
+	// maxPages stops runaway pagination if the API keeps returning next links.
+	maxPages = 100
+	// graphTimeLayout is the dateTime format of Graph dateTimeTimeZone values.
+	graphTimeLayout = "2006-01-02T15:04:05.9999999"
+)
+
+type GraphAPIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *GraphAPIError) Error() string {
+	return fmt.Sprintf("microsoft graph api returned status %d", e.StatusCode)
+}
+
+// Is makes 401 responses match calendar.ErrAuthorizationRevoked.
+func (e *GraphAPIError) Is(target error) bool {
+	return target == calendar.ErrAuthorizationRevoked && e.StatusCode == http.StatusUnauthorized
+}
+
+// GraphCalendarClient reads Outlook calendars through Microsoft Graph.
+type GraphCalendarClient struct {
+	baseURL    string
+	httpClient *http.Client
+	pageSize   int
+}
+
+func NewGraphCalendarClient() *GraphCalendarClient {
+	return &GraphCalendarClient{
+		baseURL:  graphAPIBaseURL,
+		pageSize: defaultPageSize,
+	}
+}
+
+// WithBaseURL points the client at another API root, such as a local fake server.
+func (c *GraphCalendarClient) WithBaseURL(baseURL string) *GraphCalendarClient {
+	c.baseURL = strings.TrimSuffix(baseURL, "/")
+	return c
+}
+
+func (c *GraphCalendarClient) ListCalendars(ctx context.Context, token string) ([]calendar.CalendarItem, error) {
+	query := url.Values{}
+	query.Set("$top", strconv.Itoa(c.pageSize))
+
+	items := []calendar.CalendarItem{}
+	err := c.paginate(ctx, token, c.baseURL+"/me/calendars?"+query.Encode(), func(decoder *json.Decoder) (string, error) {
+		var payload struct {
+			NextLink string `json:"@odata.nextLink"`
+			Value    []struct {
+				ID       string `json:"id"`
+				Name     string `json:"name"`
+				HexColor string `json:"hexColor"`
+			} `json:"value"`
+		}
+		if err := decoder.Decode(&payload); err != nil {
+			return "", err
+		}
+		for _, item := range payload.Value {
+			items = append(items, calendar.CalendarItem{
+				ID:    item.ID,
+				Name:  item.Name,
+				Color: item.HexColor,
+			})
+		}
+		return payload.NextLink, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// ListEvents returns every event between start and end from the calendar
+// view, which expands recurring events into single occurrences.
+func (c *GraphCalendarClient) ListEvents(ctx context.Context, token, calendarID string, start, end time.Time) ([]calendar.Event, error) {
+	query := url.Values{}
+	query.Set("startDateTime", start.UTC().Format(time.RFC3339))
+	query.Set("endDateTime", end.UTC().Format(time.RFC3339))
+	query.Set("$top", strconv.Itoa(c.pageSize))
+	endpoint := fmt.Sprintf("%s/me/calendars/%s/calendarView?%s", c.baseURL, url.PathEscape(calendarID), query.Encode())
+
+	events := []calendar.Event{}
+	err := c.paginate(ctx, token, endpoint, func(decoder *json.Decoder) (string, error) {
+		var payload struct {
+			NextLink string      `json:"@odata.nextLink"`
+			Value    []eventItem `json:"value"`
+		}
+		if err := decoder.Decode(&payload); err != nil {
+			return "", err
+		}
+		for _, item := range payload.Value {
+			event, ok := item.toEvent(calendarID)
+			if !ok {
+				continue
+			}
+			events = append(events, event)
+		}
+		return payload.NextLink, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// paginate issues GET requests starting at endpoint until decodePage returns
+// an empty next link. Graph next links are absolute URLs carrying the query.
+func (c *GraphCalendarClient) paginate(ctx context.Context, token, endpoint string, decodePage func(*json.Decoder) (string, error)) error {
+	client := c.oauthClient(ctx, token)
+	for page := 0; page < maxPages; page++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return err
+		}
+		// Event times are returned in UTC rather than each event's own timezone.
+		req.Header.Set("Prefer", `outlook.timezone="UTC"`)
+
+		next, err := doPage(client, req, decodePage)
+		if err != nil {
+			return err
+		}
+		if next == "" {
+			return nil
+		}
+		endpoint = next
+	}
+	return fmt.Errorf("microsoft graph api returned more than %d pages", maxPages)
+}
+
+func doPage(client *http.Client, req *http.Request, decodePage func(*json.Decoder) (string, error)) (string, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return "", decodeGraphAPIError(resp)
+	}
+	return decodePage(json.NewDecoder(resp.Body))
+}
+
+type eventTime struct {
+	DateTime string `json:"dateTime"`
+	TimeZone string `json:"timeZone"`
+}
+
+type eventItem struct {
+	ID             string    `json:"id"`
+	Subject        string    `json:"subject"`
+	IsAllDay       bool      `json:"isAllDay"`
+	IsCancelled    bool      `json:"isCancelled"`
+	IsOrganizer    bool      `json:"isOrganizer"`
+	ShowAs         string    `json:"showAs"`
+	Categories     []string  `json:"categories"`
+	Start          eventTime `json:"start"`
+	End            eventTime `json:"end"`
+	ResponseStatus struct {
+		Response string `json:"response"`
+	} `json:"responseStatus"`
+}
+
+// responseStatuses maps Graph responses to the user's attendance. "none" is
+// an event without invitees and has no entry.
+var responseStatuses = map[string]calendar.ResponseStatus{
+	"organizer":           calendar.ResponseAccepted,
+	"accepted":            calendar.ResponseAccepted,
+	"tentativelyAccepted": calendar.ResponseTentative,
+	"declined":            calendar.ResponseDeclined,
+	"notResponded":        calendar.ResponseNeedsAction,
+}
+
+// toEvent converts an API item into a calendar.Event. All-day events keep only
+// their dates, as midnight UTC. The user's own response is reported as a self
+// attendee. Items with unparseable times are reported as not ok.
+func (item eventItem) toEvent(calendarID string) (calendar.Event, bool) {
+	startAt, err := parseEventTime(item.Start)
+	if err != nil {
+		return calendar.Event{}, false
+	}
+	endAt, err := parseEventTime(item.End)
+	if err != nil {
+		return calendar.Event{}, false
+	}
+
+	event := calendar.Event{
+		ID:          item.ID,
+		CalendarID:  calendarID,
+		Summary:     item.Subject,
+		Status:      calendar.EventConfirmed,
+		Transparent: item.ShowAs == "free",
+		Categories:  item.Categories,
+		AllDay:      item.IsAllDay,
+		Start:       startAt,
+		End:         endAt,
+	}
+	if item.IsCancelled {
+		event.Status = calendar.EventCancelled
+	}
+	if event.AllDay {
+		event.Start = time.Date(startAt.Year(), startAt.Month(), startAt.Day(), 0, 0, 0, 0, time.UTC)
+		event.End = time.Date(endAt.Year(), endAt.Month(), endAt.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	if status, ok := responseStatuses[item.ResponseStatus.Response]; ok {
+		event.Attendees = []calendar.Attendee{{
+			Self:           true,
+			Organizer:      item.IsOrganizer,
+			ResponseStatus: status,
+		}}
+	}
+
+	return event, true
+}
+
+// parseEventTime reads a dateTimeTimeZone value. Times are requested in UTC;
+// other IANA zones are honored and unknown names fall back to UTC.
+func parseEventTime(value eventTime) (time.Time, error) {
+	loc := time.UTC
+	if value.TimeZone != "" && value.TimeZone != "UTC" {
+		if zone, err := time.LoadLocation(value.TimeZone); err == nil {
+			loc = zone
+		}
+	}
+	return time.ParseInLocation(graphTimeLayout, value.DateTime, loc)
+}
+
+func (c *GraphCalendarClient) oauthClient(ctx context.Context, token string) *http.Client {
+	if c.httpClient != nil {
+		return c.httpClient
+	}
+	return oauth2.NewClient(context.WithValue(ctx, oauth2.HTTPClient, telemetry.HTTPClient()), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}))
+}
+
+func decodeGraphAPIError(resp *http.Response) error {
+	var payload struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&payload)
+	return &GraphAPIError{
+		StatusCode: resp.StatusCode,
+		Body:       payload.Error.Message,
+	}
+}
@@ -0,0 +1,140 @@
+package microsoft
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"energyjournal/internal/domain/calendar"
+)
+
+func TestListCalendarsFollowsNextLink(t *testing.T) {
+	t.Parallel()
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/me/calendars" {
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("$skip") == "" {
+			_, _ = w.Write([]byte(`{"value":[{"id":"a","name":"Calendar","hexColor":"#ff0000"}],"@odata.nextLink":"` + server.URL + `/me/calendars?$skip=1"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"value":[{"id":"b","name":"Birthdays"}]}`))
+	}))
+	defer server.Close()
+
+	client := NewGraphCalendarClient().WithBaseURL(server.URL)
+	client.httpClient = server.Client()
+
+	calendars, err := client.ListCalendars(context.Background(), "token")
+	if err != nil {
+		t.Fatalf("ListCalendars returned error: %v", err)
+	}
+	if len(calendars) != 2 || calendars[0].ID != "a" || calendars[0].Color != "#ff0000" || calendars[1].Name != "Birthdays" {
+		t.Fatalf("unexpected calendars: %+v", calendars)
+	}
+}
+
+func TestListEventsMapsCalendarView(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 7)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/me/calendars/cal 1/calendarView" {
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("startDateTime") != "2026-03-02T00:00:00Z" || r.URL.Query().Get("endDateTime") != "2026-03-09T00:00:00Z" {
+			t.Fatalf("unexpected range: %s", r.URL.RawQuery)
+		}
+		if r.Header.Get("Prefer") != `outlook.timezone="UTC"` {
+			t.Fatalf("unexpected Prefer header: %q", r.Header.Get("Prefer"))
+		}
+		_, _ = w.Write([]byte(`{"value":[
+			{"id":"1","subject":"Review","categories":["Deep work"],"showAs":"busy","isOrganizer":true,
+			 "responseStatus":{"response":"organizer"},
+			 "start":{"dateTime":"2026-03-02T09:00:00.0000000","timeZone":"UTC"},
+			 "end":{"dateTime":"2026-03-02T10:30:00.0000000","timeZone":"UTC"}},
+			{"id":"2","subject":"Offsite","isAllDay":true,"showAs":"free",
+			 "responseStatus":{"response":"none"},
+			 "start":{"dateTime":"2026-03-03T00:00:00.0000000","timeZone":"UTC"},
+			 "end":{"dateTime":"2026-03-05T00:00:00.0000000","timeZone":"UTC"}},
+			{"id":"3","subject":"Skipped","isCancelled":true,
+			 "responseStatus":{"response":"declined"},
+			 "start":{"dateTime":"2026-03-04T09:00:00.0000000","timeZone":"UTC"},
+			 "end":{"dateTime":"2026-03-04T10:00:00.0000000","timeZone":"UTC"}},
+			{"id":"4","subject":"Broken","start":{"dateTime":"soon"},"end":{"dateTime":"later"}}
+		]}`))
+	}))
+	defer server.Close()
+
+	client := NewGraphCalendarClient().WithBaseURL(server.URL)
+	client.httpClient = server.Client()
+
+	events, err := client.ListEvents(context.Background(), "token", "cal 1", start, end)
+	if err != nil {
+		t.Fatalf("ListEvents returned error: %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %+v", events)
+	}
+
+	review := events[0]
+	if review.CalendarID != "cal 1" || review.Summary != "Review" || review.End.Sub(review.Start) != 90*time.Minute ||
+		len(review.Categories) != 1 || review.Categories[0] != "Deep work" || review.Transparent || !review.CountsAsSpending() {
+		t.Fatalf("unexpected timed event: %+v", review)
+	}
+	offsite := events[1]
+	if !offsite.AllDay || !offsite.Transparent || len(offsite.Attendees) != 0 ||
+		!offsite.Start.Equal(time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC)) || !offsite.End.Equal(time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected all-day event: %+v", offsite)
+	}
+	skipped := events[2]
+	if skipped.Status != calendar.EventCancelled || !skipped.DeclinedBySelf() || skipped.CountsAsSpending() {
+		t.Fatalf("unexpected cancelled event: %+v", skipped)
+	}
+}
+
+func TestGraphAPIErrorMatchesRevokedAuthorization(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":{"code":"InvalidAuthenticationToken","message":"Access token has expired."}}`))
+	}))
+	defer server.Close()
+
+	client := NewGraphCalendarClient().WithBaseURL(server.URL)
+	client.httpClient = server.Client()
+
+	_, err := client.ListCalendars(context.Background(), "token")
+	var apiErr *GraphAPIError
+	if !errors.As(err, &apiErr) || apiErr.Body != "Access token has expired." {
+		t.Fatalf("expected GraphAPIError, got %v", err)
+	}
+	if !errors.Is(err, calendar.ErrAuthorizationRevoked) {
+		t.Fatalf("expected 401 to match ErrAuthorizationRevoked")
+	}
+	if errors.Is(&GraphAPIError{StatusCode: http.StatusForbidden}, calendar.ErrAuthorizationRevoked) {
+		t.Fatalf("expected 403 not to match ErrAuthorizationRevoked")
+	}
+}
+
+func TestProviderCategoryUsesFirstOutlookCategory(t *testing.T) {
+	t.Parallel()
+
+	provider := NewProvider(NewGraphCalendarClient(), NewOAuthConfig("id", "secret", "https://app.test/callback", "common"))
+	if got := provider.Category(calendar.Event{Categories: []string{"Meetings", "Travel"}}); got != "Meetings" {
+		t.Fatalf("expected first category, got %q", got)
+	}
+	if got := provider.Category(calendar.Event{}); got != "Default" {
+		t.Fatalf("expected Default, got %q", got)
+	}
+	if _, ok := provider.ColorName("1"); ok {
+		t.Fatalf("expected no color names")
+	}
+}
@@ -0,0 +1,75 @@
+package microsoft
+
+import (
+	"context"
+
+	"golang.org/x/oauth2"
+	oauth2microsoft "golang.org/x/oauth2/microsoft"
+
+	"energyjournal/internal/domain/calendar"
+	"energyjournal/internal/pkg/telemetry"
+)
+
+// Scopes are the delegated permissions requested; offline_access makes the
+// identity platform issue a refresh token.
+var Scopes = []string{"offline_access", "Calendars.Read"}
+
+// NewOAuthConfig returns the OAuth configuration of an app registered in the
+// given Entra ID tenant ("common" accepts work and personal accounts).
+func NewOAuthConfig(clientID, clientSecret, redirectURL, tenant string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Endpoint:     oauth2microsoft.AzureADEndpoint(tenant),
+		RedirectURL:  redirectURL,
+		Scopes:       Scopes,
+	}
+}
+
+// Provider is Microsoft 365 / Outlook as a calendar provider. Graph offers no
+// sync tokens or watch channels here, so its events are always read live, and
+// grants are revoked by the user in their account settings.
+type Provider struct {
+	*GraphCalendarClient
+	config *oauth2.Config
+}
+
+func NewProvider(client *GraphCalendarClient, config *oauth2.Config) *Provider {
+	return &Provider{GraphCalendarClient: client, config: config}
+}
+
+func (p *Provider) Name() calendar.Provider {
+	return calendar.ProviderMicrosoft
+}
+
+// AuthCodeURL lets the user pick among their signed-in accounts.
+func (p *Provider) AuthCodeURL(state string) string {
+	return p.config.AuthCodeURL(state, oauth2.SetAuthURLParam("prompt", "select_account"))
+}
+
+func (p *Provider) Exchange(ctx context.Context, code string) (*oauth2.Token, error) {
+	return p.config.Exchange(oauthContext(ctx), code)
+}
+
+func (p *Provider) TokenSource(ctx context.Context, t *oauth2.Token) oauth2.TokenSource {
+	return p.config.TokenSource(oauthContext(ctx), t)
+}
+
+// oauthContext sends the token requests made with ctx through the traced client.
+func oauthContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, telemetry.HTTPClient())
+}
+
+// Category names events by their first Outlook category.
+func (p *Provider) Category(event calendar.Event) string {
+	if len(event.Categories) == 0 || event.Categories[0] == "" {
+		return "Default"
+	}
+	return event.Categories[0]
+}
+
+// ColorName reports no colors: Outlook colors belong to categories, which
+// keyword rules can match by name instead.
+func (p *Provider) ColorName(string) (string, bool) {
+	return "", false
+}
@@ -0,0 +1,46 @@
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+
+	"google.golang.org/api/cloudkms/v1"
+	"google.golang.org/api/option"
+)
+
+// CloudKMSClient wraps data keys with Google Cloud KMS. Key names are
+// CryptoKey resource names such as
+// projects/p/locations/l/keyRings/r/cryptoKeys/k; Cloud KMS records the key
+// version in the ciphertext, so rotating versions there needs no change here.
+type CloudKMSClient struct {
+	keys *cloudkms.ProjectsLocationsKeyRingsCryptoKeysService
+}
+
+// NewCloudKMSClient connects to the Cloud KMS API.
+func NewCloudKMSClient(ctx context.Context, opts ...option.ClientOption) (*CloudKMSClient, error) {
+	service, err := cloudkms.NewService(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &CloudKMSClient{keys: service.Projects.Locations.KeyRings.CryptoKeys}, nil
+}
+
+func (c *CloudKMSClient) Encrypt(ctx context.Context, keyName string, plaintext []byte) ([]byte, error) {
+	resp, err := c.keys.Encrypt(keyName, &cloudkms.EncryptRequest{
+		Plaintext: base64.StdEncoding.EncodeToString(plaintext),
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(resp.Ciphertext)
+}
+
+func (c *CloudKMSClient) Decrypt(ctx context.Context, keyName string, ciphertext []byte) ([]byte, error) {
+	resp, err := c.keys.Decrypt(keyName, &cloudkms.DecryptRequest{
+		Ciphertext: base64.StdEncoding.EncodeToString(ciphertext),
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(resp.Plaintext)
+}
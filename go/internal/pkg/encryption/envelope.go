@@ -0,0 +1,138 @@
+package encryption
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// envelopePrefix marks encrypted values; anything else is legacy plaintext.
+const envelopePrefix = "enc:v1:"
+
+// errNoKeys is returned when an Envelope without keys meets an encrypted value.
+var errNoKeys = errors.New("no token encryption key is configured")
+
+// Envelope encrypts values with a fresh AES-256-GCM data key each, stored
+// wrapped by the KeyProvider next to the ciphertext:
+//
+//	enc:v1:<keyID>:<base64 wrapped key>:<base64 nonce+ciphertext>
+//
+// The ciphertext is authenticated with associated data naming the record it
+// belongs to, so that a value copied into another record does not decrypt.
+type Envelope struct {
+	keys KeyProvider
+}
+
+// NewEnvelope creates an Envelope backed by keys. A nil keys leaves values in
+// plaintext, for deployments that have not configured a key yet; once one is
+// configured, the migration job encrypts what was stored before.
+func NewEnvelope(keys KeyProvider) *Envelope {
+	return &Envelope{keys: keys}
+}
+
+// Encrypt returns the envelope encoding of plaintext, bound to
+// associatedData. Empty values stay empty, and without keys plaintext is
+// returned unchanged.
+func (e *Envelope) Encrypt(ctx context.Context, plaintext string, associatedData []byte) (string, error) {
+	if plaintext == "" || e.keys == nil {
+		return plaintext, nil
+	}
+
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return "", err
+	}
+	aead, err := newGCM(dek)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(aead, []byte(plaintext), associatedData)
+	if err != nil {
+		return "", err
+	}
+	keyID, wrapped, err := e.keys.WrapKey(ctx, dek)
+	if err != nil {
+		return "", fmt.Errorf("wrap data key: %w", err)
+	}
+
+	return envelopePrefix + keyID + ":" +
+		base64.RawStdEncoding.EncodeToString(wrapped) + ":" +
+		base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt reverses Encrypt, given the same associatedData. Values without
+// the envelope prefix are returned unchanged so documents written before
+// encryption remain readable.
+func (e *Envelope) Decrypt(ctx context.Context, value string, associatedData []byte) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if e.keys == nil {
+		return "", errNoKeys
+	}
+
+	keyID, wrappedB64, sealedB64, err := splitEnvelope(value)
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := base64.RawStdEncoding.DecodeString(wrappedB64)
+	if err != nil {
+		return "", errors.New("malformed envelope: wrapped key")
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(sealedB64)
+	if err != nil {
+		return "", errors.New("malformed envelope: ciphertext")
+	}
+
+	dek, err := e.keys.UnwrapKey(ctx, keyID, wrapped)
+	if err != nil {
+		return "", fmt.Errorf("unwrap data key: %w", err)
+	}
+	aead, err := newGCM(dek)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(aead, sealed, associatedData)
+	if err != nil {
+		return "", fmt.Errorf("decrypt: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsReencryption reports whether value is plaintext or was wrapped by a
+// key other than the current primary key. Without keys, nothing can be
+// re-encrypted.
+func (e *Envelope) NeedsReencryption(value string) bool {
+	if value == "" || e.keys == nil {
+		return false
+	}
+	if !IsEncrypted(value) {
+		return true
+	}
+	keyID, _, _, err := splitEnvelope(value)
+	return err != nil || keyID != e.keys.PrimaryKeyID()
+}
+
+// IsEncrypted reports whether value uses the envelope encoding.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, envelopePrefix)
+}
+
+// splitEnvelope parses from the right: the two base64 parts never contain a
+// colon, while key IDs may.
+func splitEnvelope(value string) (keyID, wrapped, sealed string, err error) {
+	rest := value[len(envelopePrefix):]
+	i := strings.LastIndex(rest, ":")
+	if i < 0 {
+		return "", "", "", errors.New("malformed envelope")
+	}
+	rest, sealed = rest[:i], rest[i+1:]
+	j := strings.LastIndex(rest, ":")
+	if j <= 0 {
+		return "", "", "", errors.New("malformed envelope")
+	}
+	return rest[:j], rest[j+1:], sealed, nil
+}
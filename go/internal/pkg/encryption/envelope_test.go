@@ -0,0 +1,217 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/option"
+)
+
+// record is the associated data of the values encrypted in tests.
+var record = []byte("uid-1\x00google")
+
+func testKey(t *testing.T) string {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	keys, err := NewLocalKeyProvider("k1:" + testKey(t))
+	if err != nil {
+		t.Fatalf("NewLocalKeyProvider: %v", err)
+	}
+	envelope := NewEnvelope(keys)
+
+	encrypted, err := envelope.Encrypt(context.Background(), "ya29.secret-token", record)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if !IsEncrypted(encrypted) || strings.Contains(encrypted, "secret-token") || !strings.HasPrefix(encrypted, "enc:v1:k1:") {
+		t.Fatalf("unexpected envelope: %s", encrypted)
+	}
+	again, _ := envelope.Encrypt(context.Background(), "ya29.secret-token", record)
+	if again == encrypted {
+		t.Fatal("expected a fresh data key and nonce per value")
+	}
+
+	decrypted, err := envelope.Decrypt(context.Background(), encrypted, record)
+	if err != nil || decrypted != "ya29.secret-token" {
+		t.Fatalf("Decrypt: %q, %v", decrypted, err)
+	}
+
+	if plain, err := envelope.Decrypt(context.Background(), "legacy-plaintext", record); err != nil || plain != "legacy-plaintext" {
+		t.Fatalf("plaintext should pass through, got %q, %v", plain, err)
+	}
+	if empty, _ := envelope.Encrypt(context.Background(), "", record); empty != "" {
+		t.Fatalf("empty values should stay empty, got %q", empty)
+	}
+}
+
+func TestEnvelopeKeyRotation(t *testing.T) {
+	t.Parallel()
+
+	oldKey, newKey := testKey(t), testKey(t)
+	before, _ := NewLocalKeyProvider("k1:" + oldKey)
+	encrypted, err := NewEnvelope(before).Encrypt(context.Background(), "refresh", record)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	rotated, err := NewLocalKeyProvider("k2:" + newKey + ",k1:" + oldKey)
+	if err != nil {
+		t.Fatalf("NewLocalKeyProvider: %v", err)
+	}
+	envelope := NewEnvelope(rotated)
+	if decrypted, err := envelope.Decrypt(context.Background(), encrypted, record); err != nil || decrypted != "refresh" {
+		t.Fatalf("old key should still decrypt, got %q, %v", decrypted, err)
+	}
+	if !envelope.NeedsReencryption(encrypted) || !envelope.NeedsReencryption("plaintext") {
+		t.Fatal("values not under the primary key need re-encryption")
+	}
+	fresh, _ := envelope.Encrypt(context.Background(), "refresh", record)
+	if envelope.NeedsReencryption(fresh) || envelope.NeedsReencryption("") {
+		t.Fatal("values under the primary key are current")
+	}
+
+	dropped, _ := NewLocalKeyProvider("k2:" + newKey)
+	if _, err := NewEnvelope(dropped).Decrypt(context.Background(), encrypted, record); err == nil {
+		t.Fatal("expected an error once the old key is removed")
+	}
+}
+
+func TestEnvelopeRejectsTampering(t *testing.T) {
+	t.Parallel()
+
+	keys, _ := NewLocalKeyProvider("k1:" + testKey(t))
+	envelope := NewEnvelope(keys)
+	encrypted, _ := envelope.Encrypt(context.Background(), "token", record)
+
+	tampered := encrypted[:len(encrypted)-2] + "AA"
+	if _, err := envelope.Decrypt(context.Background(), tampered, record); err == nil {
+		t.Fatal("expected authentication failure on tampered ciphertext")
+	}
+	if _, err := envelope.Decrypt(context.Background(), "enc:v1:garbage", record); err == nil {
+		t.Fatal("expected malformed envelope error")
+	}
+}
+
+func TestEnvelopeBindsValuesToTheirRecord(t *testing.T) {
+	t.Parallel()
+
+	keys, _ := NewLocalKeyProvider("k1:" + testKey(t))
+	envelope := NewEnvelope(keys)
+	encrypted, _ := envelope.Encrypt(context.Background(), "token", record)
+	if _, err := envelope.Decrypt(context.Background(), encrypted, []byte("uid-2\x00google")); err == nil {
+		t.Fatal("expected a value copied to another record not to decrypt")
+	}
+}
+
+func TestEnvelopeWithoutKeysKeepsPlaintext(t *testing.T) {
+	t.Parallel()
+
+	envelope := NewEnvelope(nil)
+	if stored, err := envelope.Encrypt(context.Background(), "token", record); err != nil || stored != "token" {
+		t.Fatalf("expected plaintext stored, got %q, %v", stored, err)
+	}
+	if envelope.NeedsReencryption("token") {
+		t.Fatal("expected nothing to re-encrypt without keys")
+	}
+
+	keys, _ := NewLocalKeyProvider("k1:" + testKey(t))
+	encrypted, _ := NewEnvelope(keys).Encrypt(context.Background(), "token", record)
+	if _, err := envelope.Decrypt(context.Background(), encrypted, record); err == nil {
+		t.Fatal("expected an error decrypting without keys")
+	}
+}
+
+func TestNewLocalKeyProviderValidates(t *testing.T) {
+	t.Parallel()
+
+	for _, spec := range []string{"", "k1", "k1:not-base64!", "k1:" + base64.StdEncoding.EncodeToString([]byte("short")), "k1:" + testKey(t) + ",k1:" + testKey(t)} {
+		if _, err := NewLocalKeyProvider(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
+
+type fakeKMS struct{ keys map[string]*LocalKeyProvider }
+
+func (k *fakeKMS) Encrypt(ctx context.Context, keyName string, plaintext []byte) ([]byte, error) {
+	_, wrapped, err := k.keys[keyName].WrapKey(ctx, plaintext)
+	return wrapped, err
+}
+
+func (k *fakeKMS) Decrypt(ctx context.Context, keyName string, ciphertext []byte) ([]byte, error) {
+	local := k.keys[keyName]
+	return local.UnwrapKey(ctx, local.PrimaryKeyID(), ciphertext)
+}
+
+func TestKMSKeyProviderKeepsKeyNameWithColons(t *testing.T) {
+	t.Parallel()
+
+	keyName := "arn:aws:kms:eu-west-1:123456789012:key/abcd"
+	local, _ := NewLocalKeyProvider("kms:" + testKey(t))
+	envelope := NewEnvelope(NewKMSKeyProvider(&fakeKMS{keys: map[string]*LocalKeyProvider{keyName: local}}, keyName))
+
+	encrypted, err := envelope.Encrypt(context.Background(), "token", record)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	decrypted, err := envelope.Decrypt(context.Background(), encrypted, record)
+	if err != nil || decrypted != "token" {
+		t.Fatalf("Decrypt: %q, %v", decrypted, err)
+	}
+	if envelope.NeedsReencryption(encrypted) {
+		t.Fatal("value wrapped by the current KMS key should be current")
+	}
+}
+
+func TestCloudKMSClientWrapsThroughTheAPI(t *testing.T) {
+	t.Parallel()
+
+	const keyName = "projects/p/locations/europe-west1/keyRings/r/cryptoKeys/tokens"
+	// The fake KMS "encrypts" by prefixing the plaintext with the key name.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Plaintext, Ciphertext string }
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		switch r.URL.Path {
+		case "/v1/" + keyName + ":encrypt":
+			plaintext, _ := base64.StdEncoding.DecodeString(body.Plaintext)
+			wrapped := append([]byte(keyName), plaintext...)
+			_ = json.NewEncoder(w).Encode(map[string]string{"ciphertext": base64.StdEncoding.EncodeToString(wrapped)})
+		case "/v1/" + keyName + ":decrypt":
+			wrapped, _ := base64.StdEncoding.DecodeString(body.Ciphertext)
+			plaintext := bytes.TrimPrefix(wrapped, []byte(keyName))
+			_ = json.NewEncoder(w).Encode(map[string]string{"plaintext": base64.StdEncoding.EncodeToString(plaintext)})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewCloudKMSClient(context.Background(), option.WithEndpoint(server.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("NewCloudKMSClient: %v", err)
+	}
+	envelope := NewEnvelope(NewKMSKeyProvider(client, keyName))
+	encrypted, err := envelope.Encrypt(context.Background(), "token", record)
+	if err != nil || !strings.HasPrefix(encrypted, envelopePrefix+keyName+":") {
+		t.Fatalf("Encrypt: %q, %v", encrypted, err)
+	}
+	if decrypted, err := envelope.Decrypt(context.Background(), encrypted, record); err != nil || decrypted != "token" {
+		t.Fatalf("Decrypt: %q, %v", decrypted, err)
+	}
+}
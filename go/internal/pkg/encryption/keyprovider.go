@@ -0,0 +1,148 @@
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeyProvider wraps and unwraps data encryption keys with a key encryption
+// key. Wrapped keys record the ID of the key that wrapped them so older keys
+// keep decrypting after rotation.
+type KeyProvider interface {
+	// PrimaryKeyID is the key used to wrap new data keys.
+	PrimaryKeyID() string
+	WrapKey(ctx context.Context, dek []byte) (keyID string, wrapped []byte, err error)
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// LocalKeyProvider wraps data keys with AES-256-GCM keys held in memory.
+type LocalKeyProvider struct {
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// NewLocalKeyProvider builds a provider from a comma-separated list of
+// id:base64key entries, e.g. "k2:...,k1:...". The first entry is the primary
+// key; the others are only used to decrypt. Keys must be 32 bytes.
+func NewLocalKeyProvider(spec string) (*LocalKeyProvider, error) {
+	p := &LocalKeyProvider{keys: map[string]cipher.AEAD{}}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid key entry %q: expected id:base64key", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: must be base64 encoded", id)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("key %s: must be 32 bytes, got %d", id, len(key))
+		}
+		if _, dup := p.keys[id]; dup {
+			return nil, fmt.Errorf("key %s: duplicate id", id)
+		}
+		aead, err := newGCM(key)
+		if err != nil {
+			return nil, err
+		}
+		p.keys[id] = aead
+		if p.primary == "" {
+			p.primary = id
+		}
+	}
+	if p.primary == "" {
+		return nil, errors.New("at least one key is required")
+	}
+	return p, nil
+}
+
+func (p *LocalKeyProvider) PrimaryKeyID() string {
+	return p.primary
+}
+
+func (p *LocalKeyProvider) WrapKey(_ context.Context, dek []byte) (string, []byte, error) {
+	wrapped, err := seal(p.keys[p.primary], dek, []byte(p.primary))
+	if err != nil {
+		return "", nil, err
+	}
+	return p.primary, wrapped, nil
+}
+
+func (p *LocalKeyProvider) UnwrapKey(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	aead, ok := p.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", keyID)
+	}
+	return open(aead, wrapped, []byte(keyID))
+}
+
+// KMSClient is the subset of a cloud KMS API needed to wrap data keys, such
+// as CloudKMSClient.
+type KMSClient interface {
+	Encrypt(ctx context.Context, keyName string, plaintext []byte) ([]byte, error)
+	Decrypt(ctx context.Context, keyName string, ciphertext []byte) ([]byte, error)
+}
+
+// KMSKeyProvider delegates key wrapping to a KMS. The key ID is the KMS key
+// name, so rotating means pointing keyName at a new key; data keys wrapped by
+// the previous one are still decrypted with the name stored next to them.
+type KMSKeyProvider struct {
+	client  KMSClient
+	keyName string
+}
+
+// NewKMSKeyProvider creates a KeyProvider that wraps new data keys with keyName.
+func NewKMSKeyProvider(client KMSClient, keyName string) *KMSKeyProvider {
+	return &KMSKeyProvider{client: client, keyName: keyName}
+}
+
+func (p *KMSKeyProvider) PrimaryKeyID() string {
+	return p.keyName
+}
+
+func (p *KMSKeyProvider) WrapKey(ctx context.Context, dek []byte) (string, []byte, error) {
+	wrapped, err := p.client.Encrypt(ctx, p.keyName, dek)
+	if err != nil {
+		return "", nil, err
+	}
+	return p.keyName, wrapped, nil
+}
+
+func (p *KMSKeyProvider) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	return p.client.Decrypt(ctx, keyID, wrapped)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext and prefixes the random nonce.
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
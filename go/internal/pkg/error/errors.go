@@ -1,6 +1,9 @@
 package error
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // InputValidationError represents a validation error for user input.
 type InputValidationError struct {
@@ -38,9 +41,11 @@ func NewNotFoundError(resource, id string) *NotFoundError {
 	return &NotFoundError{Resource: resource, ID: id}
 }
 
-// RateLimitError represents a rate limiting error.
+// RateLimitError represents a rate limiting error. RetryAfter, when known, is
+// how long the caller should wait before trying again.
 type RateLimitError struct {
-	Message string
+	Message    string
+	RetryAfter time.Duration
 }
 
 func (e *RateLimitError) Error() string {
@@ -89,6 +94,24 @@ func NewCalendarNotConnectedError(message string) *CalendarNotConnectedError {
 	return &CalendarNotConnectedError{Message: message}
 }
 
+// CalendarReauthRequiredError indicates that the stored calendar grant was
+// revoked or expired and the user has to reconnect.
+type CalendarReauthRequiredError struct {
+	Message string
+}
+
+func (e *CalendarReauthRequiredError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return "calendar authorization expired, reconnect required"
+}
+
+// NewCalendarReauthRequiredError creates a CalendarReauthRequiredError.
+func NewCalendarReauthRequiredError(message string) *CalendarReauthRequiredError {
+	return &CalendarReauthRequiredError{Message: message}
+}
+
 // UnknownError represents an unexpected internal error.
 type UnknownError struct {
 	Err error
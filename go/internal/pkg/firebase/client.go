@@ -3,57 +3,28 @@ package firebase
 import (
 	"bytes"
 	"context"
-	"encoding/base64"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"net/http"
-	"net/url"
-	"os"
 
 	"energyjournal/internal/domain/user"
+	"energyjournal/internal/pkg/telemetry"
 
 	firebase "firebase.google.com/go/v4"
 	"firebase.google.com/go/v4/auth"
 	"google.golang.org/api/option"
 )
 
+// PublicKeysURL serves the certificates ID tokens are verified with; the Admin
+// SDK fetches them when verifying tokens.
+const PublicKeysURL = "https://www.googleapis.com/robot/v1/metadata/x509/securetoken@system.gserviceaccount.com"
+
 type Client struct {
 	authClient *auth.Client
 }
 
-func loadCredentialsJSON() ([]byte, error) {
-	encoded := os.Getenv("FIREBASE_CREDENTIALS")
-	if encoded != "" {
-		credentialsJSON, err := base64.StdEncoding.DecodeString(encoded)
-		if err != nil {
-			return nil, errors.New("failed to decode FIREBASE_CREDENTIALS: must be base64 encoded")
-		}
-		return credentialsJSON, nil
-	}
-
-	candidates := []string{}
-	if path := os.Getenv("FIREBASE_CREDENTIALS_FILE"); path != "" {
-		candidates = append(candidates, path)
-	}
-	candidates = append(candidates, "firebase-credentials.json", "../../firebase-credentials.json")
-
-	for _, path := range candidates {
-		credentialsJSON, err := os.ReadFile(path)
-		if err == nil {
-			return credentialsJSON, nil
-		}
-	}
-
-	return nil, errors.New("firebase credentials not found: set FIREBASE_CREDENTIALS env or provide firebase-credentials.json (or FIREBASE_CREDENTIALS_FILE)")
-}
-
-func NewClient(ctx context.Context) (*Client, error) {
-	credentialsJSON, err := loadCredentialsJSON()
-	if err != nil {
-		return nil, err
-	}
-
+// NewClient connects to Firebase Authentication with a service-account key.
+func NewClient(ctx context.Context, credentialsJSON []byte) (*Client, error) {
 	opt := option.WithAuthCredentialsJSON(option.ServiceAccount, credentialsJSON)
 	app, err := firebase.NewApp(ctx, nil, opt)
 	if err != nil {
@@ -79,14 +50,17 @@ func (c *Client) CreateUser(ctx context.Context, email, password string) (*auth.
 	return c.authClient.CreateUser(ctx, params)
 }
 
-// AuthProvider adapts Client to the user.AuthProvider interface.
+// AuthProvider adapts Client to the user.AuthProvider interface. Sign-ins and
+// token refreshes go through the Identity Toolkit REST API; the API key is sent
+// in a header so it stays out of traced and logged URLs.
 type AuthProvider struct {
-	client *Client
-	apiKey string
+	client     *Client
+	apiKey     string
+	httpClient *http.Client
 }
 
 func NewAuthProvider(client *Client, apiKey string) *AuthProvider {
-	return &AuthProvider{client: client, apiKey: apiKey}
+	return &AuthProvider{client: client, apiKey: apiKey, httpClient: telemetry.HTTPClient()}
 }
 
 func (p *AuthProvider) CreateUser(ctx context.Context, email, password string) (string, error) {
@@ -98,7 +72,7 @@ func (p *AuthProvider) CreateUser(ctx context.Context, email, password string) (
 }
 
 func (p *AuthProvider) Login(ctx context.Context, email, password string) (*user.AuthTokens, string, error) {
-	endpoint := fmt.Sprintf("https://identitytoolkit.googleapis.com/v1/accounts:signInWithPassword?key=%s", url.QueryEscape(p.apiKey))
+	endpoint := "https://identitytoolkit.googleapis.com/v1/accounts:signInWithPassword"
 
 	body, err := json.Marshal(map[string]any{
 		"email":             email,
@@ -114,8 +88,9 @@ func (p *AuthProvider) Login(ctx context.Context, email, password string) (*user
 		return nil, "", fmt.Errorf("create login request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Goog-Api-Key", p.apiKey)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := p.httpClient.Do(req)
 	if err != nil {
 		return nil, "", fmt.Errorf("login request failed: %w", err)
 	}
@@ -150,7 +125,7 @@ func (p *AuthProvider) Login(ctx context.Context, email, password string) (*user
 }
 
 func (p *AuthProvider) RefreshToken(ctx context.Context, refreshToken string) (*user.AuthTokens, string, error) {
-	endpoint := fmt.Sprintf("https://securetoken.googleapis.com/v1/token?key=%s", url.QueryEscape(p.apiKey))
+	endpoint := "https://securetoken.googleapis.com/v1/token"
 
 	body, err := json.Marshal(map[string]string{
 		"grant_type":    "refresh_token",
@@ -165,8 +140,9 @@ func (p *AuthProvider) RefreshToken(ctx context.Context, refreshToken string) (*
 		return nil, "", fmt.Errorf("create refresh request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Goog-Api-Key", p.apiKey)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := p.httpClient.Do(req)
 	if err != nil {
 		return nil, "", fmt.Errorf("refresh request failed: %w", err)
 	}
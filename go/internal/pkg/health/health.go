@@ -0,0 +1,148 @@
+// Package health checks that the services the server depends on are usable.
+package health
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/textproto"
+	"sync"
+	"time"
+)
+
+// Checker checks one dependency; Check returns nil when it is usable.
+type Checker struct {
+	Name  string
+	Check func(ctx context.Context) error
+	// Optional checks are reported but leave the report ok: for dependencies
+	// most requests do without, such as an identity provider only used when
+	// connecting or refreshing. An outage there should not take every
+	// instance out of the load balancer.
+	Optional bool
+}
+
+// Result statuses.
+const (
+	StatusOK     = "ok"
+	StatusFailed = "failed"
+)
+
+// Result is the outcome of one checker. Error is kept out of the JSON, which
+// is public, since it may name hosts, addresses or credentials; log it instead.
+type Result struct {
+	Status     string `json:"status"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"-"`
+}
+
+// Report is the outcome of every checker, keyed by name. Status is ok only
+// when every check that is not optional passed.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// OK reports whether every check that is not optional passed.
+func (r Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+// Run runs the checkers concurrently, each given at most timeout.
+func Run(ctx context.Context, timeout time.Duration, checkers []Checker) Report {
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(checkers))}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, checker := range checkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			started := time.Now()
+			err := checker.Check(checkCtx)
+			result := Result{Status: StatusOK, DurationMS: time.Since(started).Milliseconds()}
+			if err != nil {
+				result.Status = StatusFailed
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[checker.Name] = result
+			if err != nil && !checker.Optional {
+				report.Status = StatusFailed
+			}
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+// HTTPOK checks that a GET of url answers with a 2xx status.
+func HTTPOK(name, url string) Checker {
+	return Checker{Name: name, Check: func(ctx context.Context) error {
+		status, err := get(ctx, url)
+		if err != nil {
+			return err
+		}
+		if status < 200 || status > 299 {
+			return fmt.Errorf("GET %s returned status %d", url, status)
+		}
+		return nil
+	}}
+}
+
+// Reachable checks that the server at url answers a GET, whatever the
+// response, unless it reports a server error. It suits endpoints such as
+// OAuth token URLs that only accept POSTs with credentials.
+func Reachable(name, url string) Checker {
+	return Checker{Name: name, Check: func(ctx context.Context) error {
+		status, err := get(ctx, url)
+		if err != nil {
+			return err
+		}
+		if status >= 500 {
+			return fmt.Errorf("GET %s returned status %d", url, status)
+		}
+		return nil
+	}}
+}
+
+func get(ctx context.Context, url string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// SMTP checks that the mail server at addr (host:port) greets with 220.
+func SMTP(name, addr string) Checker {
+	return Checker{Name: name, Check: func(ctx context.Context) error {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = conn.SetDeadline(deadline)
+		}
+
+		text := textproto.NewConn(conn)
+		if _, _, err := text.ReadResponse(220); err != nil {
+			return fmt.Errorf("SMTP greeting: %w", err)
+		}
+		_ = text.PrintfLine("QUIT")
+		return nil
+	}}
+}
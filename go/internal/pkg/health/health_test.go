@@ -0,0 +1,108 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRunReportsEveryCheckConcurrently(t *testing.T) {
+	t.Parallel()
+
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	started := time.Now()
+	report := Run(context.Background(), 50*time.Millisecond, []Checker{
+		{Name: "storage", Check: func(context.Context) error { return nil }},
+		{Name: "slow-a", Check: slow},
+		{Name: "slow-b", Check: slow},
+		{Name: "broken", Check: func(context.Context) error { return errors.New("boom") }},
+	})
+
+	if elapsed := time.Since(started); elapsed > 500*time.Millisecond {
+		t.Fatalf("expected checks to run concurrently within the timeout, took %v", elapsed)
+	}
+	if report.OK() || len(report.Checks) != 4 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if report.Checks["storage"].Status != StatusOK || report.Checks["broken"].Error != "boom" ||
+		report.Checks["slow-a"].Status != StatusFailed {
+		t.Fatalf("unexpected results: %+v", report.Checks)
+	}
+	optional := Run(context.Background(), time.Second, []Checker{
+		{Name: "google_oauth", Optional: true, Check: func(context.Context) error { return errors.New("boom") }},
+	})
+	if !optional.OK() || optional.Checks["google_oauth"].Status != StatusFailed {
+		t.Fatalf("expected a failed optional check reported without failing the report, got %+v", optional)
+	}
+	if empty := Run(context.Background(), time.Second, nil); !empty.OK() {
+		t.Fatalf("expected no checkers to be ready, got %+v", empty)
+	}
+}
+
+func TestHTTPCheckers(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/keys":
+			_, _ = w.Write([]byte(`{}`))
+		case "/token":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	if err := HTTPOK("keys", server.URL+"/keys").Check(ctx); err != nil {
+		t.Fatalf("expected keys ok, got %v", err)
+	}
+	if err := HTTPOK("keys", server.URL+"/token").Check(ctx); err == nil {
+		t.Fatal("expected a non-2xx status to fail HTTPOK")
+	}
+	if err := Reachable("token", server.URL+"/token").Check(ctx); err != nil {
+		t.Fatalf("expected a 405 to count as reachable, got %v", err)
+	}
+	if err := Reachable("token", server.URL+"/down").Check(ctx); err == nil {
+		t.Fatal("expected a server error to fail Reachable")
+	}
+}
+
+func TestSMTPChecksGreeting(t *testing.T) {
+	t.Parallel()
+
+	serve := func(greeting string) string {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { listener.Close() })
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			_, _ = conn.Write([]byte(greeting + "\r\n"))
+			_, _ = conn.Read(make([]byte, 64))
+		}()
+		return listener.Addr().String()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := SMTP("smtp", serve("220 mail.example ESMTP")).Check(ctx); err != nil {
+		t.Fatalf("expected the greeting accepted, got %v", err)
+	}
+	if err := SMTP("smtp", serve("554 no service")).Check(ctx); err == nil {
+		t.Fatal("expected a refusal to fail")
+	}
+}
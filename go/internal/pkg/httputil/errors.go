@@ -3,10 +3,15 @@ package httputil
 import (
 	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	errpkg "energyjournal/internal/pkg/error"
 )
 
+// ReauthRequiredCode tells the frontend to send the user through OAuth again.
+const ReauthRequiredCode = "reauth_required"
+
 func MapErrors(err error) (statusCode int, message string) {
 	var authErr *errpkg.AuthenticationError
 	if errors.As(err, &authErr) {
@@ -23,10 +28,42 @@ func MapErrors(err error) (statusCode int, message string) {
 		return http.StatusNotFound, notFoundErr.Error()
 	}
 
+	var rateLimitErr *errpkg.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return http.StatusTooManyRequests, rateLimitErr.Error()
+	}
+
+	var notConnectedErr *errpkg.CalendarNotConnectedError
+	if errors.As(err, &notConnectedErr) {
+		return http.StatusFailedDependency, notConnectedErr.Error()
+	}
+
+	var reauthErr *errpkg.CalendarReauthRequiredError
+	if errors.As(err, &reauthErr) {
+		return http.StatusFailedDependency, reauthErr.Error()
+	}
+
 	return http.StatusInternalServerError, err.Error()
 }
 
+// ErrorCode returns the machine-readable code JSON error responses carry for
+// err, or "" when the status alone says enough.
+func ErrorCode(err error) string {
+	var reauthErr *errpkg.CalendarReauthRequiredError
+	if errors.As(err, &reauthErr) {
+		return ReauthRequiredCode
+	}
+	return ""
+}
+
+// WriteError writes err with the status MapErrors gives it. Rate limit errors
+// also set Retry-After, in whole seconds rounded up.
 func WriteError(w http.ResponseWriter, err error) {
+	var rateLimitErr *errpkg.RateLimitError
+	if errors.As(err, &rateLimitErr) && rateLimitErr.RetryAfter > 0 {
+		seconds := (rateLimitErr.RetryAfter + time.Second - 1) / time.Second
+		w.Header().Set("Retry-After", strconv.FormatInt(int64(seconds), 10))
+	}
 	statusCode, message := MapErrors(err)
 	http.Error(w, message, statusCode)
 }
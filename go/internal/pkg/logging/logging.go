@@ -0,0 +1,87 @@
+// Package logging configures structured JSON logs with emails and credentials
+// redacted, whether they are written through logrus or the standard log package.
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Setup makes logger write JSON with secrets redacted, and routes the
+// standard log package through it.
+func Setup(logger *logrus.Logger) {
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.AddHook(RedactHook{})
+	log.SetFlags(0)
+	log.SetOutput(stdlibWriter{logger: logger})
+}
+
+// stdlibWriter logs each line of the standard logger at info level.
+type stdlibWriter struct {
+	logger *logrus.Logger
+}
+
+func (w stdlibWriter) Write(p []byte) (int, error) {
+	w.logger.Info(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+const redacted = "[redacted]"
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// Bearer credentials, JWTs such as Firebase ID tokens, and Google access
+	// and refresh tokens.
+	tokenPattern = regexp.MustCompile(`(?i:bearer\s+)\S+|eyJ[\w-]+\.[\w-]+\.[\w-]*|ya29\.[\w.-]+|1//[\w-]+`)
+	// Credentials passed as query or form parameters, e.g. activation links.
+	paramPattern = regexp.MustCompile(`(?i)\b(\w*(?:token|code|password|key|secret))=[^&\s"]+`)
+)
+
+// Redact replaces emails and credentials in s.
+func Redact(s string) string {
+	s = emailPattern.ReplaceAllString(s, "[email]")
+	s = paramPattern.ReplaceAllString(s, "$1="+redacted)
+	return tokenPattern.ReplaceAllStringFunc(s, func(match string) string {
+		if scheme, _, ok := strings.Cut(match, " "); ok && strings.EqualFold(scheme, "bearer") {
+			return scheme + " " + redacted
+		}
+		return redacted
+	})
+}
+
+// sensitiveFields are field names whose values are never logged.
+var sensitiveFields = map[string]bool{
+	"email": true, "password": true, "token": true, "access_token": true,
+	"refresh_token": true, "id_token": true, "authorization": true,
+}
+
+// RedactHook redacts the message and fields of every entry before it is written.
+type RedactHook struct{}
+
+func (RedactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (RedactHook) Fire(entry *logrus.Entry) error {
+	entry.Message = Redact(entry.Message)
+	for key, value := range entry.Data {
+		if sensitiveFields[strings.ToLower(key)] {
+			entry.Data[key] = redacted
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			entry.Data[key] = Redact(v)
+		case error:
+			entry.Data[key] = errors.New(Redact(v.Error()))
+		case fmt.Stringer:
+			entry.Data[key] = Redact(v.String())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const rateLimitsCollection = "rate_limits"
+
+// FirestoreStore keeps buckets in the rate_limits collection, shared by every
+// instance using the project. A TTL policy on refilledAt deletes the buckets
+// that have refilled (see firestore.indexes.json).
+type FirestoreStore struct {
+	client *firestore.Client
+}
+
+func NewFirestoreStore(client *firestore.Client) *FirestoreStore {
+	return &FirestoreStore{client: client}
+}
+
+type firestoreBucket struct {
+	Tokens     float64   `firestore:"tokens"`
+	UpdatedAt  time.Time `firestore:"updatedAt"`
+	RefilledAt time.Time `firestore:"refilledAt"`
+}
+
+func (s *FirestoreStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (allowed bool, retryAfter time.Duration, err error) {
+	ref := s.client.Collection(rateLimitsCollection).Doc(bucketDocID(key))
+	err = s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		current := limit.full(now)
+		doc, err := tx.Get(ref)
+		switch {
+		case status.Code(err) == codes.NotFound:
+		case err != nil:
+			return err
+		default:
+			var stored firestoreBucket
+			if err := doc.DataTo(&stored); err != nil {
+				return err
+			}
+			current = bucket{tokens: stored.Tokens, updated: stored.UpdatedAt}
+		}
+
+		var next bucket
+		next, allowed, retryAfter = limit.take(current, now)
+		return tx.Set(ref, firestoreBucket{Tokens: next.tokens, UpdatedAt: next.updated, RefilledAt: limit.refilledAt(next)})
+	})
+	return allowed, retryAfter, err
+}
+
+// bucketDocID hashes key, which may hold characters not allowed in document IDs.
+func bucketDocID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
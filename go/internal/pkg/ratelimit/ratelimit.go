@@ -0,0 +1,83 @@
+// Package ratelimit limits how often a key, such as a client IP or a user,
+// may repeat an action. Each key has a token bucket: it holds up to Burst
+// tokens, every attempt takes one, and one is added back every Every.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit is the size and refill rate of a token bucket.
+type Limit struct {
+	// Burst is how many attempts are allowed at once.
+	Burst int
+	// Every is how long the bucket takes to regain one token.
+	Every time.Duration
+}
+
+// ParseLimit parses "N/period", allowing N attempts per period, e.g. "5/1m"
+// or "100/h". The whole burst refills over the period.
+func ParseLimit(spec string) (Limit, error) {
+	count, period, ok := strings.Cut(strings.TrimSpace(spec), "/")
+	burst, err := strconv.Atoi(strings.TrimSpace(count))
+	if !ok || err != nil || burst <= 0 {
+		return Limit{}, fmt.Errorf("rate limit %q must be a positive count per period, such as 5/1m", spec)
+	}
+	period = strings.TrimSpace(period)
+	if period != "" && (period[0] < '0' || period[0] > '9') {
+		period = "1" + period
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("rate limit %q must be a positive count per period, such as 5/1m", spec)
+	}
+	return Limit{Burst: burst, Every: d / time.Duration(burst)}, nil
+}
+
+// String formats l as ParseLimit reads it.
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Burst, l.Every*time.Duration(l.Burst))
+}
+
+// Store keeps the buckets. Take removes a token from the bucket of key,
+// reporting whether one was left and otherwise how long until one is. Takes
+// of the same key are atomic, including across the instances sharing a store.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// bucket is the state of one key. A missing bucket is full.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// full returns the bucket of a key not seen before.
+func (l Limit) full(now time.Time) bucket {
+	return bucket{tokens: float64(l.Burst), updated: now}
+}
+
+// take refills b for the time elapsed since its last update and takes a token.
+func (l Limit) take(b bucket, now time.Time) (bucket, bool, time.Duration) {
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens += float64(elapsed) / float64(l.Every)
+	}
+	b.tokens = min(b.tokens, float64(l.Burst))
+	if now.After(b.updated) {
+		b.updated = now
+	}
+	if b.tokens < 1 {
+		return b, false, time.Duration((1 - b.tokens) * float64(l.Every))
+	}
+	b.tokens--
+	return b, true, 0
+}
+
+// refilledAt is when b will be full again, after which it can be forgotten.
+func (l Limit) refilledAt(b bucket) time.Time {
+	return b.updated.Add(time.Duration((float64(l.Burst) - b.tokens) * float64(l.Every)))
+}
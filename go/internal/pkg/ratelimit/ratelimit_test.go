@@ -0,0 +1,120 @@
+package ratelimit_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"energyjournal/internal/pkg/ratelimit"
+	"energyjournal/internal/pkg/storagetest"
+)
+
+func TestParseLimit(t *testing.T) {
+	t.Parallel()
+
+	for spec, want := range map[string]ratelimit.Limit{
+		"5/1m":   {Burst: 5, Every: 12 * time.Second},
+		"100/h":  {Burst: 100, Every: 36 * time.Second},
+		" 1/15m": {Burst: 1, Every: 15 * time.Minute},
+	} {
+		got, err := ratelimit.ParseLimit(spec)
+		if err != nil || got != want {
+			t.Errorf("ParseLimit(%q) = %+v, %v; want %+v", spec, got, err, want)
+		}
+	}
+	for _, spec := range []string{"", "5", "0/1m", "-1/1m", "5/", "5/0s", "five/1m"} {
+		if _, err := ratelimit.ParseLimit(spec); err == nil {
+			t.Errorf("ParseLimit(%q) returned no error", spec)
+		}
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	t.Parallel()
+	testStore(t, ratelimit.NewMemoryStore())
+}
+
+func TestSQLiteStore(t *testing.T) {
+	t.Parallel()
+	testStore(t, ratelimit.NewSQLStore(storagetest.SQLite(t)))
+}
+
+func TestPostgresStore(t *testing.T) {
+	testStore(t, ratelimit.NewSQLStore(storagetest.Postgres(t)))
+}
+
+func TestFirestoreStore(t *testing.T) {
+	testStore(t, ratelimit.NewFirestoreStore(storagetest.Firestore(t)))
+}
+
+// testStore checks the bucket arithmetic every store shares, and that
+// concurrent takes never let more than the burst through.
+func testStore(t *testing.T, store ratelimit.Store) {
+	t.Helper()
+
+	ctx := context.Background()
+	limit := ratelimit.Limit{Burst: 2, Every: 10 * time.Second}
+	start := time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC)
+	take := func(key string, at time.Duration) (bool, time.Duration) {
+		t.Helper()
+		allowed, retryAfter, err := store.Take(ctx, key, limit, start.Add(at))
+		if err != nil {
+			t.Fatalf("Take returned error: %v", err)
+		}
+		return allowed, retryAfter
+	}
+
+	for i := range 2 {
+		if allowed, _ := take("ip:1", 0); !allowed {
+			t.Fatalf("expected take %d within the burst allowed", i+1)
+		}
+	}
+	if allowed, retryAfter := take("ip:1", 4*time.Second); allowed || retryAfter != 6*time.Second {
+		t.Fatalf("expected the third take refused for 6s, got %v and %v", allowed, retryAfter)
+	}
+	if allowed, _ := take("ip:2", 4*time.Second); !allowed {
+		t.Fatal("expected other keys counted apart")
+	}
+	if allowed, _ := take("ip:1", 10*time.Second); !allowed {
+		t.Fatal("expected a token regained after 10s")
+	}
+	if allowed, _ := take("ip:1", 11*time.Second); allowed {
+		t.Fatal("expected the regained token spent")
+	}
+	// A bucket left alone refills up to the burst, not beyond.
+	for i := range 2 {
+		if allowed, _ := take("ip:1", time.Hour); !allowed {
+			t.Fatalf("expected take %d of the refilled bucket allowed", i+1)
+		}
+	}
+	if allowed, _ := take("ip:1", time.Hour); allowed {
+		t.Fatal("expected the refilled bucket capped at the burst")
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		granted int
+	)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			allowed, _, err := store.Take(ctx, "concurrent", limit, start)
+			if err != nil {
+				t.Errorf("Take returned error: %v", err)
+				return
+			}
+			if allowed {
+				mu.Lock()
+				granted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if granted != limit.Burst {
+		t.Fatalf("expected %d concurrent takes allowed, got %d", limit.Burst, granted)
+	}
+}
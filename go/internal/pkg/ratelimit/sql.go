@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"energyjournal/internal/pkg/sqldb"
+)
+
+// pruneInterval is how often SQLStore deletes the buckets that have refilled.
+const pruneInterval = 10 * time.Minute
+
+// SQLStore keeps buckets in the rate_limit_buckets table, shared by every
+// instance using the database.
+type SQLStore struct {
+	db *sqldb.DB
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+func NewSQLStore(db *sqldb.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+func (s *SQLStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (allowed bool, retryAfter time.Duration, err error) {
+	if s.pruneDue(now) {
+		if _, err := s.db.Exec(ctx, `DELETE FROM rate_limit_buckets WHERE refilled_at < ?`, sqldb.Time(now)); err != nil {
+			return false, 0, err
+		}
+	}
+
+	err = s.db.InTx(ctx, func(tx *sqldb.Tx) error {
+		// Inserting first gives concurrent takes of a new key a row to lock.
+		full := limit.full(now)
+		if _, err := tx.Exec(ctx, `INSERT INTO rate_limit_buckets (bucket_key, tokens, updated_at, refilled_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT (bucket_key) DO NOTHING`, key, full.tokens, sqldb.Time(full.updated), sqldb.Time(now)); err != nil {
+			return err
+		}
+		query := `SELECT tokens, updated_at FROM rate_limit_buckets WHERE bucket_key = ?`
+		if s.db.Dialect() == sqldb.Postgres {
+			query += ` FOR UPDATE`
+		}
+		var current bucket
+		if err := tx.QueryRow(ctx, query, key).Scan(&current.tokens, sqldb.ScanTime(&current.updated)); err != nil {
+			return err
+		}
+
+		var next bucket
+		next, allowed, retryAfter = limit.take(current, now)
+		_, err := tx.Exec(ctx, `UPDATE rate_limit_buckets SET tokens = ?, updated_at = ?, refilled_at = ? WHERE bucket_key = ?`,
+			next.tokens, sqldb.Time(next.updated), sqldb.Time(limit.refilledAt(next)), key)
+		return err
+	})
+	return allowed, retryAfter, err
+}
+
+// pruneDue reports whether this instance should delete refilled buckets now.
+func (s *SQLStore) pruneDue(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.lastPrune) < pruneInterval {
+		return false
+	}
+	s.lastPrune = now
+	return true
+}
@@ -0,0 +1,113 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	errpkg "energyjournal/internal/pkg/error"
+)
+
+// Job is a named task run periodically in the background.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler runs jobs on fixed intervals. Runs of the same job never overlap:
+// a manual RunNow waits for a scheduled run in progress, and vice versa.
+type Scheduler struct {
+	jobs     []Job
+	locks    map[string]*sync.Mutex
+	wg       sync.WaitGroup
+	stop     chan struct{}
+	stopOnce sync.Once
+	cancel   context.CancelFunc
+}
+
+// New creates a Scheduler for the given jobs.
+func New(jobs ...Job) *Scheduler {
+	locks := make(map[string]*sync.Mutex, len(jobs))
+	for _, job := range jobs {
+		locks[job.Name] = &sync.Mutex{}
+	}
+	return &Scheduler{jobs: jobs, locks: locks, stop: make(chan struct{}), cancel: func() {}}
+}
+
+// Start launches one goroutine per job that runs it every Interval until ctx
+// is cancelled or Stop is called. Jobs with a non-positive Interval are only
+// run via RunNow.
+func (s *Scheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	for _, job := range s.jobs {
+		if job.Interval <= 0 {
+			continue
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			ticker := time.NewTicker(job.Interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-s.stop:
+					return
+				case <-ticker.C:
+					if err := s.run(ctx, job); err != nil {
+						logrus.WithError(err).WithField("job", job.Name).Error("Job failed")
+					}
+				}
+			}
+		}()
+	}
+}
+
+// Wait blocks until every goroutine launched by Start has returned, which
+// happens once the context passed to Start is cancelled and in-flight runs finish.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// Stop stops scheduling runs and waits for the runs in progress to finish. If
+// ctx ends first, those runs are cancelled and Stop returns ctx.Err() once they
+// have returned.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.stopOnce.Do(func() { close(s.stop) })
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// RunNow runs the named job synchronously.
+func (s *Scheduler) RunNow(ctx context.Context, name string) error {
+	for _, job := range s.jobs {
+		if job.Name == name {
+			return s.run(ctx, job)
+		}
+	}
+	return errpkg.NewNotFoundError("job", name)
+}
+
+func (s *Scheduler) run(ctx context.Context, job Job) error {
+	lock := s.locks[job.Name]
+	lock.Lock()
+	defer lock.Unlock()
+	return job.Run(ctx)
+}
@@ -0,0 +1,112 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	errpkg "energyjournal/internal/pkg/error"
+)
+
+func TestStartRunsJobsUntilCancelled(t *testing.T) {
+	t.Parallel()
+
+	var runs atomic.Int32
+	s := New(Job{Name: "tick", Interval: time.Millisecond, Run: func(context.Context) error {
+		runs.Add(1)
+		return nil
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	deadline := time.Now().Add(time.Second)
+	for runs.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	s.Wait()
+
+	if runs.Load() < 3 {
+		t.Fatalf("expected at least 3 runs, got %d", runs.Load())
+	}
+	after := runs.Load()
+	time.Sleep(5 * time.Millisecond)
+	if runs.Load() != after {
+		t.Fatal("job kept running after cancellation")
+	}
+}
+
+func TestRunNow(t *testing.T) {
+	t.Parallel()
+
+	boom := errors.New("boom")
+	s := New(Job{Name: "manual", Run: func(context.Context) error { return boom }})
+
+	if err := s.RunNow(context.Background(), "manual"); !errors.Is(err, boom) {
+		t.Fatalf("expected job error, got %v", err)
+	}
+
+	var notFound *errpkg.NotFoundError
+	if err := s.RunNow(context.Background(), "missing"); !errors.As(err, &notFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestStopLetsRunsInProgressFinish(t *testing.T) {
+	t.Parallel()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	var cancelled atomic.Bool
+	s := New(Job{Name: "slow", Interval: time.Millisecond, Run: func(ctx context.Context) error {
+		select {
+		case started <- struct{}{}:
+		default:
+			return nil
+		}
+		<-release
+		cancelled.Store(ctx.Err() != nil)
+		return nil
+	}})
+	s.Start(context.Background())
+	<-started
+
+	stopped := make(chan error)
+	go func() { stopped <- s.Stop(context.Background()) }()
+	select {
+	case err := <-stopped:
+		t.Fatalf("Stop returned before the run finished: %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+	if err := <-stopped; err != nil {
+		t.Fatalf("Stop returned error: %v", err)
+	}
+	if cancelled.Load() {
+		t.Fatal("expected the run to finish with a live context")
+	}
+}
+
+func TestStopCancelsRunsAfterDeadline(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+	s := New(Job{Name: "stuck", Interval: time.Millisecond, Run: func(ctx context.Context) error {
+		select {
+		case started <- struct{}{}:
+		default:
+			return nil
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	s.Start(context.Background())
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline error, got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package sqldb
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOpenMigratesOnce(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "journal.db")
+	db, err := Open(ctx, SQLite, path)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	if _, err := db.Exec(ctx, `INSERT INTO users (uid, email, firstname, lastname, timezone, status) VALUES ('uid-1', '', '', '', '', '')`); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	db.Close()
+
+	db, err = Open(ctx, SQLite, path)
+	if err != nil {
+		t.Fatalf("reopen returned error: %v", err)
+	}
+	defer db.Close()
+	var users, versions int
+	if err := db.QueryRow(ctx, `SELECT COUNT(*) FROM users`).Scan(&users); err != nil || users != 1 {
+		t.Fatalf("expected the data kept, got %d users (%v)", users, err)
+	}
+	if err := db.QueryRow(ctx, `SELECT COUNT(*) FROM schema_migrations`).Scan(&versions); err != nil || versions == 0 {
+		t.Fatalf("expected applied migrations recorded, got %d (%v)", versions, err)
+	}
+}
+
+func TestMemoryDatabaseSurvivesTransactions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db, err := Open(ctx, SQLite, MemoryDSN)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	defer db.Close()
+
+	err = db.InTx(ctx, func(tx *Tx) error {
+		_, err := tx.Exec(ctx, `INSERT INTO activation_tokens (token, uid, expires_at) VALUES (?, ?, ?)`, "t1", "uid-1", Time(time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("InTx returned error: %v", err)
+	}
+
+	var expiresAt time.Time
+	if err := db.QueryRow(ctx, `SELECT expires_at FROM activation_tokens WHERE token = ?`, "t1").Scan(ScanTime(&expiresAt)); err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	if !expiresAt.Equal(time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)) || expiresAt.Location() != time.UTC {
+		t.Fatalf("unexpected time %v", expiresAt)
+	}
+}
+
+func TestTimeSortsAsText(t *testing.T) {
+	t.Parallel()
+
+	paris := time.FixedZone("CET", 3600)
+	earlier, _ := Time(time.Date(2026, 3, 2, 9, 30, 0, 500, paris)).Value()
+	later, _ := Time(time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)).Value()
+	if earlier.(string) >= later.(string) {
+		t.Fatalf("expected %v to sort before %v", earlier, later)
+	}
+	if zero, _ := Time(time.Time{}).Value(); zero != nil {
+		t.Fatalf("expected the zero time stored as NULL, got %v", zero)
+	}
+}
+
+func TestRebindNumbersPostgresPlaceholders(t *testing.T) {
+	t.Parallel()
+
+	query := `SELECT * FROM users WHERE uid = ? AND email <> '?' AND "odd?" IN (?, ?)`
+	if got := rebind(SQLite, query); got != query {
+		t.Fatalf("expected SQLite queries unchanged, got %s", got)
+	}
+	want := `SELECT * FROM users WHERE uid = $1 AND email <> '?' AND "odd?" IN ($2, $3)`
+	if got := rebind(Postgres, query); got != want {
+		t.Fatalf("unexpected Postgres query:\n got %s\nwant %s", got, want)
+	}
+}
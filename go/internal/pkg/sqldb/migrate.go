@@ -0,0 +1,73 @@
+package sqldb
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+)
+
+// migrations holds one directory of numbered .sql files per dialect. Files are
+// applied once each, in name order; never edit a file that has shipped.
+//
+//go:embed migrations
+var migrations embed.FS
+
+// migrationLockID is the Postgres advisory lock held while migrating.
+const migrationLockID = 7_341_220_041
+
+func (db *DB) migrate(ctx context.Context) error {
+	if _, err := db.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version TEXT PRIMARY KEY,
+		applied_at TEXT NOT NULL
+	)`); err != nil {
+		return err
+	}
+
+	dir := path.Join("migrations", string(db.dialect))
+	entries, err := fs.ReadDir(migrations, dir)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && path.Ext(entry.Name()) == ".sql" {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		script, err := fs.ReadFile(migrations, path.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		err = db.InTx(ctx, func(tx *Tx) error {
+			if db.dialect == Postgres {
+				// Instances starting together migrate one at a time.
+				if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(?)`, migrationLockID); err != nil {
+					return err
+				}
+			}
+			var applied int
+			if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM schema_migrations WHERE version = ?`, name).Scan(&applied); err != nil {
+				return err
+			}
+			if applied > 0 {
+				return nil
+			}
+			if _, err := tx.Exec(ctx, string(script)); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, name, Time(time.Now()))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+-- Same tables as the SQLite schema, with native time, boolean and JSON types.
+
+CREATE TABLE users (
+    uid        TEXT PRIMARY KEY,
+    email      TEXT NOT NULL,
+    firstname  TEXT NOT NULL,
+    lastname   TEXT NOT NULL,
+    timezone   TEXT NOT NULL,
+    status     TEXT NOT NULL,
+    created_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ
+);
+
+CREATE TABLE activation_tokens (
+    token      TEXT PRIMARY KEY,
+    uid        TEXT NOT NULL,
+    expires_at TIMESTAMPTZ
+);
+CREATE INDEX activation_tokens_expires_at ON activation_tokens (expires_at);
+
+CREATE TABLE user_preferences (
+    uid           TEXT PRIMARY KEY,
+    reminder_time TEXT NOT NULL,
+    reminder_days JSONB NOT NULL,
+    digest_opt_in BOOLEAN NOT NULL,
+    channels      JSONB NOT NULL,
+    locale        TEXT NOT NULL,
+    updated_at    TIMESTAMPTZ
+);
+
+-- The (uid, date) primary key is the composite index GetByDateRange scans.
+CREATE TABLE energy_levels (
+    uid                 TEXT NOT NULL,
+    date                TEXT NOT NULL,
+    physical            INTEGER NOT NULL,
+    mental              INTEGER NOT NULL,
+    emotional           INTEGER NOT NULL,
+    sleep_quality       INTEGER,
+    stress_level        INTEGER,
+    physical_activity   TEXT NOT NULL,
+    nutrition           TEXT NOT NULL,
+    social_interactions TEXT NOT NULL,
+    time_outdoors       TEXT NOT NULL,
+    notes               TEXT NOT NULL,
+    created_at          TIMESTAMPTZ,
+    updated_at          TIMESTAMPTZ,
+    PRIMARY KEY (uid, date)
+);
+
+CREATE TABLE calendar_connections (
+    uid           TEXT PRIMARY KEY,
+    provider      TEXT NOT NULL,
+    calendar_ids  JSONB NOT NULL,
+    access_token  TEXT NOT NULL,
+    refresh_token TEXT NOT NULL,
+    expiry        TIMESTAMPTZ,
+    needs_reauth  BOOLEAN NOT NULL
+);
+
+CREATE TABLE calendar_category_rules (
+    id         TEXT PRIMARY KEY,
+    uid        TEXT NOT NULL,
+    category   TEXT NOT NULL,
+    match_type TEXT NOT NULL,
+    value      TEXT NOT NULL,
+    priority   INTEGER NOT NULL,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+CREATE INDEX calendar_category_rules_uid ON calendar_category_rules (uid);
+
+CREATE TABLE calendar_watch_channels (
+    id          TEXT PRIMARY KEY,
+    uid         TEXT NOT NULL,
+    calendar_id TEXT NOT NULL,
+    resource_id TEXT NOT NULL,
+    token       TEXT NOT NULL,
+    expiration  TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ
+);
+CREATE INDEX calendar_watch_channels_uid ON calendar_watch_channels (uid);
+
+CREATE TABLE calendar_events (
+    uid         TEXT NOT NULL,
+    calendar_id TEXT NOT NULL,
+    id          TEXT NOT NULL,
+    summary     TEXT NOT NULL,
+    color_id    TEXT NOT NULL,
+    start_at    TIMESTAMPTZ,
+    end_at      TIMESTAMPTZ,
+    all_day     BOOLEAN NOT NULL,
+    status      TEXT NOT NULL,
+    transparent BOOLEAN NOT NULL,
+    attendees   JSONB NOT NULL,
+    categories  JSONB NOT NULL,
+    PRIMARY KEY (uid, calendar_id, id)
+);
+CREATE INDEX calendar_events_uid_end_at ON calendar_events (uid, end_at);
+
+CREATE TABLE calendar_sync_states (
+    uid            TEXT NOT NULL,
+    calendar_id    TEXT NOT NULL,
+    sync_token     TEXT NOT NULL,
+    window_start   TIMESTAMPTZ,
+    last_full_sync TIMESTAMPTZ,
+    last_synced_at TIMESTAMPTZ,
+    PRIMARY KEY (uid, calendar_id)
+);
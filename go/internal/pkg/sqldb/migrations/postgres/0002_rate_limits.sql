@@ -0,0 +1,9 @@
+-- Same table as the SQLite schema, with native time types.
+
+CREATE TABLE rate_limit_buckets (
+    bucket_key  TEXT PRIMARY KEY,
+    tokens      DOUBLE PRECISION NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL,
+    refilled_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX rate_limit_buckets_refilled_at ON rate_limit_buckets (refilled_at);
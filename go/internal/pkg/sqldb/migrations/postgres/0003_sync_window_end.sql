@@ -0,0 +1,3 @@
+-- Same column as the SQLite schema, with native time types.
+
+ALTER TABLE calendar_sync_states ADD COLUMN window_end TIMESTAMPTZ;
@@ -0,0 +1,107 @@
+-- Times are fixed-width UTC text (see sqldb.Time), lists are JSON text.
+
+CREATE TABLE users (
+    uid        TEXT PRIMARY KEY,
+    email      TEXT NOT NULL,
+    firstname  TEXT NOT NULL,
+    lastname   TEXT NOT NULL,
+    timezone   TEXT NOT NULL,
+    status     TEXT NOT NULL,
+    created_at TEXT,
+    deleted_at TEXT
+);
+
+CREATE TABLE activation_tokens (
+    token      TEXT PRIMARY KEY,
+    uid        TEXT NOT NULL,
+    expires_at TEXT
+);
+CREATE INDEX activation_tokens_expires_at ON activation_tokens (expires_at);
+
+CREATE TABLE user_preferences (
+    uid           TEXT PRIMARY KEY,
+    reminder_time TEXT NOT NULL,
+    reminder_days TEXT NOT NULL,
+    digest_opt_in INTEGER NOT NULL,
+    channels      TEXT NOT NULL,
+    locale        TEXT NOT NULL,
+    updated_at    TEXT
+);
+
+CREATE TABLE energy_levels (
+    uid                 TEXT NOT NULL,
+    date                TEXT NOT NULL,
+    physical            INTEGER NOT NULL,
+    mental              INTEGER NOT NULL,
+    emotional           INTEGER NOT NULL,
+    sleep_quality       INTEGER,
+    stress_level        INTEGER,
+    physical_activity   TEXT NOT NULL,
+    nutrition           TEXT NOT NULL,
+    social_interactions TEXT NOT NULL,
+    time_outdoors       TEXT NOT NULL,
+    notes               TEXT NOT NULL,
+    created_at          TEXT,
+    updated_at          TEXT,
+    PRIMARY KEY (uid, date)
+);
+
+CREATE TABLE calendar_connections (
+    uid           TEXT PRIMARY KEY,
+    provider      TEXT NOT NULL,
+    calendar_ids  TEXT NOT NULL,
+    access_token  TEXT NOT NULL,
+    refresh_token TEXT NOT NULL,
+    expiry        TEXT,
+    needs_reauth  INTEGER NOT NULL
+);
+
+CREATE TABLE calendar_category_rules (
+    id         TEXT PRIMARY KEY,
+    uid        TEXT NOT NULL,
+    category   TEXT NOT NULL,
+    match_type TEXT NOT NULL,
+    value      TEXT NOT NULL,
+    priority   INTEGER NOT NULL,
+    created_at TEXT,
+    updated_at TEXT
+);
+CREATE INDEX calendar_category_rules_uid ON calendar_category_rules (uid);
+
+CREATE TABLE calendar_watch_channels (
+    id          TEXT PRIMARY KEY,
+    uid         TEXT NOT NULL,
+    calendar_id TEXT NOT NULL,
+    resource_id TEXT NOT NULL,
+    token       TEXT NOT NULL,
+    expiration  TEXT,
+    created_at  TEXT
+);
+CREATE INDEX calendar_watch_channels_uid ON calendar_watch_channels (uid);
+
+CREATE TABLE calendar_events (
+    uid         TEXT NOT NULL,
+    calendar_id TEXT NOT NULL,
+    id          TEXT NOT NULL,
+    summary     TEXT NOT NULL,
+    color_id    TEXT NOT NULL,
+    start_at    TEXT,
+    end_at      TEXT,
+    all_day     INTEGER NOT NULL,
+    status      TEXT NOT NULL,
+    transparent INTEGER NOT NULL,
+    attendees   TEXT NOT NULL,
+    categories  TEXT NOT NULL,
+    PRIMARY KEY (uid, calendar_id, id)
+);
+CREATE INDEX calendar_events_uid_end_at ON calendar_events (uid, end_at);
+
+CREATE TABLE calendar_sync_states (
+    uid            TEXT NOT NULL,
+    calendar_id    TEXT NOT NULL,
+    sync_token     TEXT NOT NULL,
+    window_start   TEXT,
+    last_full_sync TEXT,
+    last_synced_at TEXT,
+    PRIMARY KEY (uid, calendar_id)
+);
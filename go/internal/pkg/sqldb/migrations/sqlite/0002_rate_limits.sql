@@ -0,0 +1,10 @@
+-- Token buckets of the shared rate limit store; a row can be dropped once
+-- refilled_at has passed.
+
+CREATE TABLE rate_limit_buckets (
+    bucket_key  TEXT PRIMARY KEY,
+    tokens      REAL NOT NULL,
+    updated_at  TEXT NOT NULL,
+    refilled_at TEXT NOT NULL
+);
+CREATE INDEX rate_limit_buckets_refilled_at ON rate_limit_buckets (refilled_at);
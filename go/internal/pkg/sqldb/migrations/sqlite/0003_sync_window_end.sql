@@ -0,0 +1,4 @@
+-- End of the window a full sync cached; states saved before it have none and
+-- are fully synced again.
+
+ALTER TABLE calendar_sync_states ADD COLUMN window_end TEXT;
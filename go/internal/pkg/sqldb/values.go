@@ -0,0 +1,103 @@
+package sqldb
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// timeLayout is fixed width so that stored times sort and compare as text.
+const timeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// Time stores t in UTC; the zero time is stored as NULL.
+func Time(t time.Time) driver.Valuer {
+	return timeValue{t: t}
+}
+
+type timeValue struct {
+	t time.Time
+}
+
+func (v timeValue) Value() (driver.Value, error) {
+	if v.t.IsZero() {
+		return nil, nil
+	}
+	return v.t.UTC().Format(timeLayout), nil
+}
+
+// ScanTime reads a time written with Time into dst, in UTC. NULL reads as the
+// zero time.
+func ScanTime(dst *time.Time) sql.Scanner {
+	return timeScanner{dst: dst}
+}
+
+type timeScanner struct {
+	dst *time.Time
+}
+
+func (s timeScanner) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*s.dst = time.Time{}
+	case time.Time:
+		*s.dst = v.UTC()
+	case string:
+		return s.parse(v)
+	case []byte:
+		return s.parse(string(v))
+	default:
+		return fmt.Errorf("cannot scan %T into a time", src)
+	}
+	return nil
+}
+
+func (s timeScanner) parse(value string) error {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return err
+	}
+	*s.dst = t.UTC()
+	return nil
+}
+
+// JSON stores v encoded as JSON, for lists and nested values.
+func JSON(v any) driver.Valuer {
+	return jsonValue{v: v}
+}
+
+type jsonValue struct {
+	v any
+}
+
+func (v jsonValue) Value() (driver.Value, error) {
+	encoded, err := json.Marshal(v.v)
+	if err != nil {
+		return nil, err
+	}
+	return string(encoded), nil
+}
+
+// ScanJSON decodes a value written with JSON into dst, a pointer. NULL leaves
+// dst unchanged.
+func ScanJSON(dst any) sql.Scanner {
+	return jsonScanner{dst: dst}
+}
+
+type jsonScanner struct {
+	dst any
+}
+
+func (s jsonScanner) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), s.dst)
+	case []byte:
+		return json.Unmarshal(v, s.dst)
+	default:
+		return fmt.Errorf("cannot scan %T as JSON", src)
+	}
+}
@@ -0,0 +1,297 @@
+package storagetest
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"energyjournal/internal/domain/calendar"
+)
+
+// ConnectionRepository checks a calendar.CalendarConnectionRepository.
+func ConnectionRepository(t *testing.T, newRepo func(t *testing.T) calendar.CalendarConnectionRepository) {
+	t.Run("missing connection is nil", func(t *testing.T) {
+		conn, err := newRepo(t).Get(ctx(), "uid-1")
+		requireNoError(t, err)
+		if conn != nil {
+			t.Fatalf("expected no connection, got %+v", conn)
+		}
+	})
+
+	t.Run("upsert, list and delete", func(t *testing.T) {
+		repo := newRepo(t)
+		conn := calendar.CalendarConnection{
+			UID:          "uid-1",
+			Provider:     calendar.ProviderMicrosoft,
+			CalendarIDs:  []string{"primary", "team@example.com"},
+			AccessToken:  "access",
+			RefreshToken: "refresh",
+			Expiry:       at(2, 10),
+			NeedsReauth:  true,
+		}
+		requireNoError(t, repo.Upsert(ctx(), conn))
+		requireNoError(t, repo.Upsert(ctx(), calendar.CalendarConnection{UID: "uid-2", AccessToken: "other"}))
+
+		got, err := repo.Get(ctx(), "uid-1")
+		requireNoError(t, err)
+		if got == nil || got.UID != "uid-1" || got.Provider != calendar.ProviderMicrosoft || len(got.CalendarIDs) != 2 ||
+			got.CalendarIDs[1] != "team@example.com" || got.AccessToken != "access" || got.RefreshToken != "refresh" ||
+			!got.Expiry.Equal(conn.Expiry) || !got.NeedsReauth {
+			t.Fatalf("unexpected connection: %+v", got)
+		}
+
+		conn.CalendarIDs = nil
+		conn.NeedsReauth = false
+		requireNoError(t, repo.Upsert(ctx(), conn))
+		got, err = repo.Get(ctx(), "uid-1")
+		requireNoError(t, err)
+		if len(got.CalendarIDs) != 0 || got.NeedsReauth {
+			t.Fatalf("expected the connection replaced, got %+v", got)
+		}
+
+		all, err := repo.List(ctx())
+		requireNoError(t, err)
+		if len(all) != 2 {
+			t.Fatalf("expected 2 connections, got %+v", all)
+		}
+
+		requireNoError(t, repo.Delete(ctx(), "uid-1"))
+		requireNoError(t, repo.Delete(ctx(), "uid-1"))
+		got, err = repo.Get(ctx(), "uid-1")
+		requireNoError(t, err)
+		if got != nil {
+			t.Fatalf("expected the connection deleted, got %+v", got)
+		}
+	})
+}
+
+// CategoryRuleRepository checks a calendar.CategoryRuleRepository.
+func CategoryRuleRepository(t *testing.T, newRepo func(t *testing.T) calendar.CategoryRuleRepository) {
+	t.Run("rules are scoped to their user", func(t *testing.T) {
+		repo := newRepo(t)
+		rule := &calendar.CategoryRule{
+			UID:       "uid-1",
+			Category:  "Focus",
+			MatchType: calendar.MatchKeyword,
+			Value:     "focus",
+			Priority:  2,
+			CreatedAt: at(2, 9),
+			UpdatedAt: at(2, 9),
+		}
+		requireNoError(t, repo.Create(ctx(), rule))
+		if rule.ID == "" {
+			t.Fatal("expected Create to set the ID")
+		}
+		requireNoError(t, repo.Create(ctx(), &calendar.CategoryRule{UID: "uid-2", Category: "Other", MatchType: calendar.MatchKeyword, Value: "x"}))
+
+		got, err := repo.Get(ctx(), "uid-1", rule.ID)
+		requireNoError(t, err)
+		if got.ID != rule.ID || got.UID != "uid-1" || got.Category != "Focus" || got.MatchType != calendar.MatchKeyword ||
+			got.Value != "focus" || got.Priority != 2 || !got.CreatedAt.Equal(rule.CreatedAt) || !got.UpdatedAt.Equal(rule.UpdatedAt) {
+			t.Fatalf("unexpected rule: %+v", got)
+		}
+		_, err = repo.Get(ctx(), "uid-2", rule.ID)
+		requireNotFound(t, err)
+		requireNotFound(t, repo.Delete(ctx(), "uid-2", rule.ID))
+
+		rules, err := repo.List(ctx(), "uid-1")
+		requireNoError(t, err)
+		if len(rules) != 1 || rules[0].ID != rule.ID {
+			t.Fatalf("unexpected rules: %+v", rules)
+		}
+
+		rule.Value = "deep work"
+		rule.UpdatedAt = at(3, 9)
+		requireNoError(t, repo.Update(ctx(), *rule))
+		got, err = repo.Get(ctx(), "uid-1", rule.ID)
+		requireNoError(t, err)
+		if got.Value != "deep work" || !got.UpdatedAt.Equal(rule.UpdatedAt) {
+			t.Fatalf("expected the update applied, got %+v", got)
+		}
+
+		requireNoError(t, repo.Delete(ctx(), "uid-1", rule.ID))
+		_, err = repo.Get(ctx(), "uid-1", rule.ID)
+		requireNotFound(t, err)
+		rules, err = repo.List(ctx(), "uid-1")
+		requireNoError(t, err)
+		if rules == nil || len(rules) != 0 {
+			t.Fatalf("expected an empty, non-nil list, got %#v", rules)
+		}
+	})
+}
+
+// WatchChannelRepository checks a calendar.WatchChannelRepository.
+func WatchChannelRepository(t *testing.T, newRepo func(t *testing.T) calendar.WatchChannelRepository) {
+	t.Run("save, list and delete", func(t *testing.T) {
+		repo := newRepo(t)
+		missing, err := repo.Get(ctx(), "ch-1")
+		requireNoError(t, err)
+		if missing != nil {
+			t.Fatalf("expected no channel, got %+v", missing)
+		}
+
+		channel := calendar.WatchChannel{
+			ID:         "ch-1",
+			UID:        "uid-1",
+			CalendarID: "primary",
+			ResourceID: "res-1",
+			Token:      "secret",
+			Expiration: at(9, 9),
+			CreatedAt:  at(2, 9),
+		}
+		requireNoError(t, repo.Save(ctx(), channel))
+		requireNoError(t, repo.Save(ctx(), calendar.WatchChannel{ID: "ch-2", UID: "uid-2", CalendarID: "primary"}))
+
+		got, err := repo.Get(ctx(), "ch-1")
+		requireNoError(t, err)
+		if got == nil || got.ID != "ch-1" || got.UID != "uid-1" || got.CalendarID != "primary" || got.ResourceID != "res-1" ||
+			got.Token != "secret" || !got.Expiration.Equal(channel.Expiration) || !got.CreatedAt.Equal(channel.CreatedAt) {
+			t.Fatalf("unexpected channel: %+v", got)
+		}
+
+		channels, err := repo.ListByUID(ctx(), "uid-1")
+		requireNoError(t, err)
+		if len(channels) != 1 || channels[0].ID != "ch-1" {
+			t.Fatalf("unexpected channels: %+v", channels)
+		}
+
+		requireNoError(t, repo.Delete(ctx(), "ch-1"))
+		got, err = repo.Get(ctx(), "ch-1")
+		requireNoError(t, err)
+		if got != nil {
+			t.Fatalf("expected the channel deleted, got %+v", got)
+		}
+	})
+}
+
+// EventCacheRepository checks a calendar.EventCacheRepository.
+func EventCacheRepository(t *testing.T, newRepo func(t *testing.T) calendar.EventCacheRepository) {
+	// Calendar IDs may hold characters that are not valid in keys or paths.
+	const work = "team/work@group.calendar.google.com"
+
+	t.Run("replace, change and list events", func(t *testing.T) {
+		repo := newRepo(t)
+		requireNoError(t, repo.ReplaceCalendar(ctx(), "uid-1", work, []calendar.Event{
+			{
+				ID:          "review",
+				CalendarID:  work,
+				Summary:     "Review",
+				ColorID:     "5",
+				Start:       at(2, 9),
+				End:         at(2, 11),
+				Status:      calendar.EventConfirmed,
+				Transparent: true,
+				Attendees:   []calendar.Attendee{{Email: "ana@example.com", Self: true, Organizer: true, ResponseStatus: calendar.ResponseAccepted}},
+				Categories:  []string{"Meetings"},
+			},
+			{ID: "offsite", CalendarID: work, Start: at(3, 0), End: at(5, 0), AllDay: true},
+			{ID: "gone", CalendarID: work, Start: at(2, 9), End: at(2, 10), Status: calendar.EventCancelled},
+			{CalendarID: work, Summary: "no id", Start: at(2, 9), End: at(2, 10)},
+		}))
+		requireNoError(t, repo.ReplaceCalendar(ctx(), "uid-1", "primary", []calendar.Event{
+			{ID: "gym", CalendarID: "primary", Start: at(2, 18), End: at(2, 19)},
+		}))
+		requireNoError(t, repo.ReplaceCalendar(ctx(), "uid-2", work, []calendar.Event{
+			{ID: "foreign", CalendarID: work, Start: at(2, 9), End: at(2, 10)},
+		}))
+
+		events := listEvents(t, repo, "uid-1", []string{work}, at(2, 0), at(3, 0))
+		if len(events) != 1 || events[0].ID != "review" {
+			t.Fatalf("unexpected events: %+v", events)
+		}
+		review := events[0]
+		if review.CalendarID != work || review.Summary != "Review" || review.ColorID != "5" || !review.Start.Equal(at(2, 9)) ||
+			!review.End.Equal(at(2, 11)) || review.AllDay || review.Status != calendar.EventConfirmed || !review.Transparent ||
+			len(review.Attendees) != 1 || review.Attendees[0] != (calendar.Attendee{Email: "ana@example.com", Self: true, Organizer: true, ResponseStatus: calendar.ResponseAccepted}) ||
+			len(review.Categories) != 1 || review.Categories[0] != "Meetings" {
+			t.Fatalf("unexpected event: %+v", review)
+		}
+
+		// The range is half-open: an event ending at start or starting at end is out.
+		if events := listEvents(t, repo, "uid-1", []string{work, "primary"}, at(2, 11), at(3, 1)); len(events) != 2 || events[0].ID != "gym" || events[1].ID != "offsite" {
+			t.Fatalf("unexpected events after the review: %+v", events)
+		}
+		if events := listEvents(t, repo, "uid-1", []string{"primary"}, at(1, 0), at(2, 18)); len(events) != 0 {
+			t.Fatalf("expected no events before the gym, got %+v", events)
+		}
+
+		requireNoError(t, repo.ApplyChanges(ctx(), "uid-1", work, []calendar.Event{
+			{ID: "review", CalendarID: work, Summary: "Review (moved)", Start: at(2, 14), End: at(2, 15)},
+			{ID: "offsite", CalendarID: work, Status: calendar.EventCancelled},
+			{ID: "lunch", CalendarID: work, Start: at(2, 12), End: at(2, 13)},
+		}))
+		events = listEvents(t, repo, "uid-1", []string{work}, at(1, 0), at(9, 0))
+		if len(events) != 2 || events[0].ID != "lunch" || events[1].ID != "review" || events[1].Summary != "Review (moved)" || !events[1].Start.Equal(at(2, 14)) {
+			t.Fatalf("unexpected events after changes: %+v", events)
+		}
+
+		requireNoError(t, repo.ReplaceCalendar(ctx(), "uid-1", work, nil))
+		if events := listEvents(t, repo, "uid-1", []string{work, "primary"}, at(1, 0), at(9, 0)); len(events) != 1 || events[0].ID != "gym" {
+			t.Fatalf("expected only the other calendar left, got %+v", events)
+		}
+	})
+
+	t.Run("sync states and user deletion", func(t *testing.T) {
+		repo := newRepo(t)
+		missing, err := repo.GetSyncState(ctx(), "uid-1", work)
+		requireNoError(t, err)
+		if missing != nil {
+			t.Fatalf("expected no sync state, got %+v", missing)
+		}
+
+		state := calendar.SyncState{
+			UID:          "uid-1",
+			CalendarID:   work,
+			SyncToken:    "token-1",
+			WindowStart:  at(1, 0),
+			WindowEnd:    at(30, 0),
+			LastFullSync: at(2, 9),
+			LastSyncedAt: at(2, 10),
+		}
+		requireNoError(t, repo.SaveSyncState(ctx(), state))
+		requireNoError(t, repo.SaveSyncState(ctx(), calendar.SyncState{UID: "uid-2", CalendarID: work, SyncToken: "token-2"}))
+		requireNoError(t, repo.ReplaceCalendar(ctx(), "uid-1", work, []calendar.Event{{ID: "review", CalendarID: work, Start: at(2, 9), End: at(2, 10)}}))
+		requireNoError(t, repo.ReplaceCalendar(ctx(), "uid-2", work, []calendar.Event{{ID: "review", CalendarID: work, Start: at(2, 9), End: at(2, 10)}}))
+
+		got, err := repo.GetSyncState(ctx(), "uid-1", work)
+		requireNoError(t, err)
+		if got == nil || got.UID != "uid-1" || got.CalendarID != work || got.SyncToken != "token-1" || !got.WindowStart.Equal(state.WindowStart) || !got.WindowEnd.Equal(state.WindowEnd) ||
+			!got.LastFullSync.Equal(state.LastFullSync) || !got.LastSyncedAt.Equal(state.LastSyncedAt) {
+			t.Fatalf("unexpected sync state: %+v", got)
+		}
+
+		state.SyncToken = "token-3"
+		requireNoError(t, repo.SaveSyncState(ctx(), state))
+		got, err = repo.GetSyncState(ctx(), "uid-1", work)
+		requireNoError(t, err)
+		if got.SyncToken != "token-3" {
+			t.Fatalf("expected the sync state replaced, got %+v", got)
+		}
+
+		requireNoError(t, repo.DeleteUser(ctx(), "uid-1"))
+		got, err = repo.GetSyncState(ctx(), "uid-1", work)
+		requireNoError(t, err)
+		if got != nil {
+			t.Fatalf("expected the sync state deleted, got %+v", got)
+		}
+		if events := listEvents(t, repo, "uid-1", []string{work}, at(1, 0), at(9, 0)); len(events) != 0 {
+			t.Fatalf("expected the events deleted, got %+v", events)
+		}
+		if other, err := repo.GetSyncState(ctx(), "uid-2", work); err != nil || other == nil {
+			t.Fatalf("expected the other user's state kept, got %+v (%v)", other, err)
+		}
+		if events := listEvents(t, repo, "uid-2", []string{work}, at(1, 0), at(9, 0)); len(events) != 1 {
+			t.Fatalf("expected the other user's events kept, got %+v", events)
+		}
+	})
+}
+
+// listEvents returns the events sorted by ID, as backends return them in any order.
+func listEvents(t *testing.T, repo calendar.EventCacheRepository, uid string, calendarIDs []string, start, end time.Time) []calendar.Event {
+	t.Helper()
+
+	events, err := repo.ListEvents(ctx(), uid, calendarIDs, start, end)
+	requireNoError(t, err)
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events
+}
@@ -0,0 +1,109 @@
+package storagetest
+
+import (
+	"testing"
+
+	"energyjournal/internal/domain/energy"
+)
+
+// EnergyRepository checks an energy.EnergyRepository. newRepo returns an
+// empty repository for each subtest.
+func EnergyRepository(t *testing.T, newRepo func(t *testing.T) energy.EnergyRepository) {
+	t.Run("missing date is not found", func(t *testing.T) {
+		_, err := newRepo(t).GetByDate(ctx(), "uid-1", "2026-03-02")
+		requireNotFound(t, err)
+	})
+
+	t.Run("round-trips every field", func(t *testing.T) {
+		repo := newRepo(t)
+		stress := 4
+		levels := energy.EnergyLevels{
+			UID:                "uid-1",
+			Date:               "2026-03-02",
+			Physical:           3,
+			Mental:             -2,
+			Emotional:          1,
+			StressLevel:        &stress,
+			PhysicalActivity:   "run",
+			Nutrition:          "balanced",
+			SocialInteractions: "dinner",
+			TimeOutdoors:       "1h",
+			Notes:              "long day",
+		}
+		requireNoError(t, repo.Upsert(ctx(), levels))
+
+		got, err := repo.GetByDate(ctx(), "uid-1", "2026-03-02")
+		requireNoError(t, err)
+		if got.UID != levels.UID || got.Date != levels.Date || got.Physical != 3 || got.Mental != -2 || got.Emotional != 1 ||
+			got.PhysicalActivity != "run" || got.Nutrition != "balanced" || got.SocialInteractions != "dinner" ||
+			got.TimeOutdoors != "1h" || got.Notes != "long day" {
+			t.Fatalf("unexpected levels: %+v", got)
+		}
+		if got.SleepQuality != nil {
+			t.Fatalf("expected no sleep quality, got %d", *got.SleepQuality)
+		}
+		if got.StressLevel == nil || *got.StressLevel != 4 {
+			t.Fatalf("expected stress level 4, got %v", got.StressLevel)
+		}
+		if got.CreatedAt.IsZero() || got.UpdatedAt.IsZero() {
+			t.Fatalf("expected timestamps, got %+v", got)
+		}
+	})
+
+	t.Run("upsert keeps createdAt", func(t *testing.T) {
+		repo := newRepo(t)
+		requireNoError(t, repo.Upsert(ctx(), energy.EnergyLevels{UID: "uid-1", Date: "2026-03-02", Physical: 1}))
+		first, err := repo.GetByDate(ctx(), "uid-1", "2026-03-02")
+		requireNoError(t, err)
+
+		sleep := 5
+		requireNoError(t, repo.Upsert(ctx(), energy.EnergyLevels{UID: "uid-1", Date: "2026-03-02", Physical: 2, SleepQuality: &sleep}))
+		second, err := repo.GetByDate(ctx(), "uid-1", "2026-03-02")
+		requireNoError(t, err)
+
+		if !second.CreatedAt.Equal(first.CreatedAt) || second.UpdatedAt.Before(first.UpdatedAt) {
+			t.Fatalf("expected createdAt %v kept, got %+v", first.CreatedAt, second)
+		}
+		if second.Physical != 2 || second.SleepQuality == nil || *second.SleepQuality != 5 {
+			t.Fatalf("expected the update applied, got %+v", second)
+		}
+	})
+
+	t.Run("upsert clears omitted scores", func(t *testing.T) {
+		repo := newRepo(t)
+		sleep, stress := 5, 2
+		requireNoError(t, repo.Upsert(ctx(), energy.EnergyLevels{UID: "uid-1", Date: "2026-03-02", SleepQuality: &sleep, StressLevel: &stress}))
+		requireNoError(t, repo.Upsert(ctx(), energy.EnergyLevels{UID: "uid-1", Date: "2026-03-02", StressLevel: &stress}))
+
+		got, err := repo.GetByDate(ctx(), "uid-1", "2026-03-02")
+		requireNoError(t, err)
+		if got.SleepQuality != nil || got.StressLevel == nil || *got.StressLevel != 2 {
+			t.Fatalf("expected only the stress level kept, got %+v", got)
+		}
+	})
+
+	t.Run("range is inclusive, ordered and per user", func(t *testing.T) {
+		repo := newRepo(t)
+		for _, date := range []string{"2026-03-04", "2026-03-01", "2026-03-03", "2026-03-02", "2026-03-05"} {
+			requireNoError(t, repo.Upsert(ctx(), energy.EnergyLevels{UID: "uid-1", Date: date}))
+		}
+		requireNoError(t, repo.Upsert(ctx(), energy.EnergyLevels{UID: "uid-2", Date: "2026-03-03"}))
+
+		got, err := repo.GetByDateRange(ctx(), "uid-1", "2026-03-02", "2026-03-04")
+		requireNoError(t, err)
+		if len(got) != 3 || got[0].Date != "2026-03-02" || got[1].Date != "2026-03-03" || got[2].Date != "2026-03-04" {
+			t.Fatalf("unexpected range: %+v", got)
+		}
+		for _, levels := range got {
+			if levels.UID != "uid-1" {
+				t.Fatalf("range leaked another user's entry: %+v", levels)
+			}
+		}
+
+		empty, err := repo.GetByDateRange(ctx(), "uid-3", "2026-03-01", "2026-03-31")
+		requireNoError(t, err)
+		if empty == nil || len(empty) != 0 {
+			t.Fatalf("expected an empty, non-nil slice, got %#v", empty)
+		}
+	})
+}
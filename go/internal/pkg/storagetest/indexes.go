@@ -0,0 +1,72 @@
+package storagetest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// RequireFirestoreIndex fails the test unless firestore.indexes.json, at the
+// module root, declares a collection-scoped index on collectionGroup with
+// exactly fields, all ascending. The emulator runs queries without indexes,
+// so this is how a query's production index is checked.
+func RequireFirestoreIndex(t *testing.T, collectionGroup string, fields ...string) {
+	t.Helper()
+
+	raw, err := os.ReadFile(filepath.Join(moduleRoot(t), "firestore.indexes.json"))
+	if err != nil {
+		t.Fatalf("read firestore.indexes.json: %v", err)
+	}
+	var declared struct {
+		Indexes []struct {
+			CollectionGroup string `json:"collectionGroup"`
+			QueryScope      string `json:"queryScope"`
+			Fields          []struct {
+				FieldPath string `json:"fieldPath"`
+				Order     string `json:"order"`
+			} `json:"fields"`
+		} `json:"indexes"`
+	}
+	if err := json.Unmarshal(raw, &declared); err != nil {
+		t.Fatalf("parse firestore.indexes.json: %v", err)
+	}
+
+	for _, index := range declared.Indexes {
+		if index.CollectionGroup != collectionGroup || index.QueryScope != "COLLECTION" || len(index.Fields) != len(fields) {
+			continue
+		}
+		matches := true
+		for i, field := range index.Fields {
+			if field.FieldPath != fields[i] || field.Order != "ASCENDING" {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return
+		}
+	}
+	t.Fatalf("firestore.indexes.json does not declare %s (%s ASC)", collectionGroup, strings.Join(fields, " ASC, "))
+}
+
+// moduleRoot returns the closest parent of the working directory holding go.mod.
+func moduleRoot(t *testing.T) string {
+	t.Helper()
+
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			t.Fatal("go.mod not found")
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,117 @@
+package storagetest
+
+import (
+	"testing"
+
+	"energyjournal/internal/domain/user"
+)
+
+// UserRepository checks a user.UserRepository.
+func UserRepository(t *testing.T, newRepo func(t *testing.T) user.UserRepository) {
+	t.Run("missing user is not found", func(t *testing.T) {
+		_, err := newRepo(t).GetByUID(ctx(), "uid-1")
+		requireNotFound(t, err)
+	})
+
+	t.Run("create, read and update", func(t *testing.T) {
+		repo := newRepo(t)
+		u := &user.User{
+			UID:       "uid-1",
+			Email:     "ana@example.com",
+			FirstName: "Ana",
+			LastName:  "Lima",
+			Timezone:  "Europe/Paris",
+			Status:    user.StatusPendingValidation,
+			CreatedAt: at(2, 9),
+		}
+		requireNoError(t, repo.Create(ctx(), u))
+
+		got, err := repo.GetByUID(ctx(), "uid-1")
+		requireNoError(t, err)
+		if got.Email != u.Email || got.FirstName != "Ana" || got.LastName != "Lima" || got.Timezone != "Europe/Paris" ||
+			got.Status != user.StatusPendingValidation || !got.CreatedAt.Equal(u.CreatedAt) || got.DeletedAt != nil {
+			t.Fatalf("unexpected user: %+v", got)
+		}
+
+		deletedAt := at(5, 12)
+		u.Status = user.StatusDeleted
+		u.DeletedAt = &deletedAt
+		requireNoError(t, repo.Update(ctx(), u))
+
+		got, err = repo.GetByUID(ctx(), "uid-1")
+		requireNoError(t, err)
+		if got.Status != user.StatusDeleted || got.DeletedAt == nil || !got.DeletedAt.Equal(deletedAt) || !got.CreatedAt.Equal(u.CreatedAt) {
+			t.Fatalf("unexpected updated user: %+v", got)
+		}
+	})
+}
+
+// ActivationTokenRepository checks a user.ActivationTokenRepository.
+func ActivationTokenRepository(t *testing.T, newRepo func(t *testing.T) user.ActivationTokenRepository) {
+	t.Run("missing token is not found", func(t *testing.T) {
+		_, err := newRepo(t).GetByToken(ctx(), "nope")
+		requireNotFound(t, err)
+	})
+
+	t.Run("create, find expired and delete", func(t *testing.T) {
+		repo := newRepo(t)
+		expired := &user.ActivationToken{Token: "expired", UID: "uid-1", ExpiresAt: at(1, 0)}
+		valid := &user.ActivationToken{Token: "valid", UID: "uid-2", ExpiresAt: at(1, 0).AddDate(100, 0, 0)}
+		requireNoError(t, repo.Create(ctx(), expired))
+		requireNoError(t, repo.Create(ctx(), valid))
+
+		got, err := repo.GetByToken(ctx(), "valid")
+		requireNoError(t, err)
+		if got.Token != "valid" || got.UID != "uid-2" || !got.ExpiresAt.Equal(valid.ExpiresAt) {
+			t.Fatalf("unexpected token: %+v", got)
+		}
+
+		found, err := repo.FindExpired(ctx())
+		requireNoError(t, err)
+		if len(found) != 1 || found[0].Token != "expired" || found[0].UID != "uid-1" {
+			t.Fatalf("unexpected expired tokens: %+v", found)
+		}
+
+		requireNoError(t, repo.Delete(ctx(), "expired"))
+		_, err = repo.GetByToken(ctx(), "expired")
+		requireNotFound(t, err)
+		requireNoError(t, repo.Delete(ctx(), "expired"))
+	})
+}
+
+// PreferencesRepository checks a user.PreferencesRepository.
+func PreferencesRepository(t *testing.T, newRepo func(t *testing.T) user.PreferencesRepository) {
+	t.Run("missing preferences are not found", func(t *testing.T) {
+		_, err := newRepo(t).Get(ctx(), "uid-1")
+		requireNotFound(t, err)
+	})
+
+	t.Run("upsert replaces the preferences", func(t *testing.T) {
+		repo := newRepo(t)
+		prefs := user.Preferences{
+			UID:          "uid-1",
+			ReminderTime: "20:30",
+			ReminderDays: []string{"mon", "thu"},
+			DigestOptIn:  true,
+			Channels:     []user.NotificationChannel{user.ChannelEmail, user.ChannelPush},
+			Locale:       "fr",
+			UpdatedAt:    at(2, 9),
+		}
+		requireNoError(t, repo.Upsert(ctx(), prefs))
+
+		got, err := repo.Get(ctx(), "uid-1")
+		requireNoError(t, err)
+		if got.UID != "uid-1" || got.ReminderTime != "20:30" || len(got.ReminderDays) != 2 || got.ReminderDays[1] != "thu" ||
+			!got.DigestOptIn || len(got.Channels) != 2 || got.Channels[1] != user.ChannelPush || got.Locale != "fr" ||
+			!got.UpdatedAt.Equal(prefs.UpdatedAt) {
+			t.Fatalf("unexpected preferences: %+v", got)
+		}
+
+		requireNoError(t, repo.Upsert(ctx(), user.Preferences{UID: "uid-1", ReminderTime: "08:00", Locale: "en", UpdatedAt: at(3, 9)}))
+		got, err = repo.Get(ctx(), "uid-1")
+		requireNoError(t, err)
+		if got.ReminderTime != "08:00" || len(got.ReminderDays) != 0 || got.DigestOptIn || len(got.Channels) != 0 || got.Locale != "en" {
+			t.Fatalf("unexpected replaced preferences: %+v", got)
+		}
+	})
+}
@@ -0,0 +1,202 @@
+// Package telemetry sets up OpenTelemetry tracing and metrics. Code records
+// spans and measurements through the global providers, which discard them
+// until Setup installs exporters: OTLP, and Prometheus for the container.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Options selects where telemetry is exported.
+type Options struct {
+	ServiceName string
+	// OTLPEndpoint is the base URL of an OTLP/HTTP collector receiving traces
+	// and metrics; nothing is pushed when it is empty.
+	OTLPEndpoint string
+	// Prometheus makes MetricsHandler serve the metrics.
+	Prometheus bool
+}
+
+// Setup installs the global tracer and meter providers opts asks for. The
+// returned function flushes and stops the exporters.
+func Setup(ctx context.Context, opts Options) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if opts.OTLPEndpoint == "" && !opts.Prometheus {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(attribute.String("service.name", opts.ServiceName)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	meterOptions := []sdkmetric.Option{sdkmetric.WithResource(res), sdkmetric.WithView(serverMetricsView)}
+	var shutdowns []func(context.Context) error
+
+	if opts.OTLPEndpoint != "" {
+		endpoint := strings.TrimSuffix(opts.OTLPEndpoint, "/")
+		traceExporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint+"/v1/traces"))
+		if err != nil {
+			return nil, err
+		}
+		metricExporter, err := otlpmetrichttp.New(ctx, otlpmetrichttp.WithEndpointURL(endpoint+"/v1/metrics"))
+		if err != nil {
+			return nil, err
+		}
+		tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(traceExporter), sdktrace.WithResource(res))
+		otel.SetTracerProvider(tracerProvider)
+		shutdowns = append(shutdowns, tracerProvider.Shutdown)
+		meterOptions = append(meterOptions, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)))
+	}
+	if opts.Prometheus {
+		reader, err := otelprometheus.New(otelprometheus.WithRegisterer(registry), otelprometheus.WithoutScopeInfo())
+		if err != nil {
+			return nil, err
+		}
+		meterOptions = append(meterOptions, sdkmetric.WithReader(reader))
+	}
+
+	meterProvider := sdkmetric.NewMeterProvider(meterOptions...)
+	otel.SetMeterProvider(meterProvider)
+	shutdowns = append(shutdowns, meterProvider.Shutdown)
+	return func(ctx context.Context) error {
+		var errs []error
+		for _, shutdown := range shutdowns {
+			errs = append(errs, shutdown(ctx))
+		}
+		return errors.Join(errs...)
+	}, nil
+}
+
+// serverMetricsView keeps the labels of the HTTP server metrics bounded: the
+// Host header and protocol a client sends are dropped, the route is kept.
+var serverMetricsView = sdkmetric.NewView(
+	sdkmetric.Instrument{Name: "http.server.*"},
+	sdkmetric.Stream{AttributeFilter: attribute.NewAllowKeysFilter(
+		"http.request.method", "http.route", "http.response.status_code",
+	)},
+)
+
+// registry holds the metrics MetricsHandler serves, with Go runtime and
+// process metrics.
+var registry = prometheus.NewRegistry()
+
+func init() {
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+}
+
+// MetricsHandler serves the metrics in the Prometheus text format, once Setup
+// has run with Options.Prometheus.
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Counter returns the counter name in the meter of scope, a no-op counter if
+// name is invalid.
+func Counter(scope, name, description string) metric.Int64Counter {
+	counter, err := otel.Meter(scope).Int64Counter(name, metric.WithDescription(description))
+	if err != nil {
+		otel.Handle(err)
+	}
+	return counter
+}
+
+// Flush exports the spans and metrics recorded so far. On Lambda it runs
+// after each invocation, since the environment is frozen between them.
+func Flush(ctx context.Context) error {
+	var errs []error
+	if provider, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider); ok {
+		errs = append(errs, provider.ForceFlush(ctx))
+	}
+	if provider, ok := otel.GetMeterProvider().(*sdkmetric.MeterProvider); ok {
+		errs = append(errs, provider.ForceFlush(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// End records err, if any, on span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// untraced paths are called every few seconds by load balancers and scrapers.
+var untraced = map[string]bool{"/healthz": true, "/readyz": true, "/metrics": true}
+
+// Handler traces the requests served by next and records their duration,
+// method, status and route (the http.ServeMux pattern) as RED metrics. next
+// must hand the request to the mux unchanged, so that the pattern the mux
+// sets is visible once it returns. Probes and scrapes are left out.
+func Handler(next http.Handler) http.Handler {
+	routed := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		if route := Route(r); route != "" {
+			attr := attribute.String("http.route", route)
+			trace.SpanFromContext(r.Context()).SetAttributes(attr)
+			if labeler, ok := otelhttp.LabelerFromContext(r.Context()); ok {
+				labeler.Add(attr)
+			}
+		}
+	})
+	return otelhttp.NewHandler(routed, "http.server",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			if route := Route(r); route != "" {
+				return r.Method + " " + route
+			}
+			return r.Method
+		}),
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return !untraced[r.URL.Path]
+		}),
+	)
+}
+
+// Route returns the path of the mux pattern r matched, such as
+// /calendar/categories/{id}, or "" before routing or when nothing matched.
+func Route(r *http.Request) string {
+	if i := strings.IndexByte(r.Pattern, '/'); i >= 0 {
+		return r.Pattern[i:]
+	}
+	return ""
+}
+
+// Transport wraps base so that each request it sends is traced, carries the
+// trace context and is measured.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(base, otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return r.Method + " " + r.URL.Host
+	}))
+}
+
+var client = &http.Client{Transport: Transport(http.DefaultTransport)}
+
+// HTTPClient returns a client sending requests through Transport.
+func HTTPClient() *http.Client {
+	return client
+}
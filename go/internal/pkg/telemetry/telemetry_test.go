@@ -0,0 +1,186 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// record installs in-memory global providers for the duration of the test.
+// Tests using it cannot run in parallel.
+func record(t *testing.T) (*tracetest.SpanRecorder, *sdkmetric.ManualReader) {
+	t.Helper()
+	tracerProvider, meterProvider := otel.GetTracerProvider(), otel.GetMeterProvider()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(tracerProvider)
+		otel.SetMeterProvider(meterProvider)
+	})
+
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	return spans, reader
+}
+
+func TestHandlerNamesSpansAndMetricsAfterTheRoute(t *testing.T) {
+	spans, reader := record(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /calendar/categories/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/healthz", func(http.ResponseWriter, *http.Request) {})
+	handler := Handler(mux)
+
+	for _, path := range []string{"/calendar/categories/42", "/healthz"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	ended := spans.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("expected one span, probes excluded, got %d", len(ended))
+	}
+	if ended[0].Name() != "GET /calendar/categories/{id}" {
+		t.Fatalf("unexpected span name %q", ended[0].Name())
+	}
+	if !hasAttribute(ended[0].Attributes(), "http.route", "/calendar/categories/{id}") {
+		t.Fatalf("expected the route on the span, got %v", ended[0].Attributes())
+	}
+
+	var metrics metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &metrics); err != nil {
+		t.Fatal(err)
+	}
+	duration, ok := findHistogram(metrics, "http.server.request.duration")
+	if !ok || len(duration.DataPoints) != 1 {
+		t.Fatalf("expected one request duration series, got %+v", duration)
+	}
+	point := duration.DataPoints[0]
+	route, _ := point.Attributes.Value("http.route")
+	status, _ := point.Attributes.Value("http.response.status_code")
+	if route.AsString() != "/calendar/categories/{id}" || status.AsInt64() != http.StatusNotFound || point.Count != 1 {
+		t.Fatalf("unexpected request duration point: %v count %d", point.Attributes.ToSlice(), point.Count)
+	}
+}
+
+func TestTransportPropagatesTheTraceContext(t *testing.T) {
+	spans, _ := record(t)
+	if _, err := Setup(context.Background(), Options{ServiceName: "energyjournal"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var traceparent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer upstream.Close()
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL, nil)
+	resp, err := (&http.Client{Transport: Transport(http.DefaultTransport)}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	parent.End()
+
+	traceID := parent.SpanContext().TraceID().String()
+	if len(traceparent) < 35 || traceparent[3:35] != traceID {
+		t.Fatalf("expected traceparent of trace %s, got %q", traceID, traceparent)
+	}
+	ended := spans.Ended()
+	if len(ended) != 2 || ended[0].Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatalf("expected a client span under the parent, got %d spans", len(ended))
+	}
+}
+
+func TestMetricsHandlerServesPrometheusMetrics(t *testing.T) {
+	tracerProvider, meterProvider := otel.GetTracerProvider(), otel.GetMeterProvider()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(tracerProvider)
+		otel.SetMeterProvider(meterProvider)
+	})
+	shutdown, err := Setup(context.Background(), Options{ServiceName: "energyjournal", Prometheus: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(context.Background())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /energy", func(w http.ResponseWriter, r *http.Request) {
+		Counter("test", "energyjournal.test.saves", "Saves.").Add(r.Context(), 1)
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.Handle("GET /metrics", MetricsHandler())
+	handler := Handler(mux)
+	for range 2 {
+		req := httptest.NewRequest(http.MethodPost, "/energy", nil)
+		req.Host = "attacker-chosen.example"
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rr.Body.String()
+	for _, want := range []string{
+		`http_server_request_duration_seconds_count{http_request_method="POST",http_response_status_code="201",http_route="/energy"} 2`,
+		`energyjournal_test_saves_total 2`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %s in the metrics, got:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "attacker-chosen") || strings.Contains(body, `http_route="/metrics"`) {
+		t.Errorf("expected only bounded labels and no scrape metrics, got:\n%s", body)
+	}
+}
+
+func TestEndRecordsErrors(t *testing.T) {
+	spans, _ := record(t)
+
+	_, failed := otel.Tracer("test").Start(context.Background(), "failed")
+	End(failed, errors.New("boom"))
+	_, succeeded := otel.Tracer("test").Start(context.Background(), "succeeded")
+	End(succeeded, nil)
+
+	ended := spans.Ended()
+	if ended[0].Status().Code != codes.Error || ended[0].Status().Description != "boom" || len(ended[0].Events()) != 1 {
+		t.Fatalf("expected the error recorded, got %+v", ended[0].Status())
+	}
+	if ended[1].Status().Code != codes.Unset {
+		t.Fatalf("expected no status on success, got %+v", ended[1].Status())
+	}
+}
+
+func hasAttribute(attrs []attribute.KeyValue, key, value string) bool {
+	for _, attr := range attrs {
+		if string(attr.Key) == key && attr.Value.AsString() == value {
+			return true
+		}
+	}
+	return false
+}
+
+func findHistogram(metrics metricdata.ResourceMetrics, name string) (metricdata.Histogram[float64], bool) {
+	for _, scope := range metrics.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if h, ok := m.Data.(metricdata.Histogram[float64]); ok && m.Name == name {
+				return h, true
+			}
+		}
+	}
+	return metricdata.Histogram[float64]{}, false
+}
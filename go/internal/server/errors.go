@@ -2,21 +2,25 @@ package server
 
 import (
 	"encoding/json"
-	"errors"
 	"net/http"
 
-	errpkg "energyjournal/internal/pkg/error"
 	"energyjournal/internal/pkg/httputil"
 )
 
+// WriteError answers calendar errors in JSON, as the calendar endpoints do,
+// and anything else as httputil.WriteError does.
 func WriteError(w http.ResponseWriter, err error) {
-	var calendarErr *errpkg.CalendarNotConnectedError
-	if errors.As(err, &calendarErr) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusFailedDependency)
-		_ = json.NewEncoder(w).Encode(map[string]string{"error": calendarErr.Error()})
+	statusCode, message := httputil.MapErrors(err)
+	if statusCode != http.StatusFailedDependency {
+		httputil.WriteError(w, err)
 		return
 	}
 
-	httputil.WriteError(w, err)
+	body := map[string]string{"error": message}
+	if code := httputil.ErrorCode(err); code != "" {
+		body["code"] = code
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(body)
 }
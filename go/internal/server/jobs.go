@@ -0,0 +1,29 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"energyjournal/internal/pkg/scheduler"
+)
+
+// jobsTokenHeader carries the shared secret required to trigger jobs over HTTP.
+const jobsTokenHeader = "X-Jobs-Token"
+
+// triggerJob runs a background job on demand. It lets an external scheduler
+// drive jobs where no long-running process exists, such as on Lambda.
+func triggerJob(jobs *scheduler.Scheduler, token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		provided := r.Header.Get(jobsTokenHeader)
+		if provided == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if err := jobs.RunNow(r.Context(), r.PathValue("name")); err != nil {
+			WriteError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
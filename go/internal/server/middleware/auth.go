@@ -57,6 +57,7 @@ func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 			return
 		}
 
+		recordUID(r.Context(), decoded.UID)
 		ctx := context.WithValue(r.Context(), ContextKeyUID, decoded.UID)
 		if email, ok := decoded.Claims["email"].(string); ok {
 			ctx = context.WithValue(ctx, ContextKeyEmail, email)
@@ -85,6 +86,7 @@ func (m *AuthMiddleware) RequireActiveUser(next http.Handler) http.Handler {
 			return
 		}
 
+		recordUID(r.Context(), decoded.UID)
 		u, err := m.userRepo.GetByUID(r.Context(), decoded.UID)
 		if err != nil {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RequestIDHeader carries the request ID, taken from the caller when valid so
+// that a request can be followed across services.
+const RequestIDHeader = "X-Request-ID"
+
+const contextKeyRequestLog ContextKey = "request_log"
+
+// requestLog is the per-request logging state. The UID is recorded by the
+// auth middleware, deeper in the chain than RequestLogger.
+type requestLog struct {
+	id    string
+	entry *logrus.Entry
+
+	mu  sync.Mutex
+	uid string
+}
+
+// RequestLogger assigns every request an ID, echoed in the X-Request-ID
+// response header, stores a logger carrying it in the request context and
+// writes an access log line once the request completes. Query strings are left
+// out of the logged path since they may hold tokens.
+func RequestLogger(logger *logrus.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			started := time.Now()
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, id)
+
+			state := &requestLog{id: id, entry: logger.WithField("request_id", id)}
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), contextKeyRequestLog, state)))
+
+			fields := logrus.Fields{
+				"method":     r.Method,
+				"path":       r.URL.Path,
+				"status":     recorder.status,
+				"bytes":      recorder.bytes,
+				"latency_ms": float64(time.Since(started).Microseconds()) / 1000,
+			}
+			if uid := state.userID(); uid != "" {
+				fields["uid"] = uid
+			}
+			entry := state.entry.WithFields(fields)
+			switch {
+			case recorder.status >= http.StatusInternalServerError:
+				entry.Error("request completed")
+			case recorder.status >= http.StatusBadRequest:
+				entry.Warn("request completed")
+			default:
+				entry.Info("request completed")
+			}
+		})
+	}
+}
+
+// Logger returns the logger of the request ctx belongs to, carrying its
+// request ID and, once authenticated, its UID. Outside requests it returns
+// the standard logger.
+func Logger(ctx context.Context) *logrus.Entry {
+	state, ok := ctx.Value(contextKeyRequestLog).(*requestLog)
+	if !ok {
+		return logrus.NewEntry(logrus.StandardLogger())
+	}
+	if uid := state.userID(); uid != "" {
+		return state.entry.WithField("uid", uid)
+	}
+	return state.entry
+}
+
+// RequestIDFromContext returns the ID RequestLogger gave the request.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	state, ok := ctx.Value(contextKeyRequestLog).(*requestLog)
+	if !ok {
+		return "", false
+	}
+	return state.id, true
+}
+
+// recordUID attaches the authenticated UID to the request's logs.
+func recordUID(ctx context.Context, uid string) {
+	if state, ok := ctx.Value(contextKeyRequestLog).(*requestLog); ok {
+		state.mu.Lock()
+		state.uid = uid
+		state.mu.Unlock()
+	}
+}
+
+func (s *requestLog) userID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.uid
+}
+
+// validRequestID accepts caller IDs of up to 128 letters, digits, '-', '_' and '.'.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// statusRecorder captures the status and size of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
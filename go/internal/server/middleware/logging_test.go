@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/sirupsen/logrus"
+)
+
+type stubVerifier struct{}
+
+func (stubVerifier) VerifyIDToken(_ context.Context, token string) (*auth.Token, error) {
+	return &auth.Token{UID: "uid-" + token}, nil
+}
+
+func TestRequestLoggerLogsRequestsWithIDAndUID(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&out)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	var handlerRequestID string
+	protected := NewAuthMiddlewareWithVerifier(stubVerifier{}, nil).RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerRequestID, _ = RequestIDFromContext(r.Context())
+		Logger(r.Context()).Info("inside handler")
+		w.WriteHeader(http.StatusTeapot)
+		_, _ = w.Write([]byte("short and stout"))
+	}))
+	handler := RequestLogger(logger)(protected)
+
+	req := httptest.NewRequest(http.MethodGet, "/calendar/status?token=secret", nil)
+	req.Header.Set("Authorization", "Bearer 1")
+	req.Header.Set(RequestIDHeader, "caller-id.42")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get(RequestIDHeader) != "caller-id.42" || handlerRequestID != "caller-id.42" {
+		t.Fatalf("expected the caller's request ID propagated, got header %q and context %q", rr.Header().Get(RequestIDHeader), handlerRequestID)
+	}
+	decoder := json.NewDecoder(&out)
+	var inside, access map[string]any
+	if err := decoder.Decode(&inside); err != nil {
+		t.Fatal(err)
+	}
+	if err := decoder.Decode(&access); err != nil {
+		t.Fatal(err)
+	}
+	if inside["request_id"] != "caller-id.42" || inside["uid"] != "uid-1" {
+		t.Fatalf("unexpected handler log: %v", inside)
+	}
+	if access["msg"] != "request completed" || access["request_id"] != "caller-id.42" || access["uid"] != "uid-1" ||
+		access["method"] != "GET" || access["path"] != "/calendar/status" || access["status"] != float64(http.StatusTeapot) ||
+		access["bytes"] != float64(len("short and stout")) || access["level"] != "warning" {
+		t.Fatalf("unexpected access log: %v", access)
+	}
+	if _, ok := access["latency_ms"].(float64); !ok {
+		t.Fatalf("expected a latency, got %v", access)
+	}
+}
+
+func TestRequestLoggerReplacesInvalidRequestIDs(t *testing.T) {
+	t.Parallel()
+
+	logger := logrus.New()
+	logger.SetOutput(&bytes.Buffer{})
+	handler := RequestLogger(logger)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	req.Header.Set(RequestIDHeader, "bad id\nwith newline")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	id := rr.Header().Get(RequestIDHeader)
+	if id == "" || id == req.Header.Get(RequestIDHeader) || !validRequestID(id) {
+		t.Fatalf("expected a generated request ID, got %q", id)
+	}
+}
@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	pkgerror "energyjournal/internal/pkg/error"
+	"energyjournal/internal/pkg/httputil"
+	"energyjournal/internal/pkg/ratelimit"
+	"energyjournal/internal/pkg/telemetry"
+)
+
+// maxEmailPeek bounds how much of a request body is read to find its email.
+const maxEmailPeek = 64 << 10
+
+var rateLimited = telemetry.Counter("energyjournal/internal/server/middleware", "energyjournal.http.rate_limited",
+	"Requests refused with 429 Too Many Requests, by route.")
+
+// RateLimiter refuses requests beyond the limit of their route with 429 Too
+// Many Requests and a Retry-After header. A request counts against its
+// client, the authenticated user or else the client IP, and against the email
+// in its JSON body, if any, so that one account cannot be brute-forced from
+// many addresses.
+type RateLimiter struct {
+	store  ratelimit.Store
+	limits map[string]ratelimit.Limit
+	// trustForwardedFor takes the client IP from X-Forwarded-For, as set by
+	// the load balancer in front of the server.
+	trustForwardedFor bool
+	now               func() time.Time
+}
+
+// NewRateLimiter limits the routes of limits, keyed by mux pattern such as
+// "POST /users/login", keeping buckets in store.
+func NewRateLimiter(store ratelimit.Store, limits map[string]ratelimit.Limit, trustForwardedFor bool) *RateLimiter {
+	return &RateLimiter{store: store, limits: limits, trustForwardedFor: trustForwardedFor, now: time.Now}
+}
+
+// Limit wraps the handler of route. Routes without a limit, or a nil
+// RateLimiter, leave next unwrapped. For the user to be known, Limit goes
+// inside the auth middleware.
+func (l *RateLimiter) Limit(route string, next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	limit, ok := l.limits[route]
+	if !ok {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, authenticated := l.clientKey(r)
+		keys := []string{route + "|" + client}
+		if !authenticated {
+			if email := peekEmail(r); email != "" {
+				keys = append(keys, route+"|email:"+email)
+			}
+		}
+
+		// Keys are taken in order up to the first refusal, so that a client
+		// refused by its own limit does not drain the bucket of the email.
+		now := l.now()
+		var retryAfter time.Duration
+		for _, key := range keys {
+			allowed, wait, err := l.store.Take(r.Context(), key, limit, now)
+			if err != nil {
+				// An unavailable store should not take the API down with it.
+				Logger(r.Context()).WithError(err).Warn("Rate limit store failed, letting the request through")
+				continue
+			}
+			if !allowed {
+				retryAfter = wait
+				break
+			}
+		}
+		if retryAfter > 0 {
+			rateLimited.Add(r.Context(), 1, metric.WithAttributes(attribute.String("route", route)))
+			httputil.WriteError(w, &pkgerror.RateLimitError{Message: "Too many requests", RetryAfter: retryAfter})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientKey names who sent r: the authenticated user, or else the client IP.
+func (l *RateLimiter) clientKey(r *http.Request) (key string, authenticated bool) {
+	if u, ok := UserFromContext(r.Context()); ok {
+		return "uid:" + u.UID, true
+	}
+	if uid, ok := UIDFromContext(r.Context()); ok {
+		return "uid:" + uid, true
+	}
+	return "ip:" + l.clientIP(r), false
+}
+
+// clientIP returns the address r came from. Only the last X-Forwarded-For
+// entry is used, since the ones before it are whatever the client sent.
+// IPv6 addresses are grouped by /64, the block a single host usually gets.
+func (l *RateLimiter) clientIP(r *http.Request) string {
+	addr := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	if l.trustForwardedFor {
+		if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+			entries := strings.Split(forwarded[len(forwarded)-1], ",")
+			if last := strings.TrimSpace(entries[len(entries)-1]); last != "" {
+				addr = last
+			}
+		}
+	}
+
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return addr
+	}
+	if ip.To4() == nil {
+		return ip.Mask(net.CIDRMask(64, 128)).String() + "/64"
+	}
+	return ip.String()
+}
+
+// peekEmail returns a hash of the email in r's JSON body, leaving the body
+// unread for the handler. The hash keeps emails out of shared stores.
+func peekEmail(r *http.Request) string {
+	if r.Body == nil || r.Body == http.NoBody {
+		return ""
+	}
+	peeked, err := io.ReadAll(io.LimitReader(r.Body, maxEmailPeek))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peeked), r.Body), r.Body}
+	if err != nil {
+		return ""
+	}
+
+	var body struct {
+		Email string `json:"email"`
+	}
+	if json.Unmarshal(peeked, &body) != nil {
+		return ""
+	}
+	email := strings.ToLower(strings.TrimSpace(body.Email))
+	if email == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(email))
+	return hex.EncodeToString(sum[:])
+}
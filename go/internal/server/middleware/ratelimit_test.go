@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"energyjournal/internal/pkg/ratelimit"
+)
+
+const loginRoute = "POST /users/login"
+
+// echoBody answers with the request body, to check that limiting left it intact.
+var echoBody = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	_, _ = io.Copy(w, r.Body)
+})
+
+func newTestLimiter(store ratelimit.Store, trustForwardedFor bool) *RateLimiter {
+	limiter := NewRateLimiter(store, map[string]ratelimit.Limit{loginRoute: {Burst: 2, Every: 10 * time.Second}}, trustForwardedFor)
+	start := time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return start }
+	return limiter
+}
+
+func login(handler http.Handler, remoteAddr, body string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/users/login", strings.NewReader(body))
+	req.RemoteAddr = remoteAddr
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRateLimiterLimitsByIPAndEmail(t *testing.T) {
+	t.Parallel()
+
+	handler := newTestLimiter(ratelimit.NewMemoryStore(), false).Limit(loginRoute, echoBody)
+	body := `{"email":"Ada@example.com","password":"secret"}`
+	for i := range 2 {
+		if rr := login(handler, "192.0.2.1:1234", body, nil); rr.Code != http.StatusOK || rr.Body.String() != body {
+			t.Fatalf("expected attempt %d served with its body intact, got %d %q", i+1, rr.Code, rr.Body.String())
+		}
+	}
+
+	rr := login(handler, "192.0.2.1:5678", `{"email":"grace@example.com"}`, nil)
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") != "10" {
+		t.Fatalf("expected the IP limited with Retry-After 10, got %d %q", rr.Code, rr.Header().Get("Retry-After"))
+	}
+	if rr := login(handler, "198.51.100.7:1234", `{"email":" ada@EXAMPLE.com "}`, nil); rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the email limited from another IP, got %d", rr.Code)
+	}
+	if rr := login(handler, "198.51.100.8:1234", `{"email":"grace@example.com"}`, nil); rr.Code != http.StatusOK {
+		t.Fatalf("expected another client and email served, got %d", rr.Code)
+	}
+}
+
+func TestRateLimiterLeavesEmailBucketToRefusedClients(t *testing.T) {
+	t.Parallel()
+
+	handler := newTestLimiter(ratelimit.NewMemoryStore(), false).Limit(loginRoute, echoBody)
+	for range 4 {
+		login(handler, "192.0.2.1:1234", `{"email":"mallory@example.com"}`, nil)
+	}
+	body := `{"email":"ada@example.com"}`
+	for range 3 {
+		if rr := login(handler, "192.0.2.1:1234", body, nil); rr.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected the IP limited, got %d", rr.Code)
+		}
+	}
+	if rr := login(handler, "198.51.100.7:1234", body, nil); rr.Code != http.StatusOK {
+		t.Fatalf("expected the email untouched by refused attempts, got %d", rr.Code)
+	}
+}
+
+func TestRateLimiterKeysOnForwardedForOnlyWhenTrusted(t *testing.T) {
+	t.Parallel()
+
+	for _, trusted := range []bool{false, true} {
+		handler := newTestLimiter(ratelimit.NewMemoryStore(), trusted).Limit(loginRoute, echoBody)
+		codes := make([]int, 0, 3)
+		for _, client := range []string{"203.0.113.1", "203.0.113.2", "203.0.113.3"} {
+			// The proxy appends the client it saw; earlier entries are forged.
+			header := http.Header{"X-Forwarded-For": {"10.0.0.1, " + client}}
+			codes = append(codes, login(handler, "10.1.1.1:443", "", header).Code)
+		}
+		if limited := codes[2] == http.StatusTooManyRequests; limited == trusted {
+			t.Fatalf("trusting X-Forwarded-For %v, got %v", trusted, codes)
+		}
+	}
+}
+
+func TestRateLimiterGroupsIPv6ByPrefix(t *testing.T) {
+	t.Parallel()
+
+	handler := newTestLimiter(ratelimit.NewMemoryStore(), false).Limit(loginRoute, echoBody)
+	for i, addr := range []string{"[2001:db8::1]:1", "[2001:db8::2]:1", "[2001:db8::3]:1"} {
+		if rr := login(handler, addr, "", nil); (rr.Code == http.StatusTooManyRequests) != (i == 2) {
+			t.Fatalf("expected the /64 limited on the third attempt, got %d for %s", rr.Code, addr)
+		}
+	}
+}
+
+func TestRateLimiterKeysAuthenticatedRequestsByUID(t *testing.T) {
+	t.Parallel()
+
+	limiter := newTestLimiter(ratelimit.NewMemoryStore(), false)
+	handler := NewAuthMiddlewareWithVerifier(stubVerifier{}, nil).RequireAuth(limiter.Limit(loginRoute, echoBody))
+	send := func(token string) int {
+		header := http.Header{"Authorization": {"Bearer " + token}}
+		return login(handler, "192.0.2.1:1234", `{"email":"ada@example.com"}`, header).Code
+	}
+
+	if codes := []int{send("1"), send("1"), send("1")}; codes[2] != http.StatusTooManyRequests {
+		t.Fatalf("expected the user limited on the third request, got %v", codes)
+	}
+	if code := send("2"); code != http.StatusOK {
+		t.Fatalf("expected another user on the same IP and email served, got %d", code)
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, ratelimit.Limit, time.Time) (bool, time.Duration, error) {
+	return false, 0, errors.New("store unavailable")
+}
+
+func TestRateLimiterFailsOpen(t *testing.T) {
+	t.Parallel()
+
+	handler := newTestLimiter(failingStore{}, false).Limit(loginRoute, echoBody)
+	for range 3 {
+		if rr := login(handler, "192.0.2.1:1234", "", nil); rr.Code != http.StatusOK {
+			t.Fatalf("expected requests served while the store fails, got %d", rr.Code)
+		}
+	}
+}
+
+func TestRateLimiterLeavesOtherRoutesUnwrapped(t *testing.T) {
+	t.Parallel()
+
+	var limiter *RateLimiter
+	if limiter.Limit(loginRoute, echoBody) == nil {
+		t.Fatal("expected a nil limiter to return the handler")
+	}
+	limited := newTestLimiter(ratelimit.NewMemoryStore(), false)
+	for range 3 {
+		if rr := login(limited.Limit("POST /users", echoBody), "192.0.2.1:1234", "", nil); rr.Code != http.StatusOK {
+			t.Fatalf("expected a route without a limit served, got %d", rr.Code)
+		}
+	}
+}
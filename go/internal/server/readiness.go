@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"energyjournal/internal/pkg/health"
+	"energyjournal/internal/server/middleware"
+)
+
+const (
+	// statusShuttingDown is the readiness status once shutdown has begun.
+	statusShuttingDown = "shutting_down"
+	// readinessReportTTL is how long a report answers GET /readyz before the
+	// checks run again, so that probes and anonymous callers do not multiply
+	// the calls made to the dependencies.
+	readinessReportTTL = 3 * time.Second
+)
+
+// Readiness answers GET /readyz by running its checkers concurrently and
+// returning their JSON breakdown, with status 503 when any fails. Failures are
+// logged with their error; the public breakdown only names them. Unlike
+// /healthz, it also fails once shutdown has begun, so that load balancers stop
+// routing new requests to the instance while the requests in flight complete.
+// Reports are reused for readinessReportTTL, and concurrent requests share
+// one run of the checks.
+type Readiness struct {
+	timeout      time.Duration
+	checkers     []health.Checker
+	shuttingDown atomic.Bool
+
+	ttl       time.Duration
+	runs      singleflight.Group
+	mu        sync.Mutex
+	last      health.Report
+	checkedAt time.Time
+	now       func() time.Time
+}
+
+// NewReadiness runs checkers when a request finds no recent report, giving
+// each at most timeout.
+func NewReadiness(timeout time.Duration, checkers ...health.Checker) *Readiness {
+	return &Readiness{timeout: timeout, checkers: checkers, ttl: readinessReportTTL, now: time.Now}
+}
+
+// ShuttingDown marks the server as draining; it stays unready from then on.
+func (r *Readiness) ShuttingDown() {
+	r.shuttingDown.Store(true)
+}
+
+func (r *Readiness) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if r.shuttingDown.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = json.NewEncoder(w).Encode(health.Report{Status: statusShuttingDown, Checks: map[string]health.Result{}})
+		return
+	}
+
+	report := r.report(req.Context())
+	if !report.OK() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// report returns the last report while it is recent, and otherwise runs the
+// checks once for every caller waiting on them. Failures are logged by the
+// run, not by each response repeating them.
+func (r *Readiness) report(ctx context.Context) health.Report {
+	r.mu.Lock()
+	if r.now().Sub(r.checkedAt) < r.ttl {
+		report := r.last
+		r.mu.Unlock()
+		return report
+	}
+	r.mu.Unlock()
+
+	shared, _, _ := r.runs.Do("readyz", func() (any, error) {
+		// The run outlives a caller that goes away: others may be waiting on it.
+		report := health.Run(context.WithoutCancel(ctx), r.timeout, r.checkers)
+		for name, result := range report.Checks {
+			if result.Status == health.StatusFailed {
+				middleware.Logger(ctx).WithField("check", name).WithField("error", result.Error).Warn("Readiness check failed")
+			}
+		}
+		r.mu.Lock()
+		r.last, r.checkedAt = report, r.now()
+		r.mu.Unlock()
+		return report, nil
+	})
+	return shared.(health.Report)
+}
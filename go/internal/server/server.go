@@ -1,136 +1,117 @@
 package server
 
 import (
-	"context"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
-	"os"
-	"strings"
 
+	"energyjournal/internal/config"
 	"energyjournal/internal/domain/calendar"
 	"energyjournal/internal/domain/energy"
+	"energyjournal/internal/domain/insights"
 	"energyjournal/internal/domain/user"
 	calendarhandler "energyjournal/internal/handler/calendar"
 	energyhandler "energyjournal/internal/handler/energy"
+	insightshandler "energyjournal/internal/handler/insights"
 	userhandler "energyjournal/internal/handler/user"
-	integgoogle "energyjournal/internal/integration/google"
-	"energyjournal/internal/pkg/firebase"
-	"energyjournal/internal/pkg/firestore"
+	"energyjournal/internal/pkg/scheduler"
+	"energyjournal/internal/pkg/telemetry"
 	"energyjournal/internal/server/middleware"
-	calendarservice "energyjournal/internal/service/calendar"
-	calendarstorage "energyjournal/internal/service/calendar/storage"
-	energyservice "energyjournal/internal/service/energy"
-	energystorage "energyjournal/internal/service/energy/storage"
-	userservice "energyjournal/internal/service/user"
-	userstorage "energyjournal/internal/service/user/storage"
-	"golang.org/x/oauth2"
-	oauth2google "golang.org/x/oauth2/google"
+	"github.com/sirupsen/logrus"
 )
 
-// Dependencies groups external services that the HTTP server needs.
+// Dependencies groups external services that the HTTP server needs. Routes
+// of a missing service are not registered, and routes needing authentication
+// fail closed without AuthMiddleware.
 type Dependencies struct {
-	CalendarService calendar.CalendarService
-	UserService     user.UserService
-	EnergyService   energy.EnergyService
-	AuthMiddleware  *middleware.AuthMiddleware
-	FrontendBaseURL string
+	CalendarService    calendar.CalendarService
+	CategoryService    calendar.CategoryService
+	WatchService       calendar.WatchService
+	UserService        user.UserService
+	PreferencesService user.PreferencesService
+	EnergyService      energy.EnergyService
+	InsightsService    insights.InsightsService
+	AuthMiddleware     *middleware.AuthMiddleware
+	Jobs               *scheduler.Scheduler
+	// Readiness serves GET /readyz when set.
+	Readiness *Readiness
+	// Metrics serves GET /metrics when set.
+	Metrics http.Handler
+	// RateLimiter limits the routes it has a limit for, when set.
+	RateLimiter *middleware.RateLimiter
 }
 
-// New creates the HTTP server with the default routes.
-func New(addr string) *http.Server {
-	mux := http.NewServeMux()
-	ctx := context.Background()
-
-	firebaseClient, err := firebase.NewClient(ctx)
-	if err != nil {
-		log.Fatalf("Failed to initialize Firebase client: %v", err)
-	}
-
-	projectID := os.Getenv("GCP_PROJECT_ID")
-	if projectID == "" {
-		log.Fatal("GCP_PROJECT_ID environment variable is required")
+// NewWithDependencies creates the HTTP server serving deps on cfg.HTTP.Addr.
+func NewWithDependencies(cfg config.Config, deps Dependencies) (*http.Server, error) {
+	if cfg.HTTP.Addr == "" {
+		return nil, errors.New("server: an address to listen on is required")
 	}
-
-	firestoreClient, err := firestore.NewClient(ctx, projectID)
-	if err != nil {
-		log.Fatalf("Failed to initialize Firestore client: %v", err)
+	if cfg.Jobs.TriggerToken != "" && deps.Jobs == nil {
+		return nil, errors.New("server: a jobs trigger token is configured without a job scheduler")
 	}
 
-	userRepo := userstorage.NewUserRepository(firestoreClient.Client)
-	tokenRepo := userstorage.NewActivationTokenRepository(firestoreClient.Client)
-	authProvider := firebase.NewAuthProvider(firebaseClient, os.Getenv("FIREBASE_API_KEY"))
-	emailSender := &noopEmailSender{} // TODO: implement real email sender
-
-	activationBaseURL := lookupEnvOrDefault("FRONTEND_ACTIVATION_BASE_URL", "http://localhost:8080")
-	frontendBaseURL := requiredEnv("FRONTEND_BASE_URL")
-	googleClientID := requiredEnv("GOOGLE_CLIENT_ID")
-	googleClientSecret := requiredEnv("GOOGLE_CLIENT_SECRET")
-	googleRedirectURI := requiredEnv("GOOGLE_OAUTH_REDIRECT_URI")
-	googleStateSecret := requiredEnv("GOOGLE_OAUTH_STATE_SECRET")
-
-	userService := userservice.NewUserService(userRepo, tokenRepo, authProvider, emailSender, activationBaseURL)
-	energyRepo := energystorage.NewEnergyRepository(firestoreClient.Client)
-	energyLevelsService := energyservice.NewEnergyService(energyRepo)
-	authMiddleware := middleware.NewAuthMiddleware(firebaseClient, userRepo)
-	connectionRepo := calendarstorage.NewConnectionRepository(firestoreClient.Client)
-	googleClient := integgoogle.NewGoogleCalendarClient()
-	calendarOAuthConfig := &oauth2.Config{
-		ClientID:     googleClientID,
-		ClientSecret: googleClientSecret,
-		Endpoint:     oauth2google.Endpoint,
-		RedirectURL:  googleRedirectURI,
-		Scopes:       []string{"https://www.googleapis.com/auth/calendar.readonly"},
-	}
-	stateSecret := googleStateSecret
-
-	deps := Dependencies{
-		CalendarService: calendarservice.NewCalendarService(connectionRepo, googleClient, calendarOAuthConfig, stateSecret),
-		UserService:     userService,
-		EnergyService:   energyLevelsService,
-		AuthMiddleware:  authMiddleware,
-		FrontendBaseURL: frontendBaseURL,
-	}
-	register(mux, deps)
-
+	mux := http.NewServeMux()
+	register(mux, cfg, deps)
 	return &http.Server{
-		Addr:    addr,
-		Handler: applyCORS(mux),
-	}
-}
-
-// noopEmailSender is a placeholder email sender that does nothing.
-// TODO: Replace with real implementation (e.g., Brevo, SendGrid).
-type noopEmailSender struct{}
-
-func (s *noopEmailSender) SendActivationEmail(ctx context.Context, email, activationLink string) error {
-	log.Printf("Would send activation email to %s with link %s", email, activationLink)
-	return nil
+		Addr:         cfg.HTTP.Addr,
+		Handler:      middleware.RequestLogger(logrus.StandardLogger())(telemetry.Handler(applyCORS(mux, cfg.HTTP.AllowedOrigins))),
+		ReadTimeout:  cfg.HTTP.ReadTimeout,
+		WriteTimeout: cfg.HTTP.WriteTimeout,
+		IdleTimeout:  cfg.HTTP.IdleTimeout,
+	}, nil
 }
 
 // register wires all HTTP handlers onto the given mux.
-func register(mux *http.ServeMux, deps Dependencies) {
-	mux.HandleFunc("/healthz", health)
+func register(mux *http.ServeMux, cfg config.Config, deps Dependencies) {
+	mux.HandleFunc("/healthz", liveness)
+	if deps.Readiness != nil {
+		mux.Handle("GET /readyz", deps.Readiness)
+	}
+	if deps.Metrics != nil {
+		mux.Handle("GET /metrics", deps.Metrics)
+	}
+	// limit goes inside the auth middleware, so that users are limited by UID.
+	limit := func(pattern string, handler http.HandlerFunc) http.Handler {
+		return deps.RateLimiter.Limit(pattern, handler)
+	}
 
 	if deps.CalendarService != nil && deps.AuthMiddleware != nil {
 		calendarHandler := calendarhandler.NewCalendarHandler(deps.CalendarService)
-		oauthHandler := calendarhandler.NewOAuthHandler(deps.CalendarService, deps.FrontendBaseURL)
+		oauthHandler := calendarhandler.NewOAuthHandler(deps.CalendarService, cfg.Frontend.BaseURL)
 		spendingHandler := calendarhandler.NewSpendingHandler(deps.CalendarService)
 
 		mux.Handle("GET /calendar/status", deps.AuthMiddleware.RequireAuth(http.HandlerFunc(calendarHandler.GetStatus)))
 		mux.Handle("GET /calendar/auth", deps.AuthMiddleware.RequireAuth(http.HandlerFunc(oauthHandler.GetAuthURL)))
 		mux.HandleFunc("GET /calendar/auth/callback", oauthHandler.Callback)
 		mux.Handle("GET /calendar/calendars", deps.AuthMiddleware.RequireAuth(http.HandlerFunc(calendarHandler.GetCalendars)))
+		mux.Handle("POST /calendar/feed", deps.AuthMiddleware.RequireAuth(limit("POST /calendar/feed", calendarHandler.ConnectFeed)))
 		mux.Handle("PUT /calendar/connection", deps.AuthMiddleware.RequireAuth(http.HandlerFunc(calendarHandler.SetConnection)))
+		mux.Handle("DELETE /calendar/connection", deps.AuthMiddleware.RequireAuth(http.HandlerFunc(calendarHandler.Disconnect)))
 		mux.Handle("GET /calendar/spending", deps.AuthMiddleware.RequireAuth(http.HandlerFunc(spendingHandler.GetSpending)))
+
+		if deps.CategoryService != nil {
+			categoryHandler := calendarhandler.NewCategoryHandler(deps.CategoryService)
+			mux.Handle("GET /calendar/categories", deps.AuthMiddleware.RequireAuth(http.HandlerFunc(categoryHandler.ListRules)))
+			mux.Handle("POST /calendar/categories", deps.AuthMiddleware.RequireAuth(limit("POST /calendar/categories", categoryHandler.CreateRule)))
+			mux.Handle("PUT /calendar/categories/{id}", deps.AuthMiddleware.RequireAuth(http.HandlerFunc(categoryHandler.UpdateRule)))
+			mux.Handle("DELETE /calendar/categories/{id}", deps.AuthMiddleware.RequireAuth(http.HandlerFunc(categoryHandler.DeleteRule)))
+			mux.Handle("POST /calendar/categories/preview", deps.AuthMiddleware.RequireAuth(limit("POST /calendar/categories/preview", categoryHandler.Preview)))
+		}
 	} else {
 		// Fail closed when auth middleware is unavailable.
 		for _, route := range []string{
 			"GET /calendar/status",
 			"GET /calendar/auth",
 			"GET /calendar/calendars",
+			"POST /calendar/feed",
 			"PUT /calendar/connection",
+			"DELETE /calendar/connection",
 			"GET /calendar/spending",
+			"GET /calendar/categories",
+			"POST /calendar/categories",
+			"PUT /calendar/categories/{id}",
+			"DELETE /calendar/categories/{id}",
+			"POST /calendar/categories/preview",
 		} {
 			mux.HandleFunc(route, func(w http.ResponseWriter, _ *http.Request) {
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -138,38 +119,67 @@ func register(mux *http.ServeMux, deps Dependencies) {
 		}
 	}
 
+	// Google push notifications - authenticated by the per-channel token
+	if deps.WatchService != nil {
+		webhookHandler := calendarhandler.NewWebhookHandler(deps.WatchService)
+		NewRoute(mux, http.MethodPost, "/calendar/webhook", webhookHandler.Notify)
+	}
+
 	// User routes
 	if deps.UserService != nil && deps.AuthMiddleware != nil {
 		userHandler := userhandler.NewUserHandler(deps.UserService)
 
 		// POST /users - requires auth but no active status check
-		mux.Handle("POST /users", http.HandlerFunc(userHandler.Create))
+		mux.Handle("POST /users", limit("POST /users", userHandler.Create))
 
 		// POST /users/activate - no auth required
-		NewRoute(mux, http.MethodPost, "/users/activate", userHandler.Activate)
+		mux.Handle("POST /users/activate", limit("POST /users/activate", userHandler.Activate))
 
 		// POST /users/login - no auth required
-		NewRoute(mux, http.MethodPost, "/users/login", userHandler.Login)
+		mux.Handle("POST /users/login", limit("POST /users/login", userHandler.Login))
 
 		// POST /users/refresh - no auth required
-		NewRoute(mux, http.MethodPost, "/users/refresh", userHandler.RefreshToken)
+		mux.Handle("POST /users/refresh", limit("POST /users/refresh", userHandler.RefreshToken))
 
 		// Protected routes - require active user
 		mux.Handle("GET /users/me", deps.AuthMiddleware.RequireActiveUser(http.HandlerFunc(userHandler.GetProfile)))
-		mux.Handle("PUT /users/me", deps.AuthMiddleware.RequireActiveUser(http.HandlerFunc(userHandler.UpdateProfile)))
+		mux.Handle("PUT /users/me", deps.AuthMiddleware.RequireActiveUser(limit("PUT /users/me", userHandler.UpdateProfile)))
 		mux.Handle("DELETE /users/me", deps.AuthMiddleware.RequireActiveUser(http.HandlerFunc(userHandler.DeleteProfile)))
 	}
 
+	// Preference routes
+	if deps.PreferencesService != nil && deps.AuthMiddleware != nil {
+		preferencesHandler := userhandler.NewPreferencesHandler(deps.PreferencesService)
+
+		// POST /users/unsubscribe - no auth required, the token is signed
+		mux.Handle("POST /users/unsubscribe", limit("POST /users/unsubscribe", preferencesHandler.Unsubscribe))
+
+		mux.Handle("GET /users/me/preferences", deps.AuthMiddleware.RequireActiveUser(http.HandlerFunc(preferencesHandler.GetPreferences)))
+		mux.Handle("PUT /users/me/preferences", deps.AuthMiddleware.RequireActiveUser(http.HandlerFunc(preferencesHandler.UpdatePreferences)))
+	}
+
 	// Energy routes
 	if deps.EnergyService != nil && deps.AuthMiddleware != nil {
 		energyLevelsHandler := energyhandler.New(deps.EnergyService)
 		mux.Handle("GET /energy/levels", deps.AuthMiddleware.RequireActiveUser(http.HandlerFunc(energyLevelsHandler.GetLevels)))
 		mux.Handle("GET /energy/levels/range", deps.AuthMiddleware.RequireActiveUser(http.HandlerFunc(energyLevelsHandler.GetLevelsByRange)))
-		mux.Handle("PUT /energy/levels", deps.AuthMiddleware.RequireActiveUser(http.HandlerFunc(energyLevelsHandler.SaveLevels)))
+		mux.Handle("PUT /energy/levels", deps.AuthMiddleware.RequireActiveUser(limit("PUT /energy/levels", energyLevelsHandler.SaveLevels)))
+	}
+
+	// Insights routes
+	if deps.InsightsService != nil && deps.AuthMiddleware != nil {
+		insightsHandler := insightshandler.New(deps.InsightsService)
+		mux.Handle("GET /insights/time-energy", deps.AuthMiddleware.RequireActiveUser(http.HandlerFunc(insightsHandler.GetTimeEnergy)))
+	}
+
+	// Job routes - only exposed when a trigger token is configured
+	if deps.Jobs != nil && cfg.Jobs.TriggerToken != "" {
+		mux.HandleFunc("POST /internal/jobs/{name}", triggerJob(deps.Jobs, cfg.Jobs.TriggerToken))
 	}
 }
 
-func health(w http.ResponseWriter, r *http.Request) {
+// liveness answers /healthz without checking dependencies, see Readiness.
+func liveness(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte("ok"))
 }
@@ -178,8 +188,11 @@ func NewRoute(mux *http.ServeMux, method string, path string, next http.HandlerF
 	mux.HandleFunc(fmt.Sprintf("%s %s", method, path), next)
 }
 
-func applyCORS(next http.Handler) http.Handler {
-	allowedOrigins := loadAllowedOrigins()
+func applyCORS(next http.Handler, origins []string) http.Handler {
+	allowedOrigins := map[string]bool{}
+	for _, origin := range origins {
+		allowedOrigins[origin] = true
+	}
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		origin := r.Header.Get("Origin")
@@ -189,7 +202,8 @@ func applyCORS(next http.Handler) http.Handler {
 
 		w.Header().Add("Vary", "Origin")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+middleware.RequestIDHeader)
+		w.Header().Set("Access-Control-Expose-Headers", middleware.RequestIDHeader+", Retry-After")
 
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusNoContent)
@@ -199,36 +213,3 @@ func applyCORS(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
-
-func loadAllowedOrigins() map[string]bool {
-	value := os.Getenv("ALLOWED_ORIGINS")
-	if value == "" {
-		value = "http://localhost:8080"
-	}
-
-	origins := map[string]bool{}
-	for _, origin := range strings.Split(value, ",") {
-		trimmed := strings.TrimSpace(origin)
-		if trimmed == "" {
-			continue
-		}
-		origins[trimmed] = true
-	}
-
-	return origins
-}
-
-func lookupEnvOrDefault(key, def string) string {
-	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
-		return v
-	}
-	return def
-}
-
-func requiredEnv(key string) string {
-	value := strings.TrimSpace(os.Getenv(key))
-	if value == "" {
-		log.Fatalf("%s environment variable is required", key)
-	}
-	return value
-}
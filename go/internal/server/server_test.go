@@ -3,25 +3,32 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"energyjournal/internal/config"
 	"energyjournal/internal/domain/calendar"
 	"energyjournal/internal/domain/energy"
 	"energyjournal/internal/domain/user"
+	"energyjournal/internal/pkg/health"
+	"energyjournal/internal/pkg/ratelimit"
+	"energyjournal/internal/pkg/scheduler"
 	"energyjournal/internal/server/middleware"
 	"firebase.google.com/go/v4/auth"
 )
 
 type stubSpendingService struct {
 	getStatus    func(ctx context.Context, uid string) (calendar.ConnectionStatus, error)
-	buildAuthURL func(uid string) string
+	buildAuthURL func(uid string, provider calendar.Provider) (string, error)
 	callback     func(ctx context.Context, code, state string) error
 	getCalendars func(ctx context.Context, uid string) ([]calendar.CalendarItem, error)
-	setCalendar  func(ctx context.Context, uid, calendarID string) error
+	setCalendars func(ctx context.Context, uid string, calendarIDs []string) error
 	getSpending  func(ctx context.Context, uid string, start, end time.Time) (calendar.Spendings, error)
 }
 
@@ -32,11 +39,11 @@ func (s *stubSpendingService) GetStatus(ctx context.Context, uid string) (calend
 	return calendar.StatusDisconnected, nil
 }
 
-func (s *stubSpendingService) BuildAuthURL(uid string) string {
+func (s *stubSpendingService) BuildAuthURL(uid string, provider calendar.Provider) (string, error) {
 	if s.buildAuthURL != nil {
-		return s.buildAuthURL(uid)
+		return s.buildAuthURL(uid, provider)
 	}
-	return "https://accounts.example/auth"
+	return "https://accounts.example/auth", nil
 }
 
 func (s *stubSpendingService) HandleCallback(ctx context.Context, code, state string) error {
@@ -46,6 +53,10 @@ func (s *stubSpendingService) HandleCallback(ctx context.Context, code, state st
 	return nil
 }
 
+func (s *stubSpendingService) ConnectFeed(ctx context.Context, uid string, provider calendar.Provider, source calendar.FeedSource) error {
+	return nil
+}
+
 func (s *stubSpendingService) GetCalendars(ctx context.Context, uid string) ([]calendar.CalendarItem, error) {
 	if s.getCalendars != nil {
 		return s.getCalendars(ctx, uid)
@@ -53,17 +64,33 @@ func (s *stubSpendingService) GetCalendars(ctx context.Context, uid string) ([]c
 	return nil, nil
 }
 
-func (s *stubSpendingService) SetCalendar(ctx context.Context, uid, calendarID string) error {
-	if s.setCalendar != nil {
-		return s.setCalendar(ctx, uid, calendarID)
+func (s *stubSpendingService) SetCalendars(ctx context.Context, uid string, calendarIDs []string) error {
+	if s.setCalendars != nil {
+		return s.setCalendars(ctx, uid, calendarIDs)
 	}
 	return nil
 }
 
+func (s *stubSpendingService) Disconnect(ctx context.Context, uid string) error {
+	return nil
+}
+
 func (s *stubSpendingService) GetSpending(ctx context.Context, uid string, start, end time.Time) (calendar.Spendings, error) {
 	return s.getSpending(ctx, uid, start, end)
 }
 
+func (s *stubSpendingService) GetSpendingByCalendar(ctx context.Context, uid string, start, end time.Time) (calendar.CalendarSpendings, error) {
+	return calendar.CalendarSpendings{}, nil
+}
+
+func (s *stubSpendingService) GetTimeUsage(ctx context.Context, uid string, start, end time.Time) (*calendar.TimeUsage, error) {
+	return &calendar.TimeUsage{Location: time.UTC, Spendings: calendar.Spendings{}}, nil
+}
+
+func (s *stubSpendingService) GetSpendingSeries(ctx context.Context, uid string, start, end time.Time, granularity calendar.Granularity) (*calendar.SpendingSeries, error) {
+	return &calendar.SpendingSeries{Granularity: granularity, Location: time.UTC}, nil
+}
+
 type stubVerifier struct {
 	verifyIDToken func(ctx context.Context, idToken string) (*auth.Token, error)
 }
@@ -122,7 +149,7 @@ func TestRegister_CalendarSpending_UnauthorizedWithoutToken(t *testing.T) {
 	t.Parallel()
 
 	mux := http.NewServeMux()
-	register(mux, Dependencies{
+	register(mux, config.Config{}, Dependencies{
 		CalendarService: &stubSpendingService{
 			getSpending: func(ctx context.Context, uid string, start, end time.Time) (calendar.Spendings, error) {
 				t.Fatal("spending service should not be called")
@@ -150,7 +177,7 @@ func TestRegister_CalendarSpending_AuthorizedWithToken(t *testing.T) {
 	t.Parallel()
 
 	mux := http.NewServeMux()
-	register(mux, Dependencies{
+	register(mux, config.Config{}, Dependencies{
 		CalendarService: &stubSpendingService{
 			getSpending: func(ctx context.Context, uid string, start, end time.Time) (calendar.Spendings, error) {
 				return calendar.Spendings{"Work": 12.5}, nil
@@ -188,7 +215,7 @@ func TestRegister_CalendarSpending_WithoutAuthMiddlewareFailsClosed(t *testing.T
 	t.Parallel()
 
 	mux := http.NewServeMux()
-	register(mux, Dependencies{
+	register(mux, config.Config{}, Dependencies{
 		CalendarService: &stubSpendingService{
 			getSpending: func(ctx context.Context, uid string, start, end time.Time) (calendar.Spendings, error) {
 				t.Fatal("spending service should not be called")
@@ -211,7 +238,7 @@ func TestRegister_EnergyLevels_UnauthorizedWithoutToken_GET(t *testing.T) {
 	t.Parallel()
 
 	mux := http.NewServeMux()
-	register(mux, Dependencies{
+	register(mux, config.Config{}, Dependencies{
 		EnergyService: &stubEnergyService{
 			getByDate: func(ctx context.Context, uid, date string) (*energy.EnergyLevels, error) {
 				t.Fatal("energy service should not be called")
@@ -239,7 +266,7 @@ func TestRegister_EnergyLevelsRange_UnauthorizedWithoutToken_GET(t *testing.T) {
 	t.Parallel()
 
 	mux := http.NewServeMux()
-	register(mux, Dependencies{
+	register(mux, config.Config{}, Dependencies{
 		EnergyService: &stubEnergyService{
 			getByDateRange: func(ctx context.Context, uid, from, to string) ([]energy.EnergyLevels, error) {
 				t.Fatal("energy service should not be called")
@@ -267,7 +294,7 @@ func TestRegister_EnergyLevels_UnauthorizedWithoutToken_PUT(t *testing.T) {
 	t.Parallel()
 
 	mux := http.NewServeMux()
-	register(mux, Dependencies{
+	register(mux, config.Config{}, Dependencies{
 		EnergyService: &stubEnergyService{
 			save: func(ctx context.Context, levels energy.EnergyLevels) error {
 				t.Fatal("energy service should not be called")
@@ -296,7 +323,7 @@ func TestRegister_EnergyLevels_AuthorizedWithToken_GET(t *testing.T) {
 	t.Parallel()
 
 	mux := http.NewServeMux()
-	register(mux, Dependencies{
+	register(mux, config.Config{}, Dependencies{
 		EnergyService: &stubEnergyService{
 			getByDate: func(ctx context.Context, uid, date string) (*energy.EnergyLevels, error) {
 				return &energy.EnergyLevels{
@@ -333,7 +360,7 @@ func TestRegister_EnergyLevelsRange_AuthorizedWithToken_GET(t *testing.T) {
 	t.Parallel()
 
 	mux := http.NewServeMux()
-	register(mux, Dependencies{
+	register(mux, config.Config{}, Dependencies{
 		EnergyService: &stubEnergyService{
 			getByDateRange: func(ctx context.Context, uid, from, to string) ([]energy.EnergyLevels, error) {
 				return []energy.EnergyLevels{}, nil
@@ -364,7 +391,7 @@ func TestRegister_EnergyLevels_AuthorizedWithToken_PUT(t *testing.T) {
 	t.Parallel()
 
 	mux := http.NewServeMux()
-	register(mux, Dependencies{
+	register(mux, config.Config{}, Dependencies{
 		EnergyService: &stubEnergyService{
 			save: func(ctx context.Context, levels energy.EnergyLevels) error {
 				return nil
@@ -391,3 +418,216 @@ func TestRegister_EnergyLevels_AuthorizedWithToken_PUT(t *testing.T) {
 		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
 	}
 }
+
+func TestRegister_JobTrigger_RequiresToken(t *testing.T) {
+	t.Parallel()
+
+	runs := 0
+	mux := http.NewServeMux()
+	register(mux, config.Config{Jobs: config.Jobs{TriggerToken: "job-secret"}}, Dependencies{
+		Jobs: scheduler.New(scheduler.Job{Name: "calendar-sync", Run: func(context.Context) error {
+			runs++
+			return nil
+		}}),
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		token  string
+		status int
+	}{
+		{"missing token", "/internal/jobs/calendar-sync", "", http.StatusUnauthorized},
+		{"wrong token", "/internal/jobs/calendar-sync", "nope", http.StatusUnauthorized},
+		{"unknown job", "/internal/jobs/unknown", "job-secret", http.StatusNotFound},
+		{"valid", "/internal/jobs/calendar-sync", "job-secret", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+		if tt.token != "" {
+			req.Header.Set(jobsTokenHeader, tt.token)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if rr.Code != tt.status {
+			t.Fatalf("%s: expected status %d, got %d", tt.name, tt.status, rr.Code)
+		}
+	}
+	if runs != 1 {
+		t.Fatalf("expected the job to run once, got %d", runs)
+	}
+}
+
+func TestNewWithDependenciesReportsInvalidSetup(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewWithDependencies(config.Config{}, Dependencies{}); err == nil {
+		t.Fatal("expected an error without an address")
+	}
+	cfg := config.Config{HTTP: config.HTTP{Addr: ":0"}, Jobs: config.Jobs{TriggerToken: "job-secret"}}
+	if _, err := NewWithDependencies(cfg, Dependencies{}); err == nil {
+		t.Fatal("expected an error for a trigger token without jobs")
+	}
+
+	cfg.HTTP.AllowedOrigins = []string{"https://app.example"}
+	srv, err := NewWithDependencies(cfg, Dependencies{Jobs: scheduler.New()})
+	if err != nil {
+		t.Fatalf("NewWithDependencies returned error: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	req.Header.Set("Origin", "https://app.example")
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || rr.Header().Get("Access-Control-Allow-Origin") != "https://app.example" {
+		t.Fatalf("unexpected response %d with headers %v", rr.Code, rr.Header())
+	}
+}
+
+func TestRegister_Readiness_ReportsChecksAndShutdown(t *testing.T) {
+	t.Parallel()
+
+	var storageErr error
+	readiness := NewReadiness(time.Second, health.Checker{Name: "storage", Check: func(context.Context) error { return storageErr }})
+	readiness.ttl = 0
+	mux := http.NewServeMux()
+	register(mux, config.Config{}, Dependencies{Readiness: readiness})
+
+	var body string
+	readyz := func() (int, health.Report) {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		body = rr.Body.String()
+		var report health.Report
+		if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+			t.Fatalf("decode readiness: %v (%s)", err, rr.Body.String())
+		}
+		return rr.Code, report
+	}
+
+	if code, report := readyz(); code != http.StatusOK || report.Checks["storage"].Status != health.StatusOK {
+		t.Fatalf("expected ready, got %d %+v", code, report)
+	}
+	storageErr = errors.New("connection refused")
+	if code, report := readyz(); code != http.StatusServiceUnavailable || report.Checks["storage"].Status != health.StatusFailed {
+		t.Fatalf("expected the failed check reported, got %d %+v", code, report)
+	}
+	if strings.Contains(body, "connection refused") {
+		t.Fatalf("expected the check error kept out of the response, got %s", body)
+	}
+	storageErr = nil
+	readiness.ShuttingDown()
+	if code, report := readyz(); code != http.StatusServiceUnavailable || report.Status != statusShuttingDown {
+		t.Fatalf("expected unready while shutting down, got %d %+v", code, report)
+	}
+
+	// Liveness does not depend on the checks.
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected /healthz to stay ok, got %d", rr.Code)
+	}
+}
+
+func TestReadiness_SharesRecentAndConcurrentRuns(t *testing.T) {
+	t.Parallel()
+
+	var runs atomic.Int32
+	release := make(chan struct{})
+	readiness := NewReadiness(time.Second, health.Checker{Name: "storage", Check: func(context.Context) error {
+		runs.Add(1)
+		<-release
+		return nil
+	}})
+	now := time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)
+	readiness.now = func() time.Time { return now }
+
+	readyz := func() int {
+		rr := httptest.NewRecorder()
+		readiness.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rr.Code
+	}
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if code := readyz(); code != http.StatusOK {
+				t.Errorf("expected ready, got %d", code)
+			}
+		}()
+	}
+	// Requests arriving while the first run is blocked wait on it; later ones
+	// reuse its report.
+	for runs.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if code := readyz(); code != http.StatusOK || runs.Load() != 1 {
+		t.Fatalf("expected one shared run, got %d runs (status %d)", runs.Load(), code)
+	}
+	now = now.Add(readinessReportTTL)
+	if readyz(); runs.Load() != 2 {
+		t.Fatalf("expected the checks to run again once the report expired, got %d runs", runs.Load())
+	}
+}
+
+func TestRegister_Metrics_ServedOnlyWhenEnabled(t *testing.T) {
+	t.Parallel()
+
+	metrics := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("energyjournal_energy_saves_total 1\n"))
+	})
+	for _, deps := range []Dependencies{{}, {Metrics: metrics}} {
+		mux := http.NewServeMux()
+		register(mux, config.Config{}, deps)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		if enabled := deps.Metrics != nil; enabled != (rr.Code == http.StatusOK) {
+			t.Fatalf("metrics enabled %v, got %d %q", enabled, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestRegister_RateLimit_AppliesPerUserAfterAuth(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	register(mux, config.Config{}, Dependencies{
+		EnergyService: &stubEnergyService{},
+		AuthMiddleware: middleware.NewAuthMiddlewareWithVerifier(&stubVerifier{
+			verifyIDToken: func(ctx context.Context, idToken string) (*auth.Token, error) {
+				if idToken == "invalid" {
+					return nil, errors.New("invalid token")
+				}
+				return &auth.Token{UID: idToken}, nil
+			},
+		}, &stubUserRepo{}),
+		RateLimiter: middleware.NewRateLimiter(ratelimit.NewMemoryStore(), map[string]ratelimit.Limit{
+			"PUT /energy/levels": {Burst: 1, Every: time.Minute},
+		}, false),
+	})
+	save := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/energy/levels", strings.NewReader(`{"date":"2026-02-21","physical":7,"mental":5,"emotional":8}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := save("invalid"); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected unauthenticated requests refused before counting, got %d", rr.Code)
+	}
+	if rr := save("uid-1"); rr.Code != http.StatusOK {
+		t.Fatalf("expected the first save served, got %d", rr.Code)
+	}
+	if rr := save("uid-1"); rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") != "60" {
+		t.Fatalf("expected the second save limited with Retry-After 60, got %d %q", rr.Code, rr.Header().Get("Retry-After"))
+	}
+	if rr := save("uid-2"); rr.Code != http.StatusOK {
+		t.Fatalf("expected other users served, got %d", rr.Code)
+	}
+}
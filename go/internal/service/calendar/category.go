@@ -0,0 +1,279 @@
+package calendar
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"energyjournal/internal/domain/calendar"
+	errpkg "energyjournal/internal/pkg/error"
+)
+
+const (
+	maxCategoryLength = 64
+	maxRuleValueLen   = 256
+)
+
+var matchTypes = map[calendar.MatchType]struct{}{
+	calendar.MatchColor:    {},
+	calendar.MatchCalendar: {},
+	calendar.MatchKeyword:  {},
+	calendar.MatchRegex:    {},
+}
+
+// categorizer assigns events to categories using a priority-ordered rule list,
+// falling back to the provider's label (e.g. the color name) when no rule matches.
+type categorizer struct {
+	rules    []calendar.CategoryRule
+	patterns map[string]*regexp.Regexp
+	fallback func(calendar.Event) string
+}
+
+func newCategorizer(rules []calendar.CategoryRule, tieBreak ruleOrder, fallback func(calendar.Event) string) (*categorizer, error) {
+	sorted := make([]calendar.CategoryRule, len(rules))
+	copy(sorted, rules)
+	sortRules(sorted, tieBreak)
+
+	c := &categorizer{rules: sorted, patterns: map[string]*regexp.Regexp{}, fallback: fallback}
+	for _, rule := range sorted {
+		if rule.MatchType != calendar.MatchRegex {
+			continue
+		}
+		pattern, err := regexp.Compile(rule.Value)
+		if err != nil {
+			return nil, errpkg.NewInputValidationError("value", "invalid regular expression")
+		}
+		c.patterns[rule.Value] = pattern
+	}
+	return c, nil
+}
+
+func (c *categorizer) categorize(event calendar.Event) (category string, ruleID string) {
+	category, ruleID, _ = c.match(event)
+	return category, ruleID
+}
+
+// match also returns the matching rule's position in evaluation order, which
+// is its priority; color fallbacks rank after every rule.
+func (c *categorizer) match(event calendar.Event) (category string, ruleID string, rank int) {
+	for i, rule := range c.rules {
+		if c.matches(rule, event) {
+			return rule.Category, rule.ID, i
+		}
+	}
+	return c.fallback(event), "", len(c.rules)
+}
+
+func (c *categorizer) matches(rule calendar.CategoryRule, event calendar.Event) bool {
+	switch rule.MatchType {
+	case calendar.MatchColor:
+		return event.ColorID == rule.Value
+	case calendar.MatchCalendar:
+		return event.CalendarID == rule.Value
+	case calendar.MatchKeyword:
+		return strings.Contains(strings.ToLower(event.Summary), strings.ToLower(rule.Value))
+	case calendar.MatchRegex:
+		pattern, ok := c.patterns[rule.Value]
+		return ok && pattern.MatchString(event.Summary)
+	default:
+		return false
+	}
+}
+
+func (s *CalendarService) ListCategoryRules(ctx context.Context, uid string) ([]calendar.CategoryRule, error) {
+	rules, err := s.categories.List(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	sortRules(rules, byCreation)
+	return rules, nil
+}
+
+func (s *CalendarService) CreateCategoryRule(ctx context.Context, rule calendar.CategoryRule) (*calendar.CategoryRule, error) {
+	rule = normalizeRule(rule)
+	if err := s.validateRule(rule); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	rule.ID = ""
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+	if err := s.categories.Create(ctx, &rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (s *CalendarService) UpdateCategoryRule(ctx context.Context, rule calendar.CategoryRule) (*calendar.CategoryRule, error) {
+	rule = normalizeRule(rule)
+	if err := s.validateRule(rule); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.categories.Get(ctx, rule.UID, rule.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.CreatedAt = existing.CreatedAt
+	rule.UpdatedAt = s.now()
+	if err := s.categories.Update(ctx, rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (s *CalendarService) DeleteCategoryRule(ctx context.Context, uid, id string) error {
+	return s.categories.Delete(ctx, uid, id)
+}
+
+// PreviewCategories categorizes every event of the week starting at weekStart,
+// or of the current week when it is zero, in the user's timezone.
+func (s *CalendarService) PreviewCategories(ctx context.Context, uid string, weekStart time.Time, rules []calendar.CategoryRule) (*calendar.CategoryPreview, error) {
+	// Stored rules tie-break on creation time; draft rules keep request order.
+	var tieBreak ruleOrder
+	if rules == nil {
+		stored, err := s.categories.List(ctx, uid)
+		if err != nil {
+			return nil, err
+		}
+		rules = stored
+		tieBreak = byCreation
+	} else {
+		for i := range rules {
+			rules[i] = normalizeRule(rules[i])
+			if err := s.validateRule(rules[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	loc, err := s.UserLocation(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if weekStart.IsZero() {
+		weekStart = startOfWeek(s.now().In(loc))
+	}
+	start := time.Date(weekStart.Year(), weekStart.Month(), weekStart.Day(), 0, 0, 0, 0, loc)
+	end := start.AddDate(0, 0, 7)
+	eventsByCalendar, provider, err := s.fetchSelectedEvents(ctx, uid, start, end)
+	if err != nil {
+		return nil, err
+	}
+	c, err := newCategorizer(rules, tieBreak, provider.Category)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &calendar.CategoryPreview{
+		Start:     start,
+		End:       end,
+		Events:    []calendar.CategorizedEvent{},
+		Spendings: calendar.Spendings{},
+	}
+	for _, events := range eventsByCalendar {
+		for _, event := range events {
+			if !validInterval(event) || !event.CountsAsSpending() {
+				continue
+			}
+			category, ruleID := c.categorize(event)
+			hours := s.eventHours(event, start, end)
+			preview.Events = append(preview.Events, calendar.CategorizedEvent{
+				Event:    event,
+				Category: category,
+				RuleID:   ruleID,
+				Hours:    hours,
+			})
+			preview.Spendings[category] += hours
+		}
+	}
+	sort.SliceStable(preview.Events, func(i, j int) bool {
+		return preview.Events[i].Start.Before(preview.Events[j].Start)
+	})
+
+	return preview, nil
+}
+
+// loadCategorizer builds a categorizer from the user's stored rules, falling
+// back to the provider's categories.
+func (s *CalendarService) loadCategorizer(ctx context.Context, uid string, provider CalendarProvider) (*categorizer, error) {
+	if s.categories == nil {
+		return newCategorizer(nil, nil, provider.Category)
+	}
+	rules, err := s.categories.List(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	return newCategorizer(rules, byCreation, provider.Category)
+}
+
+func normalizeRule(rule calendar.CategoryRule) calendar.CategoryRule {
+	rule.Category = strings.TrimSpace(rule.Category)
+	if rule.MatchType != calendar.MatchRegex {
+		rule.Value = strings.TrimSpace(rule.Value)
+	}
+	return rule
+}
+
+func (s *CalendarService) validateRule(rule calendar.CategoryRule) error {
+	if rule.Category == "" {
+		return errpkg.NewInputValidationError("category", "required")
+	}
+	if len(rule.Category) > maxCategoryLength {
+		return errpkg.NewInputValidationError("category", "too long")
+	}
+	if _, ok := matchTypes[rule.MatchType]; !ok {
+		return errpkg.NewInputValidationError("match_type", "must be color, calendar, keyword or regex")
+	}
+	if rule.Value == "" {
+		return errpkg.NewInputValidationError("value", "required")
+	}
+	if len(rule.Value) > maxRuleValueLen {
+		return errpkg.NewInputValidationError("value", "too long")
+	}
+	if rule.Priority < 0 {
+		return errpkg.NewInputValidationError("priority", "must be zero or positive")
+	}
+
+	switch rule.MatchType {
+	case calendar.MatchColor:
+		if !s.knownColor(rule.Value) {
+			return errpkg.NewInputValidationError("value", "unknown color id")
+		}
+	case calendar.MatchRegex:
+		if _, err := regexp.Compile(rule.Value); err != nil {
+			return errpkg.NewInputValidationError("value", "invalid regular expression")
+		}
+	}
+	return nil
+}
+
+// ruleOrder reports whether rule a runs before rule b of the same priority.
+type ruleOrder func(a, b calendar.CategoryRule) bool
+
+// byCreation runs older rules first.
+func byCreation(a, b calendar.CategoryRule) bool {
+	return a.CreatedAt.Before(b.CreatedAt)
+}
+
+// sortRules orders rules by priority, then by tieBreak. A nil tieBreak keeps
+// rules of the same priority in their given order.
+func sortRules(rules []calendar.CategoryRule, tieBreak ruleOrder) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority < rules[j].Priority
+		}
+		return tieBreak != nil && tieBreak(rules[i], rules[j])
+	})
+}
+
+// startOfWeek returns the Monday at midnight of the week containing t, in t's
+// location.
+func startOfWeek(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,288 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"energyjournal/internal/domain/calendar"
+	errpkg "energyjournal/internal/pkg/error"
+)
+
+type fakeCategoryRepo struct {
+	rules  []calendar.CategoryRule
+	nextID int
+}
+
+func (r *fakeCategoryRepo) List(_ context.Context, uid string) ([]calendar.CategoryRule, error) {
+	out := []calendar.CategoryRule{}
+	for _, rule := range r.rules {
+		if rule.UID == uid {
+			out = append(out, rule)
+		}
+	}
+	return out, nil
+}
+
+func (r *fakeCategoryRepo) Get(_ context.Context, uid, id string) (*calendar.CategoryRule, error) {
+	for _, rule := range r.rules {
+		if rule.ID == id && rule.UID == uid {
+			return &rule, nil
+		}
+	}
+	return nil, errpkg.NewNotFoundError("category_rule", id)
+}
+
+func (r *fakeCategoryRepo) Create(_ context.Context, rule *calendar.CategoryRule) error {
+	r.nextID++
+	rule.ID = fmt.Sprintf("rule-%d", r.nextID)
+	r.rules = append(r.rules, *rule)
+	return nil
+}
+
+func (r *fakeCategoryRepo) Update(_ context.Context, rule calendar.CategoryRule) error {
+	for i := range r.rules {
+		if r.rules[i].ID == rule.ID {
+			r.rules[i] = rule
+			return nil
+		}
+	}
+	return errpkg.NewNotFoundError("category_rule", rule.ID)
+}
+
+func (r *fakeCategoryRepo) Delete(ctx context.Context, uid, id string) error {
+	if _, err := r.Get(ctx, uid, id); err != nil {
+		return err
+	}
+	for i := range r.rules {
+		if r.rules[i].ID == id {
+			r.rules = append(r.rules[:i], r.rules[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func connectedRepo(calendarIDs ...string) *fakeRepo {
+	return &fakeRepo{
+		getFn: func(context.Context, string) (*calendar.CalendarConnection, error) {
+			return &calendar.CalendarConnection{UID: "uid", CalendarIDs: calendarIDs, AccessToken: "token"}, nil
+		},
+	}
+}
+
+func TestCategorizerEvaluatesRulesByPriority(t *testing.T) {
+	t.Parallel()
+
+	c, err := newCategorizer([]calendar.CategoryRule{
+		{ID: "color", Category: "Travail", MatchType: calendar.MatchColor, Value: "5", Priority: 10},
+		{ID: "lunch", Category: "Repas", MatchType: calendar.MatchKeyword, Value: "lunch", Priority: 1},
+		{ID: "gym", Category: "Sport", MatchType: calendar.MatchRegex, Value: `(?i)^(gym|run)\b`, Priority: 2},
+		{ID: "family", Category: "Perso", MatchType: calendar.MatchCalendar, Value: "family", Priority: 3},
+	}, byCreation, newFakeProvider(&fakeCalendarClient{}, &fakeOAuth{}).Category)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	tests := []struct {
+		event    calendar.Event
+		category string
+		ruleID   string
+	}{
+		{calendar.Event{ColorID: "5", Summary: "Team LUNCH"}, "Repas", "lunch"},
+		{calendar.Event{ColorID: "5", Summary: "Run with Sam"}, "Sport", "gym"},
+		{calendar.Event{ColorID: "5", Summary: "Standup", CalendarID: "family"}, "Perso", "family"},
+		{calendar.Event{ColorID: "5", Summary: "Standup"}, "Travail", "color"},
+		{calendar.Event{ColorID: "1", Summary: "Dentist"}, "Tomato", ""},
+		{calendar.Event{ColorID: "99", Summary: "Unknown"}, "Default", ""},
+	}
+	for _, tt := range tests {
+		category, ruleID := c.categorize(tt.event)
+		if category != tt.category || ruleID != tt.ruleID {
+			t.Errorf("%q: expected %s/%s, got %s/%s", tt.event.Summary, tt.category, tt.ruleID, category, ruleID)
+		}
+	}
+}
+
+func TestGetSpendingUsesCategoryRules(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)
+	categories := &fakeCategoryRepo{rules: []calendar.CategoryRule{
+		{ID: "r1", UID: "uid", Category: "Travail", MatchType: calendar.MatchCalendar, Value: "work"},
+	}}
+	svc := NewCalendarService(connectedRepo("work", "personal"), newFakeProvider(&fakeCalendarClient{
+		eventsByCalendar: map[string][]calendar.Event{
+			"work":     {{ColorID: "1", Start: now, End: now.Add(2 * time.Hour)}},
+			"personal": {{ColorID: "1", Start: now, End: now.Add(time.Hour)}},
+		},
+	}, &fakeOAuth{}), "secret", categories)
+
+	result, err := svc.GetSpending(context.Background(), "uid", now, now.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if result["Travail"] != 2 || result["Tomato"] != 1 {
+		t.Fatalf("unexpected spendings: %v", result)
+	}
+}
+
+func TestCreateCategoryRuleValidates(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		rule  calendar.CategoryRule
+		field string
+	}{
+		{"missing category", calendar.CategoryRule{MatchType: calendar.MatchKeyword, Value: "x"}, "category"},
+		{"unknown match type", calendar.CategoryRule{Category: "A", MatchType: "title", Value: "x"}, "match_type"},
+		{"missing value", calendar.CategoryRule{Category: "A", MatchType: calendar.MatchKeyword, Value: "  "}, "value"},
+		{"unknown color", calendar.CategoryRule{Category: "A", MatchType: calendar.MatchColor, Value: "42"}, "value"},
+		{"bad regex", calendar.CategoryRule{Category: "A", MatchType: calendar.MatchRegex, Value: "("}, "value"},
+		{"negative priority", calendar.CategoryRule{Category: "A", MatchType: calendar.MatchKeyword, Value: "x", Priority: -1}, "priority"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			categories := &fakeCategoryRepo{}
+			svc := NewCalendarService(&fakeRepo{}, newFakeProvider(&fakeCalendarClient{}, &fakeOAuth{}), "secret", categories)
+
+			tt.rule.UID = "uid"
+			_, err := svc.CreateCategoryRule(context.Background(), tt.rule)
+			var validationErr *errpkg.InputValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("expected validation error, got %v", err)
+			}
+			if validationErr.Field != tt.field {
+				t.Fatalf("expected field %s, got %s", tt.field, validationErr.Field)
+			}
+			if len(categories.rules) != 0 {
+				t.Fatal("expected no rule to be stored")
+			}
+		})
+	}
+}
+
+func TestCategoryRuleCRUD(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)
+	categories := &fakeCategoryRepo{}
+	svc := NewCalendarService(&fakeRepo{}, newFakeProvider(&fakeCalendarClient{}, &fakeOAuth{}), "secret", categories)
+	svc.now = func() time.Time { return now }
+
+	created, err := svc.CreateCategoryRule(context.Background(), calendar.CategoryRule{
+		UID: "uid", Category: " Repas ", MatchType: calendar.MatchKeyword, Value: "lunch", Priority: 5,
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if created.ID == "" || created.Category != "Repas" || !created.CreatedAt.Equal(now) {
+		t.Fatalf("unexpected created rule: %+v", created)
+	}
+
+	svc.now = func() time.Time { return now.Add(time.Hour) }
+	updated, err := svc.UpdateCategoryRule(context.Background(), calendar.CategoryRule{
+		ID: created.ID, UID: "uid", Category: "Repas", MatchType: calendar.MatchKeyword, Value: "dinner", Priority: 1,
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if updated.Value != "dinner" || !updated.CreatedAt.Equal(now) || !updated.UpdatedAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("unexpected updated rule: %+v", updated)
+	}
+
+	_, err = svc.UpdateCategoryRule(context.Background(), calendar.CategoryRule{
+		ID: created.ID, UID: "someone-else", Category: "Repas", MatchType: calendar.MatchKeyword, Value: "x",
+	})
+	var notFoundErr *errpkg.NotFoundError
+	if !errors.As(err, &notFoundErr) {
+		t.Fatalf("expected not found for another user, got %v", err)
+	}
+
+	if err := svc.DeleteCategoryRule(context.Background(), "uid", created.ID); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	rules, err := svc.ListCategoryRules(context.Background(), "uid")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(rules) != 0 {
+		t.Fatalf("expected no rules after delete, got %v", rules)
+	}
+}
+
+func TestPreviewCategoriesWithDraftRules(t *testing.T) {
+	t.Parallel()
+
+	weekStart := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	categories := &fakeCategoryRepo{rules: []calendar.CategoryRule{
+		{ID: "stored", UID: "uid", Category: "Stored", MatchType: calendar.MatchColor, Value: "5"},
+	}}
+	svc := NewCalendarService(connectedRepo("primary"), newFakeProvider(&fakeCalendarClient{
+		events: []calendar.Event{
+			{Summary: "Lunch", ColorID: "5", Start: weekStart.Add(36 * time.Hour), End: weekStart.Add(37 * time.Hour)},
+			{Summary: "Review", ColorID: "5", Start: weekStart.Add(10 * time.Hour), End: weekStart.Add(12 * time.Hour)},
+		},
+	}, &fakeOAuth{}), "secret", categories)
+
+	preview, err := svc.PreviewCategories(context.Background(), "uid", weekStart, []calendar.CategoryRule{
+		{Category: "Repas", MatchType: calendar.MatchKeyword, Value: "lunch"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if !preview.End.Equal(weekStart.AddDate(0, 0, 7)) {
+		t.Fatalf("expected a 7-day window, got %s - %s", preview.Start, preview.End)
+	}
+	if len(preview.Events) != 2 || preview.Events[0].Summary != "Review" {
+		t.Fatalf("expected events sorted by start, got %+v", preview.Events)
+	}
+	if preview.Events[0].Category != "Sage" || preview.Events[1].Category != "Repas" {
+		t.Fatalf("draft rules should replace stored rules, got %+v", preview.Events)
+	}
+	if preview.Spendings["Repas"] != 1 || preview.Spendings["Sage"] != 2 {
+		t.Fatalf("unexpected spendings: %v", preview.Spendings)
+	}
+
+	stored, err := svc.PreviewCategories(context.Background(), "uid", weekStart, nil)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if stored.Spendings["Stored"] != 3 {
+		t.Fatalf("expected stored rules to apply, got %v", stored.Spendings)
+	}
+}
+
+func TestPreviewCategoriesDefaultsToUserWeek(t *testing.T) {
+	t.Parallel()
+
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	// Monday 01:00 in Paris is still Sunday in UTC.
+	monday := time.Date(2026, 3, 2, 0, 0, 0, 0, paris)
+	svc := NewCalendarService(connectedRepo("primary"), newFakeProvider(&fakeCalendarClient{
+		events: []calendar.Event{
+			{Summary: "Team lunch", ColorID: "5", Start: monday.Add(12 * time.Hour), End: monday.Add(13 * time.Hour)},
+		},
+	}, &fakeOAuth{}), "secret", &fakeCategoryRepo{}).WithUsers(fakeUsers{"uid": "Europe/Paris"})
+	svc.now = func() time.Time { return monday.Add(time.Hour) }
+
+	preview, err := svc.PreviewCategories(context.Background(), "uid", time.Time{}, []calendar.CategoryRule{
+		{Category: "Repas", MatchType: calendar.MatchKeyword, Value: "lunch"},
+		{Category: "Equipe", MatchType: calendar.MatchKeyword, Value: "team"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if !preview.Start.Equal(monday) || !preview.End.Equal(monday.AddDate(0, 0, 7)) {
+		t.Fatalf("expected the Paris week of %s, got %s - %s", monday, preview.Start, preview.End)
+	}
+	if len(preview.Events) != 1 || preview.Events[0].Category != "Repas" {
+		t.Fatalf("expected draft rules of equal priority to apply in request order, got %+v", preview.Events)
+	}
+}
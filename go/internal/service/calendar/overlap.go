@@ -0,0 +1,209 @@
+package calendar
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"energyjournal/internal/domain/calendar"
+	errpkg "energyjournal/internal/pkg/error"
+	"energyjournal/internal/pkg/telemetry"
+)
+
+// defaultWorkingHours is 09:00-18:00, Monday to Friday.
+var defaultWorkingHours = calendar.WorkingHours{
+	StartMinute: 9 * 60,
+	EndMinute:   18 * 60,
+	Days:        []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+}
+
+// WithWorkingHours sets the daily window free time is measured against.
+func (s *CalendarService) WithWorkingHours(hours calendar.WorkingHours) *CalendarService {
+	s.workingHours = hours
+	return s
+}
+
+// timelineEntry is an event clipped to the requested range, ready for the sweep.
+type timelineEntry struct {
+	start    time.Time
+	end      time.Time
+	category string
+	rank     int
+}
+
+// interval is a half-open [start, end) span of time.
+type interval struct {
+	start time.Time
+	end   time.Time
+}
+
+// GetTimeUsage sweeps the timeline of the selected calendars so that
+// overlapping events count once. Each slice of time goes to the category of
+// the highest-priority rule matching an event booked then (color fallbacks
+// rank last; ties go to the earliest-starting event).
+func (s *CalendarService) GetTimeUsage(ctx context.Context, uid string, start, end time.Time) (_ *calendar.TimeUsage, err error) {
+	ctx, span := tracer.Start(ctx, "CalendarService.GetTimeUsage", spanRange(start, end))
+	defer func() { telemetry.End(span, err) }()
+
+	loc, start, end, err := s.localRange(ctx, uid, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if end.After(start.AddDate(0, 0, maxSeriesBuckets)) {
+		return nil, errpkg.NewInputValidationError("end", "range too long")
+	}
+
+	eventsByCalendar, provider, err := s.fetchSelectedEvents(ctx, uid, start, end)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.loadCategorizer(ctx, uid, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &calendar.TimeUsage{Location: loc, Spendings: calendar.Spendings{}}
+	var entries []timelineEntry
+	for _, events := range eventsByCalendar {
+		for _, event := range events {
+			if !validInterval(event) || !event.CountsAsSpending() {
+				continue
+			}
+			category, _, rank := c.match(event)
+			if event.AllDay {
+				usage.Spendings[category] += s.allDayHoursWithin(event, start, end)
+				continue
+			}
+			entry := timelineEntry{start: event.Start, end: event.End, category: category, rank: rank}
+			if entry.start.Before(start) {
+				entry.start = start
+			}
+			if entry.end.After(end) {
+				entry.end = end
+			}
+			if entry.end.After(entry.start) {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	booked := sweep(entries, usage.Spendings)
+	for _, span := range booked {
+		usage.BookedHours += span.end.Sub(span.start).Hours()
+	}
+	for _, window := range s.workingWindows(start, end) {
+		usage.WorkingHours += window.end.Sub(window.start).Hours()
+		usage.BookedWorkingHours += overlapHours(booked, window)
+	}
+	usage.FreeWorkingHours = usage.WorkingHours - usage.BookedWorkingHours
+	return usage, nil
+}
+
+// sweep walks the boundaries of entries in time order, adds each slice to the
+// winning category in out and returns the booked time as merged intervals.
+func sweep(entries []timelineEntry, out calendar.Spendings) []interval {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].start.Before(entries[j].start) })
+
+	boundaries := make([]time.Time, 0, 2*len(entries))
+	for _, entry := range entries {
+		boundaries = append(boundaries, entry.start, entry.end)
+	}
+	sort.Slice(boundaries, func(i, j int) bool { return boundaries[i].Before(boundaries[j]) })
+
+	var booked []interval
+	var active []timelineEntry
+	next := 0
+	for i := 0; i+1 < len(boundaries); i++ {
+		from, to := boundaries[i], boundaries[i+1]
+		if !to.After(from) {
+			continue
+		}
+
+		kept := active[:0]
+		for _, entry := range active {
+			if entry.end.After(from) {
+				kept = append(kept, entry)
+			}
+		}
+		active = kept
+		for next < len(entries) && !entries[next].start.After(from) {
+			if entries[next].end.After(from) {
+				active = append(active, entries[next])
+			}
+			next++
+		}
+		if len(active) == 0 {
+			continue
+		}
+
+		// active stays in start order, so the first of equal ranks started earliest.
+		winner := active[0]
+		for _, entry := range active[1:] {
+			if entry.rank < winner.rank {
+				winner = entry
+			}
+		}
+		out[winner.category] += to.Sub(from).Hours()
+
+		if n := len(booked); n > 0 && booked[n-1].end.Equal(from) {
+			booked[n-1].end = to
+		} else {
+			booked = append(booked, interval{start: from, end: to})
+		}
+	}
+	return booked
+}
+
+// workingWindows returns the working-hours window of each working day in
+// [start, end), in start's location.
+func (s *CalendarService) workingWindows(start, end time.Time) []interval {
+	workdays := map[time.Weekday]bool{}
+	for _, day := range s.workingHours.Days {
+		workdays[day] = true
+	}
+
+	var windows []interval
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		if !workdays[day.Weekday()] {
+			continue
+		}
+		y, m, d := day.Date()
+		from := time.Date(y, m, d, 0, s.workingHours.StartMinute, 0, 0, day.Location())
+		to := time.Date(y, m, d, 0, s.workingHours.EndMinute, 0, 0, day.Location())
+		if to.After(from) {
+			windows = append(windows, interval{start: from, end: to})
+		}
+	}
+	return windows
+}
+
+// overlapHours sums the parts of the sorted, disjoint spans inside window.
+func overlapHours(spans []interval, window interval) float64 {
+	var hours float64
+	first := sort.Search(len(spans), func(i int) bool { return spans[i].end.After(window.start) })
+	for i := first; i < len(spans) && spans[i].start.Before(window.end); i++ {
+		from, to := spans[i].start, spans[i].end
+		if window.start.After(from) {
+			from = window.start
+		}
+		if window.end.Before(to) {
+			to = window.end
+		}
+		hours += to.Sub(from).Hours()
+	}
+	return hours
+}
+
+// allDayHoursWithin counts allDayHours for each day of the event that
+// overlaps [start, end). Days are matched by calendar date in the timezone of
+// start, since all-day events are midnights in the calendar's timezone.
+func (s *CalendarService) allDayHoursWithin(event calendar.Event, start, end time.Time) float64 {
+	var hours float64
+	for day := event.Start; day.Before(event.End); day = day.AddDate(0, 0, 1) {
+		local := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, start.Location())
+		if local.AddDate(0, 0, 1).After(start) && local.Before(end) {
+			hours += s.allDayHours
+		}
+	}
+	return hours
+}
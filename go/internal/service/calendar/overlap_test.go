@@ -0,0 +1,78 @@
+package calendar
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"energyjournal/internal/domain/calendar"
+)
+
+func TestGetTimeUsageFlattensOverlapsByPriority(t *testing.T) {
+	t.Parallel()
+
+	monday := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) time.Time {
+		return monday.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+	categories := &fakeCategoryRepo{rules: []calendar.CategoryRule{
+		{ID: "focus", UID: "uid", Category: "Focus", MatchType: calendar.MatchKeyword, Value: "focus", Priority: 1},
+	}}
+	svc := NewCalendarService(connectedRepo("work", "personal"), newFakeProvider(&fakeCalendarClient{
+		eventsByCalendar: map[string][]calendar.Event{
+			"work": {
+				{Summary: "Workshop", ColorID: "5", Start: at(9, 0), End: at(12, 0)},
+				{Summary: "Focus block", ColorID: "1", Start: at(10, 0), End: at(11, 0)},
+			},
+			"personal": {
+				{Summary: "Lunch", ColorID: "1", Start: at(11, 30), End: at(13, 0)},
+				{Summary: "Dinner", ColorID: "1", Start: at(20, 0), End: at(22, 0)},
+				{Summary: "Declined", ColorID: "1", Start: at(14, 0), End: at(15, 0), Status: calendar.EventCancelled},
+			},
+		},
+	}, &fakeOAuth{}), "secret", categories)
+
+	usage, err := svc.GetTimeUsage(context.Background(), "uid", monday, monday.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	// The focus rule wins 10-11 over the workshop; the workshop started first,
+	// so it keeps 11:30-12 over lunch, which has the same (fallback) priority.
+	if usage.Spendings["Sage"] != 2 || usage.Spendings["Focus"] != 1 || usage.Spendings["Tomato"] != 3 {
+		t.Fatalf("unexpected spendings: %v", usage.Spendings)
+	}
+	if usage.BookedHours != 6 {
+		t.Fatalf("expected 6 booked hours, got %v", usage.BookedHours)
+	}
+	if usage.WorkingHours != 9 || usage.BookedWorkingHours != 4 || usage.FreeWorkingHours != 5 {
+		t.Fatalf("unexpected working hours: %+v", usage)
+	}
+}
+
+func TestGetTimeUsageUsesConfiguredWorkingDays(t *testing.T) {
+	t.Parallel()
+
+	saturday := time.Date(2026, 3, 7, 0, 0, 0, 0, time.UTC)
+	svc := NewCalendarService(connectedRepo("primary"), newFakeProvider(&fakeCalendarClient{
+		events: []calendar.Event{
+			{ColorID: "5", Start: saturday.Add(7 * time.Hour), End: saturday.Add(9 * time.Hour)},
+			{ColorID: "5", Start: saturday.Add(-time.Hour), End: saturday.Add(time.Hour)},
+		},
+	}, &fakeOAuth{}), "secret", &fakeCategoryRepo{}).WithWorkingHours(calendar.WorkingHours{
+		StartMinute: 8 * 60,
+		EndMinute:   12 * 60,
+		Days:        []time.Weekday{time.Saturday},
+	})
+
+	usage, err := svc.GetTimeUsage(context.Background(), "uid", saturday, saturday.AddDate(0, 0, 2))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if usage.WorkingHours != 4 || usage.BookedWorkingHours != 1 || usage.FreeWorkingHours != 3 {
+		t.Fatalf("unexpected working hours: %+v", usage)
+	}
+	if usage.BookedHours != 3 || usage.Spendings["Sage"] != 3 {
+		t.Fatalf("expected the part before the range to be dropped, got %+v", usage)
+	}
+}
@@ -0,0 +1,109 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"energyjournal/internal/domain/calendar"
+)
+
+// CalendarProvider is a calendar backend, such as Google Calendar, Microsoft
+// 365 or an ICS feed. The token passed to it is the connection's credential.
+type CalendarProvider interface {
+	Name() calendar.Provider
+	ListCalendars(ctx context.Context, token string) ([]calendar.CalendarItem, error)
+	ListEvents(ctx context.Context, token, calendarID string, start, end time.Time) ([]calendar.Event, error)
+	// Category names an event no category rule matches, e.g. by its color label.
+	Category(event calendar.Event) string
+	// ColorName returns the label of a color ID usable in color rules.
+	ColorName(colorID string) (string, bool)
+}
+
+// OAuthProvider is a provider the user connects to through the OAuth consent flow.
+type OAuthProvider interface {
+	CalendarProvider
+	// AuthCodeURL returns the consent page URL, asking for offline access so
+	// that a refresh token is issued.
+	AuthCodeURL(state string) string
+	Exchange(ctx context.Context, code string) (*oauth2.Token, error)
+	TokenSource(ctx context.Context, t *oauth2.Token) oauth2.TokenSource
+}
+
+// FeedProvider is a provider connected with a URL and optional credentials.
+type FeedProvider interface {
+	CalendarProvider
+	// Connect checks that source can be read and returns the credential later
+	// passed as token. Rejected credentials match calendar.ErrAuthorizationRevoked.
+	Connect(ctx context.Context, source calendar.FeedSource) (string, error)
+}
+
+// Optional provider capabilities. A provider without eventSyncer is always
+// read live; without channelWatcher it gets no push notifications; without
+// tokenRevoker disconnecting only forgets the tokens.
+type (
+	eventSyncer interface {
+		SyncEvents(ctx context.Context, token, calendarID, syncToken string, windowStart, windowEnd time.Time) (*calendar.EventChanges, error)
+	}
+	channelWatcher interface {
+		WatchEvents(ctx context.Context, token, calendarID, channelID, channelToken, address string, ttl time.Duration) (*calendar.WatchRegistration, error)
+		StopChannel(ctx context.Context, token, channelID, resourceID string) error
+	}
+	tokenRevoker interface {
+		RevokeToken(ctx context.Context, token string) error
+	}
+)
+
+// WithProvider registers an additional calendar provider.
+func (s *CalendarService) WithProvider(provider CalendarProvider) *CalendarService {
+	s.providers[provider.Name()] = provider
+	return s
+}
+
+// oauthProvider returns a registered provider connected through OAuth.
+func (s *CalendarService) oauthProvider(name calendar.Provider) (OAuthProvider, error) {
+	provider, err := s.lookupProvider(name)
+	if err != nil {
+		return nil, err
+	}
+	oauth, ok := provider.(OAuthProvider)
+	if !ok {
+		return nil, fmt.Errorf("calendar provider %q does not use OAuth", provider.Name())
+	}
+	return oauth, nil
+}
+
+// lookupProvider returns a registered provider; the empty name is the default
+// provider, which connections stored before providers existed belong to.
+func (s *CalendarService) lookupProvider(name calendar.Provider) (CalendarProvider, error) {
+	if name == "" {
+		name = s.defaultProvider
+	}
+	provider, ok := s.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("calendar provider %q is not configured", name)
+	}
+	return provider, nil
+}
+
+// syncer returns the provider's incremental sync support when the event cache
+// is enabled.
+func (s *CalendarService) syncer(provider CalendarProvider) (eventSyncer, bool) {
+	if s.cache == nil {
+		return nil, false
+	}
+	syncer, ok := provider.(eventSyncer)
+	return syncer, ok
+}
+
+// knownColor reports whether any registered provider has the color ID.
+func (s *CalendarService) knownColor(colorID string) bool {
+	for _, provider := range s.providers {
+		if _, ok := provider.ColorName(colorID); ok {
+			return true
+		}
+	}
+	return false
+}
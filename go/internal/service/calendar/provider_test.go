@@ -0,0 +1,326 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"energyjournal/internal/domain/calendar"
+	"energyjournal/internal/integration/ical"
+	"energyjournal/internal/integration/microsoft"
+	errpkg "energyjournal/internal/pkg/error"
+)
+
+// outlookProvider hides the optional capabilities of the wrapped fake, so it
+// is read live like Microsoft Graph, and names events by Outlook category.
+type outlookProvider struct {
+	OAuthProvider
+}
+
+func (p outlookProvider) Name() calendar.Provider {
+	return calendar.ProviderMicrosoft
+}
+
+func (p outlookProvider) Category(event calendar.Event) string {
+	return microsoft.NewProvider(nil, nil).Category(event)
+}
+
+func (p outlookProvider) ColorName(string) (string, bool) {
+	return "", false
+}
+
+func TestBuildAuthURLSelectsProvider(t *testing.T) {
+	t.Parallel()
+
+	outlook := outlookProvider{newFakeProvider(&fakeCalendarClient{}, &fakeOAuth{authURL: "https://login.example/authorize"})}
+	svc := NewCalendarService(&fakeRepo{}, newFakeProvider(&fakeCalendarClient{}, &fakeOAuth{}), "secret", &fakeCategoryRepo{}).
+		WithProvider(outlook)
+
+	authURL, err := svc.BuildAuthURL("uid-1", calendar.ProviderMicrosoft)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if !strings.HasPrefix(authURL, "https://login.example/authorize?state=") {
+		t.Fatalf("expected the microsoft consent page, got %s", authURL)
+	}
+	uid, provider, err := svc.verifyState(strings.TrimPrefix(authURL, "https://login.example/authorize?state="))
+	if err != nil || uid != "uid-1" || provider != calendar.ProviderMicrosoft {
+		t.Fatalf("unexpected state: %s %s %v", uid, provider, err)
+	}
+
+	authURL, err = svc.BuildAuthURL("uid-1", "")
+	if err != nil || !strings.HasPrefix(authURL, "https://accounts.example/oauth?state=") {
+		t.Fatalf("expected google by default, got %s (%v)", authURL, err)
+	}
+
+	_, err = svc.BuildAuthURL("uid-1", "yahoo")
+	var validationErr *errpkg.InputValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+}
+
+func TestHandleCallbackStoresProviderAndDropsSelectionOfAnother(t *testing.T) {
+	t.Parallel()
+
+	var saved calendar.CalendarConnection
+	outlook := outlookProvider{newFakeProvider(&fakeCalendarClient{}, &fakeOAuth{exchangeTok: &oauth2.Token{AccessToken: "graph-access", RefreshToken: "graph-refresh"}})}
+	svc := NewCalendarService(&fakeRepo{
+		getFn: func(context.Context, string) (*calendar.CalendarConnection, error) {
+			return &calendar.CalendarConnection{UID: "uid-1", CalendarIDs: []string{"primary"}, NeedsReauth: true}, nil
+		},
+		upsertFn: func(_ context.Context, conn calendar.CalendarConnection) error {
+			saved = conn
+			return nil
+		},
+	}, newFakeProvider(&fakeCalendarClient{}, &fakeOAuth{}), "secret", &fakeCategoryRepo{}).WithProvider(outlook)
+
+	state := svc.signState("uid-1", calendar.ProviderMicrosoft, svc.now())
+	if err := svc.HandleCallback(context.Background(), "code", state); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if saved.Provider != calendar.ProviderMicrosoft || saved.AccessToken != "graph-access" || len(saved.CalendarIDs) != 0 {
+		t.Fatalf("unexpected saved connection: %+v", saved)
+	}
+}
+
+// googleConnection stores a Google connection with a cached calendar and a
+// watch channel, for tests replacing it with another provider.
+func googleConnection(t *testing.T) (*fakeRepo, **calendar.CalendarConnection, *fakeEventCache, *fakeChannelRepo) {
+	t.Helper()
+
+	stored := &calendar.CalendarConnection{UID: "uid", Provider: calendar.ProviderGoogle, CalendarIDs: []string{"primary"}, AccessToken: "access", RefreshToken: "refresh"}
+	repo := &fakeRepo{
+		getFn: func(context.Context, string) (*calendar.CalendarConnection, error) {
+			return stored, nil
+		},
+		upsertFn: func(_ context.Context, conn calendar.CalendarConnection) error {
+			stored = &conn
+			return nil
+		},
+	}
+	now := time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)
+	cache := newFakeEventCache()
+	_ = cache.ReplaceCalendar(context.Background(), "uid", "primary", []calendar.Event{{ID: "e", CalendarID: "primary", Start: now, End: now.Add(time.Hour)}})
+	_ = cache.SaveSyncState(context.Background(), calendar.SyncState{UID: "uid", CalendarID: "primary", SyncToken: "t"})
+	channels := newFakeChannelRepo()
+	_ = channels.Save(context.Background(), calendar.WatchChannel{ID: "ch", UID: "uid", CalendarID: "primary"})
+	return repo, &stored, cache, channels
+}
+
+func requireGoogleTornDown(t *testing.T, client *fakeCalendarClient, cache *fakeEventCache, channels *fakeChannelRepo) {
+	t.Helper()
+
+	if len(client.revoked) != 1 || client.revoked[0] != "refresh" {
+		t.Fatalf("expected the Google grant revoked, got %v", client.revoked)
+	}
+	if remaining, _ := channels.ListByUID(context.Background(), "uid"); len(remaining) != 0 {
+		t.Fatalf("expected the Google watch channels dropped, got %+v", remaining)
+	}
+	if state, _ := cache.GetSyncState(context.Background(), "uid", "primary"); state != nil || len(cache.events["uid"]) != 0 {
+		t.Fatalf("expected the Google events uncached, got %+v and %v", state, cache.events["uid"])
+	}
+}
+
+func TestHandleCallbackTearsDownTheConnectionOfAnotherProvider(t *testing.T) {
+	t.Parallel()
+
+	repo, stored, cache, channels := googleConnection(t)
+	google := &fakeCalendarClient{}
+	outlook := outlookProvider{newFakeProvider(&fakeCalendarClient{}, &fakeOAuth{exchangeTok: &oauth2.Token{AccessToken: "graph-access", RefreshToken: "graph-refresh"}})}
+	svc := NewCalendarService(repo, newFakeProvider(google, &fakeOAuth{}), "secret", &fakeCategoryRepo{}).
+		WithProvider(outlook).
+		WithEventCache(cache).
+		WithWatchChannels(channels, "https://api.example.test/calendar/webhook")
+
+	if err := svc.HandleCallback(context.Background(), "code", svc.signState("uid", calendar.ProviderMicrosoft, svc.now())); err != nil {
+		t.Fatalf("HandleCallback returned error: %v", err)
+	}
+	if (*stored).Provider != calendar.ProviderMicrosoft || (*stored).RefreshToken != "graph-refresh" {
+		t.Fatalf("expected the Microsoft connection stored, got %+v", *stored)
+	}
+	requireGoogleTornDown(t, google, cache, channels)
+}
+
+func TestHandleCallbackKeepsTheGrantWhenReconnectingTheSameProvider(t *testing.T) {
+	t.Parallel()
+
+	repo, _, cache, channels := googleConnection(t)
+	google := &fakeCalendarClient{}
+	svc := NewCalendarService(repo, newFakeProvider(google, &fakeOAuth{exchangeTok: &oauth2.Token{AccessToken: "new-access", RefreshToken: "new-refresh"}}), "secret", &fakeCategoryRepo{}).
+		WithEventCache(cache).
+		WithWatchChannels(channels, "https://api.example.test/calendar/webhook")
+
+	if err := svc.HandleCallback(context.Background(), "code", svc.signState("uid", calendar.ProviderGoogle, svc.now())); err != nil {
+		t.Fatalf("HandleCallback returned error: %v", err)
+	}
+	if len(google.revoked) != 0 || len(cache.events["uid"]) != 1 {
+		t.Fatalf("expected the same provider's grant and cache kept, got %v revoked and %v cached", google.revoked, cache.events["uid"])
+	}
+}
+
+func TestLiveProviderBypassesCacheAndFallsBackToItsCategories(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	outlook := outlookProvider{newFakeProvider(&fakeCalendarClient{
+		events: []calendar.Event{
+			{Summary: "Design review", Categories: []string{"Deep work"}, Start: start.Add(9 * time.Hour), End: start.Add(11 * time.Hour)},
+			{Summary: "Coffee", Start: start.Add(14 * time.Hour), End: start.Add(15 * time.Hour)},
+		},
+	}, &fakeOAuth{})}
+	repo := &fakeRepo{
+		getFn: func(context.Context, string) (*calendar.CalendarConnection, error) {
+			return &calendar.CalendarConnection{UID: "uid", Provider: calendar.ProviderMicrosoft, CalendarIDs: []string{"AAMk"}, AccessToken: "token"}, nil
+		},
+	}
+	cache := newFakeEventCache()
+	svc := NewCalendarService(repo, newFakeProvider(&fakeCalendarClient{}, &fakeOAuth{}), "secret", &fakeCategoryRepo{}).
+		WithProvider(outlook).
+		WithEventCache(cache)
+
+	if err := svc.SyncUser(context.Background(), "uid"); err != nil {
+		t.Fatalf("SyncUser returned error: %v", err)
+	}
+	result, err := svc.GetSpending(context.Background(), "uid", start, start.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if result["Deep work"] != 2 || result["Default"] != 1 {
+		t.Fatalf("unexpected spendings: %v", result)
+	}
+	if len(cache.states) != 0 {
+		t.Fatalf("expected nothing cached for a live provider, got %v", cache.states)
+	}
+}
+
+func TestConnectionOfUnconfiguredProviderFails(t *testing.T) {
+	t.Parallel()
+
+	repo := &fakeRepo{
+		getFn: func(context.Context, string) (*calendar.CalendarConnection, error) {
+			return &calendar.CalendarConnection{UID: "uid", Provider: calendar.ProviderMicrosoft, CalendarIDs: []string{"AAMk"}, AccessToken: "token"}, nil
+		},
+	}
+	svc := NewCalendarService(repo, newFakeProvider(&fakeCalendarClient{}, &fakeOAuth{}), "secret", &fakeCategoryRepo{})
+
+	if _, err := svc.GetCalendars(context.Background(), "uid"); err == nil || !strings.Contains(err.Error(), "not configured") {
+		t.Fatalf("expected unconfigured provider error, got %v", err)
+	}
+}
+
+const standupFeed = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"X-WR-CALNAME:Team\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:standup\r\n" +
+	"SUMMARY:Standup\r\n" +
+	"CATEGORIES:Meetings\r\n" +
+	"DTSTART:20260302T090000Z\r\n" +
+	"DTEND:20260302T093000Z\r\n" +
+	"RRULE:FREQ=DAILY;COUNT=5\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:focus\r\n" +
+	"SUMMARY:Focus\r\n" +
+	"DTSTART:20260302T130000Z\r\n" +
+	"DTEND:20260302T150000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestConnectFeedSelectsItsCalendarAndReadsItLive(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/calendar")
+		_, _ = w.Write([]byte(standupFeed))
+	}))
+	defer server.Close()
+
+	var saved *calendar.CalendarConnection
+	repo := &fakeRepo{
+		getFn: func(context.Context, string) (*calendar.CalendarConnection, error) {
+			return saved, nil
+		},
+		upsertFn: func(_ context.Context, conn calendar.CalendarConnection) error {
+			saved = &conn
+			return nil
+		},
+	}
+	svc := NewCalendarService(repo, newFakeProvider(&fakeCalendarClient{}, &fakeOAuth{}), "secret", &fakeCategoryRepo{}).
+		WithProvider(ical.NewFeedProvider(true)).
+		WithEventCache(newFakeEventCache())
+
+	if err := svc.ConnectFeed(context.Background(), "uid", calendar.ProviderICS, calendar.FeedSource{URL: server.URL}); err != nil {
+		t.Fatalf("ConnectFeed returned error: %v", err)
+	}
+	if saved == nil || saved.Provider != calendar.ProviderICS || len(saved.CalendarIDs) != 1 || saved.CalendarIDs[0] != ical.FeedCalendarID {
+		t.Fatalf("unexpected saved connection: %+v", saved)
+	}
+	if saved.RefreshToken != "" || !strings.Contains(saved.AccessToken, server.URL) {
+		t.Fatalf("expected the feed source as credential, got %+v", saved)
+	}
+
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	result, err := svc.GetSpending(context.Background(), "uid", start, start.AddDate(0, 0, 2))
+	if err != nil {
+		t.Fatalf("GetSpending returned error: %v", err)
+	}
+	if result["Meetings"] != 1 || result["Default"] != 2 {
+		t.Fatalf("unexpected spendings: %v", result)
+	}
+}
+
+func TestConnectFeedTearsDownAnOAuthConnection(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(standupFeed))
+	}))
+	defer server.Close()
+
+	repo, stored, cache, channels := googleConnection(t)
+	google := &fakeCalendarClient{}
+	svc := NewCalendarService(repo, newFakeProvider(google, &fakeOAuth{}), "secret", &fakeCategoryRepo{}).
+		WithProvider(ical.NewFeedProvider(true)).
+		WithEventCache(cache).
+		WithWatchChannels(channels, "https://api.example.test/calendar/webhook")
+
+	if err := svc.ConnectFeed(context.Background(), "uid", calendar.ProviderICS, calendar.FeedSource{URL: server.URL}); err != nil {
+		t.Fatalf("ConnectFeed returned error: %v", err)
+	}
+	if (*stored).Provider != calendar.ProviderICS {
+		t.Fatalf("expected the feed connection stored, got %+v", *stored)
+	}
+	requireGoogleTornDown(t, google, cache, channels)
+}
+
+func TestConnectFeedRejectsOAuthAndUnknownProviders(t *testing.T) {
+	t.Parallel()
+
+	svc := NewCalendarService(&fakeRepo{}, newFakeProvider(&fakeCalendarClient{}, &fakeOAuth{}), "secret", &fakeCategoryRepo{}).
+		WithProvider(ical.NewFeedProvider(false))
+
+	for _, tt := range []struct {
+		provider calendar.Provider
+		source   calendar.FeedSource
+		field    string
+	}{
+		{calendar.ProviderGoogle, calendar.FeedSource{URL: "https://calendar.example.com/basic.ics"}, "provider"},
+		{calendar.ProviderCalDAV, calendar.FeedSource{URL: "https://dav.example.com/"}, "provider"},
+		{calendar.ProviderICS, calendar.FeedSource{}, "url"},
+		{calendar.ProviderICS, calendar.FeedSource{URL: "http://127.0.0.1/basic.ics"}, "url"},
+	} {
+		err := svc.ConnectFeed(context.Background(), "uid", tt.provider, tt.source)
+		var validationErr *errpkg.InputValidationError
+		if !errors.As(err, &validationErr) || validationErr.Field != tt.field {
+			t.Fatalf("%s %q: expected a validation error on %s, got %v", tt.provider, tt.source.URL, tt.field, err)
+		}
+	}
+}
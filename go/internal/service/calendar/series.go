@@ -0,0 +1,164 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"energyjournal/internal/domain/calendar"
+	"energyjournal/internal/domain/user"
+	errpkg "energyjournal/internal/pkg/error"
+	"energyjournal/internal/pkg/telemetry"
+)
+
+// maxSeriesBuckets bounds a time series to about a year of daily buckets.
+const maxSeriesBuckets = 366
+
+type userDirectory interface {
+	GetByUID(ctx context.Context, uid string) (*user.User, error)
+}
+
+// WithUsers enables splitting time series at the user's local midnight, read
+// from the user profile timezone. Without it buckets are split in UTC.
+func (s *CalendarService) WithUsers(users userDirectory) *CalendarService {
+	s.users = users
+	return s
+}
+
+// GetSpendingSeries splits spendings into day or week buckets. Events crossing
+// a bucket boundary are prorated between buckets, and the parts of events
+// outside the range are not counted.
+func (s *CalendarService) GetSpendingSeries(ctx context.Context, uid string, start, end time.Time, granularity calendar.Granularity) (_ *calendar.SpendingSeries, err error) {
+	ctx, span := tracer.Start(ctx, "CalendarService.GetSpendingSeries", spanRange(start, end))
+	defer func() { telemetry.End(span, err) }()
+
+	if granularity != calendar.GranularityDay && granularity != calendar.GranularityWeek {
+		return nil, errpkg.NewInputValidationError("granularity", "must be day or week")
+	}
+
+	loc, start, end, err := s.localRange(ctx, uid, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := spendingBuckets(start, end, granularity)
+	if len(buckets) > maxSeriesBuckets {
+		return nil, errpkg.NewInputValidationError("end", "range too long for this granularity")
+	}
+
+	eventsByCalendar, provider, err := s.fetchSelectedEvents(ctx, uid, start, end)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.loadCategorizer(ctx, uid, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, events := range eventsByCalendar {
+		for _, event := range events {
+			if !validInterval(event) || !event.CountsAsSpending() {
+				continue
+			}
+			category, _ := c.categorize(event)
+			if event.AllDay {
+				s.spreadAllDay(buckets, category, event, loc)
+			} else {
+				spreadTimed(buckets, category, event)
+			}
+		}
+	}
+
+	return &calendar.SpendingSeries{Granularity: granularity, Location: loc, Buckets: buckets}, nil
+}
+
+// spendingBuckets cuts [start, end) at every midnight (day) or every Monday
+// midnight (week) in start's location. The first and last weeks may be partial.
+func spendingBuckets(start, end time.Time, granularity calendar.Granularity) []calendar.SpendingBucket {
+	var buckets []calendar.SpendingBucket
+	for bucketStart := start; bucketStart.Before(end); {
+		y, m, d := bucketStart.Date()
+		next := time.Date(y, m, d+1, 0, 0, 0, 0, bucketStart.Location())
+		if granularity == calendar.GranularityWeek {
+			offset := (int(bucketStart.Weekday()) + 6) % 7
+			next = time.Date(y, m, d-offset+7, 0, 0, 0, 0, bucketStart.Location())
+		}
+		if next.After(end) {
+			next = end
+		}
+		buckets = append(buckets, calendar.SpendingBucket{Start: bucketStart, End: next, Spendings: calendar.Spendings{}})
+		bucketStart = next
+	}
+	return buckets
+}
+
+// spreadTimed adds the part of the event falling in each bucket.
+func spreadTimed(buckets []calendar.SpendingBucket, category string, event calendar.Event) {
+	first := sort.Search(len(buckets), func(i int) bool { return buckets[i].End.After(event.Start) })
+	for i := first; i < len(buckets) && buckets[i].Start.Before(event.End); i++ {
+		from, to := buckets[i].Start, buckets[i].End
+		if event.Start.After(from) {
+			from = event.Start
+		}
+		if event.End.Before(to) {
+			to = event.End
+		}
+		buckets[i].Spendings[category] += to.Sub(from).Hours()
+	}
+}
+
+// spreadAllDay adds allDayHours to the bucket holding each day of the event.
+// Days are matched by calendar date, since all-day events are midnights in
+// the calendar's timezone rather than the user's.
+func (s *CalendarService) spreadAllDay(buckets []calendar.SpendingBucket, category string, event calendar.Event, loc *time.Location) {
+	if s.allDayHours == 0 {
+		return
+	}
+	for day := event.Start; day.Before(event.End); day = day.AddDate(0, 0, 1) {
+		local := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+		i := sort.Search(len(buckets), func(i int) bool { return buckets[i].End.After(local) })
+		if i < len(buckets) && !buckets[i].Start.After(local) {
+			buckets[i].Spendings[category] += s.allDayHours
+		}
+	}
+}
+
+// localRange moves the calendar dates of start and end to midnight in the
+// user's timezone.
+func (s *CalendarService) localRange(ctx context.Context, uid string, start, end time.Time) (*time.Location, time.Time, time.Time, error) {
+	loc, err := s.UserLocation(ctx, uid)
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, err
+	}
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+	end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, loc)
+	if !end.After(start) {
+		return nil, time.Time{}, time.Time{}, errpkg.NewInputValidationError("end", "must be after start")
+	}
+	return loc, start, end, nil
+}
+
+// UserLocation returns the user's profile timezone, or UTC when it is unknown.
+func (s *CalendarService) UserLocation(ctx context.Context, uid string) (*time.Location, error) {
+	if s.users == nil {
+		return time.UTC, nil
+	}
+	u, err := s.users.GetByUID(ctx, uid)
+	var notFound *errpkg.NotFoundError
+	if errors.As(err, &notFound) {
+		return time.UTC, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if u == nil || u.Timezone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		// Profile timezones are not validated on write.
+		return time.UTC, nil
+	}
+	return loc, nil
+}
@@ -0,0 +1,120 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"energyjournal/internal/domain/calendar"
+	"energyjournal/internal/domain/user"
+	errpkg "energyjournal/internal/pkg/error"
+)
+
+type fakeUsers map[string]string
+
+func (u fakeUsers) GetByUID(_ context.Context, uid string) (*user.User, error) {
+	timezone, ok := u[uid]
+	if !ok {
+		return nil, errpkg.NewNotFoundError("user", uid)
+	}
+	return &user.User{UID: uid, Timezone: timezone}, nil
+}
+
+func TestGetSpendingSeriesProratesAcrossLocalMidnight(t *testing.T) {
+	t.Parallel()
+
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	// 22:00-02:00 Paris time, crossing midnight between the 3rd and the 4th.
+	late := time.Date(2026, 3, 3, 22, 0, 0, 0, paris)
+	svc := NewCalendarService(connectedRepo("primary"), newFakeProvider(&fakeCalendarClient{
+		events: []calendar.Event{
+			{ColorID: "5", Start: late, End: late.Add(4 * time.Hour)},
+			{ColorID: "1", Start: late.Add(-12 * time.Hour), End: late.Add(-11 * time.Hour)},
+			{ColorID: "1", Start: late.Add(-time.Hour), End: late, Status: calendar.EventCancelled},
+		},
+	}, &fakeOAuth{}), "secret", &fakeCategoryRepo{}).WithUsers(fakeUsers{"uid": "Europe/Paris"})
+
+	series, err := svc.GetSpendingSeries(context.Background(), "uid",
+		time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC), calendar.GranularityDay)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if series.Location.String() != "Europe/Paris" || len(series.Buckets) != 2 {
+		t.Fatalf("unexpected series: %+v", series)
+	}
+	first, second := series.Buckets[0], series.Buckets[1]
+	if !first.Start.Equal(time.Date(2026, 3, 3, 0, 0, 0, 0, paris)) || !first.End.Equal(second.Start) {
+		t.Fatalf("expected buckets split at Paris midnight, got %s - %s", first.Start, first.End)
+	}
+	if first.Spendings["Sage"] != 2 || first.Spendings["Tomato"] != 1 || second.Spendings["Sage"] != 2 {
+		t.Fatalf("unexpected spendings: %v / %v", first.Spendings, second.Spendings)
+	}
+}
+
+func TestGetSpendingSeriesWeeksStartOnMonday(t *testing.T) {
+	t.Parallel()
+
+	// Wednesday 2026-03-04 to Wednesday 2026-03-18.
+	start := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 3, 18, 0, 0, 0, 0, time.UTC)
+	allDay := time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC) // Sunday and Monday
+	svc := NewCalendarService(connectedRepo("primary"), newFakeProvider(&fakeCalendarClient{
+		events: []calendar.Event{
+			{ColorID: "5", Start: allDay, End: allDay.AddDate(0, 0, 2), AllDay: true},
+			{ColorID: "5", Start: end.Add(-time.Hour), End: end.Add(time.Hour)},
+		},
+	}, &fakeOAuth{}), "secret", &fakeCategoryRepo{}).WithAllDayHours(8)
+
+	series, err := svc.GetSpendingSeries(context.Background(), "uid", start, end, calendar.GranularityWeek)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if series.Location != time.UTC || len(series.Buckets) != 3 {
+		t.Fatalf("unexpected series: %+v", series)
+	}
+
+	wantStarts := []int{4, 9, 16}
+	for i, bucket := range series.Buckets {
+		if bucket.Start.Day() != wantStarts[i] {
+			t.Fatalf("bucket %d starts on %s", i, bucket.Start)
+		}
+	}
+	if !series.Buckets[2].End.Equal(end) {
+		t.Fatalf("expected last bucket clipped to the range, got %s", series.Buckets[2].End)
+	}
+	if series.Buckets[0].Spendings["Sage"] != 8 || series.Buckets[1].Spendings["Sage"] != 8 {
+		t.Fatalf("expected one all-day day per week, got %+v", series.Buckets)
+	}
+	if series.Buckets[2].Spendings["Sage"] != 1 {
+		t.Fatalf("expected the part after the range to be dropped, got %v", series.Buckets[2].Spendings)
+	}
+}
+
+func TestGetSpendingSeriesValidatesInput(t *testing.T) {
+	t.Parallel()
+
+	svc := NewCalendarService(connectedRepo("primary"), newFakeProvider(&fakeCalendarClient{}, &fakeOAuth{}), "secret", &fakeCategoryRepo{})
+	day := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		end         time.Time
+		granularity calendar.Granularity
+		field       string
+	}{
+		{"unknown granularity", day.AddDate(0, 0, 1), "month", "granularity"},
+		{"empty range", day, calendar.GranularityDay, "end"},
+		{"too many buckets", day.AddDate(2, 0, 0), calendar.GranularityDay, "end"},
+	}
+	for _, tt := range tests {
+		_, err := svc.GetSpendingSeries(context.Background(), "uid", day, tt.end, tt.granularity)
+		var validationErr *errpkg.InputValidationError
+		if !errors.As(err, &validationErr) || validationErr.Field != tt.field {
+			t.Fatalf("%s: expected validation error on %s, got %v", tt.name, tt.field, err)
+		}
+	}
+}
@@ -12,58 +12,81 @@ import (
 	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/oauth2"
+	"golang.org/x/sync/errgroup"
 
 	"energyjournal/internal/domain/calendar"
 	errpkg "energyjournal/internal/pkg/error"
+	"energyjournal/internal/pkg/telemetry"
+	"energyjournal/internal/server/middleware"
 )
 
-var colorNamesByID = map[string]string{
-	"":   "Default",
-	"1":  "Tomato",
-	"2":  "Flamingo",
-	"3":  "Tangerine",
-	"4":  "Banana",
-	"5":  "Sage",
-	"6":  "Basil",
-	"7":  "Peacock",
-	"8":  "Blueberry",
-	"9":  "Lavender",
-	"10": "Grape",
-	"11": "Graphite",
-}
+// defaultMaxConcurrentFetches bounds how many calendars are fetched at once.
+const defaultMaxConcurrentFetches = 4
 
-type oauthProvider interface {
-	AuthCodeURL(state string, opts ...oauth2.AuthCodeOption) string
-	Exchange(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error)
-	TokenSource(ctx context.Context, t *oauth2.Token) oauth2.TokenSource
-}
+const scope = "energyjournal/internal/service/calendar"
+
+var (
+	tracer         = otel.Tracer(scope)
+	tokenRefreshes = telemetry.Counter(scope, "energyjournal.calendar.token_refreshes",
+		"OAuth access token refreshes, by provider and outcome: refreshed, revoked or failed.")
+)
 
-type calendarClient interface {
-	ListCalendars(ctx context.Context, token string) ([]calendar.CalendarItem, error)
-	ListEvents(ctx context.Context, token, calendarID string, start, end time.Time) ([]calendar.Event, error)
+// spanRange records the length of the requested range on a span.
+func spanRange(start, end time.Time) trace.SpanStartOption {
+	return trace.WithAttributes(attribute.Float64("calendar.range_days", end.Sub(start).Hours()/24))
 }
 
 type CalendarService struct {
-	repo           calendar.CalendarConnectionRepository
-	calendarClient calendarClient
-	oauth          oauthProvider
-	stateSecret    string
-	stateTTL       time.Duration
-	now            func() time.Time
+	repo                 calendar.CalendarConnectionRepository
+	categories           calendar.CategoryRuleRepository
+	cache                calendar.EventCacheRepository
+	channels             calendar.WatchChannelRepository
+	users                userDirectory
+	providers            map[calendar.Provider]CalendarProvider
+	defaultProvider      calendar.Provider
+	stateSecret          string
+	stateTTL             time.Duration
+	maxConcurrentFetches int
+	allDayHours          float64
+	workingHours         calendar.WorkingHours
+	syncWindow           time.Duration
+	syncHorizon          time.Duration
+	webhookURL           string
+	channelTTL           time.Duration
+	now                  func() time.Time
 }
 
-func NewCalendarService(repo calendar.CalendarConnectionRepository, client calendarClient, oauth oauthProvider, stateSecret string) *CalendarService {
+// NewCalendarService creates the service with provider as the default calendar
+// provider; more can be added with WithProvider.
+func NewCalendarService(repo calendar.CalendarConnectionRepository, provider CalendarProvider, stateSecret string, categories calendar.CategoryRuleRepository) *CalendarService {
 	return &CalendarService{
-		repo:           repo,
-		calendarClient: client,
-		oauth:        oauth,
-		stateSecret:  stateSecret,
-		stateTTL:     15 * time.Minute,
-		now:          time.Now,
+		repo:                 repo,
+		categories:           categories,
+		providers:            map[calendar.Provider]CalendarProvider{provider.Name(): provider},
+		defaultProvider:      provider.Name(),
+		stateSecret:          stateSecret,
+		stateTTL:             15 * time.Minute,
+		maxConcurrentFetches: defaultMaxConcurrentFetches,
+		syncWindow:           defaultSyncWindow,
+		syncHorizon:          defaultSyncHorizon,
+		channelTTL:           defaultChannelTTL,
+		workingHours:         defaultWorkingHours,
+		now:                  time.Now,
 	}
 }
 
+// WithAllDayHours sets how many hours each day of an all-day event counts for.
+// All-day events are ignored (0 hours) by default.
+func (s *CalendarService) WithAllDayHours(hours float64) *CalendarService {
+	s.allDayHours = hours
+	return s
+}
+
 func (s *CalendarService) GetStatus(ctx context.Context, uid string) (calendar.ConnectionStatus, error) {
 	conn, err := s.repo.Get(ctx, uid)
 	if err != nil {
@@ -72,41 +95,116 @@ func (s *CalendarService) GetStatus(ctx context.Context, uid string) (calendar.C
 	if conn == nil {
 		return calendar.StatusDisconnected, nil
 	}
-	if conn.CalendarID == "" {
+	if conn.NeedsReauth {
+		return calendar.StatusNeedsReauth, nil
+	}
+	if len(conn.CalendarIDs) == 0 {
 		return calendar.StatusPendingSelection, nil
 	}
 	return calendar.StatusConnected, nil
 }
 
-func (s *CalendarService) BuildAuthURL(uid string) string {
-	state := s.signState(uid, s.now())
-	return s.oauth.AuthCodeURL(
-		state,
-		oauth2.AccessTypeOffline,
-		oauth2.SetAuthURLParam("prompt", "consent"),
-	)
+func (s *CalendarService) BuildAuthURL(uid string, name calendar.Provider) (string, error) {
+	provider, err := s.oauthProvider(name)
+	if err != nil {
+		return "", errpkg.NewInputValidationError("provider", "unknown calendar provider")
+	}
+	state := s.signState(uid, provider.Name(), s.now())
+	return provider.AuthCodeURL(state), nil
 }
 
-func (s *CalendarService) HandleCallback(ctx context.Context, code, state string) error {
-	uid, err := s.verifyState(state)
+func (s *CalendarService) HandleCallback(ctx context.Context, code, state string) (err error) {
+	ctx, span := tracer.Start(ctx, "CalendarService.HandleCallback")
+	defer func() { telemetry.End(span, err) }()
+
+	uid, name, err := s.verifyState(state)
+	if err != nil {
+		return errpkg.NewInputValidationError("state", "invalid state")
+	}
+	provider, err := s.oauthProvider(name)
 	if err != nil {
 		return errpkg.NewInputValidationError("state", "invalid state")
 	}
 
-	token, err := s.oauth.Exchange(ctx, code)
+	token, err := provider.Exchange(ctx, code)
 	if err != nil {
 		return errpkg.NewInputValidationError("code", "invalid authorization code")
 	}
 
+	// Reconnecting the same provider after a revoked grant keeps the previous
+	// calendar selection.
+	var calendarIDs []string
+	existing, err := s.repo.Get(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.NeedsReauth {
+		if previous, err := s.lookupProvider(existing.Provider); err == nil && previous.Name() == provider.Name() {
+			calendarIDs = existing.CalendarIDs
+		}
+	}
+	if err := s.replaceConnection(ctx, existing, provider.Name()); err != nil {
+		return err
+	}
+
 	return s.repo.Upsert(ctx, calendar.CalendarConnection{
 		UID:          uid,
-		CalendarID:   "",
+		Provider:     provider.Name(),
+		CalendarIDs:  calendarIDs,
 		AccessToken:  token.AccessToken,
 		RefreshToken: token.RefreshToken,
 		Expiry:       token.Expiry,
 	})
 }
 
+// ConnectFeed replaces the user's connection with an ICS feed or CalDAV
+// server. Sources that cannot be read are rejected as invalid input.
+func (s *CalendarService) ConnectFeed(ctx context.Context, uid string, name calendar.Provider, source calendar.FeedSource) error {
+	provider, err := s.lookupProvider(name)
+	if err != nil {
+		return errpkg.NewInputValidationError("provider", "unknown calendar provider")
+	}
+	feed, ok := provider.(FeedProvider)
+	if !ok {
+		return errpkg.NewInputValidationError("provider", "provider connects through OAuth")
+	}
+	if source.URL == "" {
+		return errpkg.NewInputValidationError("url", "required")
+	}
+
+	token, err := feed.Connect(ctx, source)
+	if errors.Is(err, calendar.ErrAuthorizationRevoked) {
+		return errpkg.NewInputValidationError("password", "credentials rejected")
+	}
+	if err != nil {
+		middleware.Logger(ctx).WithError(err).Warn("Calendar feed connection failed")
+		return errpkg.NewInputValidationError("url", "calendar could not be read")
+	}
+	calendars, err := feed.ListCalendars(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	existing, err := s.repo.Get(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if err := s.replaceConnection(ctx, existing, feed.Name()); err != nil {
+		return err
+	}
+
+	var calendarIDs []string
+	if len(calendars) == 1 {
+		calendarIDs = []string{calendars[0].ID}
+	}
+	return s.repo.Upsert(ctx, calendar.CalendarConnection{
+		UID:         uid,
+		Provider:    feed.Name(),
+		CalendarIDs: calendarIDs,
+		AccessToken: token,
+	})
+}
+
 func (s *CalendarService) GetCalendars(ctx context.Context, uid string) ([]calendar.CalendarItem, error) {
 	conn, err := s.requireConnection(ctx, uid)
 	if err != nil {
@@ -115,10 +213,21 @@ func (s *CalendarService) GetCalendars(ctx context.Context, uid string) ([]calen
 	if conn.AccessToken == "" {
 		return nil, errpkg.NewCalendarNotConnectedError("calendar not connected")
 	}
-	return s.calendarClient.ListCalendars(ctx, conn.AccessToken)
+
+	provider, err := s.lookupProvider(conn.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []calendar.CalendarItem
+	err = s.withAccessToken(ctx, conn, func(accessToken string) error {
+		items, err = provider.ListCalendars(ctx, accessToken)
+		return err
+	})
+	return items, err
 }
 
-func (s *CalendarService) SetCalendar(ctx context.Context, uid, calendarID string) error {
+func (s *CalendarService) SetCalendars(ctx context.Context, uid string, calendarIDs []string) error {
 	conn, err := s.requireConnection(ctx, uid)
 	if err != nil {
 		return err
@@ -127,59 +236,326 @@ func (s *CalendarService) SetCalendar(ctx context.Context, uid, calendarID strin
 		return errpkg.NewCalendarNotConnectedError("calendar not connected")
 	}
 
-	conn.CalendarID = calendarID
-	return s.repo.Upsert(ctx, *conn)
+	selected := make([]string, 0, len(calendarIDs))
+	seen := map[string]struct{}{}
+	for _, id := range calendarIDs {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if _, dup := seen[id]; dup {
+			continue
+		}
+		seen[id] = struct{}{}
+		selected = append(selected, id)
+	}
+	if len(selected) == 0 {
+		return errpkg.NewInputValidationError("calendar_ids", "required")
+	}
+
+	conn.CalendarIDs = selected
+	if err := s.repo.Upsert(ctx, *conn); err != nil {
+		return err
+	}
+
+	// Channels are reconciled again by the renewal job, so a failure here is not fatal.
+	if err := s.WatchUser(ctx, uid); err != nil {
+		middleware.Logger(ctx).WithError(err).Warn("Calendar watch failed")
+	}
+	return nil
 }
 
-func (s *CalendarService) GetSpending(ctx context.Context, uid string, start, end time.Time) (calendar.Spendings, error) {
+// GetSpending aggregates event durations across all selected calendars, grouped by
+// the user's categories (or the provider's label, such as the color, when no
+// category rule matches).
+func (s *CalendarService) GetSpending(ctx context.Context, uid string, start, end time.Time) (_ calendar.Spendings, err error) {
+	ctx, span := tracer.Start(ctx, "CalendarService.GetSpending", spanRange(start, end))
+	defer func() { telemetry.End(span, err) }()
+
+	eventsByCalendar, provider, err := s.fetchSelectedEvents(ctx, uid, start, end)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.loadCategorizer(ctx, uid, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	out := calendar.Spendings{}
+	for _, events := range eventsByCalendar {
+		s.addSpendings(out, c, events, start, end)
+	}
+	return out, nil
+}
+
+// GetSpendingByCalendar aggregates event durations per selected calendar, grouped by category.
+func (s *CalendarService) GetSpendingByCalendar(ctx context.Context, uid string, start, end time.Time) (_ calendar.CalendarSpendings, err error) {
+	ctx, span := tracer.Start(ctx, "CalendarService.GetSpendingByCalendar", spanRange(start, end))
+	defer func() { telemetry.End(span, err) }()
+
+	eventsByCalendar, provider, err := s.fetchSelectedEvents(ctx, uid, start, end)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.loadCategorizer(ctx, uid, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	out := calendar.CalendarSpendings{}
+	for calendarID, events := range eventsByCalendar {
+		spendings := calendar.Spendings{}
+		s.addSpendings(spendings, c, events, start, end)
+		out[calendarID] = spendings
+	}
+	return out, nil
+}
+
+// Disconnect revokes the grant at the provider before deleting anything, so that a
+// revocation failure leaves the connection intact and the call can be retried.
+func (s *CalendarService) Disconnect(ctx context.Context, uid string) error {
+	conn, err := s.repo.Get(ctx, uid)
+	if err != nil {
+		return err
+	}
+
+	if conn == nil {
+		if err := s.dropChannels(ctx, uid, nil, ""); err != nil {
+			return err
+		}
+		return s.dropCache(ctx, uid)
+	}
+	if err := s.releaseGrant(ctx, conn); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, uid); err != nil {
+		return err
+	}
+	return s.dropCache(ctx, uid)
+}
+
+// replaceConnection tears down existing before a connection to another
+// provider overwrites it, as Disconnect would: once overwritten, its grant,
+// watch channels and cached events could no longer be reached.
+func (s *CalendarService) replaceConnection(ctx context.Context, existing *calendar.CalendarConnection, next calendar.Provider) error {
+	if existing == nil {
+		return nil
+	}
+	if previous, err := s.lookupProvider(existing.Provider); err == nil && previous.Name() == next {
+		return nil
+	}
+	if err := s.releaseGrant(ctx, existing); err != nil {
+		return err
+	}
+	return s.dropCache(ctx, existing.UID)
+}
+
+// releaseGrant stops the watch channels of conn and revokes its token.
+func (s *CalendarService) releaseGrant(ctx context.Context, conn *calendar.CalendarConnection) error {
+	provider, err := s.lookupProvider(conn.Provider)
+	if err != nil {
+		return err
+	}
+	if err := s.dropChannels(ctx, conn.UID, provider, conn.AccessToken); err != nil {
+		return err
+	}
+	token := conn.RefreshToken
+	if token == "" {
+		token = conn.AccessToken
+	}
+	if revoker, ok := provider.(tokenRevoker); ok && token != "" {
+		if err := revoker.RevokeToken(ctx, token); err != nil {
+			return fmt.Errorf("revoke %s token: %w", provider.Name(), err)
+		}
+	}
+	return nil
+}
+
+// dropCache removes the user's cached events and sync states, if any are kept.
+func (s *CalendarService) dropCache(ctx context.Context, uid string) error {
+	if s.cache == nil {
+		return nil
+	}
+	return s.cache.DeleteUser(ctx, uid)
+}
+
+// fetchSelectedEvents lists events from every selected calendar, from the
+// event cache when the provider supports it and live otherwise, along with
+// the connection's provider.
+func (s *CalendarService) fetchSelectedEvents(ctx context.Context, uid string, start, end time.Time) (_ map[string][]calendar.Event, _ CalendarProvider, err error) {
+	ctx, span := tracer.Start(ctx, "calendar.fetch_events")
+	defer func() { telemetry.End(span, err) }()
+
 	conn, err := s.requireConnection(ctx, uid)
 	if err != nil {
+		return nil, nil, err
+	}
+	if conn.AccessToken == "" || len(conn.CalendarIDs) == 0 {
+		return nil, nil, errpkg.NewCalendarNotConnectedError("calendar not connected")
+	}
+	provider, err := s.lookupProvider(conn.Provider)
+	if err != nil {
+		return nil, nil, err
+	}
+	span.SetAttributes(
+		attribute.String("calendar.provider", string(provider.Name())),
+		attribute.Int("calendar.calendars", len(conn.CalendarIDs)),
+	)
+
+	var events map[string][]calendar.Event
+	err = s.withAccessToken(ctx, conn, func(accessToken string) error {
+		if syncer, ok := s.syncer(provider); ok {
+			events, err = s.cachedEvents(ctx, conn, provider, syncer, accessToken, start, end)
+		} else {
+			events, err = s.liveEvents(ctx, provider, accessToken, conn.CalendarIDs, start, end)
+		}
+		return err
+	})
+	return events, provider, err
+}
+
+// liveEvents lists events of the given calendars from the provider
+// concurrently, with at most maxConcurrentFetches requests in flight.
+func (s *CalendarService) liveEvents(ctx context.Context, provider CalendarProvider, accessToken string, calendarIDs []string, start, end time.Time) (map[string][]calendar.Event, error) {
+	results := make([][]calendar.Event, len(calendarIDs))
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(s.maxConcurrentFetches)
+	for i, calendarID := range calendarIDs {
+		group.Go(func() error {
+			events, err := provider.ListEvents(groupCtx, accessToken, calendarID, start, end)
+			if err != nil {
+				return fmt.Errorf("list events for calendar %s: %w", calendarID, err)
+			}
+			results[i] = events
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
 		return nil, err
 	}
-	if conn.AccessToken == "" || conn.CalendarID == "" {
-		return nil, errpkg.NewCalendarNotConnectedError("calendar not connected")
+
+	out := make(map[string][]calendar.Event, len(calendarIDs))
+	for i, calendarID := range calendarIDs {
+		out[calendarID] = withCalendarID(results[i], calendarID)
 	}
+	return out, nil
+}
 
+// accessToken returns a usable access token, refreshing and persisting it when expired.
+func (s *CalendarService) accessToken(ctx context.Context, conn *calendar.CalendarConnection) (string, error) {
+	if conn.NeedsReauth {
+		return "", errpkg.NewCalendarReauthRequiredError("")
+	}
+	if conn.Expiry.IsZero() || s.now().Before(conn.Expiry) {
+		return conn.AccessToken, nil
+	}
+	return s.refreshAccessToken(ctx, conn)
+}
+
+// refreshAccessToken exchanges the refresh token for a new access token. An
+// invalid_grant answer means the grant is gone and marks the connection, as
+// does a rejected feed credential, which cannot be refreshed.
+func (s *CalendarService) refreshAccessToken(ctx context.Context, conn *calendar.CalendarConnection) (_ string, err error) {
+	ctx, span := tracer.Start(ctx, "calendar.refresh_token", trace.WithAttributes(attribute.String("calendar.provider", string(conn.Provider))))
+	outcome := "failed"
+	defer func() {
+		tokenRefreshes.Add(ctx, 1, metric.WithAttributes(attribute.String("provider", string(conn.Provider)), attribute.String("outcome", outcome)))
+		telemetry.End(span, err)
+	}()
+
+	// A past expiry forces the token source to refresh, including after a 401
+	// on a token that has not reached its recorded expiry.
 	token := &oauth2.Token{
 		AccessToken:  conn.AccessToken,
 		RefreshToken: conn.RefreshToken,
-		Expiry:       conn.Expiry,
+		Expiry:       time.Unix(1, 0),
 	}
-
-	if !conn.Expiry.IsZero() && !s.now().Before(conn.Expiry) {
-		refreshed, refreshErr := s.oauth.TokenSource(ctx, token).Token()
-		if refreshErr != nil {
-			return nil, refreshErr
-		}
-		conn.AccessToken = refreshed.AccessToken
-		if refreshed.RefreshToken != "" {
-			conn.RefreshToken = refreshed.RefreshToken
-		}
-		conn.Expiry = refreshed.Expiry
-		if err := s.repo.Upsert(ctx, *conn); err != nil {
-			return nil, err
+	provider, err := s.lookupProvider(conn.Provider)
+	if err != nil {
+		return "", err
+	}
+	oauth, ok := provider.(OAuthProvider)
+	if !ok {
+		outcome = "revoked"
+		return "", s.markNeedsReauth(ctx, conn)
+	}
+	refreshed, err := oauth.TokenSource(ctx, token).Token()
+	if err != nil {
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) && retrieveErr.ErrorCode == "invalid_grant" {
+			outcome = "revoked"
+			return "", s.markNeedsReauth(ctx, conn)
 		}
-		token = refreshed
+		return "", err
 	}
+	conn.AccessToken = refreshed.AccessToken
+	if refreshed.RefreshToken != "" {
+		conn.RefreshToken = refreshed.RefreshToken
+	}
+	conn.Expiry = refreshed.Expiry
+	if err := s.repo.Upsert(ctx, *conn); err != nil {
+		return "", err
+	}
+	outcome = "refreshed"
+	return refreshed.AccessToken, nil
+}
 
-	events, err := s.calendarClient.ListEvents(ctx, token.AccessToken, conn.CalendarID, start, end)
+// withAccessToken calls fn with a usable access token. When the provider
+// rejects the token (401) it is refreshed and fn retried once; a second
+// rejection or an invalid_grant on refresh marks the connection as needing
+// reauthorization.
+func (s *CalendarService) withAccessToken(ctx context.Context, conn *calendar.CalendarConnection, fn func(accessToken string) error) error {
+	accessToken, err := s.accessToken(ctx, conn)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	err = fn(accessToken)
+	if !errors.Is(err, calendar.ErrAuthorizationRevoked) {
+		return err
 	}
 
-	out := calendar.Spendings{}
+	accessToken, err = s.refreshAccessToken(ctx, conn)
+	if err != nil {
+		return err
+	}
+	err = fn(accessToken)
+	if errors.Is(err, calendar.ErrAuthorizationRevoked) {
+		return s.markNeedsReauth(ctx, conn)
+	}
+	return err
+}
+
+// markNeedsReauth persists the flag and returns the error surfaced to callers.
+func (s *CalendarService) markNeedsReauth(ctx context.Context, conn *calendar.CalendarConnection) error {
+	conn.NeedsReauth = true
+	if err := s.repo.Upsert(ctx, *conn); err != nil {
+		return err
+	}
+	return errpkg.NewCalendarReauthRequiredError("")
+}
+
+func (s *CalendarService) addSpendings(out calendar.Spendings, c *categorizer, events []calendar.Event, start, end time.Time) {
 	for _, event := range events {
-		if event.Start.IsZero() || event.End.IsZero() || !event.End.After(event.Start) {
+		if !validInterval(event) || !event.CountsAsSpending() {
 			continue
 		}
-		name, ok := colorNamesByID[event.ColorID]
-		if !ok {
-			name = "Default"
-		}
-		out[name] += event.End.Sub(event.Start).Hours()
+		category, _ := c.categorize(event)
+		out[category] += s.eventHours(event, start, end)
 	}
+}
 
-	return out, nil
+// eventHours returns the hours an event contributes to spendings between
+// start and end. All-day events count allDayHours per day in the range
+// instead of their 24h wall-clock span.
+func (s *CalendarService) eventHours(event calendar.Event, start, end time.Time) float64 {
+	if !event.AllDay {
+		return event.End.Sub(event.Start).Hours()
+	}
+	return s.allDayHoursWithin(event, start, end)
+}
+
+func validInterval(event calendar.Event) bool {
+	return !event.Start.IsZero() && !event.End.IsZero() && event.End.After(event.Start)
 }
 
 func (s *CalendarService) requireConnection(ctx context.Context, uid string) (*calendar.CalendarConnection, error) {
@@ -193,44 +569,45 @@ func (s *CalendarService) requireConnection(ctx context.Context, uid string) (*c
 	return conn, nil
 }
 
-func (s *CalendarService) signState(uid string, now time.Time) string {
-	payload := uid + "|" + strconv.FormatInt(now.Unix(), 10)
+func (s *CalendarService) signState(uid string, provider calendar.Provider, now time.Time) string {
+	payload := uid + "|" + string(provider) + "|" + strconv.FormatInt(now.Unix(), 10)
 	mac := hmac.New(sha256.New, []byte(s.stateSecret))
 	_, _ = mac.Write([]byte(payload))
 	signature := hex.EncodeToString(mac.Sum(nil))
 	return base64.RawURLEncoding.EncodeToString([]byte(payload + "|" + signature))
 }
 
-func (s *CalendarService) verifyState(state string) (string, error) {
+func (s *CalendarService) verifyState(state string) (string, calendar.Provider, error) {
 	raw, err := base64.RawURLEncoding.DecodeString(state)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	parts := strings.Split(string(raw), "|")
-	if len(parts) != 3 {
-		return "", errors.New("invalid parts")
+	if len(parts) != 4 {
+		return "", "", errors.New("invalid parts")
 	}
 	uid := parts[0]
-	ts := parts[1]
-	sig := parts[2]
+	provider := calendar.Provider(parts[1])
+	ts := parts[2]
+	sig := parts[3]
 
-	payload := uid + "|" + ts
+	payload := uid + "|" + string(provider) + "|" + ts
 	mac := hmac.New(sha256.New, []byte(s.stateSecret))
 	_, _ = mac.Write([]byte(payload))
 	expected := hex.EncodeToString(mac.Sum(nil))
 	if !hmac.Equal([]byte(expected), []byte(sig)) {
-		return "", errors.New("invalid signature")
+		return "", "", errors.New("invalid signature")
 	}
 
 	unixSeconds, err := strconv.ParseInt(ts, 10, 64)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 	issuedAt := time.Unix(unixSeconds, 0)
 	if s.now().Sub(issuedAt) > s.stateTTL {
-		return "", fmt.Errorf("state expired")
+		return "", "", fmt.Errorf("state expired")
 	}
 
-	return uid, nil
+	return uid, provider, nil
 }
@@ -9,15 +9,28 @@ import (
 	"golang.org/x/oauth2"
 
 	"energyjournal/internal/domain/calendar"
+	"energyjournal/internal/integration/google"
 	errpkg "energyjournal/internal/pkg/error"
 )
 
 type fakeRepo struct {
 	getFn    func(ctx context.Context, uid string) (*calendar.CalendarConnection, error)
 	upsertFn func(ctx context.Context, conn calendar.CalendarConnection) error
+	listFn   func(ctx context.Context) ([]calendar.CalendarConnection, error)
+	deleteFn func(ctx context.Context, uid string) error
+}
+
+func (r *fakeRepo) Delete(ctx context.Context, uid string) error {
+	if r.deleteFn != nil {
+		return r.deleteFn(ctx, uid)
+	}
+	return nil
 }
 
 func (r *fakeRepo) Get(ctx context.Context, uid string) (*calendar.CalendarConnection, error) {
+	if r.getFn == nil {
+		return nil, nil
+	}
 	return r.getFn(ctx, uid)
 }
 
@@ -28,19 +41,64 @@ func (r *fakeRepo) Upsert(ctx context.Context, conn calendar.CalendarConnection)
 	return nil
 }
 
+func (r *fakeRepo) List(ctx context.Context) ([]calendar.CalendarConnection, error) {
+	if r.listFn != nil {
+		return r.listFn(ctx)
+	}
+	return nil, nil
+}
+
 type fakeCalendarClient struct {
-	calendars []calendar.CalendarItem
-	events    []calendar.Event
+	calendars        []calendar.CalendarItem
+	events           []calendar.Event
+	eventsByCalendar map[string][]calendar.Event
+	listEventsErr    error
+	syncFn           func(calendarID, syncToken string) (*calendar.EventChanges, error)
+	revoked          []string
+	revokeErr        error
+	rejectedToken    string
 }
 
-func (c *fakeCalendarClient) ListCalendars(context.Context, string) ([]calendar.CalendarItem, error) {
+func (c *fakeCalendarClient) RevokeToken(_ context.Context, token string) error {
+	if c.revokeErr != nil {
+		return c.revokeErr
+	}
+	c.revoked = append(c.revoked, token)
+	return nil
+}
+
+func (c *fakeCalendarClient) ListCalendars(_ context.Context, accessToken string) ([]calendar.CalendarItem, error) {
+	if c.rejectedToken != "" && accessToken == c.rejectedToken {
+		return nil, calendar.ErrAuthorizationRevoked
+	}
 	return c.calendars, nil
 }
 
-func (c *fakeCalendarClient) ListEvents(context.Context, string, string, time.Time, time.Time) ([]calendar.Event, error) {
+func (c *fakeCalendarClient) ListEvents(_ context.Context, accessToken string, calendarID string, _ time.Time, _ time.Time) ([]calendar.Event, error) {
+	if c.rejectedToken != "" && accessToken == c.rejectedToken {
+		return nil, calendar.ErrAuthorizationRevoked
+	}
+	if c.listEventsErr != nil {
+		return nil, c.listEventsErr
+	}
+	if events, ok := c.eventsByCalendar[calendarID]; ok {
+		return events, nil
+	}
 	return c.events, nil
 }
 
+func (c *fakeCalendarClient) SyncEvents(_ context.Context, _ string, calendarID, syncToken string, _, _ time.Time) (*calendar.EventChanges, error) {
+	return c.syncFn(calendarID, syncToken)
+}
+
+func (c *fakeCalendarClient) WatchEvents(context.Context, string, string, string, string, string, time.Duration) (*calendar.WatchRegistration, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *fakeCalendarClient) StopChannel(context.Context, string, string, string) error {
+	return errors.New("not implemented")
+}
+
 type fakeTokenSource struct {
 	token *oauth2.Token
 	err   error
@@ -51,20 +109,20 @@ func (s fakeTokenSource) Token() (*oauth2.Token, error) {
 }
 
 type fakeOAuth struct {
-	authURL      string
-	exchangeTok  *oauth2.Token
-	exchangeErr  error
-	tokenSource  oauth2.TokenSource
+	authURL     string
+	exchangeTok *oauth2.Token
+	exchangeErr error
+	tokenSource oauth2.TokenSource
 }
 
-func (o *fakeOAuth) AuthCodeURL(state string, _ ...oauth2.AuthCodeOption) string {
+func (o *fakeOAuth) AuthCodeURL(state string) string {
 	if o.authURL != "" {
 		return o.authURL + "?state=" + state
 	}
 	return "https://accounts.example/oauth?state=" + state
 }
 
-func (o *fakeOAuth) Exchange(context.Context, string, ...oauth2.AuthCodeOption) (*oauth2.Token, error) {
+func (o *fakeOAuth) Exchange(context.Context, string) (*oauth2.Token, error) {
 	return o.exchangeTok, o.exchangeErr
 }
 
@@ -72,6 +130,40 @@ func (o *fakeOAuth) TokenSource(context.Context, *oauth2.Token) oauth2.TokenSour
 	return o.tokenSource
 }
 
+// googleClient is the Google API surface, served by fakeCalendarClient or by
+// the real client pointed at a test server.
+type googleClient interface {
+	ListCalendars(ctx context.Context, token string) ([]calendar.CalendarItem, error)
+	ListEvents(ctx context.Context, token, calendarID string, start, end time.Time) ([]calendar.Event, error)
+	eventSyncer
+	channelWatcher
+	tokenRevoker
+}
+
+// fakeProvider is Google backed by fakes, categorizing by Google colors.
+type fakeProvider struct {
+	googleClient
+	*fakeOAuth
+	name calendar.Provider
+}
+
+func newFakeProvider(client googleClient, oauth *fakeOAuth) *fakeProvider {
+	return &fakeProvider{googleClient: client, fakeOAuth: oauth, name: calendar.ProviderGoogle}
+}
+
+func (p *fakeProvider) Name() calendar.Provider {
+	return p.name
+}
+
+func (p *fakeProvider) Category(event calendar.Event) string {
+	return google.NewProvider(nil, nil).Category(event)
+}
+
+func (p *fakeProvider) ColorName(colorID string) (string, bool) {
+	name, ok := google.ColorNames[colorID]
+	return name, ok
+}
+
 func TestGetStatus(t *testing.T) {
 	t.Parallel()
 
@@ -79,7 +171,7 @@ func TestGetStatus(t *testing.T) {
 		getFn: func(context.Context, string) (*calendar.CalendarConnection, error) {
 			return nil, nil
 		},
-	}, &fakeCalendarClient{}, &fakeOAuth{}, "secret")
+	}, newFakeProvider(&fakeCalendarClient{}, &fakeOAuth{}), "secret", &fakeCategoryRepo{})
 
 	status, err := svc.GetStatus(context.Background(), "uid")
 	if err != nil {
@@ -93,7 +185,7 @@ func TestGetStatus(t *testing.T) {
 func TestHandleCallbackInvalidState(t *testing.T) {
 	t.Parallel()
 
-	svc := NewCalendarService(&fakeRepo{}, &fakeCalendarClient{}, &fakeOAuth{}, "secret")
+	svc := NewCalendarService(&fakeRepo{}, newFakeProvider(&fakeCalendarClient{}, &fakeOAuth{}), "secret", &fakeCategoryRepo{})
 	err := svc.HandleCallback(context.Background(), "code", "invalid")
 	if err == nil {
 		t.Fatal("expected error")
@@ -121,10 +213,10 @@ func TestHandleCallbackUpsertsTokens(t *testing.T) {
 			saved = conn
 			return nil
 		},
-	}, &fakeCalendarClient{}, oauth, "secret")
+	}, newFakeProvider(&fakeCalendarClient{}, oauth), "secret", &fakeCategoryRepo{})
 	svc.now = func() time.Time { return time.Date(2026, 3, 3, 11, 0, 0, 0, time.UTC) }
 
-	state := svc.signState("uid-1", svc.now())
+	state := svc.signState("uid-1", calendar.ProviderGoogle, svc.now())
 	if err := svc.HandleCallback(context.Background(), "code", state); err != nil {
 		t.Fatalf("unexpected err: %v", err)
 	}
@@ -141,7 +233,7 @@ func TestGetCalendarsRequiresOAuthConnection(t *testing.T) {
 		getFn: func(context.Context, string) (*calendar.CalendarConnection, error) {
 			return nil, nil
 		},
-	}, &fakeCalendarClient{}, &fakeOAuth{}, "secret")
+	}, newFakeProvider(&fakeCalendarClient{}, &fakeOAuth{}), "secret", &fakeCategoryRepo{})
 
 	_, err := svc.GetCalendars(context.Background(), "uid")
 	if err == nil {
@@ -169,13 +261,33 @@ func TestSetCalendarPersistsSelection(t *testing.T) {
 			saved = conn
 			return nil
 		},
-	}, &fakeCalendarClient{}, &fakeOAuth{}, "secret")
+	}, newFakeProvider(&fakeCalendarClient{}, &fakeOAuth{}), "secret", &fakeCategoryRepo{})
 
-	if err := svc.SetCalendar(context.Background(), "uid", "primary"); err != nil {
+	if err := svc.SetCalendars(context.Background(), "uid", []string{"primary", " work ", "primary", ""}); err != nil {
 		t.Fatalf("unexpected err: %v", err)
 	}
-	if saved.CalendarID != "primary" {
-		t.Fatalf("expected saved calendarID primary, got %q", saved.CalendarID)
+	if len(saved.CalendarIDs) != 2 || saved.CalendarIDs[0] != "primary" || saved.CalendarIDs[1] != "work" {
+		t.Fatalf("expected saved calendarIDs [primary work], got %v", saved.CalendarIDs)
+	}
+}
+
+func TestSetCalendarsRequiresAtLeastOneID(t *testing.T) {
+	t.Parallel()
+
+	svc := NewCalendarService(&fakeRepo{
+		getFn: func(context.Context, string) (*calendar.CalendarConnection, error) {
+			return &calendar.CalendarConnection{UID: "uid", AccessToken: "a"}, nil
+		},
+		upsertFn: func(context.Context, calendar.CalendarConnection) error {
+			t.Fatal("upsert should not be called")
+			return nil
+		},
+	}, newFakeProvider(&fakeCalendarClient{}, &fakeOAuth{}), "secret", &fakeCategoryRepo{})
+
+	err := svc.SetCalendars(context.Background(), "uid", []string{" "})
+	var validationErr *errpkg.InputValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected validation error, got %v", err)
 	}
 }
 
@@ -188,7 +300,7 @@ func TestGetSpendingAggregatesByColorAndRefreshesToken(t *testing.T) {
 		getFn: func(context.Context, string) (*calendar.CalendarConnection, error) {
 			return &calendar.CalendarConnection{
 				UID:          "uid",
-				CalendarID:   "primary",
+				CalendarIDs:  []string{"primary"},
 				AccessToken:  "old",
 				RefreshToken: "refresh",
 				Expiry:       now.Add(-time.Minute),
@@ -201,7 +313,7 @@ func TestGetSpendingAggregatesByColorAndRefreshesToken(t *testing.T) {
 			}
 			return nil
 		},
-	}, &fakeCalendarClient{
+	}, newFakeProvider(&fakeCalendarClient{
 		events: []calendar.Event{
 			{ColorID: "5", Start: now.Add(-4 * time.Hour), End: now.Add(-3 * time.Hour)},
 			{ColorID: "5", Start: now.Add(-3 * time.Hour), End: now.Add(-90 * time.Minute)},
@@ -216,7 +328,7 @@ func TestGetSpendingAggregatesByColorAndRefreshesToken(t *testing.T) {
 				Expiry:       now.Add(time.Hour),
 			},
 		},
-	}, "secret")
+	}), "secret", &fakeCategoryRepo{})
 	svc.now = func() time.Time { return now }
 
 	result, err := svc.GetSpending(context.Background(), "uid", now.Add(-24*time.Hour), now)
@@ -234,15 +346,361 @@ func TestGetSpendingAggregatesByColorAndRefreshesToken(t *testing.T) {
 	}
 }
 
+func TestGetSpendingMergesAllSelectedCalendars(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)
+	svc := NewCalendarService(&fakeRepo{
+		getFn: func(context.Context, string) (*calendar.CalendarConnection, error) {
+			return &calendar.CalendarConnection{
+				UID:         "uid",
+				CalendarIDs: []string{"work", "personal", "family"},
+				AccessToken: "token",
+			}, nil
+		},
+	}, newFakeProvider(&fakeCalendarClient{
+		eventsByCalendar: map[string][]calendar.Event{
+			"work":     {{ColorID: "5", Start: now.Add(-2 * time.Hour), End: now}},
+			"personal": {{ColorID: "5", Start: now, End: now.Add(time.Hour)}},
+			"family":   {{ColorID: "1", Start: now, End: now.Add(30 * time.Minute)}},
+		},
+	}, &fakeOAuth{}), "secret", &fakeCategoryRepo{})
+	svc.maxConcurrentFetches = 2
+
+	total, err := svc.GetSpending(context.Background(), "uid", now.Add(-24*time.Hour), now)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if total["Sage"] != 3 || total["Tomato"] != 0.5 {
+		t.Fatalf("unexpected totals: %v", total)
+	}
+
+	byCalendar, err := svc.GetSpendingByCalendar(context.Background(), "uid", now.Add(-24*time.Hour), now)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(byCalendar) != 3 {
+		t.Fatalf("expected 3 calendars, got %d", len(byCalendar))
+	}
+	if byCalendar["work"]["Sage"] != 2 || byCalendar["personal"]["Sage"] != 1 || byCalendar["family"]["Tomato"] != 0.5 {
+		t.Fatalf("unexpected per-calendar spendings: %v", byCalendar)
+	}
+}
+
+func TestGetSpendingFailsWhenAnyCalendarFails(t *testing.T) {
+	t.Parallel()
+
+	svc := NewCalendarService(&fakeRepo{
+		getFn: func(context.Context, string) (*calendar.CalendarConnection, error) {
+			return &calendar.CalendarConnection{
+				UID:         "uid",
+				CalendarIDs: []string{"work", "personal"},
+				AccessToken: "token",
+			}, nil
+		},
+	}, newFakeProvider(&fakeCalendarClient{listEventsErr: errors.New("boom")}, &fakeOAuth{}), "secret", &fakeCategoryRepo{})
+
+	_, err := svc.GetSpending(context.Background(), "uid", time.Now().Add(-time.Hour), time.Now())
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestGetSpendingSkipsDeclinedCancelledAndFreeEvents(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)
+	declined := []calendar.Attendee{
+		{Email: "boss@example.com", Organizer: true, ResponseStatus: calendar.ResponseAccepted},
+		{Email: "me@example.com", Self: true, ResponseStatus: calendar.ResponseDeclined},
+	}
+	svc := NewCalendarService(connectedRepo("primary"), newFakeProvider(&fakeCalendarClient{
+		events: []calendar.Event{
+			{ColorID: "5", Start: now, End: now.Add(time.Hour), Status: calendar.EventConfirmed},
+			{ColorID: "5", Start: now, End: now.Add(2 * time.Hour), Status: calendar.EventCancelled},
+			{ColorID: "5", Start: now, End: now.Add(4 * time.Hour), Attendees: declined},
+			{ColorID: "5", Start: now, End: now.Add(30 * time.Minute), Status: calendar.EventTentative},
+			{ColorID: "5", Start: now, End: now.Add(8 * time.Hour), Transparent: true},
+		},
+	}, &fakeOAuth{}), "secret", &fakeCategoryRepo{})
+
+	result, err := svc.GetSpending(context.Background(), "uid", now, now.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if result["Sage"] != 1.5 {
+		t.Fatalf("expected Sage=1.5, got %v", result["Sage"])
+	}
+}
+
+func TestGetSpendingCountsAllDayEvents(t *testing.T) {
+	t.Parallel()
+
+	day := time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC)
+	client := &fakeCalendarClient{
+		events: []calendar.Event{
+			{ColorID: "1", Start: day, End: day.AddDate(0, 0, 2), AllDay: true},
+			{ColorID: "1", Start: day.Add(9 * time.Hour), End: day.Add(10 * time.Hour)},
+		},
+	}
+
+	ignored := NewCalendarService(connectedRepo("primary"), newFakeProvider(client, &fakeOAuth{}), "secret", &fakeCategoryRepo{})
+	result, err := ignored.GetSpending(context.Background(), "uid", day, day.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if result["Tomato"] != 1 {
+		t.Fatalf("expected all-day events to be ignored by default, got %v", result)
+	}
+
+	counted := NewCalendarService(connectedRepo("primary"), newFakeProvider(client, &fakeOAuth{}), "secret", &fakeCategoryRepo{}).WithAllDayHours(8)
+	result, err = counted.GetSpending(context.Background(), "uid", day, day.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if result["Tomato"] != 17 {
+		t.Fatalf("expected 2 days x 8h + 1h, got %v", result)
+	}
+}
+
+func TestGetSpendingCountsOnlyAllDayEventDaysInRange(t *testing.T) {
+	t.Parallel()
+
+	day := time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC)
+	client := &fakeCalendarClient{
+		events: []calendar.Event{{ColorID: "1", Start: day, End: day.AddDate(0, 0, 7), AllDay: true}},
+	}
+	svc := NewCalendarService(connectedRepo("primary"), newFakeProvider(client, &fakeOAuth{}), "secret", &fakeCategoryRepo{}).WithAllDayHours(8)
+
+	result, err := svc.GetSpending(context.Background(), "uid", day.AddDate(0, 0, 2), day.AddDate(0, 0, 4).Add(12*time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if result["Tomato"] != 24 {
+		t.Fatalf("expected the 3 days overlapping the range x 8h, got %v", result)
+	}
+}
+
 func TestVerifyStateExpired(t *testing.T) {
 	t.Parallel()
 
-	svc := NewCalendarService(&fakeRepo{}, &fakeCalendarClient{}, &fakeOAuth{}, "secret")
+	svc := NewCalendarService(&fakeRepo{}, newFakeProvider(&fakeCalendarClient{}, &fakeOAuth{}), "secret", &fakeCategoryRepo{})
 	svc.now = func() time.Time { return time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC) }
-	expired := svc.signState("uid", svc.now().Add(-20*time.Minute))
+	expired := svc.signState("uid", calendar.ProviderGoogle, svc.now().Add(-20*time.Minute))
 
-	_, err := svc.verifyState(expired)
+	_, _, err := svc.verifyState(expired)
 	if err == nil {
 		t.Fatal("expected expiry error")
 	}
 }
+
+func TestDisconnectRevokesAndForgetsConnection(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)
+	stored := &calendar.CalendarConnection{UID: "uid", CalendarIDs: []string{"primary"}, AccessToken: "access", RefreshToken: "refresh"}
+	repo := &fakeRepo{
+		getFn: func(context.Context, string) (*calendar.CalendarConnection, error) {
+			return stored, nil
+		},
+		deleteFn: func(context.Context, string) error {
+			stored = nil
+			return nil
+		},
+	}
+	client := &fakeCalendarClient{}
+	cache := newFakeEventCache()
+	_ = cache.ReplaceCalendar(context.Background(), "uid", "primary", []calendar.Event{{ID: "e", CalendarID: "primary", Start: now, End: now.Add(time.Hour)}})
+	_ = cache.SaveSyncState(context.Background(), calendar.SyncState{UID: "uid", CalendarID: "primary", SyncToken: "t"})
+	channels := newFakeChannelRepo()
+	_ = channels.Save(context.Background(), calendar.WatchChannel{ID: "ch", UID: "uid", CalendarID: "primary"})
+
+	svc := NewCalendarService(repo, newFakeProvider(client, &fakeOAuth{}), "secret", &fakeCategoryRepo{}).
+		WithEventCache(cache).
+		WithWatchChannels(channels, "https://api.example.test/calendar/webhook")
+
+	for range 2 {
+		if err := svc.Disconnect(context.Background(), "uid"); err != nil {
+			t.Fatalf("Disconnect returned error: %v", err)
+		}
+	}
+
+	if len(client.revoked) != 1 || client.revoked[0] != "refresh" {
+		t.Fatalf("expected the refresh token to be revoked once, got %v", client.revoked)
+	}
+	status, err := svc.GetStatus(context.Background(), "uid")
+	if err != nil || status != calendar.StatusDisconnected {
+		t.Fatalf("expected disconnected status, got %s (%v)", status, err)
+	}
+	if state, _ := cache.GetSyncState(context.Background(), "uid", "primary"); state != nil {
+		t.Fatalf("expected cached sync state to be cleared, got %+v", state)
+	}
+	if events, _ := cache.ListEvents(context.Background(), "uid", []string{"primary"}, now.Add(-time.Hour), now.Add(time.Hour)); len(events) != 0 {
+		t.Fatalf("expected cached events to be cleared, got %+v", events)
+	}
+	if remaining, _ := channels.ListByUID(context.Background(), "uid"); len(remaining) != 0 {
+		t.Fatalf("expected watch channels to be removed, got %+v", remaining)
+	}
+}
+
+func TestDisconnectKeepsConnectionWhenRevokeFails(t *testing.T) {
+	t.Parallel()
+
+	deleted := false
+	repo := &fakeRepo{
+		getFn: func(context.Context, string) (*calendar.CalendarConnection, error) {
+			return &calendar.CalendarConnection{UID: "uid", AccessToken: "access", RefreshToken: "refresh"}, nil
+		},
+		deleteFn: func(context.Context, string) error {
+			deleted = true
+			return nil
+		},
+	}
+	svc := NewCalendarService(repo, newFakeProvider(&fakeCalendarClient{revokeErr: errors.New("google down")}, &fakeOAuth{}), "secret", &fakeCategoryRepo{})
+
+	if err := svc.Disconnect(context.Background(), "uid"); err == nil {
+		t.Fatal("expected revoke error")
+	}
+	if deleted {
+		t.Fatal("connection must be kept so the disconnect can be retried")
+	}
+}
+
+func TestHandleCallbackKeepsSelectionAfterReauth(t *testing.T) {
+	t.Parallel()
+
+	var saved calendar.CalendarConnection
+	svc := NewCalendarService(&fakeRepo{
+		getFn: func(context.Context, string) (*calendar.CalendarConnection, error) {
+			return &calendar.CalendarConnection{UID: "uid-1", CalendarIDs: []string{"primary", "work"}, NeedsReauth: true}, nil
+		},
+		upsertFn: func(_ context.Context, conn calendar.CalendarConnection) error {
+			saved = conn
+			return nil
+		},
+	}, newFakeProvider(&fakeCalendarClient{}, &fakeOAuth{exchangeTok: &oauth2.Token{AccessToken: "access", RefreshToken: "refresh"}}), "secret", &fakeCategoryRepo{})
+
+	state := svc.signState("uid-1", calendar.ProviderGoogle, svc.now())
+	if err := svc.HandleCallback(context.Background(), "code", state); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if saved.NeedsReauth || len(saved.CalendarIDs) != 2 || saved.AccessToken != "access" {
+		t.Fatalf("unexpected saved connection: %+v", saved)
+	}
+}
+
+func TestGetSpendingMarksConnectionOnInvalidGrant(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)
+	stored := &calendar.CalendarConnection{
+		UID:          "uid",
+		CalendarIDs:  []string{"primary"},
+		AccessToken:  "old",
+		RefreshToken: "refresh",
+		Expiry:       now.Add(-time.Minute),
+	}
+	repo := &fakeRepo{
+		getFn: func(context.Context, string) (*calendar.CalendarConnection, error) {
+			conn := *stored
+			return &conn, nil
+		},
+		upsertFn: func(_ context.Context, conn calendar.CalendarConnection) error {
+			*stored = conn
+			return nil
+		},
+	}
+	provider := newFakeProvider(&fakeCalendarClient{}, &fakeOAuth{
+		tokenSource: fakeTokenSource{err: &oauth2.RetrieveError{ErrorCode: "invalid_grant"}},
+	})
+	svc := NewCalendarService(repo, provider, "secret", &fakeCategoryRepo{})
+	svc.now = func() time.Time { return now }
+
+	_, err := svc.GetSpending(context.Background(), "uid", now.Add(-24*time.Hour), now)
+	var reauthErr *errpkg.CalendarReauthRequiredError
+	if !errors.As(err, &reauthErr) {
+		t.Fatalf("expected CalendarReauthRequiredError, got %v", err)
+	}
+	if !stored.NeedsReauth || stored.RefreshToken != "refresh" || len(stored.CalendarIDs) != 1 {
+		t.Fatalf("expected the connection to be flagged and kept, got %+v", stored)
+	}
+
+	status, err := svc.GetStatus(context.Background(), "uid")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if status != calendar.StatusNeedsReauth {
+		t.Fatalf("expected needs_reauth, got %s", status)
+	}
+
+	// Further reads fail fast without another refresh attempt.
+	provider.fakeOAuth = &fakeOAuth{}
+	if _, err := svc.GetSpending(context.Background(), "uid", now.Add(-24*time.Hour), now); !errors.As(err, &reauthErr) {
+		t.Fatalf("expected CalendarReauthRequiredError, got %v", err)
+	}
+}
+
+func TestGetSpendingRefreshesAndRetriesAfterUnauthorized(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)
+	var saved calendar.CalendarConnection
+	svc := NewCalendarService(&fakeRepo{
+		getFn: func(context.Context, string) (*calendar.CalendarConnection, error) {
+			return &calendar.CalendarConnection{
+				UID:          "uid",
+				CalendarIDs:  []string{"primary"},
+				AccessToken:  "stale",
+				RefreshToken: "refresh",
+				Expiry:       now.Add(time.Hour),
+			}, nil
+		},
+		upsertFn: func(_ context.Context, conn calendar.CalendarConnection) error {
+			saved = conn
+			return nil
+		},
+	}, newFakeProvider(&fakeCalendarClient{
+		rejectedToken: "stale",
+		events:        []calendar.Event{{ColorID: "5", Start: now.Add(-time.Hour), End: now}},
+	}, &fakeOAuth{
+		tokenSource: fakeTokenSource{token: &oauth2.Token{AccessToken: "fresh", Expiry: now.Add(time.Hour)}},
+	}), "secret", &fakeCategoryRepo{})
+	svc.now = func() time.Time { return now }
+
+	result, err := svc.GetSpending(context.Background(), "uid", now.Add(-24*time.Hour), now)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if result["Sage"] != 1 {
+		t.Fatalf("expected Sage=1, got %v", result)
+	}
+	if saved.AccessToken != "fresh" || saved.NeedsReauth {
+		t.Fatalf("expected refreshed token to be persisted, got %+v", saved)
+	}
+}
+
+func TestGetCalendarsMarksConnectionWhenRetryIsRejected(t *testing.T) {
+	t.Parallel()
+
+	var saved calendar.CalendarConnection
+	svc := NewCalendarService(&fakeRepo{
+		getFn: func(context.Context, string) (*calendar.CalendarConnection, error) {
+			return &calendar.CalendarConnection{UID: "uid", AccessToken: "stale", RefreshToken: "refresh"}, nil
+		},
+		upsertFn: func(_ context.Context, conn calendar.CalendarConnection) error {
+			saved = conn
+			return nil
+		},
+	}, newFakeProvider(&fakeCalendarClient{rejectedToken: "stale"}, &fakeOAuth{
+		tokenSource: fakeTokenSource{token: &oauth2.Token{AccessToken: "stale"}},
+	}), "secret", &fakeCategoryRepo{})
+
+	_, err := svc.GetCalendars(context.Background(), "uid")
+	var reauthErr *errpkg.CalendarReauthRequiredError
+	if !errors.As(err, &reauthErr) {
+		t.Fatalf("expected CalendarReauthRequiredError, got %v", err)
+	}
+	if !saved.NeedsReauth {
+		t.Fatalf("expected connection to be flagged, got %+v", saved)
+	}
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"energyjournal/internal/domain/calendar"
+	errpkg "energyjournal/internal/pkg/error"
+)
+
+const categoryRulesCollection = "calendar_category_rules"
+
+type CategoryRuleRepository struct {
+	client *firestore.Client
+}
+
+func NewCategoryRuleRepository(client *firestore.Client) *CategoryRuleRepository {
+	return &CategoryRuleRepository{client: client}
+}
+
+func (r *CategoryRuleRepository) List(ctx context.Context, uid string) ([]calendar.CategoryRule, error) {
+	iter := r.client.Collection(categoryRulesCollection).Where("uid", "==", uid).Documents(ctx)
+	defer iter.Stop()
+
+	rules := []calendar.CategoryRule{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, docToCategoryRule(doc))
+	}
+	return rules, nil
+}
+
+// Get returns the rule only when it belongs to uid, so rule IDs cannot be probed across users.
+func (r *CategoryRuleRepository) Get(ctx context.Context, uid, id string) (*calendar.CategoryRule, error) {
+	doc, err := r.client.Collection(categoryRulesCollection).Doc(id).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, errpkg.NewNotFoundError("category_rule", id)
+		}
+		return nil, err
+	}
+
+	rule := docToCategoryRule(doc)
+	if rule.UID != uid {
+		return nil, errpkg.NewNotFoundError("category_rule", id)
+	}
+	return &rule, nil
+}
+
+// Create stores a new rule and sets its generated ID.
+func (r *CategoryRuleRepository) Create(ctx context.Context, rule *calendar.CategoryRule) error {
+	docRef := r.client.Collection(categoryRulesCollection).NewDoc()
+	if _, err := docRef.Set(ctx, categoryRuleData(*rule)); err != nil {
+		return err
+	}
+	rule.ID = docRef.ID
+	return nil
+}
+
+func (r *CategoryRuleRepository) Update(ctx context.Context, rule calendar.CategoryRule) error {
+	_, err := r.client.Collection(categoryRulesCollection).Doc(rule.ID).Set(ctx, categoryRuleData(rule))
+	return err
+}
+
+func (r *CategoryRuleRepository) Delete(ctx context.Context, uid, id string) error {
+	if _, err := r.Get(ctx, uid, id); err != nil {
+		return err
+	}
+	_, err := r.client.Collection(categoryRulesCollection).Doc(id).Delete(ctx)
+	return err
+}
+
+func categoryRuleData(rule calendar.CategoryRule) map[string]any {
+	return map[string]any{
+		"uid":        rule.UID,
+		"category":   rule.Category,
+		"match_type": string(rule.MatchType),
+		"value":      rule.Value,
+		"priority":   rule.Priority,
+		"created_at": rule.CreatedAt,
+		"updated_at": rule.UpdatedAt,
+	}
+}
+
+func docToCategoryRule(doc *firestore.DocumentSnapshot) calendar.CategoryRule {
+	data := doc.Data()
+	return calendar.CategoryRule{
+		ID:        doc.Ref.ID,
+		UID:       getString(data, "uid"),
+		Category:  getString(data, "category"),
+		MatchType: calendar.MatchType(getString(data, "match_type")),
+		Value:     getString(data, "value"),
+		Priority:  getInt(data, "priority"),
+		CreatedAt: getTime(data, "created_at"),
+		UpdatedAt: getTime(data, "updated_at"),
+	}
+}
+
+func getInt(data map[string]any, key string) int {
+	switch v := data[key].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	default:
+		return 0
+	}
+}
@@ -2,23 +2,32 @@ package storage
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	"energyjournal/internal/domain/calendar"
+	"energyjournal/internal/pkg/encryption"
 )
 
 const connectionCollection = "calendar_connections"
 
+// tokenFields are encrypted at rest with the repository's envelope.
+var tokenFields = []string{"access_token", "refresh_token"}
+
 type ConnectionRepository struct {
 	client *firestore.Client
+	tokens *encryption.Envelope
 }
 
-func NewConnectionRepository(client *firestore.Client) *ConnectionRepository {
-	return &ConnectionRepository{client: client}
+// NewConnectionRepository stores OAuth tokens encrypted with tokens. Documents
+// still holding plaintext tokens are read transparently until MigrateTokens runs.
+func NewConnectionRepository(client *firestore.Client, tokens *encryption.Envelope) *ConnectionRepository {
+	return &ConnectionRepository{client: client, tokens: tokens}
 }
 
 func (r *ConnectionRepository) Get(ctx context.Context, uid string) (*calendar.CalendarConnection, error) {
@@ -30,31 +39,174 @@ func (r *ConnectionRepository) Get(ctx context.Context, uid string) (*calendar.C
 		return nil, err
 	}
 
-	data := doc.Data()
-	return &calendar.CalendarConnection{
-		UID:          uid,
-		CalendarID:   getString(data, "calendar_id"),
-		AccessToken:  getString(data, "access_token"),
-		RefreshToken: getString(data, "refresh_token"),
-		Expiry:       getTime(data, "expiry"),
-	}, nil
+	conn, err := r.docToConnection(ctx, doc)
+	if err != nil {
+		return nil, err
+	}
+	return &conn, nil
+}
+
+func (r *ConnectionRepository) Delete(ctx context.Context, uid string) error {
+	_, err := r.client.Collection(connectionCollection).Doc(uid).Delete(ctx)
+	return err
+}
+
+func (r *ConnectionRepository) List(ctx context.Context) ([]calendar.CalendarConnection, error) {
+	iter := r.client.Collection(connectionCollection).Documents(ctx)
+	defer iter.Stop()
+
+	conns := []calendar.CalendarConnection{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		conn, err := r.docToConnection(ctx, doc)
+		if err != nil {
+			return nil, err
+		}
+		conns = append(conns, conn)
+	}
+	return conns, nil
 }
 
 func (r *ConnectionRepository) Upsert(ctx context.Context, conn calendar.CalendarConnection) error {
-	_, err := r.client.Collection(connectionCollection).Doc(conn.UID).Set(ctx, map[string]any{
-		"access_token":  conn.AccessToken,
-		"refresh_token": conn.RefreshToken,
-		"calendar_id":   conn.CalendarID,
+	aad := tokenAAD(conn.UID, string(conn.Provider))
+	accessToken, err := r.tokens.Encrypt(ctx, conn.AccessToken, aad)
+	if err != nil {
+		return fmt.Errorf("encrypt access token: %w", err)
+	}
+	refreshToken, err := r.tokens.Encrypt(ctx, conn.RefreshToken, aad)
+	if err != nil {
+		return fmt.Errorf("encrypt refresh token: %w", err)
+	}
+
+	_, err = r.client.Collection(connectionCollection).Doc(conn.UID).Set(ctx, map[string]any{
+		"provider":      string(conn.Provider),
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"calendar_ids":  conn.CalendarIDs,
 		"expiry":        conn.Expiry,
+		"needs_reauth":  conn.NeedsReauth,
 	})
 	return err
 }
 
+// MigrateTokens re-encrypts token fields that are still plaintext or wrapped
+// by a key other than the primary one, and returns how many documents changed.
+// Updates are conditioned on the document being unchanged since it was read,
+// so a concurrent Upsert (which always encrypts) wins.
+func (r *ConnectionRepository) MigrateTokens(ctx context.Context) (int, error) {
+	iter := r.client.Collection(connectionCollection).Documents(ctx)
+	defer iter.Stop()
+
+	migrated := 0
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return migrated, err
+		}
+
+		var updates []firestore.Update
+		aad := tokenAAD(doc.Ref.ID, getString(doc.Data(), "provider"))
+		for _, field := range tokenFields {
+			value := getString(doc.Data(), field)
+			if !r.tokens.NeedsReencryption(value) {
+				continue
+			}
+			plaintext, err := r.tokens.Decrypt(ctx, value, aad)
+			if err != nil {
+				return migrated, fmt.Errorf("%s %s: %w", doc.Ref.ID, field, err)
+			}
+			encrypted, err := r.tokens.Encrypt(ctx, plaintext, aad)
+			if err != nil {
+				return migrated, err
+			}
+			updates = append(updates, firestore.Update{Path: field, Value: encrypted})
+		}
+		if len(updates) == 0 {
+			continue
+		}
+
+		if _, err := doc.Ref.Update(ctx, updates, firestore.LastUpdateTime(doc.UpdateTime)); err != nil {
+			if status.Code(err) == codes.FailedPrecondition {
+				continue
+			}
+			return migrated, err
+		}
+		migrated++
+	}
+	return migrated, nil
+}
+
+func (r *ConnectionRepository) docToConnection(ctx context.Context, doc *firestore.DocumentSnapshot) (calendar.CalendarConnection, error) {
+	data := doc.Data()
+	aad := tokenAAD(doc.Ref.ID, getString(data, "provider"))
+	accessToken, err := r.tokens.Decrypt(ctx, getString(data, "access_token"), aad)
+	if err != nil {
+		return calendar.CalendarConnection{}, fmt.Errorf("decrypt access token: %w", err)
+	}
+	refreshToken, err := r.tokens.Decrypt(ctx, getString(data, "refresh_token"), aad)
+	if err != nil {
+		return calendar.CalendarConnection{}, fmt.Errorf("decrypt refresh token: %w", err)
+	}
+
+	return calendar.CalendarConnection{
+		UID:          doc.Ref.ID,
+		Provider:     calendar.Provider(getString(data, "provider")),
+		CalendarIDs:  getCalendarIDs(data),
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		Expiry:       getTime(data, "expiry"),
+		NeedsReauth:  getBool(data, "needs_reauth"),
+	}, nil
+}
+
+// tokenAAD binds the encrypted tokens of a connection to its user and
+// provider, so that tokens copied into another connection do not decrypt.
+func tokenAAD(uid, provider string) []byte {
+	return []byte(uid + "\x00" + provider)
+}
+
 func getString(data map[string]any, key string) string {
 	v, _ := data[key].(string)
 	return v
 }
 
+// getCalendarIDs reads the selected calendars, falling back to the
+// single calendar_id field written before multi-calendar support.
+func getCalendarIDs(data map[string]any) []string {
+	if _, ok := data["calendar_ids"].([]any); !ok {
+		if legacy := getString(data, "calendar_id"); legacy != "" {
+			return []string{legacy}
+		}
+		return nil
+	}
+	return getStrings(data, "calendar_ids")
+}
+
+// getStrings reads a string array, skipping empty and non-string items.
+func getStrings(data map[string]any, key string) []string {
+	raw, ok := data[key].([]any)
+	if !ok {
+		return nil
+	}
+
+	values := make([]string, 0, len(raw))
+	for _, item := range raw {
+		if value, ok := item.(string); ok && value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 func getTime(data map[string]any, key string) time.Time {
 	v := data[key]
 	switch t := v.(type) {
@@ -0,0 +1,293 @@
+package storage
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"energyjournal/internal/domain/calendar"
+)
+
+// Cached events live under calendar_event_cache/{uid}/events and the per-calendar
+// sync states under calendar_event_cache/{uid}/sync_states.
+const (
+	eventCacheCollection = "calendar_event_cache"
+	cachedEventsSubcoll  = "events"
+	syncStatesSubcoll    = "sync_states"
+)
+
+type EventCacheRepository struct {
+	client *firestore.Client
+}
+
+func NewEventCacheRepository(client *firestore.Client) *EventCacheRepository {
+	return &EventCacheRepository{client: client}
+}
+
+// ReplaceCalendar writes the new events before deleting the stale ones, so
+// that a failure never leaves the calendar empty. A failed replacement also
+// drops the calendar's sync state: the next sync then starts over with a full
+// one instead of trusting a partly written cache.
+func (r *EventCacheRepository) ReplaceCalendar(ctx context.Context, uid, calendarID string, events []calendar.Event) error {
+	err := r.bulkWrite(ctx, func(writes *bulkWrites) error {
+		kept := make(map[string]bool, len(events))
+		for _, event := range events {
+			if event.ID == "" || event.Status == calendar.EventCancelled {
+				continue
+			}
+			ref := r.eventDoc(uid, calendarID, event.ID)
+			kept[ref.ID] = true
+			if err := writes.set(ref, eventData(event)); err != nil {
+				return err
+			}
+		}
+
+		iter := r.events(uid).Where("calendar_id", "==", calendarID).Documents(ctx)
+		defer iter.Stop()
+		for {
+			doc, err := iter.Next()
+			if err == iterator.Done {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if kept[doc.Ref.ID] {
+				continue
+			}
+			if err := writes.delete(doc.Ref); err != nil {
+				return err
+			}
+		}
+	})
+	if err != nil {
+		_, _ = r.syncStates(uid).Doc(url.PathEscape(calendarID)).Delete(ctx)
+	}
+	return err
+}
+
+func (r *EventCacheRepository) ApplyChanges(ctx context.Context, uid, calendarID string, events []calendar.Event) error {
+	return r.bulkWrite(ctx, func(writes *bulkWrites) error {
+		for _, event := range events {
+			if event.ID == "" {
+				continue
+			}
+			ref := r.eventDoc(uid, calendarID, event.ID)
+			var err error
+			if event.Status == calendar.EventCancelled {
+				err = writes.delete(ref)
+			} else {
+				err = writes.set(ref, eventData(event))
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListEvents queries on both bounds, which needs the composite index on
+// events (end ASC, start ASC) declared in firestore.indexes.json; calendar
+// selection is applied in memory.
+func (r *EventCacheRepository) ListEvents(ctx context.Context, uid string, calendarIDs []string, start, end time.Time) ([]calendar.Event, error) {
+	selected := make(map[string]struct{}, len(calendarIDs))
+	for _, id := range calendarIDs {
+		selected[id] = struct{}{}
+	}
+
+	iter := r.events(uid).Where("end", ">", start).Where("start", "<", end).Documents(ctx)
+	defer iter.Stop()
+
+	events := []calendar.Event{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		event := docToEvent(doc)
+		if _, ok := selected[event.CalendarID]; !ok {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (r *EventCacheRepository) GetSyncState(ctx context.Context, uid, calendarID string) (*calendar.SyncState, error) {
+	doc, err := r.syncStates(uid).Doc(url.PathEscape(calendarID)).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	data := doc.Data()
+	return &calendar.SyncState{
+		UID:          uid,
+		CalendarID:   getString(data, "calendar_id"),
+		SyncToken:    getString(data, "sync_token"),
+		WindowStart:  getTime(data, "window_start"),
+		WindowEnd:    getTime(data, "window_end"),
+		LastFullSync: getTime(data, "last_full_sync"),
+		LastSyncedAt: getTime(data, "last_synced_at"),
+	}, nil
+}
+
+func (r *EventCacheRepository) SaveSyncState(ctx context.Context, state calendar.SyncState) error {
+	_, err := r.syncStates(state.UID).Doc(url.PathEscape(state.CalendarID)).Set(ctx, map[string]any{
+		"calendar_id":    state.CalendarID,
+		"sync_token":     state.SyncToken,
+		"window_start":   state.WindowStart,
+		"window_end":     state.WindowEnd,
+		"last_full_sync": state.LastFullSync,
+		"last_synced_at": state.LastSyncedAt,
+	})
+	return err
+}
+
+func (r *EventCacheRepository) DeleteUser(ctx context.Context, uid string) error {
+	return r.bulkWrite(ctx, func(writes *bulkWrites) error {
+		for _, coll := range []*firestore.CollectionRef{r.events(uid), r.syncStates(uid)} {
+			refs := coll.DocumentRefs(ctx)
+			for {
+				ref, err := refs.Next()
+				if err == iterator.Done {
+					break
+				}
+				if err != nil {
+					return err
+				}
+				if err := writes.delete(ref); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// bulkWrites keeps the jobs of a BulkWriter, whose writes fail asynchronously.
+type bulkWrites struct {
+	writer *firestore.BulkWriter
+	jobs   []*firestore.BulkWriterJob
+}
+
+func (b *bulkWrites) set(ref *firestore.DocumentRef, data map[string]any) error {
+	job, err := b.writer.Set(ref, data)
+	if err != nil {
+		return err
+	}
+	b.jobs = append(b.jobs, job)
+	return nil
+}
+
+func (b *bulkWrites) delete(ref *firestore.DocumentRef) error {
+	job, err := b.writer.Delete(ref)
+	if err != nil {
+		return err
+	}
+	b.jobs = append(b.jobs, job)
+	return nil
+}
+
+// bulkWrite runs write on a BulkWriter and waits for its writes, returning
+// the first error, so that callers only save a sync state once every change
+// is stored.
+func (r *EventCacheRepository) bulkWrite(ctx context.Context, write func(writes *bulkWrites) error) error {
+	writes := &bulkWrites{writer: r.client.BulkWriter(ctx)}
+	err := write(writes)
+	writes.writer.End()
+	if err != nil {
+		return err
+	}
+	for _, job := range writes.jobs {
+		if _, err := job.Results(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *EventCacheRepository) events(uid string) *firestore.CollectionRef {
+	return r.client.Collection(eventCacheCollection).Doc(uid).Collection(cachedEventsSubcoll)
+}
+
+func (r *EventCacheRepository) syncStates(uid string) *firestore.CollectionRef {
+	return r.client.Collection(eventCacheCollection).Doc(uid).Collection(syncStatesSubcoll)
+}
+
+// eventDoc keys events by calendar and event ID; both are escaped because
+// calendar IDs may contain characters Firestore rejects in document IDs.
+func (r *EventCacheRepository) eventDoc(uid, calendarID, eventID string) *firestore.DocumentRef {
+	return r.events(uid).Doc(url.PathEscape(calendarID) + "|" + url.PathEscape(eventID))
+}
+
+func eventData(event calendar.Event) map[string]any {
+	attendees := make([]map[string]any, 0, len(event.Attendees))
+	for _, attendee := range event.Attendees {
+		attendees = append(attendees, map[string]any{
+			"email":           attendee.Email,
+			"self":            attendee.Self,
+			"organizer":       attendee.Organizer,
+			"response_status": string(attendee.ResponseStatus),
+		})
+	}
+	return map[string]any{
+		"id":          event.ID,
+		"calendar_id": event.CalendarID,
+		"summary":     event.Summary,
+		"color_id":    event.ColorID,
+		"start":       event.Start,
+		"end":         event.End,
+		"all_day":     event.AllDay,
+		"status":      string(event.Status),
+		"transparent": event.Transparent,
+		"attendees":   attendees,
+		"categories":  event.Categories,
+	}
+}
+
+func docToEvent(doc *firestore.DocumentSnapshot) calendar.Event {
+	data := doc.Data()
+	event := calendar.Event{
+		ID:          getString(data, "id"),
+		CalendarID:  getString(data, "calendar_id"),
+		Summary:     getString(data, "summary"),
+		ColorID:     getString(data, "color_id"),
+		Start:       getTime(data, "start"),
+		End:         getTime(data, "end"),
+		AllDay:      getBool(data, "all_day"),
+		Status:      calendar.EventStatus(getString(data, "status")),
+		Transparent: getBool(data, "transparent"),
+		Categories:  getStrings(data, "categories"),
+	}
+	raw, _ := data["attendees"].([]any)
+	for _, item := range raw {
+		attendee, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		event.Attendees = append(event.Attendees, calendar.Attendee{
+			Email:          getString(attendee, "email"),
+			Self:           getBool(attendee, "self"),
+			Organizer:      getBool(attendee, "organizer"),
+			ResponseStatus: calendar.ResponseStatus(getString(attendee, "response_status")),
+		})
+	}
+	return event
+}
+
+func getBool(data map[string]any, key string) bool {
+	v, _ := data[key].(bool)
+	return v
+}
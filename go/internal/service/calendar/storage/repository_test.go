@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+
+	"energyjournal/internal/domain/calendar"
+	"energyjournal/internal/pkg/encryption"
+	"energyjournal/internal/pkg/sqldb"
+	"energyjournal/internal/pkg/storagetest"
+)
+
+func testEnvelope(t *testing.T) *encryption.Envelope {
+	t.Helper()
+
+	keys, err := encryption.NewLocalKeyProvider("k1:MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encryption.NewEnvelope(keys)
+}
+
+func TestSQLCalendarRepositories(t *testing.T) {
+	runCalendarSuites(t, storagetest.SQLite, sqlCalendarRepositories)
+}
+
+func TestPostgresCalendarRepositories(t *testing.T) {
+	runCalendarSuites(t, storagetest.Postgres, sqlCalendarRepositories)
+}
+
+func sqlCalendarRepositories(db *sqldb.DB, tokens *encryption.Envelope) calendarRepositories {
+	return calendarRepositories{
+		connections: NewSQLConnectionRepository(db, tokens),
+		categories:  NewSQLCategoryRuleRepository(db),
+		channels:    NewSQLWatchChannelRepository(db),
+		events:      NewSQLEventCacheRepository(db),
+	}
+}
+
+func TestFirestoreCalendarRepositories(t *testing.T) {
+	runCalendarSuites(t, storagetest.Firestore, func(client *firestore.Client, tokens *encryption.Envelope) calendarRepositories {
+		return calendarRepositories{
+			connections: NewConnectionRepository(client, tokens),
+			categories:  NewCategoryRuleRepository(client),
+			channels:    NewWatchChannelRepository(client),
+			events:      NewEventCacheRepository(client),
+		}
+	})
+}
+
+type calendarRepositories struct {
+	connections calendar.CalendarConnectionRepository
+	categories  calendar.CategoryRuleRepository
+	channels    calendar.WatchChannelRepository
+	events      calendar.EventCacheRepository
+}
+
+// runCalendarSuites runs every calendar suite on repositories built by build
+// from a fresh backend.
+func runCalendarSuites[B any](t *testing.T, open func(t *testing.T) B, build func(backend B, tokens *encryption.Envelope) calendarRepositories) {
+	repos := func(t *testing.T) calendarRepositories {
+		return build(open(t), testEnvelope(t))
+	}
+	t.Run("connections", func(t *testing.T) {
+		storagetest.ConnectionRepository(t, func(t *testing.T) calendar.CalendarConnectionRepository { return repos(t).connections })
+	})
+	t.Run("category rules", func(t *testing.T) {
+		storagetest.CategoryRuleRepository(t, func(t *testing.T) calendar.CategoryRuleRepository { return repos(t).categories })
+	})
+	t.Run("watch channels", func(t *testing.T) {
+		storagetest.WatchChannelRepository(t, func(t *testing.T) calendar.WatchChannelRepository { return repos(t).channels })
+	})
+	t.Run("event cache", func(t *testing.T) {
+		storagetest.EventCacheRepository(t, func(t *testing.T) calendar.EventCacheRepository { return repos(t).events })
+	})
+}
+
+func TestSQLConnectionRepositoryMigratesPlaintextTokens(t *testing.T) {
+	db := storagetest.SQLite(t)
+	repo := NewSQLConnectionRepository(db, testEnvelope(t))
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, `INSERT INTO calendar_connections (uid, provider, calendar_ids, access_token, refresh_token, needs_reauth)
+		VALUES ('uid-1', 'google', '["primary"]', 'ya29.plain', '1//plain', 0)`); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Upsert(ctx, calendar.CalendarConnection{UID: "uid-2", AccessToken: "encrypted"}); err != nil {
+		t.Fatal(err)
+	}
+
+	migrated, err := repo.MigrateTokens(ctx)
+	if err != nil || migrated != 1 {
+		t.Fatalf("expected 1 migrated connection, got %d (%v)", migrated, err)
+	}
+	var stored string
+	if err := db.QueryRow(ctx, `SELECT refresh_token FROM calendar_connections WHERE uid = 'uid-1'`).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if !encryption.IsEncrypted(stored) {
+		t.Fatalf("expected the refresh token encrypted, got %q", stored)
+	}
+	conn, err := repo.Get(ctx, "uid-1")
+	if err != nil || conn.AccessToken != "ya29.plain" || conn.RefreshToken != "1//plain" {
+		t.Fatalf("unexpected connection: %+v (%v)", conn, err)
+	}
+	if migrated, _ := repo.MigrateTokens(ctx); migrated != 0 {
+		t.Fatalf("expected nothing left to migrate, got %d", migrated)
+	}
+}
+
+func TestSQLConnectionRepositoryBindsTokensToTheirConnection(t *testing.T) {
+	db := storagetest.SQLite(t)
+	repo := NewSQLConnectionRepository(db, testEnvelope(t))
+	ctx := context.Background()
+
+	for _, uid := range []string{"uid-1", "uid-2"} {
+		conn := calendar.CalendarConnection{UID: uid, Provider: calendar.ProviderGoogle, RefreshToken: "1//" + uid}
+		if err := repo.Upsert(ctx, conn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Exec(ctx, `UPDATE calendar_connections
+		SET refresh_token = (SELECT refresh_token FROM calendar_connections WHERE uid = 'uid-1') WHERE uid = 'uid-2'`); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.Get(ctx, "uid-2"); err == nil {
+		t.Fatal("expected a token copied from another connection not to decrypt")
+	}
+}
+
+func TestFirestoreIndexesDeclareCachedEventRange(t *testing.T) {
+	t.Parallel()
+
+	storagetest.RequireFirestoreIndex(t, cachedEventsSubcoll, "end", "start")
+}
@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+
+	"energyjournal/internal/domain/calendar"
+	errpkg "energyjournal/internal/pkg/error"
+	"energyjournal/internal/pkg/sqldb"
+)
+
+const categoryRuleColumns = `id, uid, category, match_type, value, priority, created_at, updated_at`
+
+type SQLCategoryRuleRepository struct {
+	db *sqldb.DB
+}
+
+func NewSQLCategoryRuleRepository(db *sqldb.DB) *SQLCategoryRuleRepository {
+	return &SQLCategoryRuleRepository{db: db}
+}
+
+func (r *SQLCategoryRuleRepository) List(ctx context.Context, uid string) ([]calendar.CategoryRule, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+categoryRuleColumns+` FROM calendar_category_rules WHERE uid = ?`, uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []calendar.CategoryRule{}
+	for rows.Next() {
+		rule, err := scanCategoryRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// Get returns the rule only when it belongs to uid, so rule IDs cannot be probed across users.
+func (r *SQLCategoryRuleRepository) Get(ctx context.Context, uid, id string) (*calendar.CategoryRule, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+categoryRuleColumns+` FROM calendar_category_rules WHERE id = ? AND uid = ?`, id, uid)
+	rule, err := scanCategoryRule(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errpkg.NewNotFoundError("category_rule", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// Create stores a new rule and sets its generated ID.
+func (r *SQLCategoryRuleRepository) Create(ctx context.Context, rule *calendar.CategoryRule) error {
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(ctx, `INSERT INTO calendar_category_rules (`+categoryRuleColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		id,
+		rule.UID,
+		rule.Category,
+		string(rule.MatchType),
+		rule.Value,
+		rule.Priority,
+		sqldb.Time(rule.CreatedAt),
+		sqldb.Time(rule.UpdatedAt),
+	)
+	if err != nil {
+		return err
+	}
+	rule.ID = id
+	return nil
+}
+
+func (r *SQLCategoryRuleRepository) Update(ctx context.Context, rule calendar.CategoryRule) error {
+	_, err := r.db.Exec(ctx, `INSERT INTO calendar_category_rules (`+categoryRuleColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			uid = excluded.uid,
+			category = excluded.category,
+			match_type = excluded.match_type,
+			value = excluded.value,
+			priority = excluded.priority,
+			created_at = excluded.created_at,
+			updated_at = excluded.updated_at`,
+		rule.ID,
+		rule.UID,
+		rule.Category,
+		string(rule.MatchType),
+		rule.Value,
+		rule.Priority,
+		sqldb.Time(rule.CreatedAt),
+		sqldb.Time(rule.UpdatedAt),
+	)
+	return err
+}
+
+func (r *SQLCategoryRuleRepository) Delete(ctx context.Context, uid, id string) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM calendar_category_rules WHERE id = ? AND uid = ?`, id, uid)
+	if err != nil {
+		return err
+	}
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		return errpkg.NewNotFoundError("category_rule", id)
+	}
+	return nil
+}
+
+func scanCategoryRule(row sqldb.Row) (calendar.CategoryRule, error) {
+	var (
+		rule      calendar.CategoryRule
+		matchType string
+	)
+	err := row.Scan(
+		&rule.ID,
+		&rule.UID,
+		&rule.Category,
+		&matchType,
+		&rule.Value,
+		&rule.Priority,
+		sqldb.ScanTime(&rule.CreatedAt),
+		sqldb.ScanTime(&rule.UpdatedAt),
+	)
+	rule.MatchType = calendar.MatchType(matchType)
+	return rule, err
+}
+
+// newID returns a random 20 character ID, like Firestore's generated document IDs.
+func newID() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"energyjournal/internal/domain/calendar"
+	"energyjournal/internal/pkg/encryption"
+	"energyjournal/internal/pkg/sqldb"
+)
+
+const connectionColumns = `uid, provider, calendar_ids, access_token, refresh_token, expiry, needs_reauth`
+
+type SQLConnectionRepository struct {
+	db     *sqldb.DB
+	tokens *encryption.Envelope
+}
+
+// NewSQLConnectionRepository stores OAuth tokens encrypted with tokens.
+func NewSQLConnectionRepository(db *sqldb.DB, tokens *encryption.Envelope) *SQLConnectionRepository {
+	return &SQLConnectionRepository{db: db, tokens: tokens}
+}
+
+func (r *SQLConnectionRepository) Get(ctx context.Context, uid string) (*calendar.CalendarConnection, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+connectionColumns+` FROM calendar_connections WHERE uid = ?`, uid)
+	conn, err := r.scanConnection(ctx, row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &conn, nil
+}
+
+func (r *SQLConnectionRepository) Delete(ctx context.Context, uid string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM calendar_connections WHERE uid = ?`, uid)
+	return err
+}
+
+func (r *SQLConnectionRepository) List(ctx context.Context) ([]calendar.CalendarConnection, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+connectionColumns+` FROM calendar_connections`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conns := []calendar.CalendarConnection{}
+	for rows.Next() {
+		conn, err := r.scanConnection(ctx, rows)
+		if err != nil {
+			return nil, err
+		}
+		conns = append(conns, conn)
+	}
+	return conns, rows.Err()
+}
+
+func (r *SQLConnectionRepository) Upsert(ctx context.Context, conn calendar.CalendarConnection) error {
+	aad := tokenAAD(conn.UID, string(conn.Provider))
+	accessToken, err := r.tokens.Encrypt(ctx, conn.AccessToken, aad)
+	if err != nil {
+		return fmt.Errorf("encrypt access token: %w", err)
+	}
+	refreshToken, err := r.tokens.Encrypt(ctx, conn.RefreshToken, aad)
+	if err != nil {
+		return fmt.Errorf("encrypt refresh token: %w", err)
+	}
+
+	_, err = r.db.Exec(ctx, `INSERT INTO calendar_connections (`+connectionColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (uid) DO UPDATE SET
+			provider = excluded.provider,
+			calendar_ids = excluded.calendar_ids,
+			access_token = excluded.access_token,
+			refresh_token = excluded.refresh_token,
+			expiry = excluded.expiry,
+			needs_reauth = excluded.needs_reauth`,
+		conn.UID,
+		string(conn.Provider),
+		sqldb.JSON(nonNil(conn.CalendarIDs)),
+		accessToken,
+		refreshToken,
+		sqldb.Time(conn.Expiry),
+		conn.NeedsReauth,
+	)
+	return err
+}
+
+// MigrateTokens re-encrypts tokens that are still plaintext or wrapped by a
+// key other than the primary one, and returns how many connections changed.
+// Each update only applies if the stored tokens are still the ones read, so
+// a concurrent Upsert (which always encrypts) wins.
+func (r *SQLConnectionRepository) MigrateTokens(ctx context.Context) (int, error) {
+	type storedTokens struct {
+		uid, provider, access, refresh string
+	}
+	rows, err := r.db.Query(ctx, `SELECT uid, provider, access_token, refresh_token FROM calendar_connections`)
+	if err != nil {
+		return 0, err
+	}
+	var stored []storedTokens
+	for rows.Next() {
+		var s storedTokens
+		if err := rows.Scan(&s.uid, &s.provider, &s.access, &s.refresh); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if r.tokens.NeedsReencryption(s.access) || r.tokens.NeedsReencryption(s.refresh) {
+			stored = append(stored, s)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	migrated := 0
+	for _, s := range stored {
+		aad := tokenAAD(s.uid, s.provider)
+		access, err := r.reencrypt(ctx, s.access, aad)
+		if err != nil {
+			return migrated, fmt.Errorf("%s access_token: %w", s.uid, err)
+		}
+		refresh, err := r.reencrypt(ctx, s.refresh, aad)
+		if err != nil {
+			return migrated, fmt.Errorf("%s refresh_token: %w", s.uid, err)
+		}
+		result, err := r.db.Exec(ctx, `UPDATE calendar_connections SET access_token = ?, refresh_token = ?
+			WHERE uid = ? AND access_token = ? AND refresh_token = ?`, access, refresh, s.uid, s.access, s.refresh)
+		if err != nil {
+			return migrated, err
+		}
+		if changed, err := result.RowsAffected(); err == nil && changed > 0 {
+			migrated++
+		}
+	}
+	return migrated, nil
+}
+
+func (r *SQLConnectionRepository) reencrypt(ctx context.Context, value string, aad []byte) (string, error) {
+	if !r.tokens.NeedsReencryption(value) {
+		return value, nil
+	}
+	plaintext, err := r.tokens.Decrypt(ctx, value, aad)
+	if err != nil {
+		return "", err
+	}
+	return r.tokens.Encrypt(ctx, plaintext, aad)
+}
+
+func (r *SQLConnectionRepository) scanConnection(ctx context.Context, row sqldb.Row) (calendar.CalendarConnection, error) {
+	var (
+		conn                      calendar.CalendarConnection
+		provider                  string
+		accessToken, refreshToken string
+	)
+	err := row.Scan(
+		&conn.UID,
+		&provider,
+		sqldb.ScanJSON(&conn.CalendarIDs),
+		&accessToken,
+		&refreshToken,
+		sqldb.ScanTime(&conn.Expiry),
+		&conn.NeedsReauth,
+	)
+	if err != nil {
+		return calendar.CalendarConnection{}, err
+	}
+
+	conn.Provider = calendar.Provider(provider)
+	aad := tokenAAD(conn.UID, provider)
+	if conn.AccessToken, err = r.tokens.Decrypt(ctx, accessToken, aad); err != nil {
+		return calendar.CalendarConnection{}, fmt.Errorf("decrypt access token: %w", err)
+	}
+	if conn.RefreshToken, err = r.tokens.Decrypt(ctx, refreshToken, aad); err != nil {
+		return calendar.CalendarConnection{}, fmt.Errorf("decrypt refresh token: %w", err)
+	}
+	if len(conn.CalendarIDs) == 0 {
+		conn.CalendarIDs = nil
+	}
+	return conn, nil
+}
+
+// nonNil stores missing lists as empty JSON arrays.
+func nonNil[T any](values []T) []T {
+	if values == nil {
+		return []T{}
+	}
+	return values
+}
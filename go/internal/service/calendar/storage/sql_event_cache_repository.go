@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"energyjournal/internal/domain/calendar"
+	"energyjournal/internal/pkg/sqldb"
+)
+
+const cachedEventColumns = `uid, calendar_id, id, summary, color_id, start_at, end_at, all_day, status, transparent, attendees, categories`
+
+// storedAttendee is the JSON form of an attendee in calendar_events.attendees.
+type storedAttendee struct {
+	Email          string `json:"email"`
+	Self           bool   `json:"self"`
+	Organizer      bool   `json:"organizer"`
+	ResponseStatus string `json:"response_status"`
+}
+
+type SQLEventCacheRepository struct {
+	db *sqldb.DB
+}
+
+func NewSQLEventCacheRepository(db *sqldb.DB) *SQLEventCacheRepository {
+	return &SQLEventCacheRepository{db: db}
+}
+
+func (r *SQLEventCacheRepository) ReplaceCalendar(ctx context.Context, uid, calendarID string, events []calendar.Event) error {
+	return r.db.InTx(ctx, func(tx *sqldb.Tx) error {
+		if _, err := tx.Exec(ctx, `DELETE FROM calendar_events WHERE uid = ? AND calendar_id = ?`, uid, calendarID); err != nil {
+			return err
+		}
+		for _, event := range events {
+			if event.ID == "" || event.Status == calendar.EventCancelled {
+				continue
+			}
+			if err := saveEvent(ctx, tx, uid, calendarID, event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *SQLEventCacheRepository) ApplyChanges(ctx context.Context, uid, calendarID string, events []calendar.Event) error {
+	return r.db.InTx(ctx, func(tx *sqldb.Tx) error {
+		for _, event := range events {
+			if event.ID == "" {
+				continue
+			}
+			var err error
+			if event.Status == calendar.EventCancelled {
+				_, err = tx.Exec(ctx, `DELETE FROM calendar_events WHERE uid = ? AND calendar_id = ? AND id = ?`, uid, calendarID, event.ID)
+			} else {
+				err = saveEvent(ctx, tx, uid, calendarID, event)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *SQLEventCacheRepository) ListEvents(ctx context.Context, uid string, calendarIDs []string, start, end time.Time) ([]calendar.Event, error) {
+	events := []calendar.Event{}
+	if len(calendarIDs) == 0 {
+		return events, nil
+	}
+
+	args := []any{uid, sqldb.Time(start), sqldb.Time(end)}
+	for _, id := range calendarIDs {
+		args = append(args, id)
+	}
+	rows, err := r.db.Query(ctx, `SELECT `+cachedEventColumns+` FROM calendar_events
+		WHERE uid = ? AND end_at > ? AND start_at < ?
+		AND calendar_id IN (?`+strings.Repeat(", ?", len(calendarIDs)-1)+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		event, err := scanCachedEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func (r *SQLEventCacheRepository) GetSyncState(ctx context.Context, uid, calendarID string) (*calendar.SyncState, error) {
+	state := calendar.SyncState{}
+	err := r.db.QueryRow(ctx, `SELECT uid, calendar_id, sync_token, window_start, window_end, last_full_sync, last_synced_at
+		FROM calendar_sync_states WHERE uid = ? AND calendar_id = ?`, uid, calendarID).Scan(
+		&state.UID,
+		&state.CalendarID,
+		&state.SyncToken,
+		sqldb.ScanTime(&state.WindowStart),
+		sqldb.ScanTime(&state.WindowEnd),
+		sqldb.ScanTime(&state.LastFullSync),
+		sqldb.ScanTime(&state.LastSyncedAt),
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (r *SQLEventCacheRepository) SaveSyncState(ctx context.Context, state calendar.SyncState) error {
+	_, err := r.db.Exec(ctx, `INSERT INTO calendar_sync_states (uid, calendar_id, sync_token, window_start, window_end, last_full_sync, last_synced_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (uid, calendar_id) DO UPDATE SET
+			sync_token = excluded.sync_token,
+			window_start = excluded.window_start,
+			window_end = excluded.window_end,
+			last_full_sync = excluded.last_full_sync,
+			last_synced_at = excluded.last_synced_at`,
+		state.UID,
+		state.CalendarID,
+		state.SyncToken,
+		sqldb.Time(state.WindowStart),
+		sqldb.Time(state.WindowEnd),
+		sqldb.Time(state.LastFullSync),
+		sqldb.Time(state.LastSyncedAt),
+	)
+	return err
+}
+
+func (r *SQLEventCacheRepository) DeleteUser(ctx context.Context, uid string) error {
+	return r.db.InTx(ctx, func(tx *sqldb.Tx) error {
+		if _, err := tx.Exec(ctx, `DELETE FROM calendar_events WHERE uid = ?`, uid); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `DELETE FROM calendar_sync_states WHERE uid = ?`, uid)
+		return err
+	})
+}
+
+func saveEvent(ctx context.Context, q sqldb.Querier, uid, calendarID string, event calendar.Event) error {
+	attendees := make([]storedAttendee, 0, len(event.Attendees))
+	for _, attendee := range event.Attendees {
+		attendees = append(attendees, storedAttendee{
+			Email:          attendee.Email,
+			Self:           attendee.Self,
+			Organizer:      attendee.Organizer,
+			ResponseStatus: string(attendee.ResponseStatus),
+		})
+	}
+	_, err := q.Exec(ctx, `INSERT INTO calendar_events (`+cachedEventColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (uid, calendar_id, id) DO UPDATE SET
+			summary = excluded.summary,
+			color_id = excluded.color_id,
+			start_at = excluded.start_at,
+			end_at = excluded.end_at,
+			all_day = excluded.all_day,
+			status = excluded.status,
+			transparent = excluded.transparent,
+			attendees = excluded.attendees,
+			categories = excluded.categories`,
+		uid,
+		calendarID,
+		event.ID,
+		event.Summary,
+		event.ColorID,
+		sqldb.Time(event.Start),
+		sqldb.Time(event.End),
+		event.AllDay,
+		string(event.Status),
+		event.Transparent,
+		sqldb.JSON(attendees),
+		sqldb.JSON(nonNil(event.Categories)),
+	)
+	return err
+}
+
+func scanCachedEvent(row sqldb.Row) (calendar.Event, error) {
+	var (
+		event     calendar.Event
+		uid       string
+		status    string
+		attendees []storedAttendee
+	)
+	err := row.Scan(
+		&uid,
+		&event.CalendarID,
+		&event.ID,
+		&event.Summary,
+		&event.ColorID,
+		sqldb.ScanTime(&event.Start),
+		sqldb.ScanTime(&event.End),
+		&event.AllDay,
+		&status,
+		&event.Transparent,
+		sqldb.ScanJSON(&attendees),
+		sqldb.ScanJSON(&event.Categories),
+	)
+	if err != nil {
+		return calendar.Event{}, err
+	}
+
+	event.Status = calendar.EventStatus(status)
+	for _, attendee := range attendees {
+		event.Attendees = append(event.Attendees, calendar.Attendee{
+			Email:          attendee.Email,
+			Self:           attendee.Self,
+			Organizer:      attendee.Organizer,
+			ResponseStatus: calendar.ResponseStatus(attendee.ResponseStatus),
+		})
+	}
+	if len(event.Categories) == 0 {
+		event.Categories = nil
+	}
+	return event, nil
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"energyjournal/internal/domain/calendar"
+	"energyjournal/internal/pkg/sqldb"
+)
+
+const watchChannelColumns = `id, uid, calendar_id, resource_id, token, expiration, created_at`
+
+type SQLWatchChannelRepository struct {
+	db *sqldb.DB
+}
+
+func NewSQLWatchChannelRepository(db *sqldb.DB) *SQLWatchChannelRepository {
+	return &SQLWatchChannelRepository{db: db}
+}
+
+func (r *SQLWatchChannelRepository) Get(ctx context.Context, id string) (*calendar.WatchChannel, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+watchChannelColumns+` FROM calendar_watch_channels WHERE id = ?`, id)
+	channel, err := scanWatchChannel(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
+
+func (r *SQLWatchChannelRepository) ListByUID(ctx context.Context, uid string) ([]calendar.WatchChannel, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+watchChannelColumns+` FROM calendar_watch_channels WHERE uid = ?`, uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	channels := []calendar.WatchChannel{}
+	for rows.Next() {
+		channel, err := scanWatchChannel(rows)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, channel)
+	}
+	return channels, rows.Err()
+}
+
+func (r *SQLWatchChannelRepository) Save(ctx context.Context, channel calendar.WatchChannel) error {
+	_, err := r.db.Exec(ctx, `INSERT INTO calendar_watch_channels (`+watchChannelColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			uid = excluded.uid,
+			calendar_id = excluded.calendar_id,
+			resource_id = excluded.resource_id,
+			token = excluded.token,
+			expiration = excluded.expiration,
+			created_at = excluded.created_at`,
+		channel.ID,
+		channel.UID,
+		channel.CalendarID,
+		channel.ResourceID,
+		channel.Token,
+		sqldb.Time(channel.Expiration),
+		sqldb.Time(channel.CreatedAt),
+	)
+	return err
+}
+
+func (r *SQLWatchChannelRepository) Delete(ctx context.Context, id string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM calendar_watch_channels WHERE id = ?`, id)
+	return err
+}
+
+func scanWatchChannel(row sqldb.Row) (calendar.WatchChannel, error) {
+	var channel calendar.WatchChannel
+	err := row.Scan(
+		&channel.ID,
+		&channel.UID,
+		&channel.CalendarID,
+		&channel.ResourceID,
+		&channel.Token,
+		sqldb.ScanTime(&channel.Expiration),
+		sqldb.ScanTime(&channel.CreatedAt),
+	)
+	return channel, err
+}
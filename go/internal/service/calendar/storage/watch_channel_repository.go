@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"energyjournal/internal/domain/calendar"
+)
+
+const watchChannelCollection = "calendar_watch_channels"
+
+type WatchChannelRepository struct {
+	client *firestore.Client
+}
+
+func NewWatchChannelRepository(client *firestore.Client) *WatchChannelRepository {
+	return &WatchChannelRepository{client: client}
+}
+
+func (r *WatchChannelRepository) Get(ctx context.Context, id string) (*calendar.WatchChannel, error) {
+	doc, err := r.client.Collection(watchChannelCollection).Doc(id).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	channel := docToWatchChannel(doc)
+	return &channel, nil
+}
+
+func (r *WatchChannelRepository) ListByUID(ctx context.Context, uid string) ([]calendar.WatchChannel, error) {
+	iter := r.client.Collection(watchChannelCollection).Where("uid", "==", uid).Documents(ctx)
+	defer iter.Stop()
+
+	channels := []calendar.WatchChannel{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, docToWatchChannel(doc))
+	}
+	return channels, nil
+}
+
+func (r *WatchChannelRepository) Save(ctx context.Context, channel calendar.WatchChannel) error {
+	_, err := r.client.Collection(watchChannelCollection).Doc(channel.ID).Set(ctx, map[string]any{
+		"uid":         channel.UID,
+		"calendar_id": channel.CalendarID,
+		"resource_id": channel.ResourceID,
+		"token":       channel.Token,
+		"expiration":  channel.Expiration,
+		"created_at":  channel.CreatedAt,
+	})
+	return err
+}
+
+func (r *WatchChannelRepository) Delete(ctx context.Context, id string) error {
+	_, err := r.client.Collection(watchChannelCollection).Doc(id).Delete(ctx)
+	return err
+}
+
+func docToWatchChannel(doc *firestore.DocumentSnapshot) calendar.WatchChannel {
+	data := doc.Data()
+	return calendar.WatchChannel{
+		ID:         doc.Ref.ID,
+		UID:        getString(data, "uid"),
+		CalendarID: getString(data, "calendar_id"),
+		ResourceID: getString(data, "resource_id"),
+		Token:      getString(data, "token"),
+		Expiration: getTime(data, "expiration"),
+		CreatedAt:  getTime(data, "created_at"),
+	}
+}
@@ -0,0 +1,227 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
+
+	"energyjournal/internal/domain/calendar"
+	"energyjournal/internal/pkg/telemetry"
+	"energyjournal/internal/server/middleware"
+)
+
+const (
+	// defaultSyncWindow is how far back a full sync caches events.
+	defaultSyncWindow = 365 * 24 * time.Hour
+	// defaultSyncHorizon is how far ahead a full sync caches events, bounding
+	// the expansion of open-ended recurrences.
+	defaultSyncHorizon = 365 * 24 * time.Hour
+	// maxConcurrentUserSyncs bounds how many users SyncAll syncs at once.
+	maxConcurrentUserSyncs = 4
+)
+
+// WithEventCache makes spending read from cache, which is filled by full
+// syncs and kept current by SyncUser / SyncAll.
+func (s *CalendarService) WithEventCache(cache calendar.EventCacheRepository) *CalendarService {
+	s.cache = cache
+	return s
+}
+
+// SyncUser brings the cache of every selected calendar of uid up to date.
+// Users without a usable connection, or whose provider is always read live,
+// are skipped.
+func (s *CalendarService) SyncUser(ctx context.Context, uid string) error {
+	if s.cache == nil {
+		return nil
+	}
+	conn, err := s.repo.Get(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if conn == nil || conn.AccessToken == "" || conn.NeedsReauth || len(conn.CalendarIDs) == 0 {
+		return nil
+	}
+	return s.syncConnection(ctx, conn)
+}
+
+// SyncAll syncs every connected user. A failing user does not stop the
+// others; all failures are returned joined.
+func (s *CalendarService) SyncAll(ctx context.Context) (err error) {
+	if s.cache == nil {
+		return nil
+	}
+	ctx, span := tracer.Start(ctx, "CalendarService.SyncAll")
+	defer func() { telemetry.End(span, err) }()
+
+	conns, err := s.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+	span.SetAttributes(attribute.Int("calendar.connections", len(conns)))
+
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	var group errgroup.Group
+	group.SetLimit(maxConcurrentUserSyncs)
+	for _, conn := range conns {
+		if conn.AccessToken == "" || conn.NeedsReauth || len(conn.CalendarIDs) == 0 {
+			continue
+		}
+		group.Go(func() error {
+			if err := s.syncConnection(ctx, &conn); err != nil {
+				middleware.Logger(ctx).WithError(err).WithField("uid", conn.UID).Warn("Calendar sync failed")
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("sync %s: %w", conn.UID, err))
+				mu.Unlock()
+			}
+			return nil
+		})
+	}
+	_ = group.Wait()
+	return errors.Join(errs...)
+}
+
+func (s *CalendarService) syncConnection(ctx context.Context, conn *calendar.CalendarConnection) error {
+	provider, err := s.lookupProvider(conn.Provider)
+	if err != nil {
+		return err
+	}
+	syncer, ok := s.syncer(provider)
+	if !ok {
+		return nil
+	}
+	return s.withAccessToken(ctx, conn, func(accessToken string) error {
+		group, groupCtx := errgroup.WithContext(ctx)
+		group.SetLimit(s.maxConcurrentFetches)
+		for _, calendarID := range conn.CalendarIDs {
+			group.Go(func() error {
+				_, err := s.syncCalendar(groupCtx, syncer, conn.UID, accessToken, calendarID)
+				return err
+			})
+		}
+		return group.Wait()
+	})
+}
+
+// syncCalendar runs an incremental sync when a sync token is stored and a full
+// sync otherwise, when the provider reports the token as expired, or once
+// half the horizon has passed so that the cached window moves forward.
+func (s *CalendarService) syncCalendar(ctx context.Context, syncer eventSyncer, uid, accessToken, calendarID string) (_ *calendar.SyncState, err error) {
+	ctx, span := tracer.Start(ctx, "calendar.sync_calendar")
+	defer func() { telemetry.End(span, err) }()
+
+	state, err := s.cache.GetSyncState(ctx, uid, calendarID)
+	if err != nil {
+		return nil, err
+	}
+
+	if state != nil && state.SyncToken != "" && s.now().Add(s.syncHorizon/2).Before(state.WindowEnd) {
+		changes, err := syncer.SyncEvents(ctx, accessToken, calendarID, state.SyncToken, state.WindowStart, state.WindowEnd)
+		switch {
+		case err == nil:
+			if err := s.cache.ApplyChanges(ctx, uid, calendarID, withCalendarID(changes.Events, calendarID)); err != nil {
+				return nil, err
+			}
+			state.SyncToken = changes.NextSyncToken
+			state.LastSyncedAt = s.now()
+			if err := s.cache.SaveSyncState(ctx, *state); err != nil {
+				return nil, err
+			}
+			return state, nil
+		case !errors.Is(err, calendar.ErrSyncTokenExpired):
+			return nil, err
+		}
+	}
+
+	span.SetAttributes(attribute.Bool("calendar.full_sync", true))
+	return s.fullSync(ctx, syncer, uid, accessToken, calendarID)
+}
+
+func (s *CalendarService) fullSync(ctx context.Context, syncer eventSyncer, uid, accessToken, calendarID string) (*calendar.SyncState, error) {
+	now := s.now()
+	windowStart := now.Add(-s.syncWindow)
+	windowEnd := now.Add(s.syncHorizon)
+	changes, err := syncer.SyncEvents(ctx, accessToken, calendarID, "", windowStart, windowEnd)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.cache.ReplaceCalendar(ctx, uid, calendarID, withCalendarID(changes.Events, calendarID)); err != nil {
+		return nil, err
+	}
+
+	state := calendar.SyncState{
+		UID:          uid,
+		CalendarID:   calendarID,
+		SyncToken:    changes.NextSyncToken,
+		WindowStart:  windowStart,
+		WindowEnd:    windowEnd,
+		LastFullSync: now,
+		LastSyncedAt: now,
+	}
+	if err := s.cache.SaveSyncState(ctx, state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// cachedEvents reads the selected calendars from cache, running the initial
+// full sync for calendars never synced. Ranges starting before the cached
+// window are fetched live.
+func (s *CalendarService) cachedEvents(ctx context.Context, conn *calendar.CalendarConnection, provider CalendarProvider, syncer eventSyncer, accessToken string, start, end time.Time) (map[string][]calendar.Event, error) {
+	var cachedIDs, liveIDs []string
+	for _, calendarID := range conn.CalendarIDs {
+		state, err := s.cache.GetSyncState(ctx, conn.UID, calendarID)
+		if err != nil {
+			return nil, err
+		}
+		if state == nil {
+			if state, err = s.syncCalendar(ctx, syncer, conn.UID, accessToken, calendarID); err != nil {
+				return nil, err
+			}
+		}
+		if start.Before(state.WindowStart) || end.After(state.WindowEnd) {
+			liveIDs = append(liveIDs, calendarID)
+			continue
+		}
+		cachedIDs = append(cachedIDs, calendarID)
+	}
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.Int("calendar.cached_calendars", len(cachedIDs)),
+		attribute.Int("calendar.live_calendars", len(liveIDs)),
+	)
+
+	out, err := s.liveEvents(ctx, provider, accessToken, liveIDs, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if len(cachedIDs) == 0 {
+		return out, nil
+	}
+
+	events, err := s.cache.ListEvents(ctx, conn.UID, cachedIDs, start, end)
+	if err != nil {
+		return nil, err
+	}
+	for _, calendarID := range cachedIDs {
+		out[calendarID] = []calendar.Event{}
+	}
+	for _, event := range events {
+		out[event.CalendarID] = append(out[event.CalendarID], event)
+	}
+	return out, nil
+}
+
+func withCalendarID(events []calendar.Event, calendarID string) []calendar.Event {
+	for i := range events {
+		events[i].CalendarID = calendarID
+	}
+	return events
+}
@@ -0,0 +1,369 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"energyjournal/internal/domain/calendar"
+)
+
+// fakeEventCache is an in-memory EventCacheRepository.
+type fakeEventCache struct {
+	mu     sync.Mutex
+	events map[string]map[string]calendar.Event // uid -> calendarID|eventID -> event
+	states map[string]calendar.SyncState        // uid|calendarID -> state
+	// applyErr fails ApplyChanges, as a store losing writes would.
+	applyErr error
+}
+
+func newFakeEventCache() *fakeEventCache {
+	return &fakeEventCache{events: map[string]map[string]calendar.Event{}, states: map[string]calendar.SyncState{}}
+}
+
+func (c *fakeEventCache) ReplaceCalendar(_ context.Context, uid, calendarID string, events []calendar.Event) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, event := range c.events[uid] {
+		if event.CalendarID == calendarID {
+			delete(c.events[uid], key)
+		}
+	}
+	c.apply(uid, calendarID, events)
+	return nil
+}
+
+func (c *fakeEventCache) ApplyChanges(_ context.Context, uid, calendarID string, events []calendar.Event) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.applyErr != nil {
+		return c.applyErr
+	}
+	c.apply(uid, calendarID, events)
+	return nil
+}
+
+func (c *fakeEventCache) apply(uid, calendarID string, events []calendar.Event) {
+	if c.events[uid] == nil {
+		c.events[uid] = map[string]calendar.Event{}
+	}
+	for _, event := range events {
+		key := calendarID + "|" + event.ID
+		if event.Status == calendar.EventCancelled {
+			delete(c.events[uid], key)
+			continue
+		}
+		c.events[uid][key] = event
+	}
+}
+
+func (c *fakeEventCache) ListEvents(_ context.Context, uid string, calendarIDs []string, start, end time.Time) ([]calendar.Event, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	selected := map[string]bool{}
+	for _, id := range calendarIDs {
+		selected[id] = true
+	}
+	out := []calendar.Event{}
+	for _, event := range c.events[uid] {
+		if selected[event.CalendarID] && event.End.After(start) && event.Start.Before(end) {
+			out = append(out, event)
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeEventCache) GetSyncState(_ context.Context, uid, calendarID string) (*calendar.SyncState, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state, ok := c.states[uid+"|"+calendarID]
+	if !ok {
+		return nil, nil
+	}
+	return &state, nil
+}
+
+func (c *fakeEventCache) SaveSyncState(_ context.Context, state calendar.SyncState) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.states[state.UID+"|"+state.CalendarID] = state
+	return nil
+}
+
+func (c *fakeEventCache) DeleteUser(_ context.Context, uid string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.events, uid)
+	for key, state := range c.states {
+		if state.UID == uid {
+			delete(c.states, key)
+		}
+	}
+	return nil
+}
+
+func TestGetSpendingFromCacheRunsInitialFullSync(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)
+	var calls []string
+	client := &fakeCalendarClient{
+		listEventsErr: errors.New("spending must not hit the live API"),
+		syncFn: func(calendarID, syncToken string) (*calendar.EventChanges, error) {
+			calls = append(calls, calendarID+":"+syncToken)
+			return &calendar.EventChanges{
+				Events:        []calendar.Event{{ID: "e1", ColorID: "5", Start: now, End: now.Add(2 * time.Hour)}},
+				NextSyncToken: "token-1",
+			}, nil
+		},
+	}
+	cache := newFakeEventCache()
+	svc := NewCalendarService(connectedRepo("primary"), newFakeProvider(client, &fakeOAuth{}), "secret", &fakeCategoryRepo{}).WithEventCache(cache)
+	svc.now = func() time.Time { return now }
+
+	for range 2 {
+		result, err := svc.GetSpending(context.Background(), "uid", now.Add(-24*time.Hour), now.Add(24*time.Hour))
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if result["Sage"] != 2 {
+			t.Fatalf("expected Sage=2 from cache, got %v", result)
+		}
+	}
+	if len(calls) != 1 || calls[0] != "primary:" {
+		t.Fatalf("expected a single full sync, got %v", calls)
+	}
+
+	state, _ := cache.GetSyncState(context.Background(), "uid", "primary")
+	if state.SyncToken != "token-1" || !state.WindowStart.Equal(now.Add(-defaultSyncWindow)) || !state.WindowEnd.Equal(now.Add(defaultSyncHorizon)) {
+		t.Fatalf("unexpected sync state: %+v", state)
+	}
+}
+
+func TestSyncUserAppliesIncrementalChanges(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)
+	cache := newFakeEventCache()
+	_ = cache.ReplaceCalendar(context.Background(), "uid", "primary", []calendar.Event{
+		{ID: "keep", CalendarID: "primary", ColorID: "5", Start: now, End: now.Add(time.Hour)},
+		{ID: "gone", CalendarID: "primary", ColorID: "5", Start: now, End: now.Add(time.Hour)},
+	})
+	_ = cache.SaveSyncState(context.Background(), calendar.SyncState{UID: "uid", CalendarID: "primary", SyncToken: "old", WindowStart: now.AddDate(0, -1, 0), WindowEnd: now.AddDate(1, 0, 0)})
+
+	client := &fakeCalendarClient{
+		syncFn: func(calendarID, syncToken string) (*calendar.EventChanges, error) {
+			if syncToken != "old" {
+				return nil, fmt.Errorf("unexpected sync token %q", syncToken)
+			}
+			return &calendar.EventChanges{
+				Events: []calendar.Event{
+					{ID: "gone", Status: calendar.EventCancelled},
+					{ID: "new", ColorID: "1", Start: now, End: now.Add(30 * time.Minute)},
+				},
+				NextSyncToken: "new",
+			}, nil
+		},
+	}
+	svc := NewCalendarService(connectedRepo("primary"), newFakeProvider(client, &fakeOAuth{}), "secret", &fakeCategoryRepo{}).WithEventCache(cache)
+	svc.now = func() time.Time { return now }
+
+	if err := svc.SyncUser(context.Background(), "uid"); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	result, err := svc.GetSpending(context.Background(), "uid", now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if result["Sage"] != 1 || result["Tomato"] != 0.5 {
+		t.Fatalf("unexpected spendings after incremental sync: %v", result)
+	}
+	state, _ := cache.GetSyncState(context.Background(), "uid", "primary")
+	if state.SyncToken != "new" || !state.LastSyncedAt.Equal(now) {
+		t.Fatalf("unexpected sync state: %+v", state)
+	}
+}
+
+func TestSyncUserKeepsSyncTokenWhenChangesAreNotStored(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)
+	cache := newFakeEventCache()
+	_ = cache.SaveSyncState(context.Background(), calendar.SyncState{UID: "uid", CalendarID: "primary", SyncToken: "old", WindowStart: now.AddDate(0, -1, 0), WindowEnd: now.AddDate(1, 0, 0)})
+	cache.applyErr = errors.New("write failed")
+
+	client := &fakeCalendarClient{
+		syncFn: func(calendarID, syncToken string) (*calendar.EventChanges, error) {
+			return &calendar.EventChanges{
+				Events:        []calendar.Event{{ID: "new", ColorID: "1", Start: now, End: now.Add(30 * time.Minute)}},
+				NextSyncToken: "new",
+			}, nil
+		},
+	}
+	svc := NewCalendarService(connectedRepo("primary"), newFakeProvider(client, &fakeOAuth{}), "secret", &fakeCategoryRepo{}).WithEventCache(cache)
+	svc.now = func() time.Time { return now }
+
+	if err := svc.SyncUser(context.Background(), "uid"); !errors.Is(err, cache.applyErr) {
+		t.Fatalf("expected the write error, got %v", err)
+	}
+	// The next sync must fetch the lost changes again.
+	if state, _ := cache.GetSyncState(context.Background(), "uid", "primary"); state.SyncToken != "old" {
+		t.Fatalf("expected the sync token kept, got %+v", state)
+	}
+}
+
+func TestSyncUserFallsBackToFullSyncWhenTokenExpired(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)
+	cache := newFakeEventCache()
+	_ = cache.ReplaceCalendar(context.Background(), "uid", "primary", []calendar.Event{
+		{ID: "stale", CalendarID: "primary", ColorID: "5", Start: now, End: now.Add(time.Hour)},
+	})
+	_ = cache.SaveSyncState(context.Background(), calendar.SyncState{UID: "uid", CalendarID: "primary", SyncToken: "expired", WindowEnd: now.AddDate(1, 0, 0)})
+
+	var tokens []string
+	client := &fakeCalendarClient{
+		syncFn: func(calendarID, syncToken string) (*calendar.EventChanges, error) {
+			tokens = append(tokens, syncToken)
+			if syncToken != "" {
+				return nil, fmt.Errorf("%w: 410", calendar.ErrSyncTokenExpired)
+			}
+			return &calendar.EventChanges{
+				Events:        []calendar.Event{{ID: "fresh", ColorID: "1", Start: now, End: now.Add(time.Hour)}},
+				NextSyncToken: "fresh-token",
+			}, nil
+		},
+	}
+	svc := NewCalendarService(connectedRepo("primary"), newFakeProvider(client, &fakeOAuth{}), "secret", &fakeCategoryRepo{}).WithEventCache(cache)
+	svc.now = func() time.Time { return now }
+
+	if err := svc.SyncUser(context.Background(), "uid"); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(tokens) != 2 || tokens[0] != "expired" || tokens[1] != "" {
+		t.Fatalf("expected incremental then full sync, got %q", tokens)
+	}
+
+	events, _ := cache.ListEvents(context.Background(), "uid", []string{"primary"}, now.Add(-time.Hour), now.Add(time.Hour))
+	if len(events) != 1 || events[0].ID != "fresh" {
+		t.Fatalf("expected the full resync to replace the cache, got %+v", events)
+	}
+	state, _ := cache.GetSyncState(context.Background(), "uid", "primary")
+	if state.SyncToken != "fresh-token" || !state.LastFullSync.Equal(now) {
+		t.Fatalf("unexpected sync state: %+v", state)
+	}
+}
+
+func TestSyncUserRollsTheWindowForwardWithAFullSync(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)
+	cache := newFakeEventCache()
+	_ = cache.SaveSyncState(context.Background(), calendar.SyncState{UID: "uid", CalendarID: "primary", SyncToken: "old", WindowEnd: now.AddDate(0, 1, 0)})
+
+	var tokens []string
+	client := &fakeCalendarClient{
+		syncFn: func(calendarID, syncToken string) (*calendar.EventChanges, error) {
+			tokens = append(tokens, syncToken)
+			return &calendar.EventChanges{Events: []calendar.Event{}, NextSyncToken: "fresh-token"}, nil
+		},
+	}
+	svc := NewCalendarService(connectedRepo("primary"), newFakeProvider(client, &fakeOAuth{}), "secret", &fakeCategoryRepo{}).WithEventCache(cache)
+	svc.now = func() time.Time { return now }
+
+	if err := svc.SyncUser(context.Background(), "uid"); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(tokens) != 1 || tokens[0] != "" {
+		t.Fatalf("expected a full sync once the window nears its end, got %q", tokens)
+	}
+	state, _ := cache.GetSyncState(context.Background(), "uid", "primary")
+	if !state.WindowEnd.Equal(now.Add(defaultSyncHorizon)) {
+		t.Fatalf("expected the window to move forward, got %+v", state)
+	}
+}
+
+func TestSyncAllContinuesPastFailingUsers(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)
+	repo := &fakeRepo{
+		listFn: func(context.Context) ([]calendar.CalendarConnection, error) {
+			return []calendar.CalendarConnection{
+				{UID: "ok", CalendarIDs: []string{"good"}, AccessToken: "token"},
+				{UID: "broken", CalendarIDs: []string{"bad"}, AccessToken: "token"},
+				{UID: "pending", AccessToken: "token"},
+			}, nil
+		},
+	}
+	client := &fakeCalendarClient{
+		syncFn: func(calendarID, _ string) (*calendar.EventChanges, error) {
+			if calendarID == "bad" {
+				return nil, errors.New("boom")
+			}
+			return &calendar.EventChanges{NextSyncToken: "t"}, nil
+		},
+	}
+	cache := newFakeEventCache()
+	svc := NewCalendarService(repo, newFakeProvider(client, &fakeOAuth{}), "secret", &fakeCategoryRepo{}).WithEventCache(cache)
+	svc.now = func() time.Time { return now }
+
+	err := svc.SyncAll(context.Background())
+	if err == nil {
+		t.Fatal("expected the failing user to be reported")
+	}
+	if state, _ := cache.GetSyncState(context.Background(), "ok", "good"); state == nil {
+		t.Fatal("expected the healthy user to be synced")
+	}
+}
+
+func TestCachedSpendingFetchesLiveBeforeSyncWindow(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)
+	old := now.AddDate(-2, 0, 0)
+	cache := newFakeEventCache()
+	_ = cache.SaveSyncState(context.Background(), calendar.SyncState{UID: "uid", CalendarID: "primary", SyncToken: "t", WindowStart: now.AddDate(-1, 0, 0), WindowEnd: now.AddDate(1, 0, 0)})
+
+	client := &fakeCalendarClient{
+		events: []calendar.Event{{ColorID: "5", Start: old, End: old.Add(3 * time.Hour)}},
+	}
+	svc := NewCalendarService(connectedRepo("primary"), newFakeProvider(client, &fakeOAuth{}), "secret", &fakeCategoryRepo{}).WithEventCache(cache)
+	svc.now = func() time.Time { return now }
+
+	result, err := svc.GetSpending(context.Background(), "uid", old.Add(-time.Hour), old.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if result["Sage"] != 3 {
+		t.Fatalf("expected live events outside the cached window, got %v", result)
+	}
+}
+
+func TestCachedSpendingFetchesLiveAfterSyncWindow(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)
+	later := now.AddDate(2, 0, 0)
+	cache := newFakeEventCache()
+	_ = cache.SaveSyncState(context.Background(), calendar.SyncState{UID: "uid", CalendarID: "primary", SyncToken: "t", WindowStart: now.AddDate(-1, 0, 0), WindowEnd: now.AddDate(1, 0, 0)})
+
+	client := &fakeCalendarClient{
+		events: []calendar.Event{{ColorID: "5", Start: later, End: later.Add(3 * time.Hour)}},
+	}
+	svc := NewCalendarService(connectedRepo("primary"), newFakeProvider(client, &fakeOAuth{}), "secret", &fakeCategoryRepo{}).WithEventCache(cache)
+	svc.now = func() time.Time { return now }
+
+	result, err := svc.GetSpending(context.Background(), "uid", later.Add(-time.Hour), later.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if result["Sage"] != 3 {
+		t.Fatalf("expected live events outside the cached window, got %v", result)
+	}
+}
@@ -0,0 +1,231 @@
+package calendar
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"energyjournal/internal/domain/calendar"
+	errpkg "energyjournal/internal/pkg/error"
+	"energyjournal/internal/server/middleware"
+)
+
+const (
+	// defaultChannelTTL is the lifetime requested for new watch channels.
+	defaultChannelTTL = 7 * 24 * time.Hour
+	// channelRenewBefore is how long before expiry a channel is replaced.
+	channelRenewBefore = 24 * time.Hour
+	// resourceStateSync is sent once when a channel is opened; it carries no changes.
+	resourceStateSync = "sync"
+)
+
+// WithWatchChannels enables push notifications delivered to webhookURL.
+// Without it WatchUser and RenewChannels do nothing.
+func (s *CalendarService) WithWatchChannels(channels calendar.WatchChannelRepository, webhookURL string) *CalendarService {
+	s.channels = channels
+	s.webhookURL = webhookURL
+	return s
+}
+
+func (s *CalendarService) HandleNotification(ctx context.Context, notification calendar.Notification) error {
+	if s.channels == nil {
+		return errpkg.NewNotFoundError("watch_channel", notification.ChannelID)
+	}
+	channel, err := s.channels.Get(ctx, notification.ChannelID)
+	if err != nil {
+		return err
+	}
+	if channel == nil {
+		return errpkg.NewNotFoundError("watch_channel", notification.ChannelID)
+	}
+	if subtle.ConstantTimeCompare([]byte(notification.ChannelToken), []byte(channel.Token)) != 1 ||
+		notification.ResourceID != channel.ResourceID {
+		return errpkg.NewAuthenticationError("invalid channel token")
+	}
+	if notification.ResourceState == resourceStateSync || s.cache == nil {
+		return nil
+	}
+
+	conn, err := s.repo.Get(ctx, channel.UID)
+	if err != nil {
+		return err
+	}
+	// A channel outliving its calendar selection is stopped on the next renewal.
+	if conn == nil || conn.AccessToken == "" || conn.NeedsReauth || !slices.Contains(conn.CalendarIDs, channel.CalendarID) {
+		return nil
+	}
+	provider, err := s.lookupProvider(conn.Provider)
+	if err != nil {
+		return err
+	}
+	syncer, ok := s.syncer(provider)
+	if !ok {
+		return nil
+	}
+	return s.withAccessToken(ctx, conn, func(accessToken string) error {
+		_, err := s.syncCalendar(ctx, syncer, channel.UID, accessToken, channel.CalendarID)
+		return err
+	})
+}
+
+func (s *CalendarService) WatchUser(ctx context.Context, uid string) error {
+	if s.channels == nil || s.webhookURL == "" {
+		return nil
+	}
+	existing, err := s.channels.ListByUID(ctx, uid)
+	if err != nil {
+		return err
+	}
+	conn, err := s.repo.Get(ctx, uid)
+	if err != nil {
+		return err
+	}
+	// Channels of a revoked grant are left to expire; the user reconnects first.
+	if conn != nil && conn.NeedsReauth {
+		return nil
+	}
+
+	// Channels are kept only for providers supporting them; any others left
+	// from an earlier connection are stopped below.
+	var (
+		watcher     channelWatcher
+		selected    []string
+		accessToken string
+	)
+	if conn != nil && conn.AccessToken != "" {
+		provider, err := s.lookupProvider(conn.Provider)
+		if err != nil {
+			return err
+		}
+		if w, ok := provider.(channelWatcher); ok {
+			watcher = w
+			selected = conn.CalendarIDs
+			if accessToken, err = s.accessToken(ctx, conn); err != nil {
+				return err
+			}
+		}
+	}
+
+	renewAt := s.now().Add(channelRenewBefore)
+	active := map[string]bool{}
+	var stale []calendar.WatchChannel
+	for _, channel := range existing {
+		if !slices.Contains(selected, channel.CalendarID) || channel.Expiration.Before(renewAt) || active[channel.CalendarID] {
+			stale = append(stale, channel)
+			continue
+		}
+		active[channel.CalendarID] = true
+	}
+
+	// Open replacements before stopping old channels so no change is missed.
+	var errs []error
+	for _, calendarID := range selected {
+		if active[calendarID] {
+			continue
+		}
+		if err := s.openChannel(ctx, watcher, uid, accessToken, calendarID); err != nil {
+			errs = append(errs, fmt.Errorf("watch calendar %s: %w", calendarID, err))
+		}
+	}
+	for _, channel := range stale {
+		if watcher != nil {
+			if err := watcher.StopChannel(ctx, accessToken, channel.ID, channel.ResourceID); err != nil {
+				errs = append(errs, fmt.Errorf("stop channel %s: %w", channel.ID, err))
+				continue
+			}
+		}
+		if err := s.channels.Delete(ctx, channel.ID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RenewChannels reconciles the channels of every connected user.
+func (s *CalendarService) RenewChannels(ctx context.Context) error {
+	if s.channels == nil || s.webhookURL == "" {
+		return nil
+	}
+	conns, err := s.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, conn := range conns {
+		if err := s.WatchUser(ctx, conn.UID); err != nil {
+			middleware.Logger(ctx).WithError(err).WithField("uid", conn.UID).Warn("Calendar watch renewal failed")
+			errs = append(errs, fmt.Errorf("renew %s: %w", conn.UID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// dropChannels stops and forgets every channel of uid. Stopping is best effort:
+// a channel left open only produces notifications the webhook rejects. A nil
+// provider, or one without channels, only forgets them.
+func (s *CalendarService) dropChannels(ctx context.Context, uid string, provider CalendarProvider, accessToken string) error {
+	if s.channels == nil {
+		return nil
+	}
+	channels, err := s.channels.ListByUID(ctx, uid)
+	if err != nil {
+		return err
+	}
+	watcher, _ := provider.(channelWatcher)
+	for _, channel := range channels {
+		if watcher != nil && accessToken != "" {
+			if err := watcher.StopChannel(ctx, accessToken, channel.ID, channel.ResourceID); err != nil {
+				middleware.Logger(ctx).WithError(err).WithField("channel_id", channel.ID).Warn("Stopping watch channel failed")
+			}
+		}
+		if err := s.channels.Delete(ctx, channel.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *CalendarService) openChannel(ctx context.Context, watcher channelWatcher, uid, accessToken, calendarID string) error {
+	channelID, err := randomHex(16)
+	if err != nil {
+		return err
+	}
+	channelToken, err := randomHex(32)
+	if err != nil {
+		return err
+	}
+
+	registration, err := watcher.WatchEvents(ctx, accessToken, calendarID, channelID, channelToken, s.webhookURL, s.channelTTL)
+	if err != nil {
+		return err
+	}
+
+	now := s.now()
+	expiration := registration.Expiration
+	if expiration.IsZero() {
+		expiration = now.Add(s.channelTTL)
+	}
+	return s.channels.Save(ctx, calendar.WatchChannel{
+		ID:         channelID,
+		UID:        uid,
+		CalendarID: calendarID,
+		ResourceID: registration.ResourceID,
+		Token:      channelToken,
+		Expiration: expiration,
+		CreatedAt:  now,
+	})
+}
+
+func randomHex(size int) (string, error) {
+	bytes := make([]byte, size)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
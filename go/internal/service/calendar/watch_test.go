@@ -0,0 +1,268 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"energyjournal/internal/domain/calendar"
+	"energyjournal/internal/integration/google"
+	errpkg "energyjournal/internal/pkg/error"
+)
+
+type fakeChannelRepo struct {
+	mu       sync.Mutex
+	channels map[string]calendar.WatchChannel
+}
+
+func newFakeChannelRepo() *fakeChannelRepo {
+	return &fakeChannelRepo{channels: map[string]calendar.WatchChannel{}}
+}
+
+func (r *fakeChannelRepo) Get(_ context.Context, id string) (*calendar.WatchChannel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	channel, ok := r.channels[id]
+	if !ok {
+		return nil, nil
+	}
+	return &channel, nil
+}
+
+func (r *fakeChannelRepo) ListByUID(_ context.Context, uid string) ([]calendar.WatchChannel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := []calendar.WatchChannel{}
+	for _, channel := range r.channels {
+		if channel.UID == uid {
+			out = append(out, channel)
+		}
+	}
+	return out, nil
+}
+
+func (r *fakeChannelRepo) Save(_ context.Context, channel calendar.WatchChannel) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.channels[channel.ID] = channel
+	return nil
+}
+
+func (r *fakeChannelRepo) Delete(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.channels, id)
+	return nil
+}
+
+func (r *fakeChannelRepo) forCalendar(calendarID string) []calendar.WatchChannel {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := []calendar.WatchChannel{}
+	for _, channel := range r.channels {
+		if channel.CalendarID == calendarID {
+			out = append(out, channel)
+		}
+	}
+	return out
+}
+
+// fakeGoogle is a local stand-in for the Calendar API endpoints used by
+// watch channels and sync: events.list, events.watch and channels.stop.
+type fakeGoogle struct {
+	t       *testing.T
+	mu      sync.Mutex
+	events  map[string][]string // calendarID -> event JSON served on full sync
+	changes map[string][]string // calendarID -> event JSON served on incremental sync
+	watches []map[string]any
+	stopped []string
+}
+
+func newFakeGoogle(t *testing.T) (*fakeGoogle, *httptest.Server) {
+	f := &fakeGoogle{t: t, events: map[string][]string{}, changes: map[string][]string{}}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+func (f *fakeGoogle) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/calendars/")
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/channels/stop":
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		f.stopped = append(f.stopped, body["id"])
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/events/watch"):
+		calendarID := strings.TrimSuffix(path, "/events/watch")
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		f.watches = append(f.watches, body)
+		expiration := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC).UnixMilli()
+		_, _ = fmt.Fprintf(w, `{"id":%q,"resourceId":"res-%s","expiration":"%d"}`, body["id"], calendarID, expiration)
+	case r.Method == http.MethodGet && strings.HasSuffix(path, "/events"):
+		calendarID := strings.TrimSuffix(path, "/events")
+		items := f.events[calendarID]
+		if r.URL.Query().Get("syncToken") != "" {
+			items = f.changes[calendarID]
+		}
+		_, _ = fmt.Fprintf(w, `{"nextSyncToken":"sync-%d","items":[%s]}`, len(f.watches), strings.Join(items, ","))
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeGoogle) watchCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.watches)
+}
+
+func newWatchTestService(t *testing.T, server *httptest.Server, now time.Time) (*CalendarService, *fakeChannelRepo, *fakeEventCache) {
+	t.Helper()
+	channels := newFakeChannelRepo()
+	cache := newFakeEventCache()
+	client := google.NewGoogleCalendarClient().WithBaseURL(server.URL)
+	svc := NewCalendarService(connectedRepo("primary"), newFakeProvider(client, &fakeOAuth{}), "secret", &fakeCategoryRepo{}).
+		WithEventCache(cache).
+		WithWatchChannels(channels, "https://api.example.test/calendar/webhook")
+	svc.now = func() time.Time { return now }
+	return svc, channels, cache
+}
+
+func TestWatchNotificationTriggersIncrementalSync(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)
+	fake, server := newFakeGoogle(t)
+	fake.events["primary"] = []string{`{"id":"a","colorId":"5","start":{"dateTime":"2026-03-03T09:00:00Z"},"end":{"dateTime":"2026-03-03T10:00:00Z"}}`}
+	fake.changes["primary"] = []string{`{"id":"b","colorId":"5","start":{"dateTime":"2026-03-03T13:00:00Z"},"end":{"dateTime":"2026-03-03T15:00:00Z"}}`}
+	svc, channels, cache := newWatchTestService(t, server, now)
+
+	if err := svc.WatchUser(context.Background(), "uid"); err != nil {
+		t.Fatalf("WatchUser returned error: %v", err)
+	}
+	registered := channels.forCalendar("primary")
+	if len(registered) != 1 {
+		t.Fatalf("expected one channel, got %+v", registered)
+	}
+	channel := registered[0]
+	if channel.ResourceID != "res-primary" || !channel.Expiration.Equal(time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected channel: %+v", channel)
+	}
+	if fake.watches[0]["address"] != "https://api.example.test/calendar/webhook" || fake.watches[0]["token"] != channel.Token {
+		t.Fatalf("unexpected watch request: %v", fake.watches[0])
+	}
+
+	syncer, _ := svc.syncer(svc.providers[calendar.ProviderGoogle])
+	if _, err := svc.syncCalendar(context.Background(), syncer, "uid", "token", "primary"); err != nil {
+		t.Fatalf("initial sync failed: %v", err)
+	}
+
+	notification := calendar.Notification{
+		ChannelID:     channel.ID,
+		ChannelToken:  channel.Token,
+		ResourceID:    channel.ResourceID,
+		ResourceState: "exists",
+	}
+	if err := svc.HandleNotification(context.Background(), notification); err != nil {
+		t.Fatalf("HandleNotification returned error: %v", err)
+	}
+
+	events, _ := cache.ListEvents(context.Background(), "uid", []string{"primary"}, now.Add(-12*time.Hour), now.Add(12*time.Hour))
+	if len(events) != 2 {
+		t.Fatalf("expected the notification to sync the new event, got %+v", events)
+	}
+}
+
+func TestHandleNotificationRejectsInvalidChannels(t *testing.T) {
+	t.Parallel()
+
+	_, server := newFakeGoogle(t)
+	svc, channels, _ := newWatchTestService(t, server, time.Now())
+	_ = channels.Save(context.Background(), calendar.WatchChannel{ID: "ch", UID: "uid", CalendarID: "primary", ResourceID: "res", Token: "secret"})
+
+	tests := []struct {
+		name         string
+		notification calendar.Notification
+		expectAuth   bool
+	}{
+		{"unknown channel", calendar.Notification{ChannelID: "other", ChannelToken: "secret", ResourceID: "res"}, false},
+		{"wrong token", calendar.Notification{ChannelID: "ch", ChannelToken: "guess", ResourceID: "res"}, true},
+		{"wrong resource", calendar.Notification{ChannelID: "ch", ChannelToken: "secret", ResourceID: "res-2"}, true},
+	}
+	for _, tt := range tests {
+		err := svc.HandleNotification(context.Background(), tt.notification)
+		var authErr *errpkg.AuthenticationError
+		var notFoundErr *errpkg.NotFoundError
+		if tt.expectAuth && !errors.As(err, &authErr) {
+			t.Errorf("%s: expected authentication error, got %v", tt.name, err)
+		}
+		if !tt.expectAuth && !errors.As(err, &notFoundErr) {
+			t.Errorf("%s: expected not found, got %v", tt.name, err)
+		}
+	}
+
+	handshake := calendar.Notification{ChannelID: "ch", ChannelToken: "secret", ResourceID: "res", ResourceState: "sync"}
+	if err := svc.HandleNotification(context.Background(), handshake); err != nil {
+		t.Fatalf("sync handshake should be accepted, got %v", err)
+	}
+}
+
+func TestRenewChannelsReplacesExpiringAndStopsDeselected(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)
+	fake, server := newFakeGoogle(t)
+	svc, channels, _ := newWatchTestService(t, server, now)
+	svc.repo = &fakeRepo{
+		getFn: connectedRepo("primary").getFn,
+		listFn: func(context.Context) ([]calendar.CalendarConnection, error) {
+			return []calendar.CalendarConnection{{UID: "uid", CalendarIDs: []string{"primary"}, AccessToken: "token"}}, nil
+		},
+	}
+	_ = channels.Save(context.Background(), calendar.WatchChannel{ID: "expiring", UID: "uid", CalendarID: "primary", ResourceID: "r1", Expiration: now.Add(time.Hour)})
+	_ = channels.Save(context.Background(), calendar.WatchChannel{ID: "deselected", UID: "uid", CalendarID: "old", ResourceID: "r2", Expiration: now.AddDate(0, 0, 5)})
+
+	if err := svc.RenewChannels(context.Background()); err != nil {
+		t.Fatalf("RenewChannels returned error: %v", err)
+	}
+
+	if fake.watchCount() != 1 {
+		t.Fatalf("expected one new channel, got %d", fake.watchCount())
+	}
+	if len(fake.stopped) != 2 {
+		t.Fatalf("expected both old channels to be stopped, got %v", fake.stopped)
+	}
+	primary := channels.forCalendar("primary")
+	if len(primary) != 1 || primary[0].ID == "expiring" {
+		t.Fatalf("expected the expiring channel to be replaced, got %+v", primary)
+	}
+	if len(channels.forCalendar("old")) != 0 {
+		t.Fatal("expected the deselected calendar channel to be deleted")
+	}
+
+	// A second run finds a fresh channel and leaves it alone.
+	if err := svc.RenewChannels(context.Background()); err != nil {
+		t.Fatalf("RenewChannels returned error: %v", err)
+	}
+	if fake.watchCount() != 1 {
+		t.Fatalf("expected no new channel, got %d", fake.watchCount())
+	}
+}
@@ -5,8 +5,18 @@ import (
 	"regexp"
 	"time"
 
+	"go.opentelemetry.io/otel"
+
 	domain "energyjournal/internal/domain/energy"
 	pkgerror "energyjournal/internal/pkg/error"
+	"energyjournal/internal/pkg/telemetry"
+)
+
+const scope = "energyjournal/internal/service/energy"
+
+var (
+	tracer = otel.Tracer(scope)
+	saves  = telemetry.Counter(scope, "energyjournal.energy.saves", "Energy level entries saved.")
 )
 
 var datePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
@@ -57,7 +67,10 @@ func newServiceWithClock(repo domain.EnergyRepository, timeNow func() time.Time)
 	}
 }
 
-func (s *service) GetByDate(ctx context.Context, uid, date string) (*domain.EnergyLevels, error) {
+func (s *service) GetByDate(ctx context.Context, uid, date string) (_ *domain.EnergyLevels, err error) {
+	ctx, span := tracer.Start(ctx, "EnergyService.GetByDate")
+	defer func() { telemetry.End(span, err) }()
+
 	if err := validateDate(date); err != nil {
 		return nil, err
 	}
@@ -65,7 +78,10 @@ func (s *service) GetByDate(ctx context.Context, uid, date string) (*domain.Ener
 	return s.repo.GetByDate(ctx, uid, date)
 }
 
-func (s *service) GetByDateRange(ctx context.Context, uid, from, to string) ([]domain.EnergyLevels, error) {
+func (s *service) GetByDateRange(ctx context.Context, uid, from, to string) (_ []domain.EnergyLevels, err error) {
+	ctx, span := tracer.Start(ctx, "EnergyService.GetByDateRange")
+	defer func() { telemetry.End(span, err) }()
+
 	fromDate, fromOK := parseDate(from)
 	toDate, toOK := parseDate(to)
 	if !fromOK || !toOK || toDate.Before(fromDate) {
@@ -84,7 +100,10 @@ func (s *service) GetByDateRange(ctx context.Context, uid, from, to string) ([]d
 	return s.repo.GetByDateRange(ctx, uid, from, to)
 }
 
-func (s *service) Save(ctx context.Context, levels domain.EnergyLevels) error {
+func (s *service) Save(ctx context.Context, levels domain.EnergyLevels) (err error) {
+	ctx, span := tracer.Start(ctx, "EnergyService.Save")
+	defer func() { telemetry.End(span, err) }()
+
 	if err := validateDate(levels.Date); err != nil {
 		return err
 	}
@@ -118,7 +137,11 @@ func (s *service) Save(ctx context.Context, levels domain.EnergyLevels) error {
 	}
 
 	levels.UpdatedAt = s.timeNow()
-	return s.repo.Upsert(ctx, levels)
+	if err := s.repo.Upsert(ctx, levels); err != nil {
+		return err
+	}
+	saves.Add(ctx, 1)
+	return nil
 }
 
 func validateDate(date string) error {
@@ -57,8 +57,9 @@ func (r *FirestoreEnergyRepository) GetByDate(ctx context.Context, uid, date str
 }
 
 // GetByDateRange returns energy levels for uid between from and to (inclusive), ordered by date ASC.
-// Requires a Firestore composite index on energy_levels: uid ASC + date ASC.
-// Create it manually in the Firebase console before deploying this method.
+// Requires the composite index on energy_levels (uid ASC, date ASC) declared in
+// firestore.indexes.json; deploy it with `firebase deploy --only firestore:indexes`.
+// The emulator does not enforce indexes, so a test checks the declaration.
 func (r *FirestoreEnergyRepository) GetByDateRange(ctx context.Context, uid, from, to string) ([]energy.EnergyLevels, error) {
 	iter := r.client.Collection(energyLevelsCollection).
 		Where("uid", "==", uid).
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"energyjournal/internal/domain/energy"
+	"energyjournal/internal/pkg/storagetest"
+)
+
+func TestSQLEnergyRepository(t *testing.T) {
+	storagetest.EnergyRepository(t, func(t *testing.T) energy.EnergyRepository {
+		return NewSQLEnergyRepository(storagetest.SQLite(t))
+	})
+}
+
+func TestFirestoreEnergyRepository(t *testing.T) {
+	storagetest.EnergyRepository(t, func(t *testing.T) energy.EnergyRepository {
+		return NewEnergyRepository(storagetest.Firestore(t))
+	})
+}
+
+func TestPostgresEnergyRepository(t *testing.T) {
+	storagetest.EnergyRepository(t, func(t *testing.T) energy.EnergyRepository {
+		return NewSQLEnergyRepository(storagetest.Postgres(t))
+	})
+}
+
+func TestFirestoreEnergyRepositoryStampsTimes(t *testing.T) {
+	client := storagetest.Firestore(t)
+	repo := NewEnergyRepository(client)
+	ctx := context.Background()
+	created := time.Date(2026, 3, 2, 21, 0, 0, 0, time.UTC)
+	updated := created.Add(time.Hour)
+
+	repo.timeNow = func() time.Time { return created }
+	if err := repo.Upsert(ctx, energy.EnergyLevels{UID: "uid-1", Date: "2026-03-02"}); err != nil {
+		t.Fatalf("Upsert returned error: %v", err)
+	}
+	repo.timeNow = func() time.Time { return updated }
+	if err := repo.Upsert(ctx, energy.EnergyLevels{UID: "uid-1", Date: "2026-03-02", Physical: 2}); err != nil {
+		t.Fatalf("Upsert returned error: %v", err)
+	}
+
+	got, err := repo.GetByDate(ctx, "uid-1", "2026-03-02")
+	if err != nil {
+		t.Fatalf("GetByDate returned error: %v", err)
+	}
+	if !got.CreatedAt.Equal(created) || !got.UpdatedAt.Equal(updated) {
+		t.Fatalf("expected created %v and updated %v, got %+v", created, updated, got)
+	}
+
+	// Missing scores are stored as explicit nulls, so that an upsert clears them.
+	snapshot, err := client.Collection(energyLevelsCollection).Doc(energyLevelDocID("uid-1", "2026-03-02")).Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, ok := snapshot.Data()["sleepQuality"]; !ok || value != nil {
+		t.Fatalf("expected sleepQuality stored as null, got %v (present %v)", value, ok)
+	}
+}
+
+// The emulator runs queries without indexes, so the composite index the range
+// query needs in production is checked here.
+func TestFirestoreIndexesDeclareEnergyRange(t *testing.T) {
+	t.Parallel()
+
+	storagetest.RequireFirestoreIndex(t, energyLevelsCollection, "uid", "date")
+}
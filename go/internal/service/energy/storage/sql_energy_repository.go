@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"energyjournal/internal/domain/energy"
+	pkgerror "energyjournal/internal/pkg/error"
+	"energyjournal/internal/pkg/sqldb"
+)
+
+const energyLevelsColumns = `uid, date, physical, mental, emotional, sleep_quality, stress_level,
+	physical_activity, nutrition, social_interactions, time_outdoors, notes, created_at, updated_at`
+
+// SQLEnergyRepository stores energy levels in the energy_levels table, keyed by
+// (uid, date).
+type SQLEnergyRepository struct {
+	db      *sqldb.DB
+	timeNow func() time.Time
+}
+
+func NewSQLEnergyRepository(db *sqldb.DB) *SQLEnergyRepository {
+	return &SQLEnergyRepository{
+		db:      db,
+		timeNow: time.Now,
+	}
+}
+
+func (r *SQLEnergyRepository) GetByDate(ctx context.Context, uid, date string) (*energy.EnergyLevels, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+energyLevelsColumns+` FROM energy_levels WHERE uid = ? AND date = ?`, uid, date)
+	levels, err := scanEnergyLevels(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, pkgerror.NewNotFoundError("energy_levels", energyLevelDocID(uid, date))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &levels, nil
+}
+
+// GetByDateRange returns energy levels for uid between from and to (inclusive), ordered by date ASC.
+func (r *SQLEnergyRepository) GetByDateRange(ctx context.Context, uid, from, to string) ([]energy.EnergyLevels, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+energyLevelsColumns+` FROM energy_levels
+		WHERE uid = ? AND date >= ? AND date <= ? ORDER BY date ASC`, uid, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	levels := []energy.EnergyLevels{}
+	for rows.Next() {
+		entry, err := scanEnergyLevels(rows)
+		if err != nil {
+			return nil, err
+		}
+		levels = append(levels, entry)
+	}
+	return levels, rows.Err()
+}
+
+// Upsert keeps the createdAt of an existing entry. It reads the entry and
+// writes it back in one transaction; the update leaves created_at alone, so a
+// concurrent first insert of the same day keeps its createdAt too.
+func (r *SQLEnergyRepository) Upsert(ctx context.Context, levels energy.EnergyLevels) error {
+	now := r.timeNow()
+	return r.db.InTx(ctx, func(tx *sqldb.Tx) error {
+		createdAt := now
+		err := tx.QueryRow(ctx, `SELECT created_at FROM energy_levels WHERE uid = ? AND date = ?`, levels.UID, levels.Date).
+			Scan(sqldb.ScanTime(&createdAt))
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `INSERT INTO energy_levels (`+energyLevelsColumns+`)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (uid, date) DO UPDATE SET
+				physical = excluded.physical,
+				mental = excluded.mental,
+				emotional = excluded.emotional,
+				sleep_quality = excluded.sleep_quality,
+				stress_level = excluded.stress_level,
+				physical_activity = excluded.physical_activity,
+				nutrition = excluded.nutrition,
+				social_interactions = excluded.social_interactions,
+				time_outdoors = excluded.time_outdoors,
+				notes = excluded.notes,
+				updated_at = excluded.updated_at`,
+			levels.UID,
+			levels.Date,
+			levels.Physical,
+			levels.Mental,
+			levels.Emotional,
+			nullableInt(levels.SleepQuality),
+			nullableInt(levels.StressLevel),
+			levels.PhysicalActivity,
+			levels.Nutrition,
+			levels.SocialInteractions,
+			levels.TimeOutdoors,
+			levels.Notes,
+			sqldb.Time(createdAt),
+			sqldb.Time(now),
+		)
+		return err
+	})
+}
+
+func scanEnergyLevels(row sqldb.Row) (energy.EnergyLevels, error) {
+	var (
+		levels       energy.EnergyLevels
+		sleepQuality sql.NullInt64
+		stressLevel  sql.NullInt64
+	)
+	err := row.Scan(
+		&levels.UID,
+		&levels.Date,
+		&levels.Physical,
+		&levels.Mental,
+		&levels.Emotional,
+		&sleepQuality,
+		&stressLevel,
+		&levels.PhysicalActivity,
+		&levels.Nutrition,
+		&levels.SocialInteractions,
+		&levels.TimeOutdoors,
+		&levels.Notes,
+		sqldb.ScanTime(&levels.CreatedAt),
+		sqldb.ScanTime(&levels.UpdatedAt),
+	)
+	levels.SleepQuality = intFromNull(sleepQuality)
+	levels.StressLevel = intFromNull(stressLevel)
+	return levels, err
+}
+
+func nullableInt(p *int) sql.NullInt64 {
+	if p == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(*p), Valid: true}
+}
+
+func intFromNull(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	i := int(n.Int64)
+	return &i
+}
@@ -0,0 +1,247 @@
+package insights
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/otel"
+
+	"energyjournal/internal/domain/calendar"
+	"energyjournal/internal/domain/energy"
+	domain "energyjournal/internal/domain/insights"
+	pkgerror "energyjournal/internal/pkg/error"
+	"energyjournal/internal/pkg/telemetry"
+)
+
+var tracer = otel.Tracer("energyjournal/internal/service/insights")
+
+const (
+	dateFormat = "2006-01-02"
+	// defaultRangeDays is used when from or to is missing.
+	defaultRangeDays = 90
+	// maxRangeDays keeps the daily spending series within its bucket limit.
+	maxRangeDays = 365
+	// minGroupDays is the number of days needed on each side (with and without
+	// the category) before an effect is reported as a signal.
+	minGroupDays = 5
+	// minEffectSize is Cohen's threshold for a medium effect.
+	minEffectSize = 0.5
+)
+
+type spendingSeries interface {
+	GetSpendingSeries(ctx context.Context, uid string, start, end time.Time, granularity calendar.Granularity) (*calendar.SpendingSeries, error)
+	UserLocation(ctx context.Context, uid string) (*time.Location, error)
+}
+
+type service struct {
+	spendings spendingSeries
+	energy    energy.EnergyRepository
+	timeNow   func() time.Time
+}
+
+func NewInsightsService(spendings spendingSeries, energyRepo energy.EnergyRepository) domain.InsightsService {
+	return newServiceWithClock(spendings, energyRepo, time.Now)
+}
+
+func newServiceWithClock(spendings spendingSeries, energyRepo energy.EnergyRepository, timeNow func() time.Time) *service {
+	return &service{
+		spendings: spendings,
+		energy:    energyRepo,
+		timeNow:   timeNow,
+	}
+}
+
+// TimeEnergy joins the daily spendings of the selected calendars with the
+// energy levels of the same dates. Next-day effects compare a day's calendar
+// with the following day's score, both inside the range.
+func (s *service) TimeEnergy(ctx context.Context, uid, from, to string) (_ *domain.TimeEnergyInsights, err error) {
+	ctx, span := tracer.Start(ctx, "InsightsService.TimeEnergy")
+	defer func() { telemetry.End(span, err) }()
+
+	fromDate, toDate, err := s.parseRange(ctx, uid, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	series, err := s.spendings.GetSpendingSeries(ctx, uid, fromDate, toDate.AddDate(0, 0, 1), calendar.GranularityDay)
+	if err != nil {
+		return nil, err
+	}
+	levels, err := s.energy.GetByDateRange(ctx, uid, fromDate.Format(dateFormat), toDate.Format(dateFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	scores := map[string]float64{}
+	for _, level := range levels {
+		scores[level.Date] = float64(level.Physical+level.Mental+level.Emotional) / 3
+	}
+	categorySet := map[string]struct{}{}
+	for _, bucket := range series.Buckets {
+		for category, hours := range bucket.Spendings {
+			if hours > 0 {
+				categorySet[category] = struct{}{}
+			}
+		}
+	}
+	categories := make([]string, 0, len(categorySet))
+	for category := range categorySet {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	return &domain.TimeEnergyInsights{
+		From:    fromDate.Format(dateFormat),
+		To:      toDate.Format(dateFormat),
+		SameDay: effects(series.Buckets, scores, categories, domain.HorizonSameDay),
+		NextDay: effects(series.Buckets, scores, categories, domain.HorizonNextDay),
+	}, nil
+}
+
+// parseRange defaults to the days up to today in the user's timezone, the
+// dates their calendar and energy entries are kept in. from and to are given
+// together or not at all.
+func (s *service) parseRange(ctx context.Context, uid, from, to string) (time.Time, time.Time, error) {
+	switch {
+	case from == "" && to != "":
+		return time.Time{}, time.Time{}, pkgerror.NewInputValidationError("from", "required when to is given")
+	case from != "" && to == "":
+		return time.Time{}, time.Time{}, pkgerror.NewInputValidationError("to", "required when from is given")
+	}
+	if from == "" {
+		loc, err := s.spendings.UserLocation(ctx, uid)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		now := s.timeNow().In(loc)
+		toDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		return toDate.AddDate(0, 0, -(defaultRangeDays - 1)), toDate, nil
+	}
+
+	fromDate, err := time.Parse(dateFormat, from)
+	if err != nil {
+		return time.Time{}, time.Time{}, pkgerror.NewInputValidationError("from", "invalid date format, expected YYYY-MM-DD")
+	}
+	toDate, err := time.Parse(dateFormat, to)
+	if err != nil {
+		return time.Time{}, time.Time{}, pkgerror.NewInputValidationError("to", "invalid date format, expected YYYY-MM-DD")
+	}
+	if toDate.Before(fromDate) {
+		return time.Time{}, time.Time{}, pkgerror.NewInputValidationError("to", "must not be before from")
+	}
+	if toDate.After(fromDate.AddDate(0, 0, maxRangeDays-1)) {
+		return time.Time{}, time.Time{}, pkgerror.NewInputValidationError("to", "range must not exceed 365 days")
+	}
+	return fromDate, toDate, nil
+}
+
+// effects pairs each day's hours per category with the score of the same or
+// the next day, skipping days without a score, and sorts the strongest first.
+func effects(buckets []calendar.SpendingBucket, scores map[string]float64, categories []string, horizon domain.Horizon) []domain.CategoryEffect {
+	offset := 0
+	if horizon == domain.HorizonNextDay {
+		offset = 1
+	}
+
+	out := make([]domain.CategoryEffect, 0, len(categories))
+	for _, category := range categories {
+		var hours, with, without, all []float64
+		for i := 0; i+offset < len(buckets); i++ {
+			score, ok := scores[buckets[i+offset].Start.Format(dateFormat)]
+			if !ok {
+				continue
+			}
+			spent := buckets[i].Spendings[category]
+			hours = append(hours, spent)
+			all = append(all, score)
+			if spent > 0 {
+				with = append(with, score)
+			} else {
+				without = append(without, score)
+			}
+		}
+
+		effect := domain.CategoryEffect{
+			Category:         category,
+			Horizon:          horizon,
+			DaysWith:         len(with),
+			DaysWithout:      len(without),
+			MeanScoreWith:    mean(with),
+			MeanScoreWithout: mean(without),
+			EffectSize:       cohensD(with, without),
+			Correlation:      pearson(hours, all),
+			Signal:           domain.SignalInconclusive,
+		}
+		if effect.DaysWith >= minGroupDays && effect.DaysWithout >= minGroupDays {
+			switch {
+			case effect.EffectSize >= minEffectSize:
+				effect.Signal = domain.SignalHighEnergy
+			case effect.EffectSize <= -minEffectSize:
+				effect.Signal = domain.SignalLowEnergy
+			}
+		}
+		out = append(out, effect)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return math.Abs(out[i].EffectSize) > math.Abs(out[j].EffectSize)
+	})
+	return out
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// sampleVariance uses Bessel's correction; it needs at least two values.
+func sampleVariance(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	m := mean(values)
+	var sum float64
+	for _, v := range values {
+		sum += (v - m) * (v - m)
+	}
+	return sum / float64(len(values)-1)
+}
+
+// cohensD is the difference of means over the pooled standard deviation, or 0
+// when either group is too small or the scores do not vary.
+func cohensD(a, b []float64) float64 {
+	if len(a) < 2 || len(b) < 2 {
+		return 0
+	}
+	pooled := math.Sqrt((float64(len(a)-1)*sampleVariance(a) + float64(len(b)-1)*sampleVariance(b)) / float64(len(a)+len(b)-2))
+	if pooled == 0 {
+		return 0
+	}
+	return (mean(a) - mean(b)) / pooled
+}
+
+// pearson returns the correlation coefficient of x and y, or 0 when either is constant.
+func pearson(x, y []float64) float64 {
+	if len(x) < 2 {
+		return 0
+	}
+	mx, my := mean(x), mean(y)
+	var cov, vx, vy float64
+	for i := range x {
+		cov += (x[i] - mx) * (y[i] - my)
+		vx += (x[i] - mx) * (x[i] - mx)
+		vy += (y[i] - my) * (y[i] - my)
+	}
+	if vx == 0 || vy == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(vx*vy)
+}
@@ -0,0 +1,175 @@
+package insights
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"energyjournal/internal/domain/calendar"
+	"energyjournal/internal/domain/energy"
+	domain "energyjournal/internal/domain/insights"
+	pkgerror "energyjournal/internal/pkg/error"
+)
+
+type stubSpendingSeries struct {
+	series   func(start, end time.Time) (*calendar.SpendingSeries, error)
+	location *time.Location
+}
+
+func (s *stubSpendingSeries) GetSpendingSeries(_ context.Context, _ string, start, end time.Time, _ calendar.Granularity) (*calendar.SpendingSeries, error) {
+	return s.series(start, end)
+}
+
+func (s *stubSpendingSeries) UserLocation(context.Context, string) (*time.Location, error) {
+	if s.location == nil {
+		return time.UTC, nil
+	}
+	return s.location, nil
+}
+
+type stubEnergyRepository struct {
+	levels []energy.EnergyLevels
+	from   string
+	to     string
+}
+
+func (r *stubEnergyRepository) GetByDate(context.Context, string, string) (*energy.EnergyLevels, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *stubEnergyRepository) GetByDateRange(_ context.Context, _ string, from, to string) ([]energy.EnergyLevels, error) {
+	r.from, r.to = from, to
+	return r.levels, nil
+}
+
+func (r *stubEnergyRepository) Upsert(context.Context, energy.EnergyLevels) error {
+	return errors.New("not implemented")
+}
+
+func TestService_TimeEnergy_ReportsSameAndNextDayEffects(t *testing.T) {
+	t.Parallel()
+
+	first := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	var gotStart, gotEnd time.Time
+	spendings := &stubSpendingSeries{series: func(start, end time.Time) (*calendar.SpendingSeries, error) {
+		gotStart, gotEnd = start, end
+		series := &calendar.SpendingSeries{Granularity: calendar.GranularityDay, Location: time.UTC}
+		for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+			spent := calendar.Spendings{"Meetings": 2}
+			if int(day.Sub(first).Hours()/24)%2 == 0 {
+				spent["Sport"] = 1
+			}
+			series.Buckets = append(series.Buckets, calendar.SpendingBucket{Start: day, End: day.AddDate(0, 0, 1), Spendings: spent})
+		}
+		return series, nil
+	}}
+
+	// Days after sport (odd days) score 7-8, the others 4-5.
+	repo := &stubEnergyRepository{}
+	for i := 0; i < 20; i++ {
+		score := 4 + (i/2)%2
+		if i%2 == 1 {
+			score += 3
+		}
+		repo.levels = append(repo.levels, energy.EnergyLevels{
+			Date: first.AddDate(0, 0, i).Format(dateFormat), Physical: score, Mental: score, Emotional: score,
+		})
+	}
+
+	svc := NewInsightsService(spendings, repo)
+	result, err := svc.TimeEnergy(context.Background(), "uid-1", "2026-03-01", "2026-03-20")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if !gotStart.Equal(first) || !gotEnd.Equal(first.AddDate(0, 0, 20)) {
+		t.Fatalf("unexpected spending range %s - %s", gotStart, gotEnd)
+	}
+	if repo.from != "2026-03-01" || repo.to != "2026-03-20" {
+		t.Fatalf("unexpected energy range %s - %s", repo.from, repo.to)
+	}
+
+	if len(result.NextDay) != 2 || len(result.SameDay) != 2 {
+		t.Fatalf("expected two categories per horizon, got %+v", result)
+	}
+	sport := result.NextDay[0]
+	if sport.Category != "Sport" || sport.Horizon != domain.HorizonNextDay || sport.Signal != domain.SignalHighEnergy {
+		t.Fatalf("expected sport to predict high next-day energy, got %+v", sport)
+	}
+	if sport.DaysWith != 10 || sport.DaysWithout != 9 || sport.MeanScoreWith != 7.5 || sport.Correlation <= 0.9 {
+		t.Fatalf("unexpected sport effect: %+v", sport)
+	}
+	if sameDay := result.SameDay[0]; sameDay.Category != "Sport" || sameDay.Signal != domain.SignalLowEnergy || sameDay.EffectSize >= 0 {
+		t.Fatalf("expected sport to come with low same-day energy, got %+v", sameDay)
+	}
+	if meetings := result.NextDay[1]; meetings.Signal != domain.SignalInconclusive || meetings.DaysWithout != 0 || meetings.EffectSize != 0 {
+		t.Fatalf("a daily category cannot be compared, got %+v", meetings)
+	}
+}
+
+func TestService_TimeEnergy_DefaultsToLastNinetyDays(t *testing.T) {
+	t.Parallel()
+
+	var gotStart, gotEnd time.Time
+	spendings := &stubSpendingSeries{series: func(start, end time.Time) (*calendar.SpendingSeries, error) {
+		gotStart, gotEnd = start, end
+		return &calendar.SpendingSeries{Location: time.UTC}, nil
+	}}
+	svc := newServiceWithClock(spendings, &stubEnergyRepository{}, func() time.Time {
+		return time.Date(2026, 3, 31, 18, 0, 0, 0, time.UTC)
+	})
+
+	result, err := svc.TimeEnergy(context.Background(), "uid-1", "", "")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if result.From != "2026-01-01" || result.To != "2026-03-31" {
+		t.Fatalf("unexpected range %s - %s", result.From, result.To)
+	}
+	if !gotStart.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) || !gotEnd.Equal(time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected spending range %s - %s", gotStart, gotEnd)
+	}
+}
+
+func TestService_TimeEnergy_DefaultRangeEndsOnTheUsersToday(t *testing.T) {
+	t.Parallel()
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	spendings := &stubSpendingSeries{location: tokyo, series: func(time.Time, time.Time) (*calendar.SpendingSeries, error) {
+		return &calendar.SpendingSeries{Location: tokyo}, nil
+	}}
+	// Already April 1st in Tokyo.
+	svc := newServiceWithClock(spendings, &stubEnergyRepository{}, func() time.Time {
+		return time.Date(2026, 3, 31, 18, 0, 0, 0, time.UTC)
+	})
+
+	result, err := svc.TimeEnergy(context.Background(), "uid-1", "", "")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if result.From != "2026-01-02" || result.To != "2026-04-01" {
+		t.Fatalf("unexpected range %s - %s", result.From, result.To)
+	}
+}
+
+func TestService_TimeEnergy_InvalidRangeReturnsValidationError(t *testing.T) {
+	t.Parallel()
+
+	svc := NewInsightsService(&stubSpendingSeries{}, &stubEnergyRepository{})
+	for _, tc := range []struct{ from, to string }{
+		{"2026/03/01", "2026-03-20"},
+		{"2026-03-20", "2026-03-01"},
+		{"2025-01-01", "2026-03-01"},
+		{"2026-03-01", ""},
+		{"", "2026-03-20"},
+	} {
+		_, err := svc.TimeEnergy(context.Background(), "uid-1", tc.from, tc.to)
+		var validationErr *pkgerror.InputValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("%s..%s: expected InputValidationError, got %v", tc.from, tc.to, err)
+		}
+	}
+}
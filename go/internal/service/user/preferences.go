@@ -0,0 +1,235 @@
+package user
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"energyjournal/internal/domain/user"
+	pkgerror "energyjournal/internal/pkg/error"
+)
+
+var reminderTimePattern = regexp.MustCompile(`^([01]\d|2[0-3]):[0-5]\d$`)
+
+var localePattern = regexp.MustCompile(`^[a-z]{2}(-[A-Z]{2})?$`)
+
+var reminderDayValues = map[string]struct{}{
+	"mon": {},
+	"tue": {},
+	"wed": {},
+	"thu": {},
+	"fri": {},
+	"sat": {},
+	"sun": {},
+}
+
+var channelValues = map[user.NotificationChannel]struct{}{
+	user.ChannelEmail: {},
+	user.ChannelPush:  {},
+}
+
+// unsubscribeTokenTTL bounds how long an unsubscribe link keeps working: long
+// enough for any email still worth acting on, so that a leaked link does not
+// stay valid forever.
+const unsubscribeTokenTTL = 365 * 24 * time.Hour
+
+var unsubscribeTopics = map[user.UnsubscribeTopic]struct{}{
+	user.TopicReminders: {},
+	user.TopicDigest:    {},
+	user.TopicAll:       {},
+}
+
+type preferencesService struct {
+	repo               user.PreferencesRepository
+	users              user.UserRepository
+	tokenSecret        string
+	unsubscribeBaseURL string
+	timeNow            func() time.Time
+}
+
+func NewPreferencesService(repo user.PreferencesRepository, users user.UserRepository, tokenSecret, unsubscribeBaseURL string) user.PreferencesService {
+	return &preferencesService{
+		repo:               repo,
+		users:              users,
+		tokenSecret:        tokenSecret,
+		unsubscribeBaseURL: unsubscribeBaseURL,
+		timeNow:            time.Now,
+	}
+}
+
+// Get returns the stored preferences, or the defaults when none were saved yet.
+func (s *preferencesService) Get(ctx context.Context, uid string) (*user.Preferences, error) {
+	prefs, err := s.repo.Get(ctx, uid)
+	if err != nil {
+		var notFoundErr *pkgerror.NotFoundError
+		if errors.As(err, &notFoundErr) {
+			return defaultPreferences(uid), nil
+		}
+		return nil, err
+	}
+	return prefs, nil
+}
+
+func (s *preferencesService) Update(ctx context.Context, prefs user.Preferences) (*user.Preferences, error) {
+	if err := validatePreferences(prefs); err != nil {
+		return nil, err
+	}
+
+	prefs.UpdatedAt = s.timeNow()
+	if err := s.repo.Upsert(ctx, prefs); err != nil {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+// UnsubscribeLink builds a link that turns off topic for uid without requiring
+// a login. It opens the web app's /unsubscribe page, which posts the token to
+// POST /users/unsubscribe once the recipient confirms.
+func (s *preferencesService) UnsubscribeLink(uid string, topic user.UnsubscribeTopic) string {
+	return fmt.Sprintf("%s/unsubscribe?token=%s", s.unsubscribeBaseURL, url.QueryEscape(s.signUnsubscribeToken(uid, topic)))
+}
+
+func (s *preferencesService) Unsubscribe(ctx context.Context, token string) error {
+	uid, topic, err := s.verifyUnsubscribeToken(token)
+	if err != nil {
+		return pkgerror.NewInputValidationError("token", "invalid unsubscribe token")
+	}
+
+	// A deleted account receives no email: there is nothing to turn off, and
+	// saving preferences would bring a document back for it.
+	if _, err := s.users.GetByUID(ctx, uid); err != nil {
+		var notFoundErr *pkgerror.NotFoundError
+		if errors.As(err, &notFoundErr) {
+			return nil
+		}
+		return err
+	}
+
+	prefs, err := s.Get(ctx, uid)
+	if err != nil {
+		return err
+	}
+
+	switch topic {
+	case user.TopicReminders:
+		prefs.ReminderDays = []string{}
+	case user.TopicDigest:
+		prefs.DigestOptIn = false
+	case user.TopicAll:
+		prefs.ReminderDays = []string{}
+		prefs.DigestOptIn = false
+		prefs.Channels = removeChannel(prefs.Channels, user.ChannelEmail)
+	}
+
+	prefs.UID = uid
+	prefs.UpdatedAt = s.timeNow()
+	return s.repo.Upsert(ctx, *prefs)
+}
+
+// Unsubscribe tokens carry the time they were issued, so that links in old
+// emails stop working after unsubscribeTokenTTL.
+func (s *preferencesService) signUnsubscribeToken(uid string, topic user.UnsubscribeTopic) string {
+	payload := uid + "|" + string(topic) + "|" + strconv.FormatInt(s.timeNow().Unix(), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(payload + "|" + s.sign(payload)))
+}
+
+func (s *preferencesService) verifyUnsubscribeToken(token string) (string, user.UnsubscribeTopic, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", "", err
+	}
+
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 4 || parts[0] == "" {
+		return "", "", errors.New("invalid parts")
+	}
+	uid := parts[0]
+	topic := user.UnsubscribeTopic(parts[1])
+	sig := parts[3]
+
+	if !hmac.Equal([]byte(s.sign(strings.Join(parts[:3], "|"))), []byte(sig)) {
+		return "", "", errors.New("invalid signature")
+	}
+	if _, ok := unsubscribeTopics[topic]; !ok {
+		return "", "", errors.New("unknown topic")
+	}
+	issuedAt, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return "", "", errors.New("invalid issue time")
+	}
+	if s.timeNow().Sub(time.Unix(issuedAt, 0)) > unsubscribeTokenTTL {
+		return "", "", errors.New("expired token")
+	}
+
+	return uid, topic, nil
+}
+
+func (s *preferencesService) sign(payload string) string {
+	mac := hmac.New(sha256.New, []byte(s.tokenSecret))
+	_, _ = mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func defaultPreferences(uid string) *user.Preferences {
+	return &user.Preferences{
+		UID:          uid,
+		ReminderTime: "20:00",
+		ReminderDays: []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"},
+		DigestOptIn:  false,
+		Channels:     []user.NotificationChannel{user.ChannelEmail},
+		Locale:       "en",
+	}
+}
+
+func validatePreferences(prefs user.Preferences) error {
+	if !reminderTimePattern.MatchString(prefs.ReminderTime) {
+		return pkgerror.NewInputValidationError("reminderTime", "invalid time format, expected HH:MM")
+	}
+
+	seenDays := map[string]struct{}{}
+	for _, day := range prefs.ReminderDays {
+		if _, ok := reminderDayValues[day]; !ok {
+			return pkgerror.NewInputValidationError("reminderDays", "invalid value")
+		}
+		if _, dup := seenDays[day]; dup {
+			return pkgerror.NewInputValidationError("reminderDays", "duplicate value")
+		}
+		seenDays[day] = struct{}{}
+	}
+
+	seenChannels := map[user.NotificationChannel]struct{}{}
+	for _, channel := range prefs.Channels {
+		if _, ok := channelValues[channel]; !ok {
+			return pkgerror.NewInputValidationError("channels", "invalid value")
+		}
+		if _, dup := seenChannels[channel]; dup {
+			return pkgerror.NewInputValidationError("channels", "duplicate value")
+		}
+		seenChannels[channel] = struct{}{}
+	}
+
+	if !localePattern.MatchString(prefs.Locale) {
+		return pkgerror.NewInputValidationError("locale", "invalid locale, expected e.g. en or fr-FR")
+	}
+
+	return nil
+}
+
+func removeChannel(channels []user.NotificationChannel, target user.NotificationChannel) []user.NotificationChannel {
+	out := make([]user.NotificationChannel, 0, len(channels))
+	for _, channel := range channels {
+		if channel != target {
+			out = append(out, channel)
+		}
+	}
+	return out
+}
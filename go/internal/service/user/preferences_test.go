@@ -0,0 +1,243 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"energyjournal/internal/domain/user"
+	pkgerror "energyjournal/internal/pkg/error"
+)
+
+type mockPreferencesRepo struct {
+	prefs map[string]user.Preferences
+}
+
+func newMockPreferencesRepo() *mockPreferencesRepo {
+	return &mockPreferencesRepo{prefs: make(map[string]user.Preferences)}
+}
+
+func (m *mockPreferencesRepo) Get(ctx context.Context, uid string) (*user.Preferences, error) {
+	p, ok := m.prefs[uid]
+	if !ok {
+		return nil, pkgerror.NewNotFoundError("user_preferences", uid)
+	}
+	return &p, nil
+}
+
+func (m *mockPreferencesRepo) Upsert(ctx context.Context, prefs user.Preferences) error {
+	m.prefs[prefs.UID] = prefs
+	return nil
+}
+
+func usersWith(uids ...string) *mockUserRepo {
+	repo := newMockUserRepo()
+	for _, uid := range uids {
+		repo.users[uid] = &user.User{UID: uid}
+	}
+	return repo
+}
+
+// deletedUserRepo reports every user as not found, like the real repositories.
+type deletedUserRepo struct {
+	mockUserRepo
+}
+
+func (deletedUserRepo) GetByUID(ctx context.Context, uid string) (*user.User, error) {
+	return nil, pkgerror.NewNotFoundError("user", uid)
+}
+
+func validPreferences(uid string) user.Preferences {
+	return user.Preferences{
+		UID:          uid,
+		ReminderTime: "21:30",
+		ReminderDays: []string{"mon", "wed", "fri"},
+		DigestOptIn:  true,
+		Channels:     []user.NotificationChannel{user.ChannelEmail, user.ChannelPush},
+		Locale:       "fr-FR",
+	}
+}
+
+// Preferences: missing document falls back to defaults.
+func TestPreferencesGet_NotFound_ReturnsDefaults(t *testing.T) {
+	svc := NewPreferencesService(newMockPreferencesRepo(), usersWith("uid-1"), "secret", "http://localhost:8080")
+
+	prefs, err := svc.Get(context.Background(), "uid-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if prefs.UID != "uid-1" || prefs.ReminderTime != "20:00" || len(prefs.ReminderDays) != 7 || prefs.Locale != "en" {
+		t.Fatalf("unexpected defaults: %+v", prefs)
+	}
+}
+
+// Preferences: valid update is persisted with an UpdatedAt timestamp.
+func TestPreferencesUpdate_Valid_Persists(t *testing.T) {
+	repo := newMockPreferencesRepo()
+	svc := NewPreferencesService(repo, usersWith("uid-1"), "secret", "http://localhost:8080")
+
+	updated, err := svc.Update(context.Background(), validPreferences("uid-1"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated.UpdatedAt.IsZero() {
+		t.Error("expected UpdatedAt to be set")
+	}
+
+	saved, ok := repo.prefs["uid-1"]
+	if !ok {
+		t.Fatal("expected preferences to be saved")
+	}
+	if saved.ReminderTime != "21:30" || !saved.DigestOptIn || len(saved.Channels) != 2 {
+		t.Fatalf("unexpected saved preferences: %+v", saved)
+	}
+}
+
+// Preferences: each invalid field is reported as a validation error.
+func TestPreferencesUpdate_Invalid_ReturnsValidationError(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(p *user.Preferences)
+		field  string
+	}{
+		{"bad time", func(p *user.Preferences) { p.ReminderTime = "25:00" }, "reminderTime"},
+		{"empty time", func(p *user.Preferences) { p.ReminderTime = "" }, "reminderTime"},
+		{"unknown day", func(p *user.Preferences) { p.ReminderDays = []string{"monday"} }, "reminderDays"},
+		{"duplicate day", func(p *user.Preferences) { p.ReminderDays = []string{"mon", "mon"} }, "reminderDays"},
+		{"unknown channel", func(p *user.Preferences) { p.Channels = []user.NotificationChannel{"sms"} }, "channels"},
+		{"duplicate channel", func(p *user.Preferences) {
+			p.Channels = []user.NotificationChannel{user.ChannelEmail, user.ChannelEmail}
+		}, "channels"},
+		{"bad locale", func(p *user.Preferences) { p.Locale = "french" }, "locale"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMockPreferencesRepo()
+			svc := NewPreferencesService(repo, usersWith("uid-1"), "secret", "http://localhost:8080")
+
+			prefs := validPreferences("uid-1")
+			tt.mutate(&prefs)
+
+			_, err := svc.Update(context.Background(), prefs)
+			var validationErr *pkgerror.InputValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("expected InputValidationError, got %v", err)
+			}
+			if validationErr.Field != tt.field {
+				t.Errorf("expected field %s, got %s", tt.field, validationErr.Field)
+			}
+			if len(repo.prefs) != 0 {
+				t.Error("expected nothing to be saved")
+			}
+		})
+	}
+}
+
+// Unsubscribe: a signed link disables the digest without touching other settings.
+func TestUnsubscribe_DigestLink_DisablesDigest(t *testing.T) {
+	repo := newMockPreferencesRepo()
+	repo.prefs["uid-1"] = validPreferences("uid-1")
+	svc := NewPreferencesService(repo, usersWith("uid-1"), "secret", "http://localhost:8080")
+
+	token := tokenFromLink(t, svc.UnsubscribeLink("uid-1", user.TopicDigest))
+	if err := svc.Unsubscribe(context.Background(), token); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	saved := repo.prefs["uid-1"]
+	if saved.DigestOptIn {
+		t.Error("expected digest to be disabled")
+	}
+	if len(saved.ReminderDays) != 3 {
+		t.Errorf("expected reminder days to be kept, got %v", saved.ReminderDays)
+	}
+}
+
+// Unsubscribe: the "all" topic stops every email.
+func TestUnsubscribe_AllLink_RemovesEmailChannel(t *testing.T) {
+	repo := newMockPreferencesRepo()
+	repo.prefs["uid-1"] = validPreferences("uid-1")
+	svc := NewPreferencesService(repo, usersWith("uid-1"), "secret", "http://localhost:8080")
+
+	token := tokenFromLink(t, svc.UnsubscribeLink("uid-1", user.TopicAll))
+	if err := svc.Unsubscribe(context.Background(), token); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	saved := repo.prefs["uid-1"]
+	if saved.DigestOptIn || len(saved.ReminderDays) != 0 {
+		t.Errorf("expected digest and reminders off, got %+v", saved)
+	}
+	if len(saved.Channels) != 1 || saved.Channels[0] != user.ChannelPush {
+		t.Errorf("expected only push channel left, got %v", saved.Channels)
+	}
+}
+
+// Unsubscribe: tokens signed with another secret are rejected.
+func TestUnsubscribe_ForgedToken_Rejected(t *testing.T) {
+	repo := newMockPreferencesRepo()
+	svc := NewPreferencesService(repo, usersWith("uid-1"), "secret", "http://localhost:8080")
+	forger := NewPreferencesService(repo, usersWith("uid-1"), "other-secret", "http://localhost:8080")
+
+	token := tokenFromLink(t, forger.UnsubscribeLink("uid-1", user.TopicAll))
+	err := svc.Unsubscribe(context.Background(), token)
+
+	var validationErr *pkgerror.InputValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected InputValidationError, got %v", err)
+	}
+	if len(repo.prefs) != 0 {
+		t.Error("expected nothing to be saved")
+	}
+}
+
+// Unsubscribe: links stop working once unsubscribeTokenTTL has passed.
+func TestUnsubscribe_ExpiredToken_Rejected(t *testing.T) {
+	repo := newMockPreferencesRepo()
+	repo.prefs["uid-1"] = validPreferences("uid-1")
+	svc := NewPreferencesService(repo, usersWith("uid-1"), "secret", "http://localhost:8080").(*preferencesService)
+	issued := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc.timeNow = func() time.Time { return issued }
+	token := tokenFromLink(t, svc.UnsubscribeLink("uid-1", user.TopicDigest))
+
+	svc.timeNow = func() time.Time { return issued.Add(unsubscribeTokenTTL + time.Hour) }
+	err := svc.Unsubscribe(context.Background(), token)
+
+	var validationErr *pkgerror.InputValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected InputValidationError, got %v", err)
+	}
+	if !repo.prefs["uid-1"].DigestOptIn {
+		t.Error("expected digest to stay enabled")
+	}
+}
+
+// Unsubscribe: a valid link for a deleted account saves nothing.
+func TestUnsubscribe_DeletedUser_SavesNothing(t *testing.T) {
+	repo := newMockPreferencesRepo()
+	svc := NewPreferencesService(repo, &deletedUserRepo{}, "secret", "http://localhost:8080")
+
+	token := tokenFromLink(t, svc.UnsubscribeLink("uid-1", user.TopicAll))
+	if err := svc.Unsubscribe(context.Background(), token); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.prefs) != 0 {
+		t.Errorf("expected nothing to be saved, got %+v", repo.prefs)
+	}
+}
+
+func tokenFromLink(t *testing.T, link string) string {
+	t.Helper()
+	if !strings.HasPrefix(link, "http://localhost:8080/unsubscribe?token=") {
+		t.Fatalf("unexpected link: %s", link)
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Query().Get("token")
+}
@@ -31,3 +31,25 @@ func getTimestamp(data map[string]interface{}, key string) (time.Time, error) {
 		return time.Time{}, nil
 	}
 }
+
+func getBool(data map[string]interface{}, key string) bool {
+	if v, ok := data[key].(bool); ok {
+		return v
+	}
+	return false
+}
+
+func getStringSlice(data map[string]interface{}, key string) []string {
+	raw, ok := data[key].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	values := make([]string, 0, len(raw))
+	for _, item := range raw {
+		if s, ok := item.(string); ok {
+			values = append(values, s)
+		}
+	}
+	return values
+}
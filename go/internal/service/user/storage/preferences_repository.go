@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+
+	"energyjournal/internal/domain/user"
+	pkgerror "energyjournal/internal/pkg/error"
+)
+
+const preferencesCollection = "user_preferences"
+
+type PreferencesRepository struct {
+	client *firestore.Client
+}
+
+func NewPreferencesRepository(client *firestore.Client) *PreferencesRepository {
+	return &PreferencesRepository{client: client}
+}
+
+func (r *PreferencesRepository) Get(ctx context.Context, uid string) (*user.Preferences, error) {
+	doc, err := r.client.Collection(preferencesCollection).Doc(uid).Get(ctx)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, pkgerror.NewNotFoundError("user_preferences", uid)
+		}
+		return nil, err
+	}
+
+	return docToPreferences(doc)
+}
+
+func (r *PreferencesRepository) Upsert(ctx context.Context, prefs user.Preferences) error {
+	channels := make([]string, 0, len(prefs.Channels))
+	for _, channel := range prefs.Channels {
+		channels = append(channels, string(channel))
+	}
+
+	_, err := r.client.Collection(preferencesCollection).Doc(prefs.UID).Set(ctx, map[string]interface{}{
+		"uid":          prefs.UID,
+		"reminderTime": prefs.ReminderTime,
+		"reminderDays": prefs.ReminderDays,
+		"digestOptIn":  prefs.DigestOptIn,
+		"channels":     channels,
+		"locale":       prefs.Locale,
+		"updatedAt":    prefs.UpdatedAt,
+	})
+	return err
+}
+
+func docToPreferences(doc *firestore.DocumentSnapshot) (*user.Preferences, error) {
+	data := doc.Data()
+
+	prefs := &user.Preferences{
+		UID:          getString(data, "uid"),
+		ReminderTime: getString(data, "reminderTime"),
+		ReminderDays: getStringSlice(data, "reminderDays"),
+		DigestOptIn:  getBool(data, "digestOptIn"),
+		Locale:       getString(data, "locale"),
+	}
+
+	for _, channel := range getStringSlice(data, "channels") {
+		prefs.Channels = append(prefs.Channels, user.NotificationChannel(channel))
+	}
+
+	if t, err := getTimestamp(data, "updatedAt"); err == nil {
+		prefs.UpdatedAt = t
+	}
+
+	return prefs, nil
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"testing"
+
+	"energyjournal/internal/domain/user"
+	"energyjournal/internal/pkg/storagetest"
+)
+
+func TestSQLUserRepositories(t *testing.T) {
+	t.Run("users", func(t *testing.T) {
+		storagetest.UserRepository(t, func(t *testing.T) user.UserRepository {
+			return NewSQLUserRepository(storagetest.SQLite(t))
+		})
+	})
+	t.Run("activation tokens", func(t *testing.T) {
+		storagetest.ActivationTokenRepository(t, func(t *testing.T) user.ActivationTokenRepository {
+			return NewSQLActivationTokenRepository(storagetest.SQLite(t))
+		})
+	})
+	t.Run("preferences", func(t *testing.T) {
+		storagetest.PreferencesRepository(t, func(t *testing.T) user.PreferencesRepository {
+			return NewSQLPreferencesRepository(storagetest.SQLite(t))
+		})
+	})
+}
+
+func TestPostgresUserRepositories(t *testing.T) {
+	t.Run("users", func(t *testing.T) {
+		storagetest.UserRepository(t, func(t *testing.T) user.UserRepository {
+			return NewSQLUserRepository(storagetest.Postgres(t))
+		})
+	})
+	t.Run("activation tokens", func(t *testing.T) {
+		storagetest.ActivationTokenRepository(t, func(t *testing.T) user.ActivationTokenRepository {
+			return NewSQLActivationTokenRepository(storagetest.Postgres(t))
+		})
+	})
+	t.Run("preferences", func(t *testing.T) {
+		storagetest.PreferencesRepository(t, func(t *testing.T) user.PreferencesRepository {
+			return NewSQLPreferencesRepository(storagetest.Postgres(t))
+		})
+	})
+}
+
+func TestFirestoreUserRepositories(t *testing.T) {
+	t.Run("users", func(t *testing.T) {
+		storagetest.UserRepository(t, func(t *testing.T) user.UserRepository {
+			return NewUserRepository(storagetest.Firestore(t))
+		})
+	})
+	t.Run("activation tokens", func(t *testing.T) {
+		storagetest.ActivationTokenRepository(t, func(t *testing.T) user.ActivationTokenRepository {
+			return NewActivationTokenRepository(storagetest.Firestore(t))
+		})
+	})
+	t.Run("preferences", func(t *testing.T) {
+		storagetest.PreferencesRepository(t, func(t *testing.T) user.PreferencesRepository {
+			return NewPreferencesRepository(storagetest.Firestore(t))
+		})
+	})
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"energyjournal/internal/domain/user"
+	pkgerror "energyjournal/internal/pkg/error"
+	"energyjournal/internal/pkg/sqldb"
+)
+
+type SQLActivationTokenRepository struct {
+	db *sqldb.DB
+}
+
+func NewSQLActivationTokenRepository(db *sqldb.DB) *SQLActivationTokenRepository {
+	return &SQLActivationTokenRepository{db: db}
+}
+
+func (r *SQLActivationTokenRepository) Create(ctx context.Context, token *user.ActivationToken) error {
+	_, err := r.db.Exec(ctx, `INSERT INTO activation_tokens (token, uid, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (token) DO UPDATE SET uid = excluded.uid, expires_at = excluded.expires_at`,
+		token.Token, token.UID, sqldb.Time(token.ExpiresAt))
+	return err
+}
+
+func (r *SQLActivationTokenRepository) GetByToken(ctx context.Context, token string) (*user.ActivationToken, error) {
+	row := r.db.QueryRow(ctx, `SELECT token, uid, expires_at FROM activation_tokens WHERE token = ?`, token)
+	activation, err := scanActivationToken(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, pkgerror.NewNotFoundError("activation_token", token)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return activation, nil
+}
+
+func (r *SQLActivationTokenRepository) Delete(ctx context.Context, token string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM activation_tokens WHERE token = ?`, token)
+	return err
+}
+
+func (r *SQLActivationTokenRepository) FindExpired(ctx context.Context) ([]*user.ActivationToken, error) {
+	rows, err := r.db.Query(ctx, `SELECT token, uid, expires_at FROM activation_tokens WHERE expires_at < ?`, sqldb.Time(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*user.ActivationToken
+	for rows.Next() {
+		token, err := scanActivationToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+func scanActivationToken(row sqldb.Row) (*user.ActivationToken, error) {
+	var token user.ActivationToken
+	if err := row.Scan(&token.Token, &token.UID, sqldb.ScanTime(&token.ExpiresAt)); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
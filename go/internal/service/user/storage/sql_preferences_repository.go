@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"energyjournal/internal/domain/user"
+	pkgerror "energyjournal/internal/pkg/error"
+	"energyjournal/internal/pkg/sqldb"
+)
+
+type SQLPreferencesRepository struct {
+	db *sqldb.DB
+}
+
+func NewSQLPreferencesRepository(db *sqldb.DB) *SQLPreferencesRepository {
+	return &SQLPreferencesRepository{db: db}
+}
+
+func (r *SQLPreferencesRepository) Get(ctx context.Context, uid string) (*user.Preferences, error) {
+	var prefs user.Preferences
+	err := r.db.QueryRow(ctx, `SELECT uid, reminder_time, reminder_days, digest_opt_in, channels, locale, updated_at
+		FROM user_preferences WHERE uid = ?`, uid).Scan(
+		&prefs.UID,
+		&prefs.ReminderTime,
+		sqldb.ScanJSON(&prefs.ReminderDays),
+		&prefs.DigestOptIn,
+		sqldb.ScanJSON(&prefs.Channels),
+		&prefs.Locale,
+		sqldb.ScanTime(&prefs.UpdatedAt),
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, pkgerror.NewNotFoundError("user_preferences", uid)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+func (r *SQLPreferencesRepository) Upsert(ctx context.Context, prefs user.Preferences) error {
+	_, err := r.db.Exec(ctx, `INSERT INTO user_preferences (uid, reminder_time, reminder_days, digest_opt_in, channels, locale, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (uid) DO UPDATE SET
+			reminder_time = excluded.reminder_time,
+			reminder_days = excluded.reminder_days,
+			digest_opt_in = excluded.digest_opt_in,
+			channels = excluded.channels,
+			locale = excluded.locale,
+			updated_at = excluded.updated_at`,
+		prefs.UID,
+		prefs.ReminderTime,
+		sqldb.JSON(nonNil(prefs.ReminderDays)),
+		prefs.DigestOptIn,
+		sqldb.JSON(nonNil(prefs.Channels)),
+		prefs.Locale,
+		sqldb.Time(prefs.UpdatedAt),
+	)
+	return err
+}
+
+// nonNil stores missing lists as empty JSON arrays.
+func nonNil[T any](values []T) []T {
+	if values == nil {
+		return []T{}
+	}
+	return values
+}
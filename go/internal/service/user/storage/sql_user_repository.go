@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"energyjournal/internal/domain/user"
+	pkgerror "energyjournal/internal/pkg/error"
+	"energyjournal/internal/pkg/sqldb"
+)
+
+type SQLUserRepository struct {
+	db *sqldb.DB
+}
+
+func NewSQLUserRepository(db *sqldb.DB) *SQLUserRepository {
+	return &SQLUserRepository{db: db}
+}
+
+// Create stores the user, replacing any user with the same UID like the
+// Firestore repository does.
+func (r *SQLUserRepository) Create(ctx context.Context, u *user.User) error {
+	return r.save(ctx, u)
+}
+
+func (r *SQLUserRepository) GetByUID(ctx context.Context, uid string) (*user.User, error) {
+	var (
+		u         user.User
+		status    string
+		deletedAt time.Time
+	)
+	err := r.db.QueryRow(ctx, `SELECT uid, email, firstname, lastname, timezone, status, created_at, deleted_at
+		FROM users WHERE uid = ?`, uid).Scan(
+		&u.UID,
+		&u.Email,
+		&u.FirstName,
+		&u.LastName,
+		&u.Timezone,
+		&status,
+		sqldb.ScanTime(&u.CreatedAt),
+		sqldb.ScanTime(&deletedAt),
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, pkgerror.NewNotFoundError("user", uid)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	u.Status = user.UserStatus(status)
+	if !deletedAt.IsZero() {
+		u.DeletedAt = &deletedAt
+	}
+	return &u, nil
+}
+
+func (r *SQLUserRepository) Update(ctx context.Context, u *user.User) error {
+	return r.save(ctx, u)
+}
+
+func (r *SQLUserRepository) save(ctx context.Context, u *user.User) error {
+	var deletedAt time.Time
+	if u.DeletedAt != nil {
+		deletedAt = *u.DeletedAt
+	}
+	_, err := r.db.Exec(ctx, `INSERT INTO users (uid, email, firstname, lastname, timezone, status, created_at, deleted_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (uid) DO UPDATE SET
+			email = excluded.email,
+			firstname = excluded.firstname,
+			lastname = excluded.lastname,
+			timezone = excluded.timezone,
+			status = excluded.status,
+			created_at = excluded.created_at,
+			deleted_at = excluded.deleted_at`,
+		u.UID,
+		u.Email,
+		u.FirstName,
+		u.LastName,
+		u.Timezone,
+		string(u.Status),
+		sqldb.Time(u.CreatedAt),
+		sqldb.Time(deletedAt),
+	)
+	return err
+}
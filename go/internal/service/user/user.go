@@ -7,8 +7,19 @@ import (
 	"fmt"
 	"time"
 
+	"go.opentelemetry.io/otel"
+
 	"energyjournal/internal/domain/user"
 	pkgerror "energyjournal/internal/pkg/error"
+	"energyjournal/internal/pkg/telemetry"
+)
+
+const scope = "energyjournal/internal/service/user"
+
+var (
+	tracer      = otel.Tracer(scope)
+	signups     = telemetry.Counter(scope, "energyjournal.user.signups", "Accounts created, pending activation.")
+	activations = telemetry.Counter(scope, "energyjournal.user.activations", "Accounts activated.")
 )
 
 type userService struct {
@@ -29,7 +40,10 @@ func NewUserService(userRepo user.UserRepository, tokenRepo user.ActivationToken
 	}
 }
 
-func (s *userService) Create(ctx context.Context, email, password, firstname, lastname, timezone string) (*user.User, error) {
+func (s *userService) Create(ctx context.Context, email, password, firstname, lastname, timezone string) (_ *user.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.Create")
+	defer func() { telemetry.End(span, err) }()
+
 	// Create Firebase Auth user first
 	uid, err := s.authProvider.CreateUser(ctx, email, password)
 	if err != nil {
@@ -70,10 +84,14 @@ func (s *userService) Create(ctx context.Context, email, password, firstname, la
 		return nil, err
 	}
 
+	signups.Add(ctx, 1)
 	return u, nil
 }
 
-func (s *userService) Activate(ctx context.Context, token string) error {
+func (s *userService) Activate(ctx context.Context, token string) (err error) {
+	ctx, span := tracer.Start(ctx, "UserService.Activate")
+	defer func() { telemetry.End(span, err) }()
+
 	activationToken, err := s.tokenRepo.GetByToken(ctx, token)
 	if err != nil {
 		return err
@@ -96,6 +114,7 @@ func (s *userService) Activate(ctx context.Context, token string) error {
 	if err := s.userRepo.Update(ctx, u); err != nil {
 		return err
 	}
+	activations.Add(ctx, 1)
 
 	return s.tokenRepo.Delete(ctx, token)
 }
@@ -159,7 +178,10 @@ func (s *userService) CleanupExpired(ctx context.Context) error {
 	return nil
 }
 
-func (s *userService) Login(ctx context.Context, email, password string) (*user.AuthTokens, error) {
+func (s *userService) Login(ctx context.Context, email, password string) (_ *user.AuthTokens, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.Login")
+	defer func() { telemetry.End(span, err) }()
+
 	tokens, uid, err := s.authProvider.Login(ctx, email, password)
 	if err != nil {
 		return nil, err
@@ -177,7 +199,10 @@ func (s *userService) Login(ctx context.Context, email, password string) (*user.
 	return tokens, nil
 }
 
-func (s *userService) RefreshToken(ctx context.Context, refreshToken string) (*user.AuthTokens, error) {
+func (s *userService) RefreshToken(ctx context.Context, refreshToken string) (_ *user.AuthTokens, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.RefreshToken")
+	defer func() { telemetry.End(span, err) }()
+
 	tokens, uid, err := s.authProvider.RefreshToken(ctx, refreshToken)
 	if err != nil {
 		return nil, err